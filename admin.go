@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// AdminScope is a permission granted to an [AdminToken]
+type AdminScope string
+
+const (
+	// AdminScopeStatus permits reading status (e.g., server status, online players, backups)
+	AdminScopeStatus AdminScope = "status"
+	// AdminScopeCommand permits executing (whitelisted) console commands
+	AdminScopeCommand AdminScope = "command"
+	// AdminScopeBackup permits creating backups
+	AdminScopeBackup AdminScope = "backup"
+	// AdminScopeDestructive permits operations that discard world data (e.g., restoring backups, high-risk console commands)
+	AdminScopeDestructive AdminScope = "destructive"
+	// AdminScopeBan permits applying bans synced from sibling instances
+	AdminScopeBan AdminScope = "ban"
+)
+
+// adminScopes are all known [AdminScope]s
+var adminScopes = []AdminScope{AdminScopeStatus, AdminScopeCommand, AdminScopeBackup, AdminScopeDestructive, AdminScopeBan}
+
+// AdminToken is a named credential used to access the admin api
+type AdminToken struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	// Scopes are the permissions granted to the token (empty grants every scope)
+	Scopes []AdminScope `json:"scopes"`
+}
+
+// Determines whether the token has been granted a scope
+func (at AdminToken) HasScope(scope AdminScope) bool {
+	return len(at.Scopes) == 0 || slices.Contains(at.Scopes, scope)
+}
+
+// Validates the token.
+// Returns an error if the name or token are empty, or a scope is unknown.
+func (at AdminToken) Validate() error {
+	if at.Name == "" || at.Token == "" {
+		return fmt.Errorf("invalid admin token for %s (name and token must be set)", at.Name)
+	}
+	for _, scope := range at.Scopes {
+		if !slices.Contains(adminScopes, scope) {
+			return fmt.Errorf("admin token %s has unknown scope %s (expected one of %v)", at.Name, scope, adminScopes)
+		}
+	}
+	return nil
+}
+
+// Parses admin tokens formatted as 'name:token' (granting every scope) or 'name:token:scope+scope' (e.g., 'grafana:abc123:status').
+// Returns an error if a token is malformed.
+func ParseAdminTokens(values []string) ([]AdminToken, error) {
+	tokens := []AdminToken{}
+	for _, value := range values {
+		parts := strings.SplitN(value, ":", 3)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid admin token for %s (expected 'name:token' or 'name:token:scopes')", parts[0])
+		}
+		token := AdminToken{Name: parts[0], Token: parts[1]}
+		if len(parts) == 3 {
+			for _, scope := range strings.Split(parts[2], "+") {
+				token.Scopes = append(token.Scopes, AdminScope(scope))
+			}
+		}
+		err := token.Validate()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// Loads admin tokens from a JSON file (formatted as '{"tokens": [{"name": ..., "token": ..., "scopes": [...]}]}').
+// Returns an error if the file cannot be read or a token is invalid.
+func LoadAdminTokens(ctx context.Context, file string) ([]AdminToken, error) {
+	helper.Logger(ctx).Info("load admin tokens", "path", file)
+	data := struct {
+		Tokens []AdminToken `json:"tokens"`
+	}{}
+	err := helper.UnmarshalFile(ctx, file, &data)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range data.Tokens {
+		err := token.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data.Tokens, nil
+}
+
+// AdminApi is an authenticated http api used to administer the server
+type AdminApi struct {
+	Auditor    *Auditor
+	BackupOpts BackupOpts
+	// BanSync (if non-nil) applies bans received from sibling instances (with 'POST /api/bans')
+	BanSync *BanSync
+	// ControlPlane (if non-nil) reloads the entrypoint configuration (with 'POST /api/reload')
+	ControlPlane *ControlPlane
+	// GamePort is the port the server answers steam server queries on (used by 'GET /status')
+	GamePort int
+	// LogFilter filters the server's output (reconfigured with 'PUT /api/loglevel')
+	LogFilter *ServerLogFilter
+	// Macros (if non-nil) lists and runs macros (with 'GET /api/macros' and 'POST /api/macros/{name}')
+	Macros *MacroRunner
+	// Metrics (if non-nil) serves the recorded population and performance history to grafana (with 'POST /grafana/query')
+	Metrics *MetricHistory
+	// Positions (if non-nil) serves the polled positions of online players (with 'GET /api/players/positions')
+	Positions *PositionTracker
+	Tokens    []AdminToken
+	// TrustedProxies are the proxies whose 'X-Forwarded-For' headers identify clients (in logs, audit records and authentication rate limits)
+	TrustedProxies TrustedProxies
+	Whitelist      CommandWhitelist
+	limiter        *authLimiter
+}
+
+// Authenticates a request by its bearer token.
+// Returns the matching token (or nil if the request is unauthenticated).
+func (aa *AdminApi) authenticate(request *http.Request) *AdminToken {
+	value, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil
+	}
+	for _, token := range aa.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token.Token), []byte(value)) == 1 {
+			return &token
+		}
+	}
+	return nil
+}
+
+// adminHandlerFunc is an http handler invoked with the authenticated [AdminToken]
+type adminHandlerFunc func(writer http.ResponseWriter, request *http.Request, token AdminToken)
+
+// Wraps an [adminHandlerFunc] - rejecting unauthenticated requests and requests whose token lacks [scope]
+func (aa *AdminApi) authenticated(scope AdminScope, handler adminHandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		client := GetClientAddr(request.Context())
+		if !aa.limiter.Allow(client) {
+			writeJson(writer, http.StatusTooManyRequests, map[string]any{"error": "too many failed authentication attempts"})
+			return
+		}
+		token := aa.authenticate(request)
+		if token == nil {
+			aa.limiter.Fail(client)
+			helper.Logger(request.Context()).Warn("admin api authentication failed", "client", client, "path", request.URL.Path)
+			writeJson(writer, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+			return
+		}
+		if !token.HasScope(scope) {
+			writeJson(writer, http.StatusForbidden, map[string]any{"error": fmt.Sprintf("token lacks scope %s", scope)})
+			return
+		}
+		handler(writer, request, *token)
+	}
+}
+
+// Writes a JSON response
+func writeJson(writer http.ResponseWriter, status int, data any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(data)
+}
+
+// Handles 'POST /api/command' - executing a whitelisted console command (and recording an audit record)
+func (aa *AdminApi) handleCommand(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	body := struct {
+		Command string `json:"command"`
+	}{}
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil || strings.TrimSpace(body.Command) == "" {
+		writeJson(writer, http.StatusBadRequest, map[string]any{"error": "request body must be a JSON object with a 'command'"})
+		return
+	}
+	if getRiskyCommandPaths(body.Command) != nil && !token.HasScope(AdminScopeDestructive) {
+		writeJson(writer, http.StatusForbidden, map[string]any{"error": fmt.Sprintf("token lacks scope %s", AdminScopeDestructive)})
+		return
+	}
+	output, err := ExecAuditedCommand(request.Context(), aa.Auditor, aa.Whitelist, token.Name, "api", body.Command)
+	if errors.Is(err, ErrCommandNotAllowed) {
+		writeJson(writer, http.StatusForbidden, map[string]any{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrMultilineCommand) {
+		writeJson(writer, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJson(writer, http.StatusBadGateway, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(writer, http.StatusOK, map[string]any{"output": output})
+}
+
+// Records an audit record for an admin action (other than console commands)
+func (aa *AdminApi) audit(ctx context.Context, token AdminToken, action string, err error) {
+	record := AuditRecord{Time: time.Now(), Actor: token.Name, Source: "api", Command: action, Allowed: true, Success: err == nil}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	aa.Auditor.Record(ctx, record)
+}
+
+// Handles 'GET /api/players' - listing online players (including their ping)
+func (aa *AdminApi) handleListPlayers(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	players, err := ListPlayers(request.Context())
+	if err != nil {
+		writeJson(writer, http.StatusBadGateway, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(writer, http.StatusOK, map[string]any{"players": players})
+}
+
+// StatusPlayer is an online player reported by 'GET /status'
+type StatusPlayer struct {
+	Name       string `json:"name"`
+	PlatformId string `json:"platformId"`
+	Level      int    `json:"level"`
+	Ping       int    `json:"ping"`
+}
+
+// Handles 'GET /status' - aggregating the internal status (state, uptime, scheduled restarts and backups), telnet data (online players, in-game time) and the steam server query for dashboards.
+// Data sources that fail are omitted and reported in 'errors'.
+func (aa *AdminApi) handleStatus(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	ctx := request.Context()
+	response := struct {
+		ServerStatus
+		OnlinePlayers []StatusPlayer    `json:"onlinePlayers,omitempty"`
+		Time          *GameTime         `json:"time,omitempty"`
+		Query         *QueryInfo        `json:"query,omitempty"`
+		Errors        map[string]string `json:"errors,omitempty"`
+	}{Errors: map[string]string{}}
+	tracker := GetStatusTracker(ctx)
+	if tracker != nil {
+		response.ServerStatus = tracker.Get()
+	}
+	if response.State == ServerStateReady {
+		players, err := ListPlayers(ctx)
+		if err == nil {
+			response.OnlinePlayers = []StatusPlayer{}
+			for _, player := range players {
+				response.OnlinePlayers = append(response.OnlinePlayers, StatusPlayer{Name: player.Name, PlatformId: player.PlatformId, Level: player.Level, Ping: player.Ping})
+			}
+			response.Players = len(players)
+		} else {
+			response.Errors["players"] = err.Error()
+		}
+		gameTime, err := GetGameTime(ctx)
+		if err == nil {
+			response.Time = &gameTime
+		} else {
+			response.Errors["time"] = err.Error()
+		}
+		if aa.GamePort != 0 {
+			info, err := QueryServerInfo(ctx, fmt.Sprintf("localhost:%d", aa.GamePort), 2*time.Second)
+			if err == nil {
+				response.Query = &info
+			} else {
+				response.Errors["query"] = err.Error()
+			}
+		}
+	}
+	writeJson(writer, http.StatusOK, response)
+}
+
+// Handles 'GET /api/backups' - listing scheduled and manual backups
+func (aa *AdminApi) handleListBackups(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	backups, err := ListBackups(request.Context())
+	if err != nil {
+		writeJson(writer, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(writer, http.StatusOK, map[string]any{"backups": backups})
+}
+
+// Handles 'POST /api/backups' - creating a manual backup (with an optional label)
+func (aa *AdminApi) handleCreateBackup(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	body := struct {
+		Label string `json:"label"`
+	}{}
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeJson(writer, http.StatusBadRequest, map[string]any{"error": "request body must be a JSON object"})
+		return
+	}
+	opts := aa.BackupOpts
+	opts.Label = body.Label
+	metadata, err := CreateBackup(request.Context(), opts)
+	aa.audit(request.Context(), token, strings.TrimSpace(fmt.Sprintf("backup create %s", body.Label)), err)
+	if err != nil {
+		writeJson(writer, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(writer, http.StatusOK, metadata)
+}
+
+// Handles 'POST /api/backups/{name}/restore' - requesting that a backup (by name or label) be restored, and then shutting down the server so that it's restored on the next start
+func (aa *AdminApi) handleRestoreBackup(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	name := request.PathValue("name")
+	metadata, err := RequestBackupRestore(request.Context(), name)
+	aa.audit(request.Context(), token, fmt.Sprintf("backup restore %s", name), err)
+	if err != nil {
+		writeJson(writer, http.StatusNotFound, map[string]any{"error": err.Error()})
+		return
+	}
+	go ShutdownServer(context.WithoutCancel(request.Context()))
+	writeJson(writer, http.StatusAccepted, metadata)
+}
+
+// Handles 'POST /api/bans' - applying a ban (or unban) propagated by a sibling instance (see [BanSync])
+func (aa *AdminApi) handleSyncBan(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	if aa.BanSync == nil {
+		writeJson(writer, http.StatusNotFound, map[string]any{"error": "ban sync disabled"})
+		return
+	}
+	record := BanRecord{}
+	err := json.NewDecoder(request.Body).Decode(&record)
+	if err == nil {
+		err = record.Validate()
+	}
+	if err != nil {
+		writeJson(writer, http.StatusBadRequest, map[string]any{"error": fmt.Sprintf("invalid ban: %s", err.Error())})
+		return
+	}
+	err = aa.BanSync.Apply(request.Context(), record)
+	aa.audit(request.Context(), token, record.Command(), err)
+	if err != nil {
+		writeJson(writer, http.StatusBadGateway, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(writer, http.StatusOK, record)
+}
+
+// Creates the http handler serving the admin api.
+// Clients are rate limited after 10 failed authentication attempts within a minute.
+func (aa *AdminApi) Handler(ctx context.Context) http.Handler {
+	aa.limiter = newAuthLimiter(10, time.Minute)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", aa.authenticated(AdminScopeStatus, aa.handleStatus))
+	mux.HandleFunc("POST /api/command", aa.authenticated(AdminScopeCommand, aa.handleCommand))
+	mux.HandleFunc("GET /api/players", aa.authenticated(AdminScopeStatus, aa.handleListPlayers))
+	mux.HandleFunc("GET /api/players/positions", aa.authenticated(AdminScopeStatus, aa.handlePlayerPositions))
+	mux.HandleFunc("GET /api/backups", aa.authenticated(AdminScopeStatus, aa.handleListBackups))
+	mux.HandleFunc("POST /api/backups", aa.authenticated(AdminScopeBackup, aa.handleCreateBackup))
+	mux.HandleFunc("POST /api/backups/{name}/restore", aa.authenticated(AdminScopeDestructive, aa.handleRestoreBackup))
+	mux.HandleFunc("POST /api/bans", aa.authenticated(AdminScopeBan, aa.handleSyncBan))
+	mux.HandleFunc("GET /api/macros", aa.authenticated(AdminScopeStatus, aa.handleListMacros))
+	mux.HandleFunc("POST /api/macros/{name}", aa.authenticated(AdminScopeCommand, aa.handleRunMacro))
+	mux.HandleFunc("POST /api/reload", aa.authenticated(AdminScopeCommand, aa.handleReload))
+	mux.HandleFunc("GET /api/loglevel", aa.authenticated(AdminScopeStatus, aa.handleGetLogLevel))
+	mux.HandleFunc("PUT /api/loglevel", aa.authenticated(AdminScopeCommand, aa.handleSetLogLevel))
+	mux.HandleFunc("DELETE /api/loglevel", aa.authenticated(AdminScopeCommand, aa.handleSetLogLevel))
+	mux.HandleFunc("GET /grafana", aa.authenticated(AdminScopeStatus, aa.handleGrafanaTest))
+	mux.HandleFunc("POST /grafana/search", aa.authenticated(AdminScopeStatus, aa.handleGrafanaSearch))
+	mux.HandleFunc("POST /grafana/metrics", aa.authenticated(AdminScopeStatus, aa.handleGrafanaSearch))
+	mux.HandleFunc("POST /grafana/query", aa.authenticated(AdminScopeStatus, aa.handleGrafanaQuery))
+	return aa.TrustedProxies.Handler(ctx, "admin api", mux)
+}
+
+// Serves the admin api until the context is cancelled.
+// Returns an error if the server fails to listen.
+func (aa *AdminApi) Run(ctx context.Context, addr string) error {
+	helper.Logger(ctx).Info("start admin api", "addr", addr)
+	handler := aa.Handler(ctx)
+	server := http.Server{
+		Addr: addr,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+		Handler: handler,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// afkMoveDistance is the distance (in blocks) a player must move to be considered active
+const afkMoveDistance = 1.0
+
+// AfkPolicyOpts defines the options used in conjunction with the [AfkPolicy]
+type AfkPolicyOpts struct {
+	Exempt     []string
+	FreeSlots  int
+	MaxPlayers int
+	Timeout    time.Duration
+}
+
+// AfkPolicy tracks player activity (movement and chat) and kicks idle players when the server is near capacity
+type AfkPolicy struct {
+	Opts       AfkPolicyOpts
+	lastActive map[string]time.Time
+	positions  map[string]string
+}
+
+// Creates a new [AfkPolicy]
+func NewAfkPolicy(opts AfkPolicyOpts) *AfkPolicy {
+	return &AfkPolicy{Opts: opts, lastActive: map[string]time.Time{}, positions: map[string]string{}}
+}
+
+// Parses a 'listplayers' position (e.g., '(-1204.5, 61.1, 402.8)').
+// Returns false if the position is malformed.
+func parsePosition(value string) ([3]float64, bool) {
+	position := [3]float64{}
+	parts := strings.Split(strings.Trim(value, "()"), ",")
+	if len(parts) != 3 {
+		return position, false
+	}
+	for index, part := range parts {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return position, false
+		}
+		position[index] = parsed
+	}
+	return position, true
+}
+
+// Checks whether a player has moved between two 'listplayers' positions
+func hasMoved(from string, to string) bool {
+	fromPosition, ok := parsePosition(from)
+	toPosition, ok2 := parsePosition(to)
+	if !ok || !ok2 {
+		return from != to
+	}
+	distance := 0.0
+	for index := range fromPosition {
+		distance += math.Pow(toPosition[index]-fromPosition[index], 2)
+	}
+	return math.Sqrt(distance) > afkMoveDistance
+}
+
+// Marks a player as active (e.g., when they chat)
+func (ap *AfkPolicy) Touch(entityId string, now time.Time) {
+	ap.lastActive[entityId] = now
+}
+
+// Updates player activity from the online players - players that moved (or just joined) are marked active.
+// Players that are no longer online are forgotten.
+func (ap *AfkPolicy) Observe(players []OnlinePlayer, now time.Time) {
+	online := map[string]bool{}
+	for _, player := range players {
+		online[player.EntityId] = true
+		position, ok := ap.positions[player.EntityId]
+		if !ok || hasMoved(position, player.Position) {
+			ap.Touch(player.EntityId, now)
+		}
+		ap.positions[player.EntityId] = player.Position
+	}
+	for entityId := range ap.positions {
+		if !online[entityId] {
+			delete(ap.positions, entityId)
+			delete(ap.lastActive, entityId)
+		}
+	}
+}
+
+// Gets the idle players that should be kicked to free slots - the longest idle players first.
+// Returns nothing unless the server is near capacity (i.e., fewer than the policy's free slots are available).
+func (ap *AfkPolicy) Idle(players []OnlinePlayer, now time.Time) []OnlinePlayer {
+	needed := len(players) - (ap.Opts.MaxPlayers - ap.Opts.FreeSlots)
+	if needed <= 0 {
+		return nil
+	}
+	idle := []OnlinePlayer{}
+	for _, player := range players {
+		if player.MatchesId(ap.Opts.Exempt) {
+			continue
+		}
+		if now.Sub(ap.lastActive[player.EntityId]) >= ap.Opts.Timeout {
+			idle = append(idle, player)
+		}
+	}
+	sort.Slice(idle, func(i int, j int) bool {
+		return ap.lastActive[idle[i].EntityId].Before(ap.lastActive[idle[j].EntityId])
+	})
+	if len(idle) > needed {
+		idle = idle[:needed]
+	}
+	return idle
+}
+
+// Applies the policy to the online players - kicking idle players when the server is near capacity
+func (ap *AfkPolicy) Check(ctx context.Context, players []OnlinePlayer, now time.Time) {
+	ap.Observe(players, now)
+	for _, player := range ap.Idle(players, now) {
+		idle := now.Sub(ap.lastActive[player.EntityId]).Round(time.Minute)
+		helper.Logger(ctx).Info("kick idle player", "player", player.Name, "idle", idle, "online", len(players), "max", ap.Opts.MaxPlayers)
+		err := KickPlayer(ctx, player.EntityId, fmt.Sprintf("Kicked for being idle for %s while the server is full", idle))
+		if err != nil {
+			helper.Logger(ctx).Warn("kick player failed", "player", player.Name, "error", err.Error())
+		}
+	}
+}
+
+// Periodically applies an [AfkPolicy] to the online players until the context is cancelled.
+// Chat messages (read from the telnet session) also count as activity.
+// Returns an error if the telnet session is unavailable.
+func RunAfkPolicy(ctx context.Context, interval time.Duration, opts AfkPolicyOpts) error {
+	session := GetTelnetSession(ctx)
+	if session == nil {
+		return ErrTelnetNotConnected
+	}
+	helper.Logger(ctx).Info("start afk policy", "timeout", opts.Timeout, "free-slots", opts.FreeSlots, "max-players", opts.MaxPlayers)
+	lines, unsubscribe := session.Subscribe()
+	defer unsubscribe()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	policy := NewAfkPolicy(opts)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			message := ParseChatMessage(line)
+			if message != nil {
+				policy.Touch(message.EntityId, time.Now())
+			}
+		case <-ticker.C:
+			players, err := ListPlayers(ctx)
+			if err != nil {
+				helper.Logger(ctx).Warn("afk policy check failed", "error", err.Error())
+				continue
+			}
+			policy.Check(ctx, players, time.Now())
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+const (
+	// AlertSpeed is raised when a player moves faster than is possible in-game (e.g., speed hacks, unauthorized teleports)
+	AlertSpeed = "speed"
+	// AlertSpawns is raised when a player spawns items or entities unusually often
+	AlertSpawns = "spawns"
+)
+
+// alertCooldown is the minimum interval between alerts raised for the same player and detector
+const alertCooldown = 10 * time.Minute
+
+// alertSpawnWindow is the window over which item and entity spawns are counted
+const alertSpawnWindow = time.Minute
+
+// alertSpawnCommands are the console commands counted by the [AlertSpawns] detector
+var alertSpawnCommands = []string{"give", "giveself", "spawnentity", "se", "spawnairdrop", "spawnsupplycrate", "spawnwanderinghorde", "spawnscouts"}
+
+// SuspiciousActivity is an alert raised by a detector - including the evidence that triggered it
+type SuspiciousActivity struct {
+	Detector string    `json:"detector"`
+	Player   string    `json:"player"`
+	PlayerId string    `json:"playerId"`
+	Evidence []string  `json:"evidence"`
+	Time     time.Time `json:"time"`
+}
+
+// AlertOpts defines the options used in conjunction with the [AlertMonitor]
+type AlertOpts struct {
+	Exempt     []string
+	MaxSpeed   float64
+	SpawnLimit int
+	WebhookUrl *url.URL
+}
+
+// positionSample is a player position observed at a point in time
+type positionSample struct {
+	Position string
+	Time     time.Time
+}
+
+// spawnSample is an item or entity spawn (i.e., its log line) observed at a point in time
+type spawnSample struct {
+	Line string
+	Time time.Time
+}
+
+// AlertMonitor detects suspicious player activity (inferred from position polling and log lines) and pages admins via webhook
+type AlertMonitor struct {
+	Opts      AlertOpts
+	alerted   map[string]time.Time
+	positions map[string]positionSample
+	spawns    map[string][]spawnSample
+}
+
+// Creates a new [AlertMonitor]
+func NewAlertMonitor(opts AlertOpts) *AlertMonitor {
+	return &AlertMonitor{Opts: opts, alerted: map[string]time.Time{}, positions: map[string]positionSample{}, spawns: map[string][]spawnSample{}}
+}
+
+// Raises an alert - unless an alert was recently raised for the same player and detector
+func (am *AlertMonitor) raise(ctx context.Context, activity SuspiciousActivity) {
+	key := fmt.Sprintf("%s/%s", activity.PlayerId, activity.Detector)
+	last, ok := am.alerted[key]
+	if ok && activity.Time.Sub(last) < alertCooldown {
+		return
+	}
+	am.alerted[key] = activity.Time
+	helper.Logger(ctx).Warn("suspicious activity", "detector", activity.Detector, "player", activity.Player, "evidence", strings.Join(activity.Evidence, "; "))
+	if am.Opts.WebhookUrl != nil {
+		data, _ := json.Marshal(map[string]any{"event": "suspicious-activity", "activity": activity})
+		go postWebhook(ctx, am.Opts.WebhookUrl.String(), data)
+	}
+}
+
+// Checks the online players' movement since the previous poll - raising [AlertSpeed] alerts for players moving faster than the maximum speed
+func (am *AlertMonitor) CheckPositions(ctx context.Context, players []OnlinePlayer, now time.Time) {
+	online := map[string]bool{}
+	for _, player := range players {
+		online[player.EntityId] = true
+		previous, ok := am.positions[player.EntityId]
+		am.positions[player.EntityId] = positionSample{Position: player.Position, Time: now}
+		if !ok || player.MatchesId(am.Opts.Exempt) {
+			continue
+		}
+		from, ok := parsePosition(previous.Position)
+		to, ok2 := parsePosition(player.Position)
+		elapsed := now.Sub(previous.Time).Seconds()
+		if !ok || !ok2 || elapsed <= 0 {
+			continue
+		}
+		// only horizontal movement is considered - falling is fast, and y is relative to the terrain
+		distance := math.Hypot(to[0]-from[0], to[2]-from[2])
+		speed := distance / elapsed
+		if speed <= am.Opts.MaxSpeed {
+			continue
+		}
+		am.raise(ctx, SuspiciousActivity{
+			Detector: AlertSpeed,
+			Player:   player.Name,
+			PlayerId: player.PlatformId,
+			Evidence: []string{
+				fmt.Sprintf("%s at %s", previous.Position, previous.Time.Format(time.RFC3339)),
+				fmt.Sprintf("%s at %s", player.Position, now.Format(time.RFC3339)),
+				fmt.Sprintf("%.0f blocks in %.0fs (%.1f blocks/s, limit %.1f)", distance, elapsed, speed, am.Opts.MaxSpeed),
+			},
+			Time: now,
+		})
+	}
+	for entityId := range am.positions {
+		if !online[entityId] {
+			delete(am.positions, entityId)
+		}
+	}
+}
+
+// Checks a log line for item and entity spawns - raising [AlertSpawns] alerts for players exceeding the spawn limit within the spawn window
+func (am *AlertMonitor) CheckLine(ctx context.Context, line string, now time.Time) {
+	command := ParseClientCommand(line)
+	if command == nil || matchesPlayerId(am.Opts.Exempt, command.ClientId) {
+		return
+	}
+	fields := strings.Fields(command.Command)
+	if len(fields) == 0 || !slices.Contains(alertSpawnCommands, strings.ToLower(fields[0])) {
+		return
+	}
+	spawns := []spawnSample{}
+	for _, spawn := range am.spawns[command.ClientId] {
+		if now.Sub(spawn.Time) < alertSpawnWindow {
+			spawns = append(spawns, spawn)
+		}
+	}
+	spawns = append(spawns, spawnSample{Line: strings.TrimSpace(line), Time: now})
+	am.spawns[command.ClientId] = spawns
+	if len(spawns) <= am.Opts.SpawnLimit {
+		return
+	}
+	evidence := []string{fmt.Sprintf("%d spawns in %s (limit %d)", len(spawns), alertSpawnWindow, am.Opts.SpawnLimit)}
+	for _, spawn := range spawns {
+		evidence = append(evidence, spawn.Line)
+	}
+	am.raise(ctx, SuspiciousActivity{
+		Detector: AlertSpawns,
+		Player:   command.ClientId,
+		PlayerId: command.ClientId,
+		Evidence: evidence,
+		Time:     now,
+	})
+}
+
+// Runs the detectors until the context is cancelled - polling player positions at the given interval and reading log lines from the telnet session.
+// Returns an error if the telnet session is unavailable.
+func (am *AlertMonitor) Run(ctx context.Context, interval time.Duration) error {
+	session := GetTelnetSession(ctx)
+	if session == nil {
+		return ErrTelnetNotConnected
+	}
+	helper.Logger(ctx).Info("start suspicious activity alerts", "max-speed", am.Opts.MaxSpeed, "spawn-limit", am.Opts.SpawnLimit)
+	lines, unsubscribe := session.Subscribe()
+	defer unsubscribe()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			am.CheckLine(ctx, line, time.Now())
+		case <-ticker.C:
+			players, err := ListPlayers(ctx)
+			if err != nil {
+				helper.Logger(ctx).Warn("suspicious activity check failed", "error", err.Error())
+				continue
+			}
+			am.CheckPositions(ctx, players, time.Now())
+		}
+	}
+}
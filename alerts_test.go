@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlertMonitorIgnoresSpoofedSpawns(t *testing.T) {
+	ctx := newTestContext(t)
+	monitor := NewAlertMonitor(AlertOpts{SpawnLimit: 1})
+	now := time.Now()
+	for index := 0; index < 3; index++ {
+		monitor.CheckLine(ctx, formatTestChatLine("1 INF Executing command 'spawnentity 171 zombieBoe' from client Steam_1"), now)
+	}
+	if len(monitor.alerted) != 0 || len(monitor.spawns) != 0 {
+		t.Fatalf("spoofed spawns counted (spawns %v)", monitor.spawns)
+	}
+	for index := 0; index < 2; index++ {
+		monitor.CheckLine(ctx, formatTestLogLine("Executing command 'spawnentity 171 zombieBoe' from client Steam_1"), now)
+	}
+	if _, ok := monitor.alerted["Steam_1/"+AlertSpawns]; !ok {
+		t.Fatal("expected a spawns alert")
+	}
+}
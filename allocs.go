@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// DefaultAllocsFixesUrl is the release of Alloc's server fixes installed when enabled - the latest release, which targets the latest stable game build
+const DefaultAllocsFixesUrl = "https://illy.bz/fi/7dtd/server_fixes.tar.gz"
+
+// allocsWebTokenLevel is the permission level granted to web tokens created for Alloc's server fixes
+const allocsWebTokenLevel = 0
+
+// Creates web api tokens (used by Alloc's web map and api) with the 'webtokens' console command.
+// Returns an error if a token cannot be created.
+func CreateWebTokens(ctx context.Context, tokens []AdminToken) error {
+	for _, token := range tokens {
+		helper.Logger(ctx).Info("create web token", "name", token.Name)
+		_, err := SendCommand(ctx, fmt.Sprintf("webtokens add %s %s %d", token.Name, token.Token, allocsWebTokenLevel))
+		if err != nil {
+			return fmt.Errorf("create web token %s: %w", token.Name, err)
+		}
+	}
+	return nil
+}
+
+// Checks whether an http endpoint responds (with any non-server-error status).
+// Returns an error if the endpoint is unreachable or fails.
+func checkHttpEndpoint(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 500 {
+		return fmt.Errorf("endpoint %s returned status %d", url, response.StatusCode)
+	}
+	return nil
+}
+
+// Periodically checks http endpoints served by the server (e.g., Alloc's web map) and records their health in the status until the context is cancelled.
+func RunEndpointChecks(ctx context.Context, interval time.Duration, endpoints map[string]string) {
+	tracker := GetStatusTracker(ctx)
+	if tracker == nil {
+		return
+	}
+	for {
+		statuses := map[string]EndpointStatus{}
+		for name, url := range endpoints {
+			err := checkHttpEndpoint(ctx, url)
+			status := EndpointStatus{Url: url, Healthy: err == nil, CheckedAt: time.Now()}
+			if err != nil {
+				status.Error = err.Error()
+				helper.Logger(ctx).Warn("endpoint unhealthy", "endpoint", name, "error", err.Error())
+			}
+			statuses[name] = status
+		}
+		tracker.Update(ctx, func(status *ServerStatus) {
+			status.Endpoints = statuses
+		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
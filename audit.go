@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// AuditRecord records a console command executed on behalf of an admin
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	Source  string    `json:"source"`
+	Address string    `json:"address,omitempty"`
+	Command string    `json:"command"`
+	Allowed bool      `json:"allowed"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+	Output  []string  `json:"output,omitempty"`
+}
+
+// Auditor appends [AuditRecord]s to an audit log file (as JSON lines) and optionally posts them to a webhook
+type Auditor struct {
+	file       string
+	lock       sync.Mutex
+	webhookUrl *url.URL
+	webhooks   sync.WaitGroup
+}
+
+// Creates a new [Auditor] writing to '[data]/audit.log' (and posting to [webhookUrl] if non-nil)
+func NewAuditor(ctx context.Context, webhookUrl *url.URL) *Auditor {
+	return &Auditor{file: filepath.Join(helper.Dirs(ctx)["data"], "audit.log"), webhookUrl: webhookUrl}
+}
+
+// Records an audit record - failures are logged and otherwise ignored.
+// Webhooks are posted asynchronously.
+func (a *Auditor) Record(ctx context.Context, record AuditRecord) {
+	if record.Address == "" {
+		record.Address = GetClientAddr(ctx)
+	}
+	helper.Logger(ctx).Info("audit", "actor", record.Actor, "source", record.Source, "address", record.Address, "command", record.Command, "allowed", record.Allowed, "success", record.Success)
+	data, err := json.Marshal(record)
+	if err != nil {
+		helper.Logger(ctx).Warn("audit record failed", "error", err.Error())
+		return
+	}
+	a.lock.Lock()
+	handle, err := os.OpenFile(a.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		_, err = handle.Write(append(data, '\n'))
+		handle.Close()
+	}
+	a.lock.Unlock()
+	if err != nil {
+		helper.Logger(ctx).Warn("audit record failed", "error", err.Error())
+	}
+	if a.webhookUrl != nil {
+		a.webhooks.Add(1)
+		go func() {
+			defer a.webhooks.Done()
+			postWebhook(ctx, a.webhookUrl.String(), data)
+		}()
+	}
+}
+
+// Waits for pending webhooks to be posted
+func (a *Auditor) Flush() {
+	a.webhooks.Wait()
+}
+
+// Posts a JSON payload to a webhook - failures are logged and otherwise ignored.
+func postWebhook(ctx context.Context, url string, data []byte) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		helper.Logger(ctx).Warn("webhook failed", "error", err.Error())
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := getHttpClient(ctx).Do(request)
+	if err != nil {
+		helper.Logger(ctx).Warn("webhook failed", "error", err.Error())
+		return
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		helper.Logger(ctx).Warn("webhook failed", "status", response.StatusCode)
+	}
+}
+
+// CommandWhitelist restricts the console commands (by name) that can be executed on behalf of admins.
+// An empty whitelist permits every command.
+type CommandWhitelist []string
+
+// Determines whether a console command is permitted by the whitelist.
+// Every line of the command must be permitted (the console executes each line as a separate command).
+func (cw CommandWhitelist) Allows(command string) bool {
+	if len(cw) == 0 {
+		return true
+	}
+	lines := strings.FieldsFunc(command, func(r rune) bool {
+		return r == '\r' || r == '\n'
+	})
+	if len(lines) == 0 {
+		lines = []string{command}
+	}
+	for _, line := range lines {
+		name, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if !slices.ContainsFunc(cw, func(allowed string) bool {
+			return strings.EqualFold(allowed, name)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrCommandNotAllowed is returned when a console command is not permitted by the [CommandWhitelist]
+var ErrCommandNotAllowed = fmt.Errorf("command not allowed")
+
+// Executes a console command on behalf of an admin - checking it against the whitelist and recording an audit record.
+// High-risk commands (see [riskyCommands]) are preceded by a snapshot of the files they affect.
+// Returns the command output.
+// Returns an error if the command contains line breaks or is not allowed.
+// Returns an error if a high-risk command cannot be snapshotted.
+// Returns an error if the command fails.
+func ExecAuditedCommand(ctx context.Context, auditor *Auditor, whitelist CommandWhitelist, actor string, source string, command string) ([]string, error) {
+	record := AuditRecord{Time: time.Now(), Actor: actor, Source: source, Command: command, Allowed: whitelist.Allows(command)}
+	err := checkCommandLine(command)
+	if err != nil {
+		record.Allowed = false
+		record.Error = err.Error()
+		auditor.Record(ctx, record)
+		return nil, err
+	}
+	if !record.Allowed {
+		record.Error = ErrCommandNotAllowed.Error()
+		auditor.Record(ctx, record)
+		return nil, fmt.Errorf("%w: %s", ErrCommandNotAllowed, command)
+	}
+	paths := getRiskyCommandPaths(command)
+	if paths != nil {
+		_, err := CreateSnapshot(ctx, actor, command, paths)
+		if err != nil {
+			// the command is refused rather than run without a way to undo it
+			record.Error = fmt.Sprintf("snapshot failed: %s", err.Error())
+			auditor.Record(ctx, record)
+			return nil, fmt.Errorf("snapshot failed: %w", err)
+		}
+	}
+	output, err := SendCommand(ctx, command)
+	record.Output = output
+	record.Success = err == nil
+	if err != nil {
+		record.Error = err.Error()
+	}
+	auditor.Record(ctx, record)
+	return output, err
+}
+
+// Executes a console command passed as arguments to the 'exec' subcommand (e.g., 'entrypoint exec say "hello"') and prints its output.
+// Commands executed via the cli are not subject to the command whitelist - but are recorded in the audit log.
+// Returns an error if no command is provided.
+// Returns an error if the command fails.
+func ExecSubcommand(ctx context.Context) error {
+	if len(os.Args) < 3 {
+		return fmt.Errorf("usage: %s exec <command>", filepath.Base(os.Args[0]))
+	}
+	config := struct {
+		AuditWebhookUrl *url.URL `env:"AUDIT_WEBHOOK_URL"`
+	}{}
+	err := helper.ParseEnv(ctx, &config)
+	if err != nil {
+		return err
+	}
+	actor := os.Getenv("USER")
+	if actor == "" {
+		actor = "unknown"
+	}
+	auditor := NewAuditor(ctx, config.AuditWebhookUrl)
+	defer auditor.Flush()
+	output, err := ExecAuditedCommand(ctx, auditor, nil, actor, "cli", strings.Join(os.Args[2:], " "))
+	for _, line := range output {
+		fmt.Println(line)
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCommandWhitelistAllows(t *testing.T) {
+	whitelist := CommandWhitelist{"say", "version"}
+	for command, expected := range map[string]bool{
+		"say hi":              true,
+		"SAY hi":              true,
+		"version":             true,
+		"shutdown":            false,
+		"say hi\nshutdown":    false,
+		"say hi\r\nban add x": false,
+		"say hi\nversion":     true,
+	} {
+		if actual := whitelist.Allows(command); actual != expected {
+			t.Errorf("command %q: expected %t, got %t", command, expected, actual)
+		}
+	}
+	if !(CommandWhitelist{}).Allows("say hi\nshutdown") {
+		t.Error("expected an empty whitelist to permit every command")
+	}
+}
+
+func TestExecAuditedCommandRejectsInjectedCommands(t *testing.T) {
+	ctx := newTestContext(t)
+	fake := startFakeTelnet(t)
+	ctx, _ = startTelnetSession(t, ctx)
+	auditor := NewAuditor(ctx, nil)
+	for _, whitelist := range []CommandWhitelist{{"say"}, {}} {
+		_, err := ExecAuditedCommand(ctx, auditor, whitelist, "test", "api", "say hi\nshutdown")
+		if !errors.Is(err, ErrMultilineCommand) {
+			t.Fatalf("expected %v, got %v", ErrMultilineCommand, err)
+		}
+	}
+	// a subsequent command confirms that nothing was written before it
+	_, err := ExecAuditedCommand(ctx, auditor, CommandWhitelist{"say"}, "test", "api", "say done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForCommand(t, fake, "say done", 5*time.Second)
+	if commands := fake.Commands(); slices.Contains(commands, "shutdown") || slices.Contains(commands, "say hi") {
+		t.Fatalf("injected command sent (received %v)", commands)
+	}
+}
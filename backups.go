@@ -0,0 +1,680 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// BackupVerification records the result of verifying a backup archive
+type BackupVerification struct {
+	Status     string    `json:"status"`
+	VerifiedAt time.Time `json:"verifiedAt"`
+	Extracted  bool      `json:"extracted"`
+	Error      string    `json:"error,omitempty"`
+}
+
+const (
+	// BackupVerified indicates that a backup archive matches its checksum manifest
+	BackupVerified = "verified"
+	// BackupCorrupt indicates that a backup archive failed verification
+	BackupCorrupt = "corrupt"
+)
+
+// BackupMetadata describes a backup archive - stored alongside the archive as '[name].json'
+type BackupMetadata struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Label identifies manual backups (e.g., 'pre-darknessfalls-install') - scheduled backups are unlabeled
+	Label string     `json:"label,omitempty"`
+	Mode  BackupMode `json:"mode"`
+	// Size is the size of the archive (for full backups) or the size of the objects added to the object store (for incremental backups)
+	Size int64 `json:"size"`
+	// Files maps archived paths (relative to the data directory) to their sha256 checksums
+	Files map[string]string `json:"files"`
+	// Stats maps archived paths to their size and modification time (for incremental backups)
+	Stats        map[string]BackupFileStat `json:"stats,omitempty"`
+	Verification *BackupVerification       `json:"verification,omitempty"`
+	// Replication maps backup destinations to the outcome of replicating the backup to them
+	Replication map[string]ReplicationResult `json:"replication,omitempty"`
+}
+
+// BackupOpts defines the options used in conjunction with the [CreateBackup] function
+type BackupOpts struct {
+	// Label marks the backup as a manual backup - labeled backups aren't subject to retention
+	Label string
+	Mode  BackupMode
+	// Paused (if non-nil) refuses backups while it returns true (e.g., while free disk space is critically low)
+	Paused func() bool
+	// Targets are the destinations full backups are replicated to
+	Targets []ReplicationTarget
+	// Retention is the number of backups kept (0 keeps every backup)
+	Retention int
+	// VerifyExtract additionally extracts backups to a temporary directory during verification to check that key files are restorable
+	VerifyExtract bool
+	// WebhookUrl (if non-nil) is notified of the outcome of each backup
+	WebhookUrl *url.URL
+}
+
+// Gets the path of a backup archive
+func getBackupArchive(ctx context.Context, name string) string {
+	return filepath.Join(helper.Dirs(ctx)["backups"], fmt.Sprintf("%s.tar.gz", name))
+}
+
+// Gets the path of a backup's metadata file
+func getBackupMetadataFile(ctx context.Context, name string) string {
+	return filepath.Join(helper.Dirs(ctx)["backups"], fmt.Sprintf("%s.json", name))
+}
+
+// Walks the files within 'Saves' in the data directory - invoking [cb] with each file's path relative to the data directory.
+// Returns an error if the saves cannot be walked.
+func walkSaves(ctx context.Context, cb func(relpath string, path string, info os.FileInfo) error) error {
+	data := helper.Dirs(ctx)["data"]
+	return filepath.WalkDir(filepath.Join(data, "Saves"), func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relpath, err := filepath.Rel(data, path)
+		if err != nil {
+			return err
+		}
+		return cb(filepath.ToSlash(relpath), path, info)
+	})
+}
+
+// Writes a file to a tar archive - returning its sha256 checksum.
+// Returns an error if the file cannot be read or written.
+func addTarFile(writer *tar.Writer, relpath string, path string, info os.FileInfo) (string, error) {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return "", err
+	}
+	header.Name = relpath
+	handle, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer handle.Close()
+	err = writer.WriteHeader(header)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	_, err = io.CopyN(io.MultiWriter(writer, hash), handle, header.Size)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Writes the saves within the data directory to a tar.gz archive.
+// Returns the checksum manifest of the archived files.
+// Returns an error if the archive cannot be written.
+func writeBackupArchive(ctx context.Context, archive string) (map[string]string, error) {
+	fail := func(err error) (map[string]string, error) {
+		return nil, err
+	}
+	handle, err := os.Create(archive)
+	if err != nil {
+		return fail(err)
+	}
+	defer handle.Close()
+	gzipWriter := gzip.NewWriter(handle)
+	tarWriter := tar.NewWriter(gzipWriter)
+	files := map[string]string{}
+	err = walkSaves(ctx, func(relpath string, path string, info os.FileInfo) error {
+		checksum, err := addTarFile(tarWriter, relpath, path, info)
+		if err != nil {
+			return err
+		}
+		files[relpath] = checksum
+		return nil
+	})
+	if err != nil {
+		return fail(err)
+	}
+	for _, closer := range []io.Closer{tarWriter, gzipWriter, handle} {
+		err = closer.Close()
+		if err != nil {
+			return fail(err)
+		}
+	}
+	return files, nil
+}
+
+// Reads every entry of a tar.gz archive - computing the sha256 checksum of each file.
+// Returns an error if the archive is unreadable.
+func readBackupChecksums(archive string) (map[string]string, error) {
+	handle, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Close()
+	gzipReader, err := gzip.NewReader(handle)
+	if err != nil {
+		return nil, err
+	}
+	tarReader := tar.NewReader(gzipReader)
+	files := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, tarReader)
+		if err != nil {
+			return nil, err
+		}
+		files[header.Name] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return files, nil
+}
+
+// Checks that an extracted backup contains the key files of every archived save (i.e., 'main.ttw' in each 'Saves/[world]/[game]' folder).
+// Returns an error listing missing key files.
+func checkBackupKeyFiles(dir string, files map[string]string) error {
+	games := map[string]bool{}
+	for path := range files {
+		parts := strings.Split(path, "/")
+		if len(parts) > 3 && parts[0] == "Saves" {
+			games[strings.Join(parts[:3], "/")] = true
+		}
+	}
+	missing := []string{}
+	for game := range games {
+		keyFile := filepath.Join(dir, filepath.FromSlash(game), "main.ttw")
+		_, err := os.Lstat(keyFile)
+		if err != nil {
+			missing = append(missing, fmt.Sprintf("%s/main.ttw", game))
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		return fmt.Errorf("key files missing: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Verifies a backup archive against its checksum manifest - and, if [extract] is set, extracts it to a temporary directory and checks that key files are present.
+// Returns an error describing the verification failure.
+func VerifyBackup(ctx context.Context, archive string, files map[string]string, extract bool) error {
+	helper.Logger(ctx).Info("verify backup", "archive", archive, "extract", extract)
+	actual, err := readBackupChecksums(archive)
+	if err != nil {
+		return fmt.Errorf("archive unreadable: %w", err)
+	}
+	mismatched := []string{}
+	for path, checksum := range files {
+		if actual[path] != checksum {
+			mismatched = append(mismatched, path)
+		}
+	}
+	sort.Strings(mismatched)
+	if len(mismatched) > 0 {
+		return fmt.Errorf("checksum mismatch: %s", strings.Join(mismatched, ", "))
+	}
+	if len(actual) != len(files) {
+		return fmt.Errorf("archive contains %d files (expected %d)", len(actual), len(files))
+	}
+	if !extract {
+		return nil
+	}
+	return helper.CreateTempDir(ctx, func(tempDir string) error {
+		err := helper.Extract(ctx, archive, tempDir)
+		if err != nil {
+			return fmt.Errorf("archive not extractable: %w", err)
+		}
+		return checkBackupKeyFiles(tempDir, files)
+	})
+}
+
+// Gets the path of the lock file serializing backup operations (see [lockBackups])
+func getBackupLockFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["backups"], "backups.lock")
+}
+
+// Acquires the backup lock - an exclusive flock serializing backup creation, pruning and restores across goroutines and processes (e.g., a scheduled backup and the 'backup' subcommand), so that collecting unreferenced objects never deletes the objects of a backup still being written.
+// Waits until the lock is acquired or the context is cancelled.
+// Returns a function releasing the lock.
+// Returns an error if the lock file cannot be opened or locked, or if the context is cancelled.
+func lockBackups(ctx context.Context) (func(), error) {
+	err := helper.CreateDirs(ctx, helper.Dirs(ctx)["backups"])
+	if err != nil {
+		return nil, err
+	}
+	handle, err := os.OpenFile(getBackupLockFile(ctx), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	waiting := false
+	for {
+		err = syscall.Flock(int(handle.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			handle.Close()
+			return nil, err
+		}
+		if !waiting {
+			helper.Logger(ctx).Info("waiting for backup lock")
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			handle.Close()
+			return nil, ctx.Err()
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+	return func() {
+		syscall.Flock(int(handle.Fd()), syscall.LOCK_UN)
+		handle.Close()
+	}, nil
+}
+
+// Lists the metadata of every backup (oldest first).
+// Returns an error if the backups directory or a metadata file is unreadable.
+func ListBackups(ctx context.Context) ([]BackupMetadata, error) {
+	backups := []BackupMetadata{}
+	// only metadata files are matched (see [CreateBackup]) - other files within the backups directory aren't backups
+	matches, err := filepath.Glob(filepath.Join(helper.Dirs(ctx)["backups"], "backup-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		metadata := BackupMetadata{}
+		err := helper.UnmarshalFile(ctx, match, &metadata)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, metadata)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.Before(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// Gets the metadata of the most recent incremental backup.
+// Returns nil if no incremental backups exist.
+// Returns an error if the backups cannot be listed.
+func getLatestIncrementalBackup(ctx context.Context) (*BackupMetadata, error) {
+	backups, err := ListBackups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for index := len(backups) - 1; index >= 0; index-- {
+		if backups[index].Mode == BackupModeIncremental {
+			return &backups[index], nil
+		}
+	}
+	return nil, nil
+}
+
+// Deletes the oldest scheduled (i.e., unlabeled) backups (and their metadata) exceeding the retention count - and then deletes backup objects no longer referenced by incremental backups.
+// Must be called while holding the backup lock (see [lockBackups]).
+// Returns an error if the backups cannot be listed or deleted.
+func PruneBackups(ctx context.Context, retention int) error {
+	if retention <= 0 {
+		return nil
+	}
+	all, err := ListBackups(ctx)
+	if err != nil {
+		return err
+	}
+	backups := []BackupMetadata{}
+	for _, backup := range all {
+		if backup.Label == "" {
+			backups = append(backups, backup)
+		}
+	}
+	for len(backups) > retention {
+		helper.Logger(ctx).Info("prune backup", "name", backups[0].Name)
+		err := helper.RemovePaths(ctx, getBackupArchive(ctx, backups[0].Name), getBackupMetadataFile(ctx, backups[0].Name))
+		if err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	_, err = os.Lstat(getBackupObjectsDir(ctx))
+	if err != nil {
+		return nil
+	}
+	return CollectBackupObjects(ctx)
+}
+
+// Creates a backup of the saves within the data directory - saving the world first (if the server is running), verifying the backup and recording its metadata.
+// Verification failures are recorded in the backup metadata (and logged) rather than returned.
+// Returns the backup metadata.
+// Returns an error if the backup cannot be written.
+func CreateBackup(ctx context.Context, opts BackupOpts) (*BackupMetadata, error) {
+	fail := func(err error) (*BackupMetadata, error) {
+		return nil, err
+	}
+	if opts.Paused != nil && opts.Paused() {
+		return fail(fmt.Errorf("backups paused: %w", ErrLowDisk))
+	}
+	unlock, err := lockBackups(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	defer unlock()
+	err = SaveWorld(ctx)
+	if err != nil {
+		helper.Logger(ctx).Warn("save world failed", "error", err.Error())
+	}
+	err = helper.CreateDirs(ctx, helper.Dirs(ctx)["backups"])
+	if err != nil {
+		return fail(err)
+	}
+	now := time.Now().UTC()
+	metadata := BackupMetadata{Name: fmt.Sprintf("backup-%s", now.Format("20060102T150405.000Z")), CreatedAt: now, Label: opts.Label, Mode: opts.Mode}
+	var verify func() error
+	if opts.Mode == BackupModeIncremental {
+		previous, err := getLatestIncrementalBackup(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		helper.Logger(ctx).Info("create incremental backup", "name", metadata.Name)
+		files, stats, added, err := writeIncrementalBackup(ctx, previous)
+		if err != nil {
+			return fail(err)
+		}
+		metadata.Files = files
+		metadata.Stats = stats
+		for _, size := range added {
+			metadata.Size += size
+		}
+		verify = func() error {
+			return VerifyIncrementalBackup(ctx, metadata, added, opts.VerifyExtract)
+		}
+	} else {
+		metadata.Mode = BackupModeFull
+		archive := getBackupArchive(ctx, metadata.Name)
+		helper.Logger(ctx).Info("create backup", "archive", archive)
+		metadata.Files, err = writeBackupArchive(ctx, archive)
+		if err != nil {
+			helper.RemovePaths(ctx, archive)
+			return fail(err)
+		}
+		info, err := os.Stat(archive)
+		if err != nil {
+			return fail(err)
+		}
+		metadata.Size = info.Size()
+		verify = func() error {
+			return VerifyBackup(ctx, archive, metadata.Files, opts.VerifyExtract)
+		}
+	}
+	verification := BackupVerification{Status: BackupVerified, Extracted: opts.VerifyExtract}
+	err = verify()
+	verification.VerifiedAt = time.Now().UTC()
+	if err != nil {
+		helper.Logger(ctx).Error("backup verification failed", "name", metadata.Name, "error", err.Error())
+		verification.Status = BackupCorrupt
+		verification.Error = err.Error()
+	}
+	metadata.Verification = &verification
+	err = helper.MarshalFile(ctx, metadata, getBackupMetadataFile(ctx, metadata.Name))
+	if err != nil {
+		return fail(err)
+	}
+	helper.Logger(ctx).Info("backup created", "name", metadata.Name, "label", metadata.Label, "files", len(metadata.Files), "size", metadata.Size, "verification", verification.Status)
+	if len(opts.Targets) > 0 && metadata.Mode == BackupModeFull {
+		metadata.Replication = ReplicateBackup(ctx, metadata, opts.Targets)
+		err = helper.MarshalFile(ctx, metadata, getBackupMetadataFile(ctx, metadata.Name))
+		if err != nil {
+			return fail(err)
+		}
+	}
+	reportBackup(ctx, opts, metadata)
+	return &metadata, PruneBackups(ctx, opts.Retention)
+}
+
+// BackupReport summarizes the outcome of a backup - reported in the status file and to the backup webhook
+type BackupReport struct {
+	Name         string                       `json:"name"`
+	CreatedAt    time.Time                    `json:"createdAt"`
+	Verification string                       `json:"verification"`
+	Replication  map[string]ReplicationResult `json:"replication,omitempty"`
+}
+
+// Reports the outcome of a backup to the [StatusTracker] attached to the context (if any) and to the backup webhook (if configured)
+func reportBackup(ctx context.Context, opts BackupOpts, metadata BackupMetadata) {
+	report := BackupReport{Name: metadata.Name, CreatedAt: metadata.CreatedAt, Verification: metadata.Verification.Status, Replication: metadata.Replication}
+	tracker := GetStatusTracker(ctx)
+	if tracker != nil {
+		tracker.Update(ctx, func(status *ServerStatus) {
+			status.LastBackup = &report
+		})
+	}
+	if opts.WebhookUrl == nil {
+		return
+	}
+	data, err := json.Marshal(map[string]any{"event": "backup", "backup": report})
+	if err != nil {
+		return
+	}
+	postWebhook(ctx, opts.WebhookUrl.String(), data)
+}
+
+// Periodically creates backups until the context is cancelled.
+// Failing backups are logged and otherwise ignored.
+func RunBackupSchedule(ctx context.Context, interval time.Duration, opts BackupOpts) {
+	tracker := GetStatusTracker(ctx)
+	for {
+		if tracker != nil {
+			tracker.Update(ctx, func(status *ServerStatus) {
+				nextBackup := time.Now().Add(interval)
+				status.NextBackup = &nextBackup
+			})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		_, err := CreateBackup(ctx, opts)
+		if err != nil {
+			helper.Logger(ctx).Error("backup failed", "error", err.Error())
+		}
+	}
+}
+
+// Finds the most recent backup with the given name or label.
+// Returns an error if the backups cannot be listed.
+// Returns an error if no backup matches.
+func FindBackup(ctx context.Context, nameOrLabel string) (*BackupMetadata, error) {
+	backups, err := ListBackups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for index := len(backups) - 1; index >= 0; index-- {
+		if backups[index].Name == nameOrLabel || backups[index].Label == nameOrLabel {
+			return &backups[index], nil
+		}
+	}
+	return nil, fmt.Errorf("backup %s not found", nameOrLabel)
+}
+
+// Gets the path of the file that records a pending backup restore (kept outside of the backups directory, so that it's never mistaken for backup metadata)
+func getBackupRestoreFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "backup-restore.json")
+}
+
+// Requests that a backup be restored the next time the server starts (saves can't be safely replaced while the server is running).
+// Returns an error if the backup cannot be found.
+// Returns an error if the request cannot be written.
+func RequestBackupRestore(ctx context.Context, nameOrLabel string) (*BackupMetadata, error) {
+	metadata, err := FindBackup(ctx, nameOrLabel)
+	if err != nil {
+		return nil, err
+	}
+	helper.Logger(ctx).Info("request backup restore", "name", metadata.Name, "label", metadata.Label)
+	return metadata, helper.MarshalFile(ctx, map[string]string{"name": metadata.Name}, getBackupRestoreFile(ctx))
+}
+
+// Restores a backup - replacing the saves within the data directory.
+// The backup is first restored to a staging directory (and checked for key files) so that a failed restore leaves the existing saves intact.
+// Returns an error if the backup cannot be restored.
+func RestoreBackup(ctx context.Context, metadata BackupMetadata) error {
+	unlock, err := lockBackups(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	helper.Logger(ctx).Info("restore backup", "name", metadata.Name, "label", metadata.Label)
+	data := helper.Dirs(ctx)["data"]
+	staging := filepath.Join(data, ".restore")
+	err = helper.RemovePaths(ctx, staging)
+	if err != nil {
+		return err
+	}
+	defer helper.RemovePaths(ctx, staging)
+	err = helper.CreateDirs(ctx, staging)
+	if err != nil {
+		return err
+	}
+	if metadata.Mode == BackupModeIncremental {
+		err = restoreIncrementalBackup(ctx, metadata, staging)
+	} else {
+		err = helper.Extract(ctx, getBackupArchive(ctx, metadata.Name), staging)
+	}
+	if err != nil {
+		return err
+	}
+	err = checkBackupKeyFiles(staging, metadata.Files)
+	if err != nil {
+		return err
+	}
+	saves := filepath.Join(data, "Saves")
+	err = helper.RemovePaths(ctx, saves)
+	if err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(staging, "Saves"), saves)
+}
+
+// Restores the backup requested by [RequestBackupRestore] (if any) - and then clears the request.
+// Returns an error if the requested backup cannot be restored.
+func ApplyBackupRestore(ctx context.Context) error {
+	request := map[string]string{}
+	err := helper.UnmarshalFile(ctx, getBackupRestoreFile(ctx), &request)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	metadata, err := FindBackup(ctx, request["name"])
+	if err != nil {
+		return err
+	}
+	err = RestoreBackup(ctx, *metadata)
+	if err != nil {
+		return err
+	}
+	return helper.RemovePaths(ctx, getBackupRestoreFile(ctx))
+}
+
+// Gets the backup options from the environment (for use by subcommands).
+// Returns an error if the environment cannot be parsed.
+func getBackupOpts(ctx context.Context) (BackupOpts, error) {
+	config := EntrypointConfig{}
+	err := helper.ParseEnv(ctx, &config)
+	if err != nil {
+		return BackupOpts{}, err
+	}
+	targets, err := ParseReplicationTargets(config.BackupDestinations)
+	if err != nil {
+		return BackupOpts{}, err
+	}
+	return BackupOpts{Mode: config.BackupMode, Retention: config.BackupRetention, Targets: targets, VerifyExtract: config.BackupVerifyExtract, WebhookUrl: config.BackupWebhookUrl}, nil
+}
+
+// Manages backups from the command line:
+//
+//	entrypoint backup create [label]
+//	entrypoint backup list
+//	entrypoint backup restore [name or label]
+//
+// Restores are applied the next time the server starts - if the server is running, it is shut down.
+// Returns an error if the arguments are invalid.
+// Returns an error if the backup action fails.
+func BackupSubcommand(ctx context.Context) error {
+	usage := fmt.Errorf("usage: %s backup [create [label] | list | restore [name or label]]", filepath.Base(os.Args[0]))
+	args := os.Args[2:]
+	if len(args) == 0 {
+		return usage
+	}
+	switch {
+	case args[0] == "create" && len(args) <= 2:
+		opts, err := getBackupOpts(ctx)
+		if err != nil {
+			return err
+		}
+		if len(args) == 2 {
+			opts.Label = args[1]
+		}
+		metadata, err := CreateBackup(ctx, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\t%s\n", metadata.Name, metadata.Verification.Status)
+		return nil
+	case args[0] == "list" && len(args) == 1:
+		backups, err := ListBackups(ctx)
+		if err != nil {
+			return err
+		}
+		for _, backup := range backups {
+			status := ""
+			if backup.Verification != nil {
+				status = backup.Verification.Status
+			}
+			fmt.Printf("%s\t%s\t%s\t%d\t%s\n", backup.Name, backup.Label, backup.Mode, backup.Size, status)
+		}
+		return nil
+	case args[0] == "restore" && len(args) == 2:
+		_, err := RequestBackupRestore(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		err = ShutdownServer(ctx)
+		if err != nil {
+			helper.Logger(ctx).Info("server not running - backup will be restored on next start")
+		}
+		return nil
+	}
+	return usage
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// Writes save files to the data directory (and removes the saves and backups once the test finishes)
+func writeTestSaves(t *testing.T, files map[string]string) {
+	t.Helper()
+	ctx := newTestContext(t)
+	saves := filepath.Join(helper.Dirs(ctx)["data"], "Saves")
+	backups := helper.Dirs(ctx)["backups"]
+	t.Cleanup(func() {
+		os.RemoveAll(saves)
+		entries, _ := os.ReadDir(backups)
+		for _, entry := range entries {
+			os.RemoveAll(filepath.Join(backups, entry.Name()))
+		}
+	})
+	for relpath, content := range files {
+		path := filepath.Join(saves, filepath.FromSlash(relpath))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(content), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCollectBackupObjectsKeepsPendingObjects(t *testing.T) {
+	ctx := newTestContext(t)
+	writeTestSaves(t, map[string]string{})
+	old := time.Now().Add(-time.Hour)
+	write := func(name string, modTime time.Time) string {
+		path := filepath.Join(getBackupObjectsDir(ctx), "ab", name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = os.WriteFile(path, []byte("object"), 0644)
+		}
+		if err == nil {
+			err = os.Chtimes(path, modTime, modTime)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	unreferenced := write("ab01", old)
+	temp := write("ab02.tmp", old)
+	recent := write("ab03", time.Now().Add(time.Hour))
+	err := CollectBackupObjects(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Lstat(unreferenced)
+	if !os.IsNotExist(err) {
+		t.Fatal("expected the unreferenced object to be deleted")
+	}
+	for _, path := range []string{temp, recent} {
+		_, err = os.Lstat(path)
+		if err != nil {
+			t.Fatalf("expected %s to be kept: %v", path, err)
+		}
+	}
+}
+
+func TestLockBackupsWaitsForHolder(t *testing.T) {
+	ctx := newTestContext(t)
+	writeTestSaves(t, map[string]string{})
+	unlock, err := lockBackups(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acquired := make(chan struct{})
+	go func() {
+		second, err := lockBackups(ctx)
+		if err == nil {
+			second()
+		}
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while held")
+	case <-time.After(500 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock not acquired once released")
+	}
+}
+
+func TestConcurrentIncrementalBackups(t *testing.T) {
+	ctx := newTestContext(t)
+	setTelnetAddr(t, getFreeAddr(t))
+	writeTestSaves(t, map[string]string{"world/game/main.ttw": "main"})
+	saves := filepath.Join(helper.Dirs(ctx)["data"], "Saves")
+	waitGroup := sync.WaitGroup{}
+	errs := make(chan error, 8)
+	for index := 0; index < 8; index++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			// every backup adds an object of its own - which pruning by a concurrent backup must not delete
+			err := os.WriteFile(filepath.Join(saves, "world", "game", fmt.Sprintf("region-%d", index)), []byte(fmt.Sprintf("region %d", index)), 0644)
+			if err == nil {
+				_, err = CreateBackup(ctx, BackupOpts{Mode: BackupModeIncremental, Retention: 2})
+			}
+			errs <- err
+		}()
+	}
+	waitGroup.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err := ListBackups(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %d", len(backups))
+	}
+	for _, backup := range backups {
+		err = VerifyIncrementalBackup(ctx, backup, map[string]int64{}, true)
+		if err != nil {
+			t.Fatalf("backup %s: %v", backup.Name, err)
+		}
+	}
+}
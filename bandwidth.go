@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// bandwidthRetention is the number of months of bandwidth usage kept (the oldest months are deleted first)
+const bandwidthRetention = 12
+
+// BandwidthUsage is the network usage of the server during a calendar month (in UTC) - so that usage can be compared against the transfer caps of hosting plans
+type BandwidthUsage struct {
+	// Month is formatted 'YYYY-MM'
+	Month string `json:"month"`
+	// DepotBytes are downloaded by DepotDownloader (i.e., server installs and updates)
+	DepotBytes int64 `json:"depotBytes"`
+	// DownloadBytes are downloaded over http (e.g., mods, prefab packs and mirrored server archives)
+	DownloadBytes int64 `json:"downloadBytes"`
+	// ReceivedBytes and SentBytes are counted by the network interfaces (excluding loopback) while the entrypoint is running - they approximate game traffic, but include all traffic (including the downloads above)
+	ReceivedBytes int64 `json:"receivedBytes"`
+	SentBytes     int64 `json:"sentBytes"`
+}
+
+// bandwidthLock serializes updates to the bandwidth file within the process
+var bandwidthLock sync.Mutex
+
+// Gets the path of the file recording bandwidth usage (keyed by month)
+func getBandwidthFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "bandwidth.json")
+}
+
+// Gets the month (see [BandwidthUsage.Month]) of the given time
+func getBandwidthMonth(now time.Time) string {
+	return now.UTC().Format("2006-01")
+}
+
+// Lists recorded bandwidth usage (oldest month first).
+// Returns an error if the bandwidth file cannot be read.
+func ListBandwidthUsage(ctx context.Context) ([]BandwidthUsage, error) {
+	// the file is read directly (rather than with helper.UnmarshalFile) as it's updated with every status refresh
+	months := map[string]BandwidthUsage{}
+	data, err := os.ReadFile(getBandwidthFile(ctx))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &months)
+		if err != nil {
+			return nil, err
+		}
+	}
+	usages := []BandwidthUsage{}
+	for month, usage := range months {
+		usage.Month = month
+		usages = append(usages, usage)
+	}
+	slices.SortFunc(usages, func(a BandwidthUsage, b BandwidthUsage) int {
+		return strings.Compare(a.Month, b.Month)
+	})
+	return usages, nil
+}
+
+// Updates the bandwidth usage of the current month with a callback - writing the bandwidth file atomically, deleting months beyond [bandwidthRetention] and publishing the usage to the status (see [ServerStatus.Bandwidth]).
+// Returns an error if the bandwidth file cannot be read or written.
+func UpdateBandwidthUsage(ctx context.Context, cb func(usage *BandwidthUsage)) error {
+	bandwidthLock.Lock()
+	defer bandwidthLock.Unlock()
+	usages, err := ListBandwidthUsage(ctx)
+	if err != nil {
+		return err
+	}
+	month := getBandwidthMonth(time.Now())
+	if len(usages) == 0 || usages[len(usages)-1].Month != month {
+		usages = append(usages, BandwidthUsage{Month: month})
+	}
+	usages = usages[max(len(usages)-bandwidthRetention, 0):]
+	usage := &usages[len(usages)-1]
+	cb(usage)
+	months := map[string]BandwidthUsage{}
+	for _, usage := range usages {
+		months[usage.Month] = usage
+	}
+	file := getBandwidthFile(ctx)
+	data, err := json.MarshalIndent(months, "", "  ")
+	if err == nil {
+		err = os.WriteFile(file+".tmp", data, 0644)
+	}
+	if err == nil {
+		err = os.Rename(file+".tmp", file)
+	}
+	if err != nil {
+		return err
+	}
+	tracker := GetStatusTracker(ctx)
+	if tracker != nil {
+		current := *usage
+		tracker.Update(ctx, func(status *ServerStatus) {
+			status.Bandwidth = &current
+		})
+	}
+	return nil
+}
+
+// Records bytes downloaded (see [BandwidthUsage]) - failures are logged and otherwise ignored
+func recordDownloadBandwidth(ctx context.Context, cb func(usage *BandwidthUsage)) {
+	err := UpdateBandwidthUsage(ctx, cb)
+	if err != nil {
+		helper.Logger(ctx).Warn("record bandwidth failed", "error", err.Error())
+	}
+}
+
+// depotDownloadedRegex matches the download summary printed by DepotDownloader (e.g., 'Total downloaded: 1234 bytes (5678 bytes uncompressed) from 1 depots')
+var depotDownloadedRegex = regexp.MustCompile(`Total downloaded: (\d+) bytes`)
+
+// Records the bytes downloaded by DepotDownloader - parsed from its output
+func recordDepotBandwidth(ctx context.Context, output string) {
+	match := depotDownloadedRegex.FindStringSubmatch(output)
+	if match == nil {
+		helper.Logger(ctx).Warn("depot download size not found in DepotDownloader output")
+		return
+	}
+	bytes, _ := strconv.ParseInt(match[1], 10, 64)
+	helper.Logger(ctx).Info("depot downloaded", "bytes", bytes)
+	recordDownloadBandwidth(ctx, func(usage *BandwidthUsage) {
+		usage.DepotBytes += bytes
+	})
+}
+
+// Parses the received and sent byte counters of the network interfaces (excluding loopback) from '/proc/net/dev'.
+// Returns an error if the counters are malformed.
+func parseNetDev(data string) (int64, int64, error) {
+	received := int64(0)
+	sent := int64(0)
+	for _, line := range strings.Split(data, "\n") {
+		name, counters, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "lo" {
+			continue
+		}
+		// receive (bytes, packets, errs, drop, fifo, frame, compressed, multicast) then transmit (bytes, ...)
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			return 0, 0, fmt.Errorf("malformed interface counters %s", strings.TrimSpace(line))
+		}
+		rx, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		tx, err := strconv.ParseInt(fields[8], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		received += rx
+		sent += tx
+	}
+	return received, sent, nil
+}
+
+// NetworkSampler measures the traffic of the network interfaces between consecutive samples
+type NetworkSampler struct {
+	received int64
+	sent     int64
+	sampled  bool
+}
+
+// Samples the interface counters - returning the bytes received and sent since the previous sample (zero for the first sample).
+// Counter resets (e.g., recreated interfaces) are treated as starting from zero.
+// Returns an error if the counters cannot be read (e.g., outside of linux).
+func (ns *NetworkSampler) Sample() (int64, int64, error) {
+	data, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		return 0, 0, err
+	}
+	received, sent, err := parseNetDev(string(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	deltaReceived := received - ns.received
+	if deltaReceived < 0 {
+		deltaReceived = received
+	}
+	deltaSent := sent - ns.sent
+	if deltaSent < 0 {
+		deltaSent = sent
+	}
+	if !ns.sampled {
+		deltaReceived, deltaSent = 0, 0
+	}
+	ns.received, ns.sent, ns.sampled = received, sent, true
+	return deltaReceived, deltaSent, nil
+}
+
+// Manages bandwidth usage from the command line - 'bandwidth' lists the recorded usage of each month (in bytes).
+// Returns an error if the arguments are invalid.
+// Returns an error if the usage cannot be read.
+func BandwidthSubcommand(ctx context.Context) error {
+	if len(os.Args) > 2 {
+		return fmt.Errorf("usage: %s bandwidth", filepath.Base(os.Args[0]))
+	}
+	usages, err := ListBandwidthUsage(ctx)
+	if err != nil {
+		return err
+	}
+	for _, usage := range usages {
+		fmt.Printf("%s\t%d\t%d\t%d\t%d\n", usage.Month, usage.DepotBytes, usage.DownloadBytes, usage.ReceivedBytes, usage.SentBytes)
+	}
+	return nil
+}
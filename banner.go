@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// InstalledMod describes a mod installed to the sdtd 'Mods' folder
+type InstalledMod struct {
+	Folder  string `json:"folder"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// modInfoRegex matches a property of a 'ModInfo.xml' file (e.g., '<Version value="1.0" />')
+var modInfoRegex = regexp.MustCompile(`<(Name|Version)\s+value="([^"]*)"`)
+
+// Lists the mods installed to the sdtd 'Mods' folder (excluding the mods shipped with the game) - reading names and versions from each mod's 'ModInfo.xml'.
+// Returns an error if the mods folder cannot be read.
+func ListInstalledMods(ctx context.Context) ([]InstalledMod, error) {
+	mods := []InstalledMod{}
+	subpaths, err := helper.ListDir(ctx, filepath.Join(helper.Dirs(ctx)["sdtd"], "Mods"))
+	if errors.Is(err, os.ErrNotExist) {
+		return mods, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, subpath := range subpaths {
+		folder := filepath.Base(subpath)
+		if strings.HasPrefix(folder, tfpModPrefix) {
+			continue
+		}
+		mod := InstalledMod{Folder: folder, Name: folder}
+		data, err := os.ReadFile(filepath.Join(subpath, "ModInfo.xml"))
+		if err == nil {
+			for _, match := range modInfoRegex.FindAllStringSubmatch(string(data), -1) {
+				switch match[1] {
+				case "Name":
+					mod.Name = match[2]
+				case "Version":
+					mod.Version = match[2]
+				}
+			}
+		}
+		mods = append(mods, mod)
+	}
+	return mods, nil
+}
+
+// Gets the names of the optional subsystems enabled by the configuration
+func getEnabledSubsystems(config EntrypointConfig) []string {
+	subsystems := []string{}
+	enabled := map[string]bool{
+		"admin-api":          config.AdminApiEnabled,
+		"afk-kick":           config.AfkKickTimeout != nil,
+		"alerts":             config.AlertWebhookUrl != nil,
+		"allocs-fixes":       config.AllocsFixesEnabled,
+		"auto-restart":       config.AutoRestart != nil,
+		"backups":            config.BackupInterval != nil,
+		"backup-replication": len(config.BackupDestinations) > 0,
+		"ban-sync":           len(config.BanSyncPeers) > 0,
+		"chat-commands":      config.ChatCommandsEnabled,
+		"chunk-resets":       config.ChunkResetFile != "",
+		"control-socket":     config.ControlSocket != "",
+		"directory":          config.DirectoryUrl != nil,
+		"drift-checks":       config.DriftCheckInterval != nil,
+		"events-db":          config.EventsDb,
+		"failover":           config.FailoverPrimaryUrl != nil,
+		"horde-events":       config.HordeEventsFile != "",
+		"idle-shutdown":      config.IdleShutdownTimeout != nil,
+		"kill-feed":          config.KillFeedWebhookUrl != nil || config.KillFeedAdminUrl != nil,
+		"low-disk-monitor":   config.LowDiskThreshold > 0,
+		"macros":             config.MacrosFile != "",
+		"maintenance":        config.MaintenanceInterval != nil,
+		"maintenance-mode":   config.Maintenance,
+		"metrics-history":    config.MetricsHistory,
+		"mod-auto-update":    config.ModAutoUpdate,
+		"mod-bisection":      config.ModsRollback && config.ModsBisect,
+		"mod-rollback":       config.ModsRollback,
+		"mod-update-checks":  config.ModUpdateCheckInterval != nil,
+		"offline":            config.Offline,
+		"panel":              config.PanelMode,
+		"ping-kick":          config.PingKickThreshold > 0,
+		"player-positions":   config.AdminApiEnabled && config.PlayerPositions != nil,
+		"player-snapshots":   config.PlayerSnapshotDeaths,
+		"playtime-rewards":   config.PlaytimeRewardsFile != "",
+		"pod-annotations":    config.PlayerCountAnnotate,
+		"prefab-packs":       len(config.PrefabPackUrls) > 0,
+		"save-schedule":      config.SaveInterval != nil,
+		"settings-profiles":  config.SettingsProfilesFile != "",
+		"standby":            len(config.StandbyDestinations) > 0,
+		"telnet-transcript":  config.TelnetTranscript,
+		"webdav":             config.WebdavEnabled,
+	}
+	for name, ok := range enabled {
+		if ok {
+			subsystems = append(subsystems, name)
+		}
+	}
+	slices.Sort(subsystems)
+	return subsystems
+}
+
+// Logs a startup banner summarizing the game version, world, installed mods, key settings and enabled subsystems - so that support requests include the needed context from the first screen of logs
+func LogStartupBanner(ctx context.Context, config EntrypointConfig, settings ServerSettings) {
+	mods, err := ListInstalledMods(ctx)
+	if err != nil {
+		helper.Logger(ctx).Warn("list installed mods failed", "error", err.Error())
+	}
+	modNames := []string{}
+	for _, mod := range mods {
+		name := mod.Name
+		if mod.Version != "" {
+			name = name + "@" + mod.Version
+		}
+		modNames = append(modNames, name)
+	}
+	get := func(name string) string {
+		value, _ := settings.Get(name)
+		return value
+	}
+	helper.Logger(ctx).Info(
+		"startup banner",
+		slog.Group("game",
+			"version", config.GameVersion,
+			"manifest", config.ManifestId,
+			"depot", config.ExecutionMode.Depot(),
+			"mode", config.ExecutionMode,
+			"image", helper.Version(ctx),
+		),
+		slog.Group("world",
+			"world", get("GameWorld"),
+			"name", get("GameName"),
+			"seed", get("WorldGenSeed"),
+			"size", get("WorldGenSize"),
+		),
+		slog.Group("settings",
+			"name", get("ServerName"),
+			"port", get("ServerPort"),
+			"max-players", get("ServerMaxPlayerCount"),
+			"eac", get("EACEnabled"),
+			"visibility", get("ServerVisibility"),
+			"telnet-port", get("TelnetPort"),
+			"web-dashboard-port", get("WebDashboardPort"),
+		),
+		"mods", strings.Join(modNames, ","),
+		"subsystems", strings.Join(getEnabledSubsystems(config), ","),
+	)
+}
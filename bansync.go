@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// banSyncEchoWindow is how long bans applied by ban sync are ignored when they're (subsequently) logged by the server - preventing bans from bouncing between instances
+const banSyncEchoWindow = 5 * time.Minute
+
+// banSyncAttempts is the number of times a ban is sent to an unreachable peer
+const banSyncAttempts = 6
+
+// platformIdRegex matches platform user ids (e.g., 'Steam_76561198000000000', 'EOS_0002...')
+var platformIdRegex = regexp.MustCompile(`^[A-Za-z]+_\w+$`)
+
+// banUnitRegex matches ban duration units (e.g., 'minutes', 'years')
+var banUnitRegex = regexp.MustCompile(`^[a-z]+$`)
+
+// BanRecord is a ban (or unban) propagated between instances
+type BanRecord struct {
+	// PlatformId is the banned player's platform user id (e.g., 'Steam_76561198000000000')
+	PlatformId string `json:"platformId"`
+	// Remove is true if the ban is lifted
+	Remove bool `json:"remove,omitempty"`
+	// Duration and Unit are the ban's duration (e.g., 10 'years')
+	Duration int    `json:"duration,omitempty"`
+	Unit     string `json:"unit,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	// Origin identifies the instance the ban was issued on
+	Origin string `json:"origin,omitempty"`
+}
+
+// Validates a ban record received from a peer.
+// Returns an error if the platform id, duration or unit is invalid.
+func (br BanRecord) Validate() error {
+	if !platformIdRegex.MatchString(br.PlatformId) {
+		return fmt.Errorf("invalid platform id '%s'", br.PlatformId)
+	}
+	if br.Remove {
+		return nil
+	}
+	if br.Duration <= 0 || !banUnitRegex.MatchString(br.Unit) {
+		return fmt.Errorf("invalid ban duration '%d %s'", br.Duration, br.Unit)
+	}
+	return nil
+}
+
+// Formats the console command that applies the ban record
+func (br BanRecord) Command() string {
+	if br.Remove {
+		return fmt.Sprintf("ban remove %s", br.PlatformId)
+	}
+	command := fmt.Sprintf("ban add %s %d %s", br.PlatformId, br.Duration, br.Unit)
+	if br.Reason != "" {
+		command = fmt.Sprintf("%s \"%s\"", command, strings.ReplaceAll(br.Reason, "\"", "'"))
+	}
+	return command
+}
+
+// Splits a console command into arguments - double-quoted arguments may contain spaces
+func splitConsoleArgs(command string) []string {
+	args := []string{}
+	current := strings.Builder{}
+	quoted := false
+	started := false
+	for _, char := range command {
+		switch {
+		case char == '"':
+			quoted = !quoted
+			started = true
+		case char == ' ' && !quoted:
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(char)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// Parses a 'ban add [target] [duration] [unit] [reason]' or 'ban remove [target]' console command.
+// Returns the ban record and its (unresolved) target - which can be a platform id, entity id or player name.
+// Returns false if the command isn't a ban command.
+func ParseBanCommand(command string) (BanRecord, string, bool) {
+	args := splitConsoleArgs(command)
+	if len(args) < 3 || !strings.EqualFold(args[0], "ban") {
+		return BanRecord{}, "", false
+	}
+	switch strings.ToLower(args[1]) {
+	case "remove":
+		return BanRecord{Remove: true}, args[2], true
+	case "add":
+		if len(args) < 5 {
+			return BanRecord{}, "", false
+		}
+		duration, err := strconv.Atoi(args[3])
+		if err != nil {
+			return BanRecord{}, "", false
+		}
+		record := BanRecord{Duration: duration, Unit: strings.ToLower(args[4])}
+		if len(args) > 5 {
+			record.Reason = args[5]
+		}
+		return record, args[2], true
+	}
+	return BanRecord{}, "", false
+}
+
+// Parses the base urls of the admin apis of sibling instances.
+// Returns an error if a url is malformed or isn't an http(s) url.
+func ParseBanSyncPeers(values []string) ([]*url.URL, error) {
+	peers := []*url.URL{}
+	for _, value := range values {
+		peer, err := url.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		if (peer.Scheme != "http" && peer.Scheme != "https") || peer.Host == "" {
+			return nil, fmt.Errorf("peer %s must be an http(s) url", value)
+		}
+		peers = append(peers, peer)
+	}
+	return peers, nil
+}
+
+// BanSyncOpts defines the options used by [BanSync]
+type BanSyncOpts struct {
+	// Origin identifies this instance to peers
+	Origin string
+	// Peers are the base urls (e.g., 'http://server-b:8083') of the admin apis of sibling instances
+	Peers []*url.URL
+	// Token authenticates requests to the peers' admin apis
+	Token string
+}
+
+// BanSync propagates bans issued on this instance to sibling instances (via their admin api) - and applies bans received from them.
+// Bans are detected from the server log, so bans issued from any source (console, in-game, web dashboard, admin api) are propagated.
+type BanSync struct {
+	Opts    BanSyncOpts
+	lock    sync.Mutex
+	applied map[string]time.Time
+}
+
+// Creates a new [BanSync].  Call [BanSync.Run] to propagate bans.
+func NewBanSync(opts BanSyncOpts) *BanSync {
+	return &BanSync{Opts: opts, applied: map[string]time.Time{}}
+}
+
+// Gets the key used to recognize echoes of applied ban records
+func banSyncKey(record BanRecord) string {
+	return fmt.Sprintf("%s:%t", strings.ToLower(record.PlatformId), record.Remove)
+}
+
+// Applies a ban record received from a peer with a console command (which the server persists to serveradmin.xml).
+// Returns an error if the command fails.
+func (bs *BanSync) Apply(ctx context.Context, record BanRecord) error {
+	bs.lock.Lock()
+	bs.applied[banSyncKey(record)] = time.Now()
+	bs.lock.Unlock()
+	helper.Logger(ctx).Info("apply synced ban", "platform-id", record.PlatformId, "remove", record.Remove, "origin", record.Origin)
+	_, err := SendCommand(ctx, record.Command())
+	return err
+}
+
+// Determines whether a ban record was recently applied by [BanSync.Apply] (and shouldn't be propagated)
+func (bs *BanSync) isEcho(record BanRecord) bool {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+	now := time.Now()
+	for key, appliedAt := range bs.applied {
+		if now.Sub(appliedAt) > banSyncEchoWindow {
+			delete(bs.applied, key)
+		}
+	}
+	_, ok := bs.applied[banSyncKey(record)]
+	return ok
+}
+
+// Resolves the target of a ban command to a platform id - entity ids and names are resolved against the online players.
+// Returns an error if the target isn't online (and isn't a platform id).
+func resolveBanTarget(ctx context.Context, target string) (string, error) {
+	if platformIdRegex.MatchString(target) {
+		return target, nil
+	}
+	players, err := ListPlayers(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, player := range players {
+		if player.EntityId == target || strings.EqualFold(player.Name, target) {
+			return player.PlatformId, nil
+		}
+	}
+	return "", fmt.Errorf("ban target %s is not a platform id or an online player", target)
+}
+
+// Sends a ban record to a peer - retrying (with backoff) until the peer accepts it or [banSyncAttempts] is reached.
+// Returns an error if the peer never accepts the ban record.
+func (bs *BanSync) send(ctx context.Context, peer *url.URL, record BanRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	endpoint := peer.JoinPath("api", "bans").String()
+	backoff := Backoff{Initial: 5 * time.Second, Max: 5 * time.Minute}
+	for attempt := 1; ; attempt++ {
+		err = func() error {
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+			request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", bs.Opts.Token))
+			request.Header.Set("Content-Type", "application/json")
+			response, err := getHttpClient(ctx).Do(request)
+			if err != nil {
+				return err
+			}
+			response.Body.Close()
+			if response.StatusCode >= 300 {
+				return fmt.Errorf("peer responded with status %d", response.StatusCode)
+			}
+			return nil
+		}()
+		if err == nil || attempt == banSyncAttempts {
+			return err
+		}
+		helper.Logger(ctx).Debug("ban sync retry", "peer", peer.Redacted(), "attempt", attempt, "error", err.Error())
+		err = backoff.Wait(ctx)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Propagates a ban record to every peer - failures are logged and otherwise ignored
+func (bs *BanSync) Propagate(ctx context.Context, record BanRecord) {
+	helper.Logger(ctx).Info("propagate ban", "platform-id", record.PlatformId, "remove", record.Remove, "peers", len(bs.Opts.Peers))
+	for _, peer := range bs.Opts.Peers {
+		go func() {
+			err := bs.send(ctx, peer, record)
+			if err != nil {
+				helper.Logger(ctx).Warn("ban sync failed", "peer", peer.Redacted(), "platform-id", record.PlatformId, "error", err.Error())
+			}
+		}()
+	}
+}
+
+// Resolves (and propagates) a ban command issued on this instance - unless it was applied by ban sync
+func (bs *BanSync) handleCommand(ctx context.Context, command string, record BanRecord, target string) {
+	platformId, err := resolveBanTarget(ctx, target)
+	if err != nil {
+		helper.Logger(ctx).Warn("ban not propagated", "command", command, "error", err.Error())
+		return
+	}
+	record.PlatformId = platformId
+	record.Origin = bs.Opts.Origin
+	if bs.isEcho(record) {
+		return
+	}
+	bs.Propagate(ctx, record)
+}
+
+// Checks a server log line for an executed ban command - propagating the ban (in the background, as resolving its target sends console commands)
+func (bs *BanSync) CheckLine(ctx context.Context, line string) {
+	match := executedCommandRegex.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+	record, target, ok := ParseBanCommand(match[1])
+	if !ok {
+		return
+	}
+	go bs.handleCommand(ctx, match[1], record, target)
+}
+
+// Propagates bans logged by the server until the context is cancelled.
+// Returns an error if the server is not ready before the timeout.
+func (bs *BanSync) Run(ctx context.Context, readyTimeout time.Duration) error {
+	session := GetTelnetSession(ctx)
+	err := session.WaitReady(ctx, readyTimeout)
+	if err != nil {
+		return err
+	}
+	lines, unsubscribe := session.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			bs.CheckLine(ctx, line)
+		}
+	}
+}
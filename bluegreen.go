@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// stagingPortOffset is added to the server's ports to get the ports of the staging ('green') instance
+const stagingPortOffset = 100
+
+// StagedUpdate is a game version that was installed and validated by a staging instance while the live server was running
+type StagedUpdate struct {
+	ManifestId string `json:"manifestId"`
+	// Replaces is the manifest id (i.e., MANIFEST_ID) that was configured when the update was staged - the staged update is used while MANIFEST_ID is unchanged
+	Replaces    string    `json:"replaces"`
+	ValidatedAt time.Time `json:"validatedAt"`
+}
+
+// Gets the directory containing the staged update (located within the data directory so that it survives container restarts)
+func getStagedUpdateDir(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], ".staged-update")
+}
+
+// Gets the path of the file describing the staged update
+func getStagedUpdateFile(ctx context.Context) string {
+	return filepath.Join(getStagedUpdateDir(ctx), "update.json")
+}
+
+// Installs a game version into a staging directory and validates it by starting a staging instance (on alternate ports, with a copy of the saves) and waiting for it to become ready.
+// Once validated, the staged update is recorded so that it's applied when the live server next starts.
+// Returns an error if the installation fails or the staging instance doesn't become ready.
+func StageUpdate(ctx context.Context, config EntrypointConfig, manifestId string) error {
+	dirs := helper.Dirs(ctx)
+	live := map[string]string{"data": dirs["data"], "generated": dirs["generated"], "sdtd": dirs["sdtd"]}
+	stagingDir := getStagedUpdateDir(ctx)
+	updateFile := getStagedUpdateFile(ctx)
+	helper.Logger(ctx).Info("stage update", "manifest", manifestId, "path", stagingDir)
+	err := helper.RemovePaths(ctx, stagingDir)
+	if err != nil {
+		return err
+	}
+	// subcommands run in their own process - so the staging install is prepared by pointing this process's directories at the staging directory
+	for name := range live {
+		dirs[name] = filepath.Join(stagingDir, name)
+	}
+	defer func() {
+		for name, path := range live {
+			dirs[name] = path
+		}
+	}()
+	err = helper.CreateDirs(ctx, dirs["data"], dirs["generated"], dirs["sdtd"])
+	if err != nil {
+		return err
+	}
+	mirrors, err := ParseMirrorRules(config.DownloadMirrors)
+	if err != nil {
+		return err
+	}
+	ctx = WithDownloadConfig(ctx, DownloadConfig{Mirrors: mirrors, Offline: config.Offline, Proxy: config.DownloadProxy})
+	ctx = WithExecutionMode(ctx, config.ExecutionMode)
+	err = DownloadSdtd(ctx, manifestId)
+	if err != nil {
+		return err
+	}
+	installModsOpts := InstallModsOpts{}
+	if config.ModPolicyFile != "" {
+		installModsOpts.Policy, err = LoadModPolicy(ctx, config.ModPolicyFile)
+		if err != nil {
+			return err
+		}
+	}
+	err = InstallMods(ctx, installModsOpts, dirs["sdtd"], config.RootUrls...)
+	if err == nil {
+		err = InstallMods(ctx, installModsOpts, filepath.Join(dirs["sdtd"], "Mods"), config.ModUrls...)
+	}
+	if err == nil {
+		err = InstallPrefabPacks(ctx, installModsOpts, config.PrefabPackUrls...)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = os.Stat(filepath.Join(live["data"], "Saves"))
+	if err == nil {
+		helper.Logger(ctx).Info("copy saves to staging instance")
+		_, err = helper.Command(ctx, []string{"cp", "-a", filepath.Join(live["data"], "Saves"), dirs["data"]}, helper.CmdOpts{}).Run()
+		if err != nil {
+			return err
+		}
+	}
+	defaultSettings, err := GetDefaultServerSettings(ctx)
+	if err != nil {
+		return err
+	}
+	presetSettings := ServerSettings{}
+	if config.Preset != "" {
+		presetSettings, err = GetPresetSettings(config.Preset)
+		if err != nil {
+			return err
+		}
+	}
+	settings := MergeServerSettings(defaultSettings, presetSettings, TranslateDeprecatedSettings(ctx, config.GameVersion, GetEnvServerSettings(ctx)))
+	port, err := settings.GetInt("ServerPort")
+	if err != nil {
+		port = 26900
+	}
+	telnetPort := 8081 + stagingPortOffset
+	settings = MergeServerSettings(settings, ServerSettings{
+		"ServerPort":          fmt.Sprintf("%d", port+stagingPortOffset),
+		"ServerVisibility":    "0",
+		"TelnetEnabled":       "true",
+		"TelnetPort":          fmt.Sprintf("%d", telnetPort),
+		"UserDataFolder":      dirs["data"],
+		"WebDashboardEnabled": "false",
+	})
+	settingsFile, err := WriteServerSettings(ctx, settings)
+	if err != nil {
+		return err
+	}
+	cmd, env, err := GetExecutionMode(ctx).ServerCommand(ctx, "-batchmode", fmt.Sprintf("-configfile=%s", settingsFile), "-dedicated", "-logfile", "-", "-nographics", "-quit")
+	if err != nil {
+		return err
+	}
+	helper.Logger(ctx).Info("start staging instance", "port", port+stagingPortOffset, "telnet-port", telnetPort)
+	serverCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	session := NewTelnetSession(fmt.Sprintf("localhost:%d", telnetPort))
+	go session.Run(serverCtx)
+	exited := make(chan error, 1)
+	go func() {
+		_, err := helper.Command(ctx, cmd, helper.CmdOpts{Cwd: dirs["sdtd"], Env: append(env, getDirsEnv(ctx)...)}).Run()
+		exited <- err
+	}()
+	ready := make(chan error, 1)
+	go func() {
+		ready <- session.WaitReady(serverCtx, config.ServerReadyTimeout)
+	}()
+	select {
+	case err = <-ready:
+	case err = <-exited:
+		if err == nil {
+			err = errors.New("staging instance exited before becoming ready")
+		}
+		return fmt.Errorf("staging instance failed: %w", err)
+	}
+	helper.Logger(ctx).Info("stop staging instance")
+	session.Exec(ctx, "shutdown", TelnetResponseIdleTimeout)
+	select {
+	case <-exited:
+	case <-time.After(time.Minute):
+		helper.Logger(ctx).Warn("staging instance did not stop")
+	}
+	if err != nil {
+		return fmt.Errorf("staging instance not ready: %w", err)
+	}
+	err = helper.RemovePaths(ctx, filepath.Join(dirs["data"], "Saves"))
+	if err != nil {
+		return err
+	}
+	update := StagedUpdate{ManifestId: manifestId, Replaces: config.ManifestId, ValidatedAt: time.Now()}
+	return helper.MarshalFile(ctx, update, updateFile)
+}
+
+// Applies a staged update (see [StageUpdate]) when the server starts - replacing the installed game version with the staged install.
+// Staged updates apply while MANIFEST_ID is unchanged since the update was staged - returning the manifest id to use, and whether the staged install was applied (in which case downloading the game is unnecessary).
+// Stale staged updates (i.e., MANIFEST_ID has since changed) are removed.
+// Returns an error if the staged install cannot be applied.
+func ApplyStagedUpdate(ctx context.Context, manifestId string) (string, bool, error) {
+	update := StagedUpdate{}
+	err := helper.UnmarshalFile(ctx, getStagedUpdateFile(ctx), &update)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifestId, false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if update.Replaces != manifestId {
+		helper.Logger(ctx).Info("remove stale staged update", "manifest", update.ManifestId)
+		return manifestId, false, helper.RemovePaths(ctx, getStagedUpdateDir(ctx))
+	}
+	helper.Logger(ctx).Warn("use staged update - set MANIFEST_ID to keep this version", "manifest", update.ManifestId, "replaces", update.Replaces)
+	stagedSdtd := filepath.Join(getStagedUpdateDir(ctx), "sdtd")
+	_, err = os.Stat(stagedSdtd)
+	if errors.Is(err, os.ErrNotExist) {
+		// the staged install was applied by a previous start
+		return update.ManifestId, false, nil
+	}
+	helper.Logger(ctx).Info("apply staged update", "manifest", update.ManifestId)
+	subpaths, err := helper.ListDir(ctx, helper.Dirs(ctx)["sdtd"])
+	if err == nil {
+		err = helper.RemovePaths(ctx, subpaths...)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	_, err = helper.Command(ctx, []string{"cp", "-a", fmt.Sprintf("%s/.", stagedSdtd), helper.Dirs(ctx)["sdtd"]}, helper.CmdOpts{}).Run()
+	if err != nil {
+		return "", false, err
+	}
+	err = helper.RemovePaths(ctx, stagedSdtd, filepath.Join(getStagedUpdateDir(ctx), "data"), filepath.Join(getStagedUpdateDir(ctx), "generated"))
+	if err != nil {
+		return "", false, err
+	}
+	return update.ManifestId, true, nil
+}
+
+// Provides the 'update' subcommand - staging a game version (see [StageUpdate]) while the server is running, and then shutting down the live server so that the staged version is used when it restarts:
+//
+//	entrypoint update [manifest id]
+//
+// Returns an error if the arguments are invalid.
+// Returns an error if staging fails (in which case the live server keeps running).
+func UpdateSubcommand(ctx context.Context) error {
+	usage := fmt.Errorf("usage: %s update [manifest id]", filepath.Base(os.Args[0]))
+	args := os.Args[2:]
+	if len(args) != 1 || !manifestIdRegex.MatchString(args[0]) {
+		return usage
+	}
+	config := EntrypointConfig{}
+	err := helper.ParseEnv(ctx, &config)
+	if err != nil {
+		return err
+	}
+	config.applyDefaults(ctx)
+	err = StageUpdate(ctx, config, args[0])
+	if err != nil {
+		return err
+	}
+	return ShutdownServer(ctx)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// configBundleVersion is the version of the [ConfigBundle] format written by this entrypoint
+const configBundleVersion = 1
+
+// configBundleHelperEnv are environment variables (read by the bootstrapping helper, rather than [EntrypointConfig]) included in config bundles
+var configBundleHelperEnv = []string{"CACHE_ENABLED", "CACHE_SIZE_LIMIT", "GID", "UID"}
+
+// configBundleFileEnv are environment variables referencing files whose content is included in config bundles
+var configBundleFileEnv = []string{"ADMIN_API_TOKENS_FILE", "CHUNK_RESET_FILE", "HORDE_EVENTS_FILE", "MACROS_FILE", "MOD_POLICY_FILE", "PLAYTIME_REWARDS_FILE", "SETTINGS_PROFILES_FILE"}
+
+// configBundleDataFiles are files (relative to the data directory) included in config bundles
+var configBundleDataFiles = []string{"settings-overrides.json"}
+
+// configBundleExcludedSettings are settings forced by the entrypoint - and so excluded from config bundles
+var configBundleExcludedSettings = []string{"TelnetEnabled", "TelnetPort", "UserDataFolder", "WebDashboardPort"}
+
+// ConfigBundle is the complete effective configuration of a server - used to migrate servers between hosts with a single file
+type ConfigBundle struct {
+	Version      int       `json:"version"`
+	CreatedAt    time.Time `json:"createdAt"`
+	ImageVersion string    `json:"imageVersion"`
+	// Env holds the entrypoint's environment variables (including 'SETTING_[Key]' variables)
+	Env map[string]string `json:"env"`
+	// EnvFiles holds the content of files referenced by environment variables (e.g., 'SETTINGS_PROFILES_FILE') - keyed by environment variable
+	EnvFiles map[string]string `json:"envFiles"`
+	// Files holds the content of configuration files - keyed by their path relative to the data directory (e.g., 'Saves/serveradmin.xml')
+	Files map[string]string `json:"files"`
+	// Mods lists the installed mods (informational - mods are installed from 'MOD_URLS' and 'ROOT_URLS')
+	Mods []string `json:"mods"`
+	// Settings are the effective settings of the generated 'serverconfig.xml'
+	Settings ServerSettings `json:"settings"`
+}
+
+// Gets the names of the environment variables parsed into [EntrypointConfig]
+func getEntrypointConfigEnv() []string {
+	names := []string{}
+	value := reflect.TypeOf(EntrypointConfig{})
+	for index := 0; index < value.NumField(); index++ {
+		names = append(names, value.Field(index).Tag.Get("env"))
+	}
+	return names
+}
+
+// Gets the path of the server admin file (relative to the data directory)
+func getServerAdminFile(settings ServerSettings) string {
+	name, ok := settings.Get("AdminFileName")
+	if !ok || name == "" {
+		name = "serveradmin.xml"
+	}
+	return filepath.Join("Saves", name)
+}
+
+// Exports the effective configuration of the server as a [ConfigBundle].
+// Returns an error if the generated settings or a configuration file cannot be read.
+func ExportConfigBundle(ctx context.Context) (ConfigBundle, error) {
+	fail := func(err error) (ConfigBundle, error) {
+		return ConfigBundle{}, err
+	}
+	helper.Logger(ctx).Info("export config bundle")
+	err := MigrateConfigEnv(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	bundle := ConfigBundle{
+		Version:      configBundleVersion,
+		CreatedAt:    time.Now(),
+		ImageVersion: helper.Version(ctx),
+		Env:          map[string]string{},
+		EnvFiles:     map[string]string{},
+		Files:        map[string]string{},
+		Mods:         []string{},
+		Settings:     ServerSettings{},
+	}
+	names := append(getEntrypointConfigEnv(), configBundleHelperEnv...)
+	for _, item := range os.Environ() {
+		name, value, _ := strings.Cut(item, "=")
+		if name != "CONFIG_BUNDLE" && (slices.Contains(names, name) || strings.HasPrefix(name, "SETTING_")) {
+			bundle.Env[name] = value
+		}
+	}
+	// exported environment variables use the current layout
+	bundle.Env["CONFIG_VERSION"] = strconv.Itoa(CurrentConfigVersion)
+	for _, name := range configBundleFileEnv {
+		path := bundle.Env[name]
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fail(err)
+		}
+		bundle.EnvFiles[name] = string(data)
+	}
+	xss := XmlServerSettings{}
+	err = helper.UnmarshalFile(ctx, filepath.Join(helper.Dirs(ctx)["generated"], "serverconfig.xml"), &xss)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fail(err)
+	}
+	bundle.Settings = xss.Map()
+	for _, name := range configBundleExcludedSettings {
+		bundle.Settings.Delete(name)
+	}
+	for _, file := range append([]string{getServerAdminFile(bundle.Settings)}, configBundleDataFiles...) {
+		data, err := os.ReadFile(filepath.Join(helper.Dirs(ctx)["data"], file))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fail(err)
+		}
+		bundle.Files[filepath.ToSlash(file)] = string(data)
+	}
+	mods, err := helper.ListDir(ctx, filepath.Join(helper.Dirs(ctx)["sdtd"], "Mods"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fail(err)
+	}
+	for _, mod := range mods {
+		bundle.Mods = append(bundle.Mods, filepath.Base(mod))
+	}
+	return bundle, nil
+}
+
+// Gets the directory that imported config bundle files are written to
+func getConfigBundleDir(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "config-bundle")
+}
+
+// Imports a [ConfigBundle] on startup - returning the bundle's settings (which are merged below 'SETTING_[Key]' values).
+// Environment variables from the bundle are set unless they're already set (so that the container's environment takes precedence).
+// Configuration files are only written the first time a bundle is imported (so that later changes to them aren't overwritten on restart).
+// Returns an error if the bundle cannot be read or is of an unsupported version.
+// Returns an error if the bundle's files cannot be written.
+func ImportConfigBundle(ctx context.Context, file string) (ServerSettings, error) {
+	fail := func(err error) (ServerSettings, error) {
+		return nil, err
+	}
+	helper.Logger(ctx).Info("import config bundle", "path", file)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fail(err)
+	}
+	bundle := ConfigBundle{}
+	err = json.Unmarshal(data, &bundle)
+	if err != nil {
+		return fail(fmt.Errorf("config bundle %s: %w", file, err))
+	}
+	if bundle.Version < 1 || bundle.Version > configBundleVersion {
+		return fail(fmt.Errorf("config bundle %s has unsupported version %d (expected at most %d)", file, bundle.Version, configBundleVersion))
+	}
+	if bundle.Env == nil {
+		bundle.Env = map[string]string{}
+	}
+	dir := getConfigBundleDir(ctx)
+	err = helper.CreateDirs(ctx, dir)
+	if err != nil {
+		return fail(err)
+	}
+	for name, content := range bundle.EnvFiles {
+		path := filepath.Join(dir, strings.ToLower(name))
+		err = os.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			return fail(err)
+		}
+		bundle.Env[name] = path
+	}
+	for name, value := range bundle.Env {
+		_, ok := os.LookupEnv(name)
+		if !ok {
+			os.Setenv(name, value)
+		}
+	}
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	appliedFile := filepath.Join(dir, "applied")
+	applied, _ := os.ReadFile(appliedFile)
+	if string(applied) != checksum {
+		for name, content := range bundle.Files {
+			if !filepath.IsLocal(filepath.FromSlash(name)) {
+				return fail(fmt.Errorf("config bundle %s has invalid file path %s", file, name))
+			}
+			path := filepath.Join(helper.Dirs(ctx)["data"], filepath.FromSlash(name))
+			helper.Logger(ctx).Info("import config bundle file", "path", path)
+			err = helper.CreateDirs(ctx, filepath.Dir(path))
+			if err == nil {
+				err = os.WriteFile(path, []byte(content), 0644)
+			}
+			if err != nil {
+				return fail(err)
+			}
+		}
+		err = os.WriteFile(appliedFile, []byte(checksum), 0644)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	if bundle.Settings == nil {
+		bundle.Settings = ServerSettings{}
+	}
+	return bundle.Settings, nil
+}
+
+// Provides the 'config' subcommand - exporting the effective configuration as a [ConfigBundle] (to a file, or stdout).
+// Returns an error if the arguments are invalid.
+// Returns an error if the bundle cannot be exported or written.
+func ConfigSubcommand(ctx context.Context) error {
+	usage := fmt.Errorf("usage: %s config export [file]", filepath.Base(os.Args[0]))
+	args := os.Args[2:]
+	if len(args) == 0 || args[0] != "export" || len(args) > 2 {
+		return usage
+	}
+	bundle, err := ExportConfigBundle(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 || args[1] == "-" {
+		fmt.Println(string(data))
+		return nil
+	}
+	// bundles contain credentials (e.g., passwords, tokens)
+	return os.WriteFile(args[1], data, 0600)
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// GameVersion is a parsed seven days to die game version.
+// Alpha versions (e.g., 'A21') are represented with a major version of 0 and a minor version of the alpha number.
+type GameVersion struct {
+	Major int
+	Minor int
+}
+
+// gameVersionRegex matches game versions like 'A21', 'A21.2', '1.0', 'V 1.0' and 'v2.1'
+var gameVersionRegex = regexp.MustCompile(`^(?:[vV]\s*)?([aA])?(\d+)(?:\.(\d+))?`)
+
+// Parses a game version string.
+// Returns an error if the version is unparseable.
+func ParseGameVersion(version string) (GameVersion, error) {
+	match := gameVersionRegex.FindStringSubmatch(version)
+	if match == nil {
+		return GameVersion{}, fmt.Errorf("invalid game version %s", version)
+	}
+	first, _ := strconv.Atoi(match[2])
+	second := 0
+	if match[3] != "" {
+		second, _ = strconv.Atoi(match[3])
+	}
+	if match[1] != "" {
+		return GameVersion{Major: 0, Minor: first}, nil
+	}
+	return GameVersion{Major: first, Minor: second}, nil
+}
+
+// Compares two game versions - returning -1 if [gv] is older, 1 if [gv] is newer and 0 if they're equal.
+func (gv GameVersion) Compare(other GameVersion) int {
+	if gv.Major != other.Major {
+		if gv.Major < other.Major {
+			return -1
+		}
+		return 1
+	}
+	if gv.Minor != other.Minor {
+		if gv.Minor < other.Minor {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// SettingType identifies how the value of a server setting is interpreted
+type SettingType string
+
+const (
+	SettingTypeBool     SettingType = "bool"
+	SettingTypeDuration SettingType = "duration"
+	SettingTypeInt      SettingType = "int"
+	SettingTypeString   SettingType = "string"
+)
+
+// SettingRange is the inclusive range of valid values for an integer setting
+type SettingRange struct {
+	Min int
+	Max int
+}
+
+// SettingDefinition describes a known seven days to die server setting.
+// [Since] and [Until] are game versions that (respectively) introduced and removed the setting - empty values are unbounded.
+type SettingDefinition struct {
+	Name        string
+	Type        SettingType
+	Default     string
+	Description string
+	Range       *SettingRange
+	Values      []string
+	Unit        time.Duration
+	Since       string
+	Until       string
+}
+
+// Determines whether the setting is available in the given game version.
+// An empty version refers to the latest game version.
+func (sd SettingDefinition) AvailableIn(version string) bool {
+	if version == "" {
+		return sd.Until == ""
+	}
+	current, err := ParseGameVersion(version)
+	if err != nil {
+		return sd.Until == ""
+	}
+	if sd.Since != "" {
+		since, _ := ParseGameVersion(sd.Since)
+		if current.Compare(since) < 0 {
+			return false
+		}
+	}
+	if sd.Until != "" {
+		until, _ := ParseGameVersion(sd.Until)
+		if current.Compare(until) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Validates a raw value against the setting definition.
+// Returns an error if the value cannot be converted to the setting's type.
+// Returns an error if the value is outside of the setting's range or allowed values.
+func (sd SettingDefinition) Validate(value string) error {
+	switch sd.Type {
+	case SettingTypeBool:
+		_, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("setting %s must be a boolean (got %s)", sd.Name, value)
+		}
+	case SettingTypeDuration, SettingTypeInt:
+		intValue, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("setting %s must be an integer (got %s)", sd.Name, value)
+		}
+		if sd.Range != nil && (intValue < sd.Range.Min || intValue > sd.Range.Max) {
+			return fmt.Errorf("setting %s must be between %d and %d (got %d)", sd.Name, sd.Range.Min, sd.Range.Max, intValue)
+		}
+	}
+	if len(sd.Values) > 0 && !slices.Contains(sd.Values, value) {
+		return fmt.Errorf("setting %s must be one of %v (got %s)", sd.Name, sd.Values, value)
+	}
+	return nil
+}
+
+// Gets the definition of a setting available in the given game version.
+// Returns false if the setting is unknown.
+func GetSettingDefinition(name string, version string) (SettingDefinition, bool) {
+	for _, definition := range settingsCatalog {
+		if definition.Name == name && definition.AvailableIn(version) {
+			return definition, true
+		}
+	}
+	return SettingDefinition{}, false
+}
+
+// Gets all setting definitions available in the given game version.
+func GetSettingsCatalog(version string) []SettingDefinition {
+	definitions := []SettingDefinition{}
+	for _, definition := range settingsCatalog {
+		if definition.AvailableIn(version) {
+			definitions = append(definitions, definition)
+		}
+	}
+	return definitions
+}
+
+// Gets the default values of all settings available in the given game version.
+func GetCatalogServerSettings(version string) ServerSettings {
+	data := ServerSettings{}
+	for _, definition := range GetSettingsCatalog(version) {
+		data[definition.Name] = definition.Default
+	}
+	return data
+}
+
+// settingsCatalog is the list of known server settings (derived from the default serverconfig.xml shipped with the game)
+var settingsCatalog = []SettingDefinition{
+	// server representation
+	{Name: "ServerName", Type: SettingTypeString, Default: "My Game Host", Description: "The name of the server"},
+	{Name: "ServerDescription", Type: SettingTypeString, Default: "A 7 Days to Die server", Description: "The server description shown in the server browser"},
+	{Name: "ServerWebsiteURL", Type: SettingTypeString, Default: "", Description: "Website URL shown in the server browser"},
+	{Name: "ServerPassword", Type: SettingTypeString, Default: "", Description: "Password to gain entry to the server"},
+	{Name: "ServerLoginConfirmationText", Type: SettingTypeString, Default: "", Description: "Message users must confirm while joining the server"},
+	{Name: "Region", Type: SettingTypeString, Default: "NorthAmericaEast", Description: "The region this server is in", Values: []string{"NorthAmericaEast", "NorthAmericaWest", "CentralAmerica", "SouthAmerica", "Europe", "Russia", "Asia", "MiddleEast", "Africa", "Oceania"}},
+	{Name: "Language", Type: SettingTypeString, Default: "English", Description: "Primary language (English name) for players on this server"},
+
+	// networking
+	{Name: "ServerPort", Type: SettingTypeInt, Default: "26900", Description: "Port the server listens on", Range: &SettingRange{Min: 1, Max: 65535}},
+	{Name: "ServerVisibility", Type: SettingTypeInt, Default: "2", Description: "Visibility of this server: 2 = public, 1 = only shown to friends, 0 = not listed", Range: &SettingRange{Min: 0, Max: 2}},
+	{Name: "ServerDisabledNetworkProtocols", Type: SettingTypeString, Default: "SteamNetworking", Description: "Comma-separated networking protocols that should not be used (LiteNetLib, SteamNetworking)"},
+	{Name: "ServerMaxWorldTransferSpeedKiBs", Type: SettingTypeInt, Default: "512", Description: "Maximum speed in kiB/s the world is transferred at to a client on first connect", Range: &SettingRange{Min: 0, Max: 1300}},
+
+	// slots
+	{Name: "ServerMaxPlayerCount", Type: SettingTypeInt, Default: "8", Description: "Maximum concurrent players", Range: &SettingRange{Min: 1, Max: 128}},
+	{Name: "ServerReservedSlots", Type: SettingTypeInt, Default: "0", Description: "Slots (out of ServerMaxPlayerCount) reserved for players with a specific permission level", Range: &SettingRange{Min: 0, Max: 128}},
+	{Name: "ServerReservedSlotsPermission", Type: SettingTypeInt, Default: "100", Description: "Required permission level to use reserved slots", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "ServerAdminSlots", Type: SettingTypeInt, Default: "0", Description: "Admins that can still join when the server is full", Range: &SettingRange{Min: 0, Max: 128}},
+	{Name: "ServerAdminSlotsPermission", Type: SettingTypeInt, Default: "0", Description: "Required permission level to use admin slots", Range: &SettingRange{Min: 0, Max: 1000}},
+
+	// admin interfaces
+	{Name: "WebDashboardEnabled", Type: SettingTypeBool, Default: "false", Description: "Enable/disable the web dashboard", Since: "A21"},
+	{Name: "WebDashboardPort", Type: SettingTypeInt, Default: "8080", Description: "Port of the web dashboard", Range: &SettingRange{Min: 1, Max: 65535}, Since: "A21"},
+	{Name: "WebDashboardUrl", Type: SettingTypeString, Default: "", Description: "External URL to the web dashboard (e.g., when behind a reverse proxy)", Since: "A21"},
+	{Name: "EnableMapRendering", Type: SettingTypeBool, Default: "false", Description: "Enable/disable rendering of the map to tile images"},
+	{Name: "ControlPanelEnabled", Type: SettingTypeBool, Default: "false", Description: "Enable/disable the control panel", Until: "A21"},
+	{Name: "ControlPanelPort", Type: SettingTypeInt, Default: "8080", Description: "Port of the control panel", Range: &SettingRange{Min: 1, Max: 65535}, Until: "A21"},
+	{Name: "ControlPanelPassword", Type: SettingTypeString, Default: "CHANGEME", Description: "Password to gain entry to the control panel", Until: "A21"},
+	{Name: "TelnetEnabled", Type: SettingTypeBool, Default: "true", Description: "Enable/disable telnet"},
+	{Name: "TelnetPort", Type: SettingTypeInt, Default: "8081", Description: "Port of the telnet server", Range: &SettingRange{Min: 1, Max: 65535}},
+	{Name: "TelnetPassword", Type: SettingTypeString, Default: "", Description: "Password to gain entry to the telnet interface"},
+	{Name: "TelnetFailedLoginLimit", Type: SettingTypeInt, Default: "10", Description: "Wrong passwords allowed from a single client before it's blocked", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "TelnetFailedLoginsBlocktime", Type: SettingTypeDuration, Default: "10", Description: "How long (in seconds) a telnet block persists", Unit: time.Second},
+	{Name: "TerminalWindowEnabled", Type: SettingTypeBool, Default: "true", Description: "Show a terminal window for log output/command input (Windows only)"},
+
+	// folder and file locations
+	{Name: "AdminFileName", Type: SettingTypeString, Default: "serveradmin.xml", Description: "Server admin file name (relative to UserDataFolder/Saves)"},
+	{Name: "UserDataFolder", Type: SettingTypeString, Default: "", Description: "Where the server stores all user data (including generated worlds and saves)"},
+	{Name: "SaveGameFolder", Type: SettingTypeString, Default: "", Description: "Where the server stores save games", Until: "A20"},
+
+	// other technical settings
+	{Name: "ServerAllowCrossplay", Type: SettingTypeBool, Default: "false", Description: "Enables/disables crossplay", Since: "1.0"},
+	{Name: "EACEnabled", Type: SettingTypeBool, Default: "true", Description: "Enables/disables EasyAntiCheat"},
+	{Name: "IgnoreEOSSanctions", Type: SettingTypeBool, Default: "false", Description: "Ignore EOS sanctions when allowing players to join", Since: "1.0"},
+	{Name: "HideCommandExecutionLog", Type: SettingTypeInt, Default: "0", Description: "Hide logging of command execution (0 = show everything, 3 = hide everything)", Range: &SettingRange{Min: 0, Max: 3}},
+	{Name: "MaxUncoveredMapChunksPerPlayer", Type: SettingTypeInt, Default: "131072", Description: "How many chunks can be uncovered on the in-game map by each player", Range: &SettingRange{Min: 0, Max: 1048576}},
+	{Name: "PersistentPlayerProfiles", Type: SettingTypeBool, Default: "false", Description: "Players always join with the last profile they joined with"},
+	{Name: "MaxChunkAge", Type: SettingTypeInt, Default: "-1", Description: "In-game days since visiting a chunk before it resets (-1 disables)", Range: &SettingRange{Min: -1, Max: 10000}, Since: "1.0"},
+	{Name: "SaveDataLimit", Type: SettingTypeInt, Default: "-1", Description: "Maximum disk space (in MB) for each saved game (-1 disables)", Range: &SettingRange{Min: -1, Max: 1048576}, Since: "1.0"},
+
+	// world
+	{Name: "GameWorld", Type: SettingTypeString, Default: "Navezgane", Description: "'RWG' or an existing world name in the Worlds folder"},
+	{Name: "WorldGenSeed", Type: SettingTypeString, Default: "MyGame", Description: "If RWG, the seed used to generate the world"},
+	{Name: "WorldGenSize", Type: SettingTypeInt, Default: "6144", Description: "If RWG, the width and height of the generated world (a multiple of 2048)", Range: &SettingRange{Min: 2048, Max: 16384}},
+	{Name: "GameName", Type: SettingTypeString, Default: "MyGame", Description: "The game name - affects the save game name and decoration placement"},
+	{Name: "GameMode", Type: SettingTypeString, Default: "GameModeSurvival", Description: "The game mode", Values: []string{"GameModeSurvival"}},
+
+	// difficulty
+	{Name: "GameDifficulty", Type: SettingTypeInt, Default: "1", Description: "0 - 5, 0 = easiest, 5 = hardest", Range: &SettingRange{Min: 0, Max: 5}},
+	{Name: "BlockDamagePlayer", Type: SettingTypeInt, Default: "100", Description: "Player block damage (percentage)", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "BlockDamageAI", Type: SettingTypeInt, Default: "100", Description: "AI block damage (percentage)", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "BlockDamageAIBM", Type: SettingTypeInt, Default: "100", Description: "AI block damage during blood moons (percentage)", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "XPMultiplier", Type: SettingTypeInt, Default: "100", Description: "XP gain multiplier (percentage)", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "PlayerSafeZoneLevel", Type: SettingTypeInt, Default: "5", Description: "Players at or below this level create a safe zone when spawned", Range: &SettingRange{Min: 0, Max: 300}},
+	{Name: "PlayerSafeZoneHours", Type: SettingTypeInt, Default: "5", Description: "Hours in world time the safe zone exists", Range: &SettingRange{Min: 0, Max: 1000}},
+
+	// game rules
+	{Name: "BuildCreate", Type: SettingTypeBool, Default: "false", Description: "Cheat mode on/off"},
+	{Name: "DayNightLength", Type: SettingTypeDuration, Default: "60", Description: "Real time minutes per in-game day", Unit: time.Minute},
+	{Name: "DayLightLength", Type: SettingTypeInt, Default: "18", Description: "In-game hours of daylight per in-game day", Range: &SettingRange{Min: 0, Max: 24}},
+	{Name: "BiomeProgression", Type: SettingTypeBool, Default: "true", Description: "Enables biome hazards and loot stage caps", Since: "1.0"},
+	{Name: "StormFreq", Type: SettingTypeInt, Default: "100", Description: "Frequency of storms (percentage, 0 disables)", Values: []string{"0", "50", "100", "150", "200", "300", "400", "500"}, Since: "1.0"},
+	{Name: "DeathPenalty", Type: SettingTypeInt, Default: "1", Description: "0 = nothing, 1 = classic XP penalty, 2 = injured, 3 = permanent death", Range: &SettingRange{Min: 0, Max: 3}},
+	{Name: "DropOnDeath", Type: SettingTypeInt, Default: "1", Description: "0 = nothing, 1 = everything, 2 = toolbelt only, 3 = backpack only, 4 = delete all", Range: &SettingRange{Min: 0, Max: 4}},
+	{Name: "DropOnQuit", Type: SettingTypeInt, Default: "0", Description: "0 = nothing, 1 = everything, 2 = toolbelt only, 3 = backpack only", Range: &SettingRange{Min: 0, Max: 3}},
+	{Name: "BedrollDeadZoneSize", Type: SettingTypeInt, Default: "15", Description: "Size of the bedroll dead zone", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "BedrollExpiryTime", Type: SettingTypeDuration, Default: "45", Description: "Real world days a bedroll stays active after its owner was last online", Unit: 24 * time.Hour},
+	{Name: "AllowSpawnNearFriend", Type: SettingTypeInt, Default: "2", Description: "0 = disabled, 1 = always, 2 = only near friends in forest biome", Range: &SettingRange{Min: 0, Max: 2}, Since: "1.0"},
+
+	// performance
+	{Name: "MaxSpawnedZombies", Type: SettingTypeInt, Default: "64", Description: "Maximum zombies on the entire map at one time", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "MaxSpawnedAnimals", Type: SettingTypeInt, Default: "50", Description: "Maximum animals on the entire map at one time", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "ServerMaxAllowedViewDistance", Type: SettingTypeInt, Default: "12", Description: "Max view distance a client may request", Range: &SettingRange{Min: 6, Max: 12}},
+	{Name: "MaxQueuedMeshLayers", Type: SettingTypeInt, Default: "1000", Description: "Maximum chunk mesh layers enqueued during mesh generation", Range: &SettingRange{Min: 0, Max: 100000}},
+
+	// zombies
+	{Name: "EnemySpawnMode", Type: SettingTypeBool, Default: "true", Description: "Enable/disable enemy spawning"},
+	{Name: "EnemyDifficulty", Type: SettingTypeInt, Default: "0", Description: "0 = normal, 1 = feral", Range: &SettingRange{Min: 0, Max: 1}},
+	{Name: "ZombieFeralSense", Type: SettingTypeInt, Default: "0", Description: "0 - 3 (off, day, night, all)", Range: &SettingRange{Min: 0, Max: 3}},
+	{Name: "ZombieMove", Type: SettingTypeInt, Default: "0", Description: "0 - 4 (walk, jog, run, sprint, nightmare)", Range: &SettingRange{Min: 0, Max: 4}},
+	{Name: "ZombieMoveNight", Type: SettingTypeInt, Default: "3", Description: "0 - 4 (walk, jog, run, sprint, nightmare)", Range: &SettingRange{Min: 0, Max: 4}},
+	{Name: "ZombieFeralMove", Type: SettingTypeInt, Default: "3", Description: "0 - 4 (walk, jog, run, sprint, nightmare)", Range: &SettingRange{Min: 0, Max: 4}},
+	{Name: "ZombieBMMove", Type: SettingTypeInt, Default: "3", Description: "0 - 4 (walk, jog, run, sprint, nightmare)", Range: &SettingRange{Min: 0, Max: 4}},
+	{Name: "BloodMoonFrequency", Type: SettingTypeInt, Default: "7", Description: "Frequency (in days) of blood moons (0 disables)", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "BloodMoonRange", Type: SettingTypeInt, Default: "0", Description: "Days the blood moon can randomly deviate from BloodMoonFrequency", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "BloodMoonWarning", Type: SettingTypeInt, Default: "8", Description: "The hour the red day number begins on a blood moon day (-1 disables)", Range: &SettingRange{Min: -1, Max: 24}},
+	{Name: "BloodMoonEnemyCount", Type: SettingTypeInt, Default: "8", Description: "Zombies alive at any time per player during a blood moon", Range: &SettingRange{Min: 0, Max: 64}},
+
+	// loot
+	{Name: "LootAbundance", Type: SettingTypeInt, Default: "100", Description: "Loot abundance (percentage)", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "LootRespawnDays", Type: SettingTypeInt, Default: "7", Description: "In-game days before loot respawns", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "AirDropFrequency", Type: SettingTypeInt, Default: "72", Description: "How often (in game-hours) airdrops occur (0 disables)", Range: &SettingRange{Min: 0, Max: 10000}},
+	{Name: "AirDropMarker", Type: SettingTypeBool, Default: "true", Description: "Add a marker to the map/compass for airdrops"},
+
+	// multiplayer
+	{Name: "PartySharedKillRange", Type: SettingTypeInt, Default: "100", Description: "Distance within which party members share kill XP", Range: &SettingRange{Min: 0, Max: 10000}},
+	{Name: "PlayerKillingMode", Type: SettingTypeInt, Default: "3", Description: "0 = no killing, 1 = kill allies only, 2 = kill strangers only, 3 = kill everyone", Range: &SettingRange{Min: 0, Max: 3}},
+
+	// land claims
+	{Name: "LandClaimCount", Type: SettingTypeInt, Default: "5", Description: "Maximum allowed land claims per player", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "LandClaimSize", Type: SettingTypeInt, Default: "41", Description: "Size in blocks protected by a keystone", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "LandClaimDeadZone", Type: SettingTypeInt, Default: "30", Description: "Keystones must be this many blocks apart", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "LandClaimExpiryTime", Type: SettingTypeDuration, Default: "7", Description: "Real world days a player can be offline before their claims expire", Unit: 24 * time.Hour},
+	{Name: "LandClaimDecayMode", Type: SettingTypeInt, Default: "0", Description: "0 = slow (linear), 1 = fast (exponential), 2 = none", Range: &SettingRange{Min: 0, Max: 2}},
+	{Name: "LandClaimOnlineDurabilityModifier", Type: SettingTypeInt, Default: "4", Description: "Claim block hardness multiplier when the owner is online (0 = infinite)", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "LandClaimOfflineDurabilityModifier", Type: SettingTypeInt, Default: "4", Description: "Claim block hardness multiplier when the owner is offline (0 = infinite)", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "LandClaimOfflineDelay", Type: SettingTypeDuration, Default: "0", Description: "Minutes after logout before claim hardness transitions to offline", Unit: time.Minute},
+
+	// dynamic mesh
+	{Name: "DynamicMeshEnabled", Type: SettingTypeBool, Default: "true", Description: "Enable/disable the dynamic mesh system"},
+	{Name: "DynamicMeshLandClaimOnly", Type: SettingTypeBool, Default: "true", Description: "Only enable the dynamic mesh system in land claim areas"},
+	{Name: "DynamicMeshLandClaimBuffer", Type: SettingTypeInt, Default: "3", Description: "Dynamic mesh land claim chunk radius", Range: &SettingRange{Min: 0, Max: 100}},
+	{Name: "DynamicMeshMaxItemCache", Type: SettingTypeInt, Default: "3", Description: "Dynamic mesh items processed concurrently", Range: &SettingRange{Min: 0, Max: 100}},
+
+	// twitch
+	{Name: "TwitchServerPermission", Type: SettingTypeInt, Default: "90", Description: "Required permission level to use twitch integration", Range: &SettingRange{Min: 0, Max: 1000}},
+	{Name: "TwitchBloodMoonAllowed", Type: SettingTypeBool, Default: "false", Description: "Allow twitch actions during blood moons"},
+
+	// quests
+	{Name: "QuestProgressionDailyLimit", Type: SettingTypeInt, Default: "4", Description: "Quests per day contributing to quest tier progression", Range: &SettingRange{Min: 0, Max: 1000}, Since: "1.0"},
+}
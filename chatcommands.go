@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// chatCommandPrefix prefixes chat messages handled by the [ChatCommandService] (e.g., '!home')
+const chatCommandPrefix = "!"
+
+// chatCommandHandler handles a chat command sent by a player - returning the reply sent to the player
+type chatCommandHandler func(ctx context.Context, message ChatMessage, args []string) (string, error)
+
+// ChatCommandOpts defines the options used in conjunction with the [ChatCommandService]
+type ChatCommandOpts struct {
+	Admins   []string
+	Cooldown time.Duration
+	// Macros (if non-nil) are run by admins with '!macro [name]'
+	Macros     *MacroRunner
+	Restricted []string
+}
+
+// ChatCommandService implements quality-of-life chat commands (e.g., '!sethome', '!home', '!tp') using console commands - without requiring server-side mods
+type ChatCommandService struct {
+	Opts      ChatCommandOpts
+	cooldowns map[string]time.Time
+	handlers  map[string]chatCommandHandler
+	homes     map[string]string
+}
+
+// Gets the path of the file that persists player homes
+func getHomesFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "homes.json")
+}
+
+// Creates a new [ChatCommandService] - loading persisted player homes.
+// Returns an error if the homes file cannot be read.
+func NewChatCommandService(ctx context.Context, opts ChatCommandOpts) (*ChatCommandService, error) {
+	homes := map[string]string{}
+	err := helper.UnmarshalFile(ctx, getHomesFile(ctx), &homes)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	ccs := &ChatCommandService{Opts: opts, cooldowns: map[string]time.Time{}, homes: homes}
+	ccs.handlers = map[string]chatCommandHandler{
+		"home":    ccs.handleHome,
+		"sethome": ccs.handleSetHome,
+		"tp":      ccs.handleTp,
+	}
+	if opts.Macros != nil {
+		ccs.handlers["macro"] = ccs.handleMacro
+	}
+	return ccs, nil
+}
+
+// Finds an online player by entity id or (case-insensitive) name.
+// Returns an error if the player is not online.
+func findOnlinePlayer(ctx context.Context, query string) (OnlinePlayer, error) {
+	players, err := ListPlayers(ctx)
+	if err != nil {
+		return OnlinePlayer{}, err
+	}
+	for _, player := range players {
+		if player.EntityId == query || strings.EqualFold(player.Name, query) {
+			return player, nil
+		}
+	}
+	return OnlinePlayer{}, fmt.Errorf("player %s is not online", query)
+}
+
+// Teleports a player (by entity id) to a 'listplayers' position (e.g., '(-1204.5, 61.1, 402.8)').
+// Returns an error if the position is malformed.
+// Returns an error if the 'teleportplayer' command fails.
+func TeleportPlayer(ctx context.Context, entityId string, position string) error {
+	parsed, ok := parsePosition(position)
+	if !ok {
+		return fmt.Errorf("invalid position %s", position)
+	}
+	x, y, z := int(math.Round(parsed[0])), int(math.Round(parsed[1])), int(math.Round(parsed[2]))
+	_, err := SendCommand(ctx, fmt.Sprintf("teleportplayer %s %d %d %d", entityId, x, y, z))
+	if err != nil {
+		return fmt.Errorf("teleport player: %w", err)
+	}
+	return nil
+}
+
+// Handles '!sethome' - saving the player's current position as their home
+func (ccs *ChatCommandService) handleSetHome(ctx context.Context, message ChatMessage, args []string) (string, error) {
+	player, err := findOnlinePlayer(ctx, message.EntityId)
+	if err != nil {
+		return "", err
+	}
+	ccs.homes[message.PlatformId] = player.Position
+	err = helper.MarshalFile(ctx, ccs.homes, getHomesFile(ctx))
+	if err != nil {
+		return "", err
+	}
+	return "Home set - type !home to return here", nil
+}
+
+// Handles '!home' - teleporting the player to their home
+func (ccs *ChatCommandService) handleHome(ctx context.Context, message ChatMessage, args []string) (string, error) {
+	home, ok := ccs.homes[message.PlatformId]
+	if !ok {
+		return "You haven't set a home - type !sethome to set one", nil
+	}
+	err := TeleportPlayer(ctx, message.EntityId, home)
+	if err != nil {
+		return "", err
+	}
+	return "Welcome home", nil
+}
+
+// Handles '!tp [player]' - teleporting the player to another online player
+func (ccs *ChatCommandService) handleTp(ctx context.Context, message ChatMessage, args []string) (string, error) {
+	if len(args) == 0 {
+		return "Usage: !tp [player]", nil
+	}
+	target, err := findOnlinePlayer(ctx, strings.Join(args, " "))
+	if err != nil {
+		return "", err
+	}
+	err = TeleportPlayer(ctx, message.EntityId, target.Position)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Teleported to %s", target.Name), nil
+}
+
+// Checks whether a player is permitted to use a command - restricted commands can only be used by admins
+func (ccs *ChatCommandService) allowed(message ChatMessage, command string) bool {
+	for _, restricted := range ccs.Opts.Restricted {
+		if strings.EqualFold(restricted, command) {
+			return matchesPlayerId(ccs.Opts.Admins, message.PlatformId)
+		}
+	}
+	return true
+}
+
+// Handles a chat message - executing the chat command it contains (if any) and replying to the player.
+// Commands are subject to permission checks and per-player cooldowns (admins are exempt from cooldowns).
+func (ccs *ChatCommandService) Handle(ctx context.Context, message ChatMessage, now time.Time) {
+	fields := strings.Fields(message.Message)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], chatCommandPrefix) {
+		return
+	}
+	command := strings.ToLower(strings.TrimPrefix(fields[0], chatCommandPrefix))
+	handler, ok := ccs.handlers[command]
+	if !ok {
+		return
+	}
+	if !ccs.allowed(message, command) {
+		SayPlayer(ctx, message.EntityId, fmt.Sprintf("You aren't permitted to use %s%s", chatCommandPrefix, command))
+		return
+	}
+	key := fmt.Sprintf("%s/%s", message.PlatformId, command)
+	admin := matchesPlayerId(ccs.Opts.Admins, message.PlatformId)
+	if until, ok := ccs.cooldowns[key]; ok && now.Before(until) && !admin {
+		SayPlayer(ctx, message.EntityId, fmt.Sprintf("You can use %s%s again in %s", chatCommandPrefix, command, until.Sub(now).Round(time.Second)))
+		return
+	}
+	helper.Logger(ctx).Info("chat command", "player", message.Name, "command", command)
+	reply, err := handler(ctx, message, fields[1:])
+	if err != nil {
+		helper.Logger(ctx).Warn("chat command failed", "player", message.Name, "command", command, "error", err.Error())
+		SayPlayer(ctx, message.EntityId, fmt.Sprintf("%s%s failed: %s", chatCommandPrefix, command, err.Error()))
+		return
+	}
+	ccs.cooldowns[key] = now.Add(ccs.Opts.Cooldown)
+	if reply != "" {
+		SayPlayer(ctx, message.EntityId, reply)
+	}
+}
+
+// Handles chat commands sent by players until the context is cancelled.
+// Returns an error if the telnet session is unavailable.
+func (ccs *ChatCommandService) Run(ctx context.Context) error {
+	session := GetTelnetSession(ctx)
+	if session == nil {
+		return ErrTelnetNotConnected
+	}
+	helper.Logger(ctx).Info("start chat commands", "cooldown", ccs.Opts.Cooldown)
+	lines, unsubscribe := session.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			message := ParseChatMessage(line)
+			if message != nil {
+				ccs.Handle(ctx, *message, time.Now())
+			}
+		}
+	}
+}
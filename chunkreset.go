@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// regionSize is the width (in blocks) of the area stored within a single region file (32x32 chunks of 16x16 blocks)
+const regionSize = 512
+
+// regionFileRegex matches a region file name (capturing the region x and z coordinates)
+var regionFileRegex = regexp.MustCompile(`^r\.(-?\d+)\.(-?\d+)\.7rg$`)
+
+// landClaimRegex matches the position of a land claim within 'listlandprotection' output
+var landClaimRegex = regexp.MustCompile(`\((-?\d+),\s*(-?\d+),\s*(-?\d+)\)`)
+
+// ChunkResetArea is a rectangular area of the world (in block coordinates, inclusive)
+type ChunkResetArea struct {
+	X1 int `json:"x1"`
+	Z1 int `json:"z1"`
+	X2 int `json:"x2"`
+	Z2 int `json:"z2"`
+}
+
+// Determines whether the area fully contains a rectangle (in block coordinates, inclusive)
+func (cra ChunkResetArea) contains(x1 int, z1 int, x2 int, z2 int) bool {
+	return min(cra.X1, cra.X2) <= x1 && x2 <= max(cra.X1, cra.X2) && min(cra.Z1, cra.Z2) <= z1 && z2 <= max(cra.Z1, cra.Z2)
+}
+
+// Determines whether the area overlaps a rectangle (in block coordinates, inclusive)
+func (cra ChunkResetArea) overlaps(x1 int, z1 int, x2 int, z2 int) bool {
+	return min(cra.X1, cra.X2) <= x2 && x1 <= max(cra.X1, cra.X2) && min(cra.Z1, cra.Z2) <= z2 && z1 <= max(cra.Z1, cra.Z2)
+}
+
+// ChunkResetRule defines region files that are reset (i.e., deleted - so that the game regenerates them with fresh loot and POIs) during restarts
+type ChunkResetRule struct {
+	Name string `json:"name"`
+	// Areas restricts the rule to region files fully contained by one of the areas (empty applies to the entire world)
+	Areas []ChunkResetArea `json:"areas"`
+	// Exclude protects region files overlapping any of the areas (e.g., a spawn town)
+	Exclude []ChunkResetArea `json:"exclude"`
+	// Untouched is the time (e.g., '720h') since a region file was last modified after which it's reset
+	Untouched string `json:"untouched"`
+	untouched time.Duration
+}
+
+// Validates the rule - parsing its durations.
+// Returns an error if the untouched duration is invalid.
+func (crr *ChunkResetRule) Validate() error {
+	untouched, err := time.ParseDuration(crr.Untouched)
+	if err != nil || untouched <= 0 {
+		return fmt.Errorf("chunk reset rule %s: invalid untouched duration %s (expected a positive duration)", crr.Name, crr.Untouched)
+	}
+	crr.untouched = untouched
+	return nil
+}
+
+// Determines whether the rule resets a region file (by region coordinates and modification time)
+func (crr *ChunkResetRule) matches(x int, z int, modified time.Time, now time.Time) bool {
+	if now.Sub(modified) < crr.untouched {
+		return false
+	}
+	x1, z1 := x*regionSize, z*regionSize
+	x2, z2 := x1+regionSize-1, z1+regionSize-1
+	for _, area := range crr.Exclude {
+		if area.overlaps(x1, z1, x2, z2) {
+			return false
+		}
+	}
+	if len(crr.Areas) == 0 {
+		return true
+	}
+	for _, area := range crr.Areas {
+		if area.contains(x1, z1, x2, z2) {
+			return true
+		}
+	}
+	return false
+}
+
+// Loads chunk reset rules from a JSON file (formatted as '{"rules": [...]}').
+// Returns an error if the file cannot be read or a rule is invalid.
+func LoadChunkResetRules(ctx context.Context, file string) ([]ChunkResetRule, error) {
+	helper.Logger(ctx).Info("load chunk reset rules", "path", file)
+	data := struct {
+		Rules []ChunkResetRule `json:"rules"`
+	}{}
+	err := helper.UnmarshalFile(ctx, file, &data)
+	if err != nil {
+		return nil, err
+	}
+	for index := range data.Rules {
+		err := data.Rules[index].Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data.Rules, nil
+}
+
+// LandClaims are the land claim positions recorded while the server was running
+type LandClaims struct {
+	UpdatedAt time.Time    `json:"updatedAt"`
+	Positions [][3]float64 `json:"positions"`
+}
+
+// Gets the path of the file that records land claim positions
+func getLandClaimsFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "landclaims.json")
+}
+
+// Records the positions of all land claims (via 'listlandprotection') - so that chunk resets performed while the server is stopped never touch claimed land.
+// Returns an error if the console command fails or the positions cannot be written.
+func RecordLandClaims(ctx context.Context) error {
+	lines, err := SendCommand(ctx, "listlandprotection")
+	if err != nil {
+		return err
+	}
+	claims := LandClaims{UpdatedAt: time.Now().UTC(), Positions: [][3]float64{}}
+	for _, line := range lines {
+		match := landClaimRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		position := [3]float64{}
+		for index := range position {
+			position[index], _ = strconv.ParseFloat(match[index+1], 64)
+		}
+		claims.Positions = append(claims.Positions, position)
+	}
+	return helper.MarshalFile(ctx, claims, getLandClaimsFile(ctx))
+}
+
+// Periodically records land claim positions until the context is cancelled.
+// Failures are logged and otherwise ignored.
+func RunLandClaimTracking(ctx context.Context, interval time.Duration) {
+	for {
+		err := RecordLandClaims(ctx)
+		if err != nil {
+			helper.Logger(ctx).Warn("record land claims failed", "error", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ChunkResetOpts defines the options used in conjunction with the [ResetChunks] function
+type ChunkResetOpts struct {
+	// GameName is the name of the save game whose region files are reset
+	GameName string
+	// LandClaimSize is the width (in blocks) of the area protected by a land claim
+	LandClaimSize int
+	Rules         []ChunkResetRule
+}
+
+// Resets region files matching any of the rules - deleting them so that the game regenerates them from the world on the next start.
+// Region files near recorded land claims are never reset (and nothing is reset until land claims have been recorded).
+// Must be called while the server is stopped.
+// Returns the number of reset region files.
+// Returns an error if the land claims or region files cannot be read - or a region file cannot be deleted.
+func ResetChunks(ctx context.Context, opts ChunkResetOpts) (int, error) {
+	saveGame := findSaveGame(ctx, opts.GameName)
+	if saveGame == "" {
+		return 0, nil
+	}
+	claims := LandClaims{}
+	err := helper.UnmarshalFile(ctx, getLandClaimsFile(ctx), &claims)
+	if errors.Is(err, os.ErrNotExist) {
+		helper.Logger(ctx).Warn("chunk resets skipped until land claims have been recorded")
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	// protect an additional chunk around each claim
+	margin := opts.LandClaimSize/2 + 16
+	protected := map[[2]int]bool{}
+	for _, position := range claims.Positions {
+		x, z := int(position[0]), int(position[2])
+		for rx := floorDiv(x-margin, regionSize); rx <= floorDiv(x+margin, regionSize); rx++ {
+			for rz := floorDiv(z-margin, regionSize); rz <= floorDiv(z+margin, regionSize); rz++ {
+				protected[[2]int{rx, rz}] = true
+			}
+		}
+	}
+
+	subpaths, err := helper.ListDir(ctx, filepath.Join(saveGame, "Region"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	count := 0
+	for _, subpath := range subpaths {
+		match := regionFileRegex.FindStringSubmatch(filepath.Base(subpath))
+		if match == nil {
+			continue
+		}
+		x, _ := strconv.Atoi(match[1])
+		z, _ := strconv.Atoi(match[2])
+		if protected[[2]int{x, z}] {
+			continue
+		}
+		info, err := os.Stat(subpath)
+		if err != nil {
+			return count, err
+		}
+		for index := range opts.Rules {
+			rule := &opts.Rules[index]
+			if !rule.matches(x, z, info.ModTime(), now) {
+				continue
+			}
+			helper.Logger(ctx).Info("reset region", "region", filepath.Base(subpath), "rule", rule.Name, "modified", info.ModTime())
+			err = helper.RemovePaths(ctx, subpath)
+			if err != nil {
+				return count, err
+			}
+			count += 1
+			break
+		}
+	}
+	return count, nil
+}
+
+// Divides (rounding towards negative infinity) - used to convert block coordinates into region coordinates
+func floorDiv(value int, divisor int) int {
+	return int(math.Floor(float64(value) / float64(divisor)))
+}
@@ -0,0 +1,89 @@
+// Command fakeserver runs a fake seven days to die telnet console (and http server) for local development - so that entrypoint subcommands (e.g., 'exec', 'health', 'snapshot') and lifecycle features can be exercised without a real server.
+//
+// Lines read from stdin are emitted as server log lines (e.g., 'Chat (from '-non-player-', entity id '-1', to 'Global'): 'Server': hello').
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/benfiola/seven-days-to-die/internal/fakes"
+)
+
+// Runs the fake servers until interrupted (or until a 'shutdown' command is received).
+// Returns an error if the servers cannot listen on their addresses.
+func run() error {
+	telnetAddr := flag.String("telnet", "localhost:8081", "the address the fake telnet console listens on")
+	httpAddr := flag.String("http", "localhost:8090", "the address the fake http server listens on")
+	files := flag.String("files", "", "a directory whose files are served by the fake http server (e.g., mod archives)")
+	notReady := flag.Bool("not-ready", false, "start the fake telnet console in a 'world loading' state (until 'ready' is read from stdin)")
+	flag.Parse()
+
+	telnet := fakes.NewTelnetServer()
+	telnet.SetReady(!*notReady)
+	addr, err := telnet.Listen(*telnetAddr)
+	if err != nil {
+		return err
+	}
+	defer telnet.Close()
+	fmt.Fprintf(os.Stderr, "fake telnet console listening on %s\n", addr)
+
+	server := fakes.NewHttpServer()
+	if *files != "" {
+		paths, _ := filepath.Glob(filepath.Join(*files, "*"))
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			server.Respond("/"+filepath.Base(path), fakes.HttpResponse{Body: data})
+		}
+	}
+	addr, err = server.Listen(*httpAddr)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+	fmt.Fprintf(os.Stderr, "fake http server listening on %s\n", addr)
+
+	shutdown := make(chan struct{})
+	telnet.Respond("shutdown", func(args string) []string {
+		close(shutdown)
+		return []string{"Shutting server down..."}
+	})
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "ready" {
+				telnet.SetReady(true)
+				continue
+			}
+			telnet.Emit(line)
+		}
+	}()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	select {
+	case <-signals:
+	case <-shutdown:
+		fmt.Fprintln(os.Stderr, "shutdown received")
+	}
+	for _, command := range telnet.Commands() {
+		fmt.Fprintf(os.Stderr, "received command: %s\n", command)
+	}
+	return nil
+}
+
+func main() {
+	err := run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// EntrypointConfig is the configuration for the entrypoint - parsed from the environment
+type EntrypointConfig struct {
+	AdminApiEnabled        bool           `env:"ADMIN_API_ENABLED"`
+	AdminApiPort           int            `env:"ADMIN_API_PORT" envDefault:"8083"`
+	AdminApiTokens         []string       `env:"ADMIN_API_TOKENS"`
+	AdminApiTokensFile     string         `env:"ADMIN_API_TOKENS_FILE"`
+	AdminCommandWhitelist  []string       `env:"ADMIN_COMMAND_WHITELIST" envDefault:"admin,ban,gettime,kick,listplayers,lp,saveworld,say,whitelist"`
+	AfkKickExempt          []string       `env:"AFK_KICK_EXEMPT"`
+	AfkKickFreeSlots       int            `env:"AFK_KICK_FREE_SLOTS" envDefault:"1"`
+	AfkKickTimeout         *time.Duration `env:"AFK_KICK_TIMEOUT"`
+	AlertExempt            []string       `env:"ALERT_EXEMPT"`
+	AlertMaxSpeed          float64        `env:"ALERT_MAX_SPEED" envDefault:"50"`
+	AlertSpawnLimit        int            `env:"ALERT_SPAWN_LIMIT" envDefault:"20"`
+	AlertWebhookUrl        *url.URL       `env:"ALERT_WEBHOOK_URL"`
+	AllocsFixesEnabled     bool           `env:"ALLOCS_FIXES_ENABLED"`
+	AllocsFixesTokens      []string       `env:"ALLOCS_FIXES_TOKENS"`
+	AllocsFixesUrl         string         `env:"ALLOCS_FIXES_URL"`
+	AllowWorldChange       bool           `env:"ALLOW_WORLD_SETTINGS_CHANGE"`
+	AuditWebhookUrl        *url.URL       `env:"AUDIT_WEBHOOK_URL"`
+	BackupDestinations     []string       `env:"BACKUP_DESTINATIONS"`
+	BindAddress            string         `env:"BIND_ADDRESS"`
+	BackupInterval         *time.Duration `env:"BACKUP_INTERVAL"`
+	BackupMode             BackupMode     `env:"BACKUP_MODE" envDefault:"full"`
+	BackupRetention        int            `env:"BACKUP_RETENTION" envDefault:"10"`
+	BackupVerifyExtract    bool           `env:"BACKUP_VERIFY_EXTRACT"`
+	BackupWebhookUrl       *url.URL       `env:"BACKUP_WEBHOOK_URL"`
+	BanSyncPeers           []string       `env:"BAN_SYNC_PEERS"`
+	BanSyncToken           string         `env:"BAN_SYNC_TOKEN"`
+	ChatCommandAdmins      []string       `env:"CHAT_COMMAND_ADMINS"`
+	ChatCommandCooldown    time.Duration  `env:"CHAT_COMMAND_COOLDOWN" envDefault:"5m"`
+	ChatCommandRestricted  []string       `env:"CHAT_COMMAND_RESTRICTED" envDefault:"tp"`
+	ChatCommandsEnabled    bool           `env:"CHAT_COMMANDS_ENABLED"`
+	ChunkResetFile         string         `env:"CHUNK_RESET_FILE"`
+	ConfigBundle           string         `env:"CONFIG_BUNDLE"`
+	ConfigVersion          int            `env:"CONFIG_VERSION"`
+	ConfigWatchWebhookUrl  *url.URL       `env:"CONFIG_WATCH_WEBHOOK_URL"`
+	ControlSocket          string         `env:"CONTROL_SOCKET"`
+	CpuAffinity            string         `env:"CPU_AFFINITY"`
+	DataLock               bool           `env:"DATA_LOCK" envDefault:"true"`
+	DeleteDefaultMods      bool           `env:"DELETE_DEFAULT_MODS"`
+	DeleteSettings         []string       `env:"DELETE_SETTINGS"`
+	DirectoryInterval      time.Duration  `env:"DIRECTORY_INTERVAL" envDefault:"1m"`
+	DirectoryToken         string         `env:"DIRECTORY_TOKEN"`
+	DirectoryUrl           *url.URL       `env:"DIRECTORY_URL"`
+	DownloadMirrors        []string       `env:"DOWNLOAD_MIRRORS"`
+	DownloadProxy          *url.URL       `env:"DOWNLOAD_PROXY"`
+	DriftCheckInterval     *time.Duration `env:"DRIFT_CHECK_INTERVAL"`
+	DriftPersist           bool           `env:"DRIFT_PERSIST"`
+	EacAutoDisable         bool           `env:"EAC_AUTO_DISABLE"`
+	EmulatorUrl            string         `env:"EMULATOR_URL"`
+	EnvFile                string         `env:"ENV_FILE"`
+	EventsDb               bool           `env:"EVENTS_DB"`
+	EventsDbRetention      time.Duration  `env:"EVENTS_DB_RETENTION" envDefault:"2160h"`
+	ExecutionMode          ExecutionMode  `env:"EXECUTION_MODE" envDefault:"native"`
+	FailoverCheckInterval  time.Duration  `env:"FAILOVER_CHECK_INTERVAL" envDefault:"10s"`
+	FailoverPrimaryToken   string         `env:"FAILOVER_PRIMARY_TOKEN"`
+	FailoverPrimaryUrl     *url.URL       `env:"FAILOVER_PRIMARY_URL"`
+	FailoverSource         string         `env:"FAILOVER_SOURCE"`
+	FailoverThreshold      time.Duration  `env:"FAILOVER_THRESHOLD" envDefault:"2m"`
+	FailoverWebhookUrl     *url.URL       `env:"FAILOVER_WEBHOOK_URL"`
+	GameVersion            string         `env:"GAME_VERSION"`
+	HordeEventsFile        string         `env:"HORDE_EVENTS_FILE"`
+	HordeEventsWebhookUrl  *url.URL       `env:"HORDE_EVENTS_WEBHOOK_URL"`
+	IdleShutdownInstance   string         `env:"IDLE_SHUTDOWN_INSTANCE"`
+	IdleShutdownTimeout    *time.Duration `env:"IDLE_SHUTDOWN_TIMEOUT"`
+	IdleShutdownWebhookUrl *url.URL       `env:"IDLE_SHUTDOWN_WEBHOOK_URL"`
+	InstallStaging         bool           `env:"INSTALL_STAGING"`
+	KillFeedAdminUrl       *url.URL       `env:"KILL_FEED_ADMIN_WEBHOOK_URL"`
+	KillFeedDeaths         bool           `env:"KILL_FEED_DEATHS" envDefault:"true"`
+	KillFeedWebhookUrl     *url.URL       `env:"KILL_FEED_WEBHOOK_URL"`
+	LocalizationMerge      bool           `env:"LOCALIZATION_MERGE"`
+	LocalizationUrls       []string       `env:"LOCALIZATION_URLS"`
+	LowDiskThreshold       int            `env:"LOW_DISK_THRESHOLD"`
+	LowDiskWebhookUrl      *url.URL       `env:"LOW_DISK_WEBHOOK_URL"`
+	MacrosFile             string         `env:"MACROS_FILE"`
+	Maintenance            bool           `env:"MAINTENANCE"`
+	MaintenanceInterval    *time.Duration `env:"MAINTENANCE_INTERVAL"`
+	MaintenanceLogMaxAge   time.Duration  `env:"MAINTENANCE_LOG_MAX_AGE" envDefault:"168h"`
+	MaintenanceMessage     string         `env:"MAINTENANCE_MESSAGE" envDefault:"The server is in maintenance mode - please check back later"`
+	MaintenancePassword    string         `env:"MAINTENANCE_PASSWORD"`
+	MaintenanceTileMaxAge  time.Duration  `env:"MAINTENANCE_TILE_MAX_AGE" envDefault:"720h"`
+	ManifestId             string         `env:"MANIFEST_ID"`
+	MetricsHistory         bool           `env:"METRICS_HISTORY"`
+	MetricsRetention       time.Duration  `env:"METRICS_RETENTION" envDefault:"720h"`
+	ModAutoUpdate          bool           `env:"MOD_AUTO_UPDATE"`
+	ModPolicyFile          string         `env:"MOD_POLICY_FILE"`
+	ModUpdateCheckInterval *time.Duration `env:"MOD_UPDATE_CHECK_INTERVAL"`
+	ModUrls                []string       `env:"MOD_URLS"`
+	ModsBisect             bool           `env:"MODS_BISECT"`
+	ModsRollback           bool           `env:"MODS_ROLLBACK"`
+	MountWait              []string       `env:"MOUNT_WAIT"`
+	MountWaitTimeout       time.Duration  `env:"MOUNT_WAIT_TIMEOUT" envDefault:"5m"`
+	Offline                bool           `env:"OFFLINE"`
+	PanelMode              bool           `env:"PANEL_MODE"`
+	PingKickDuration       time.Duration  `env:"PING_KICK_DURATION" envDefault:"2m"`
+	PingKickExempt         []string       `env:"PING_KICK_EXEMPT"`
+	PingKickThreshold      int            `env:"PING_KICK_THRESHOLD"`
+	PlayerCountAnnotate    bool           `env:"PLAYER_COUNT_ANNOTATE"`
+	PlayerPositions        *time.Duration `env:"PLAYER_POSITIONS_INTERVAL"`
+	PlayerSnapshotDeaths   bool           `env:"PLAYER_SNAPSHOT_DEATHS"`
+	PlayerSnapshotLimit    int            `env:"PLAYER_SNAPSHOT_LIMIT" envDefault:"10"`
+	PlaytimeRewardsFile    string         `env:"PLAYTIME_REWARDS_FILE"`
+	PluginsDir             string         `env:"PLUGINS_DIR"`
+	PodName                string         `env:"POD_NAME"`
+	PostStartCommands      []string       `env:"POST_START_COMMANDS" envSeparator:";"`
+	PrefabPackUrls         []string       `env:"PREFAB_PACK_URLS"`
+	PreflightSkip          bool           `env:"PREFLIGHT_SKIP"`
+	Preset                 string         `env:"PRESET"`
+	ProcessPriority        *int           `env:"PROCESS_PRIORITY"`
+	ProtonUrl              string         `env:"PROTON_URL"`
+	PublicAddress          string         `env:"PUBLIC_ADDRESS"`
+	RootUrls               []string       `env:"ROOT_URLS"`
+	SaveInterval           *time.Duration `env:"SAVE_INTERVAL"`
+	SaveJitter             time.Duration  `env:"SAVE_JITTER" envDefault:"2m"`
+	ServerLanguage         string         `env:"SERVER_LANGUAGE"`
+	ServerLogHide          []string       `env:"SERVER_LOG_HIDE"`
+	ServerLogSuppress      []string       `env:"SERVER_LOG_SUPPRESS"`
+	ServerReadyTimeout     time.Duration  `env:"SERVER_READY_TIMEOUT" envDefault:"10m"`
+	SettingsProfilesFile   string         `env:"SETTINGS_PROFILES_FILE"`
+	SettingsTransform      string         `env:"SETTINGS_TRANSFORM"`
+	StandbyBandwidthLimit  int            `env:"STANDBY_BANDWIDTH_LIMIT"`
+	StandbyDestinations    []string       `env:"STANDBY_DESTINATIONS"`
+	StandbyInterval        time.Duration  `env:"STANDBY_INTERVAL" envDefault:"5m"`
+	TelnetTranscript       bool           `env:"TELNET_TRANSCRIPT"`
+	TelnetTranscriptFiles  int            `env:"TELNET_TRANSCRIPT_FILES" envDefault:"5"`
+	TelnetTranscriptSize   int            `env:"TELNET_TRANSCRIPT_SIZE" envDefault:"10"`
+	TrustedProxies         []string       `env:"TRUSTED_PROXIES"`
+	UpdateVoteCommand      string         `env:"UPDATE_VOTE_COMMAND" envDefault:"/update"`
+	UpdateVoteDeadline     *time.Duration `env:"UPDATE_VOTE_DEADLINE"`
+	WebdavEnabled          bool           `env:"WEBDAV_ENABLED"`
+	WebdavPassword         string         `env:"WEBDAV_PASSWORD"`
+	WebdavPort             int            `env:"WEBDAV_PORT" envDefault:"8082"`
+	WebdavUsername         string         `env:"WEBDAV_USERNAME" envDefault:"admin"`
+	AutoRestart            *time.Duration `env:"AUTO_RESTART"`
+	AutoRestartMessage     string         `env:"AUTO_RESTART_MESSAGE" envDefault:"Restarting server in 1 minute"`
+}
+
+// manifestIdRegex matches a steam depot manifest id
+var manifestIdRegex = regexp.MustCompile(`^\d+$`)
+
+// Applies defaults that depend on runtime state (and so cannot be expressed with 'envDefault' tags)
+func (ec *EntrypointConfig) applyDefaults(ctx context.Context) {
+	if ec.PluginsDir == "" {
+		ec.PluginsDir = filepath.Join(helper.Dirs(ctx)["data"], "plugins")
+	}
+	if ec.AllocsFixesUrl == "" {
+		ec.AllocsFixesUrl = DefaultAllocsFixesUrl
+	}
+	if ec.AllocsFixesEnabled {
+		// installed alongside root urls (the archive contains a 'Mods' folder) - so that offline mode, mod policies and update checks apply
+		ec.RootUrls = append(ec.RootUrls, ec.AllocsFixesUrl)
+	}
+	if ec.ProtonUrl == "" {
+		ec.ProtonUrl = DefaultProtonUrl
+	}
+}
+
+// Validates the configuration - including checks across fields.
+// Combinations that are valid but likely unintended are returned as warnings.
+// Returns an error joining all validation failures.
+func (ec *EntrypointConfig) Validate() ([]string, error) {
+	errs := []error{}
+	warnings := []string{}
+	if !manifestIdRegex.MatchString(ec.ManifestId) {
+		errs = append(errs, fmt.Errorf("MANIFEST_ID must be a numeric manifest id (got '%s')", ec.ManifestId))
+	}
+	if ec.GameVersion != "" {
+		_, err := ParseGameVersion(ec.GameVersion)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("GAME_VERSION invalid: %w", err))
+		}
+	}
+	if ec.Preset != "" {
+		_, err := GetPresetSettings(ec.Preset)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("PRESET invalid: %w", err))
+		}
+	}
+	_, err := ParseMirrorRules(ec.DownloadMirrors)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("DOWNLOAD_MIRRORS invalid: %w", err))
+	}
+	if ec.AutoRestart != nil && *ec.AutoRestart <= time.Minute {
+		errs = append(errs, fmt.Errorf("AUTO_RESTART must be longer than 1m (the restart alert is sent 1m beforehand)"))
+	}
+	if ec.ModUpdateCheckInterval != nil && *ec.ModUpdateCheckInterval <= 0 {
+		errs = append(errs, fmt.Errorf("MOD_UPDATE_CHECK_INTERVAL must be positive"))
+	}
+	if ec.BackupInterval != nil && *ec.BackupInterval <= 0 {
+		errs = append(errs, fmt.Errorf("BACKUP_INTERVAL must be positive"))
+	}
+	if ec.SaveInterval != nil && *ec.SaveInterval <= 0 {
+		errs = append(errs, fmt.Errorf("SAVE_INTERVAL must be positive"))
+	}
+	if ec.SaveJitter < 0 {
+		errs = append(errs, fmt.Errorf("SAVE_JITTER must not be negative"))
+	}
+	_, err = ParseReplicationTargets(ec.BackupDestinations)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("BACKUP_DESTINATIONS invalid: %w", err))
+	}
+	standbyTargets, err := ParseReplicationTargets(ec.StandbyDestinations)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("STANDBY_DESTINATIONS invalid: %w", err))
+	}
+	if ec.StandbyInterval <= 0 {
+		errs = append(errs, fmt.Errorf("STANDBY_INTERVAL must be positive"))
+	}
+	if ec.StandbyBandwidthLimit < 0 {
+		errs = append(errs, fmt.Errorf("STANDBY_BANDWIDTH_LIMIT must not be negative"))
+	}
+	if ec.ServerLanguage != "" {
+		err = ValidateServerLanguage(ec.ServerLanguage)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("SERVER_LANGUAGE invalid: %w", err))
+		}
+	}
+	if ec.TelnetTranscriptFiles < 0 || ec.TelnetTranscriptSize <= 0 {
+		errs = append(errs, fmt.Errorf("TELNET_TRANSCRIPT_FILES must not be negative and TELNET_TRANSCRIPT_SIZE must be positive"))
+	}
+	if ec.IdleShutdownTimeout != nil && *ec.IdleShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("IDLE_SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if ec.IdleShutdownInstance != "" && !slices.Contains(instanceProviders, InstanceProvider(ec.IdleShutdownInstance)) {
+		errs = append(errs, fmt.Errorf("IDLE_SHUTDOWN_INSTANCE must be one of %v", instanceProviders))
+	}
+	if InstanceProvider(ec.IdleShutdownInstance) == InstanceProviderWebhook && ec.IdleShutdownWebhookUrl == nil {
+		errs = append(errs, fmt.Errorf("IDLE_SHUTDOWN_WEBHOOK_URL must be set when IDLE_SHUTDOWN_INSTANCE is webhook"))
+	}
+	if ec.FailoverPrimaryUrl != nil {
+		if !slices.Contains([]string{"http", "https", "tcp"}, ec.FailoverPrimaryUrl.Scheme) {
+			errs = append(errs, fmt.Errorf("FAILOVER_PRIMARY_URL must be an http, https or tcp url"))
+		}
+		if ec.FailoverSource == "" {
+			errs = append(errs, fmt.Errorf("FAILOVER_SOURCE must be set when FAILOVER_PRIMARY_URL is set"))
+		}
+	}
+	if ec.FailoverSource != "" {
+		_, err = ParseReplicationTarget(ec.FailoverSource)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("FAILOVER_SOURCE invalid: %w", err))
+		}
+	}
+	if ec.FailoverCheckInterval <= 0 || ec.FailoverThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("FAILOVER_CHECK_INTERVAL and FAILOVER_THRESHOLD must be positive"))
+	}
+	_, err = ParseBanSyncPeers(ec.BanSyncPeers)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("BAN_SYNC_PEERS invalid: %w", err))
+	}
+	if len(ec.BanSyncPeers) > 0 && ec.BanSyncToken == "" {
+		errs = append(errs, fmt.Errorf("BAN_SYNC_TOKEN must be set when BAN_SYNC_PEERS is set"))
+	}
+	if ec.BindAddress != "" && net.ParseIP(strings.Trim(ec.BindAddress, "[]")) == nil {
+		errs = append(errs, fmt.Errorf("BIND_ADDRESS must be an ip address (got '%s')", ec.BindAddress))
+	}
+	if ec.EventsDbRetention < 0 {
+		errs = append(errs, fmt.Errorf("EVENTS_DB_RETENTION must not be negative"))
+	}
+	_, err = ParseServerLogLevels(ec.ServerLogHide)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("SERVER_LOG_HIDE invalid: %w", err))
+	}
+	_, err = ParseServerLogPatterns(ec.ServerLogSuppress)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("SERVER_LOG_SUPPRESS invalid: %w", err))
+	}
+	for _, dir := range ec.MountWait {
+		if !filepath.IsAbs(dir) && !slices.Contains(dirNames, dir) {
+			errs = append(errs, fmt.Errorf("MOUNT_WAIT has unknown directory %s (expected an absolute path or one of %v)", dir, dirNames))
+		}
+	}
+	if ec.MountWaitTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("MOUNT_WAIT_TIMEOUT must be positive"))
+	}
+	if ec.PlayerPositions != nil && *ec.PlayerPositions <= 0 {
+		errs = append(errs, fmt.Errorf("PLAYER_POSITIONS_INTERVAL must be positive"))
+	}
+	if ec.PlayerSnapshotLimit <= 0 {
+		errs = append(errs, fmt.Errorf("PLAYER_SNAPSHOT_LIMIT must be positive"))
+	}
+	if ec.MetricsRetention <= 0 {
+		errs = append(errs, fmt.Errorf("METRICS_RETENTION must be positive"))
+	}
+	if ec.BackupRetention < 0 {
+		errs = append(errs, fmt.Errorf("BACKUP_RETENTION must not be negative"))
+	}
+	if ec.MaintenanceInterval != nil && *ec.MaintenanceInterval <= 0 {
+		errs = append(errs, fmt.Errorf("MAINTENANCE_INTERVAL must be positive"))
+	}
+	if ec.UpdateVoteDeadline != nil && *ec.UpdateVoteDeadline <= 0 {
+		errs = append(errs, fmt.Errorf("UPDATE_VOTE_DEADLINE must be positive"))
+	}
+	if ec.DriftCheckInterval != nil && *ec.DriftCheckInterval <= 0 {
+		errs = append(errs, fmt.Errorf("DRIFT_CHECK_INTERVAL must be positive"))
+	}
+	if ec.AfkKickTimeout != nil && *ec.AfkKickTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("AFK_KICK_TIMEOUT must be positive"))
+	}
+	if ec.AfkKickFreeSlots <= 0 {
+		errs = append(errs, fmt.Errorf("AFK_KICK_FREE_SLOTS must be positive"))
+	}
+	if ec.ChatCommandCooldown < 0 {
+		errs = append(errs, fmt.Errorf("CHAT_COMMAND_COOLDOWN must not be negative"))
+	}
+	if ec.AlertMaxSpeed <= 0 {
+		errs = append(errs, fmt.Errorf("ALERT_MAX_SPEED must be positive"))
+	}
+	if ec.AlertSpawnLimit <= 0 {
+		errs = append(errs, fmt.Errorf("ALERT_SPAWN_LIMIT must be positive"))
+	}
+	if ec.PingKickThreshold < 0 {
+		errs = append(errs, fmt.Errorf("PING_KICK_THRESHOLD must not be negative"))
+	}
+	if ec.PingKickThreshold > 0 && ec.PingKickDuration <= 0 {
+		errs = append(errs, fmt.Errorf("PING_KICK_DURATION must be positive"))
+	}
+	if ec.LowDiskThreshold < 0 {
+		errs = append(errs, fmt.Errorf("LOW_DISK_THRESHOLD must not be negative"))
+	}
+	if ec.DirectoryInterval <= 0 {
+		errs = append(errs, fmt.Errorf("DIRECTORY_INTERVAL must be positive"))
+	}
+	if ec.ServerReadyTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("SERVER_READY_TIMEOUT must be positive"))
+	}
+	if ec.WebdavEnabled && len(ec.WebdavPassword) < 8 {
+		errs = append(errs, fmt.Errorf("WEBDAV_PASSWORD must be at least 8 characters when WEBDAV_ENABLED is set"))
+	}
+	_, err = ParseTrustedProxies(ec.TrustedProxies)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("TRUSTED_PROXIES invalid: %w", err))
+	}
+	_, err = ParseAdminTokens(ec.AdminApiTokens)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("ADMIN_API_TOKENS invalid: %w", err))
+	}
+	_, err = ParseAdminTokens(ec.AllocsFixesTokens)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("ALLOCS_FIXES_TOKENS invalid: %w", err))
+	}
+	if ec.AdminApiEnabled && len(ec.AdminApiTokens) == 0 && ec.AdminApiTokensFile == "" {
+		errs = append(errs, fmt.Errorf("ADMIN_API_TOKENS or ADMIN_API_TOKENS_FILE must be set when ADMIN_API_ENABLED is set"))
+	}
+	if ec.CpuAffinity != "" {
+		_, err = ParseCpuList(ec.CpuAffinity)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("CPU_AFFINITY invalid: %w", err))
+		}
+	}
+	if ec.ProcessPriority != nil && (*ec.ProcessPriority < -20 || *ec.ProcessPriority > 19) {
+		errs = append(errs, fmt.Errorf("PROCESS_PRIORITY must be between -20 and 19"))
+	}
+	if ec.Offline && ec.ModUpdateCheckInterval != nil {
+		warnings = append(warnings, "MOD_UPDATE_CHECK_INTERVAL is ignored when OFFLINE is enabled")
+	}
+	if ec.IdleShutdownInstance != "" && ec.IdleShutdownTimeout == nil {
+		warnings = append(warnings, "IDLE_SHUTDOWN_INSTANCE is ignored unless IDLE_SHUTDOWN_TIMEOUT is set")
+	}
+	if ec.Offline && (ec.DownloadProxy != nil || len(ec.DownloadMirrors) > 0) {
+		warnings = append(warnings, "DOWNLOAD_PROXY and DOWNLOAD_MIRRORS are ignored when OFFLINE is enabled")
+	}
+	if ec.ModAutoUpdate && ec.ModUpdateCheckInterval == nil {
+		warnings = append(warnings, "MOD_AUTO_UPDATE only applies updates found by previous checks - set MOD_UPDATE_CHECK_INTERVAL to check for updates")
+	}
+	if ec.ModsBisect && !ec.ModsRollback {
+		warnings = append(warnings, "MODS_BISECT is ignored unless MODS_ROLLBACK is set")
+	}
+	if ec.DriftPersist && ec.DriftCheckInterval == nil {
+		warnings = append(warnings, "DRIFT_PERSIST is ignored unless DRIFT_CHECK_INTERVAL is set")
+	}
+	if len(ec.BackupDestinations) > 0 && ec.BackupMode != BackupModeFull {
+		warnings = append(warnings, "BACKUP_DESTINATIONS only replicates full backups - set BACKUP_MODE=full")
+	}
+	if ec.FailoverPrimaryUrl == nil && (ec.FailoverSource != "" || ec.FailoverWebhookUrl != nil) {
+		warnings = append(warnings, "FAILOVER_SOURCE and FAILOVER_WEBHOOK_URL are ignored unless FAILOVER_PRIMARY_URL is set")
+	}
+	for _, target := range standbyTargets {
+		if target.Retention > 0 {
+			warnings = append(warnings, fmt.Sprintf("retention is ignored for STANDBY_DESTINATIONS (%s) - standby destinations only hold the latest save", target.Destination.Name()))
+		}
+	}
+	if ec.UpdateVoteDeadline != nil && (!ec.ModAutoUpdate || ec.ModUpdateCheckInterval == nil) {
+		warnings = append(warnings, "UPDATE_VOTE_DEADLINE is ignored unless MOD_AUTO_UPDATE and MOD_UPDATE_CHECK_INTERVAL are set")
+	}
+	if !ec.KillFeedDeaths && ec.KillFeedWebhookUrl == nil && ec.KillFeedAdminUrl == nil {
+		warnings = append(warnings, "KILL_FEED_DEATHS is ignored unless KILL_FEED_WEBHOOK_URL or KILL_FEED_ADMIN_WEBHOOK_URL is set")
+	}
+	if !ec.AllocsFixesEnabled && (len(ec.AllocsFixesTokens) > 0 || ec.AllocsFixesUrl != DefaultAllocsFixesUrl) {
+		warnings = append(warnings, "ALLOCS_FIXES_TOKENS and ALLOCS_FIXES_URL are ignored unless ALLOCS_FIXES_ENABLED is set")
+	}
+	if ec.Maintenance && ec.ModAutoUpdate {
+		warnings = append(warnings, "MOD_AUTO_UPDATE is ignored while MAINTENANCE is enabled")
+	}
+	if len(ec.BanSyncPeers) > 0 && !ec.AdminApiEnabled {
+		warnings = append(warnings, "bans synced from BAN_SYNC_PEERS are only received when ADMIN_API_ENABLED is set")
+	}
+	if ec.MetricsHistory && !ec.AdminApiEnabled {
+		warnings = append(warnings, "METRICS_HISTORY is only served when ADMIN_API_ENABLED is set")
+	}
+	if ec.PlayerPositions != nil && !ec.AdminApiEnabled {
+		warnings = append(warnings, "PLAYER_POSITIONS_INTERVAL is ignored unless ADMIN_API_ENABLED is set")
+	}
+	if ec.DirectoryUrl == nil && ec.DirectoryToken != "" {
+		warnings = append(warnings, "DIRECTORY_TOKEN is ignored unless DIRECTORY_URL is set")
+	}
+	if ec.HordeEventsFile == "" && ec.HordeEventsWebhookUrl != nil {
+		warnings = append(warnings, "HORDE_EVENTS_WEBHOOK_URL is ignored unless HORDE_EVENTS_FILE is set")
+	}
+	if ec.LowDiskThreshold == 0 && ec.LowDiskWebhookUrl != nil {
+		warnings = append(warnings, "LOW_DISK_WEBHOOK_URL is ignored unless LOW_DISK_THRESHOLD is set")
+	}
+	if ec.ExecutionMode != ExecutionModeProton && ec.ProtonUrl != DefaultProtonUrl {
+		warnings = append(warnings, "PROTON_URL is ignored unless EXECUTION_MODE is 'proton'")
+	}
+	if !ec.ExecutionMode.Emulated() && ec.EmulatorUrl != "" {
+		warnings = append(warnings, "EMULATOR_URL is ignored unless EXECUTION_MODE is 'box64' or 'fex'")
+	}
+	if ec.ExecutionMode.Emulated() && runtime.GOARCH == "amd64" {
+		warnings = append(warnings, fmt.Sprintf("EXECUTION_MODE '%s' is unnecessary on x86_64 hosts (use 'native')", ec.ExecutionMode))
+	}
+	return warnings, errors.Join(errs...)
+}
+
+// Logs the effective configuration (keyed by environment variable) - redacting passwords, tokens and credentials embedded in urls
+func (ec *EntrypointConfig) Dump(ctx context.Context) {
+	args := []any{}
+	value := reflect.ValueOf(*ec)
+	for index := 0; index < value.NumField(); index++ {
+		name := value.Type().Field(index).Tag.Get("env")
+		field := value.Field(index)
+		var data any
+		switch typed := field.Interface().(type) {
+		case *url.URL:
+			if typed != nil {
+				data = typed.Redacted()
+			}
+		case *time.Duration:
+			if typed != nil {
+				data = typed.String()
+			}
+		case time.Duration:
+			data = typed.String()
+		case *int:
+			if typed != nil {
+				data = *typed
+			}
+		case string:
+			data = typed
+			if (strings.HasSuffix(name, "_PASSWORD") || strings.HasSuffix(name, "_TOKEN")) && typed != "" {
+				data = "xxxxx"
+			}
+			if strings.HasSuffix(name, "_SOURCE") && typed != "" {
+				parsed, err := url.Parse(typed)
+				if err == nil {
+					data = parsed.Redacted()
+				}
+			}
+		case []string:
+			data = strings.Join(typed, ",")
+			if strings.HasSuffix(name, "_DESTINATIONS") {
+				redacted := []string{}
+				for _, item := range typed {
+					parsed, err := url.Parse(item)
+					if err == nil {
+						item = parsed.Redacted()
+					}
+					redacted = append(redacted, item)
+				}
+				data = strings.Join(redacted, ",")
+			}
+			if strings.HasSuffix(name, "_TOKENS") {
+				tokens, _ := ParseAdminTokens(typed)
+				names := []string{}
+				for _, token := range tokens {
+					name := fmt.Sprintf("%s:xxxxx", token.Name)
+					scopes := []string{}
+					for _, scope := range token.Scopes {
+						scopes = append(scopes, string(scope))
+					}
+					if len(scopes) > 0 {
+						name = fmt.Sprintf("%s:%s", name, strings.Join(scopes, "+"))
+					}
+					names = append(names, name)
+				}
+				data = strings.Join(names, ",")
+			}
+		default:
+			data = typed
+		}
+		args = append(args, name, data)
+	}
+	helper.Logger(ctx).Info("effective config", args...)
+}
+
+// Parses the entrypoint configuration from the environment, applies defaults, validates it and logs the effective configuration.
+// Returns an error if the environment cannot be parsed.
+// Returns an error if the configuration is invalid.
+func LoadEntrypointConfig(ctx context.Context) (EntrypointConfig, error) {
+	config := EntrypointConfig{}
+	err := helper.ParseEnv(ctx, &config)
+	if err != nil {
+		return config, err
+	}
+	config.applyDefaults(ctx)
+	config.Dump(ctx)
+	warnings, err := config.Validate()
+	for _, warning := range warnings {
+		helper.Logger(ctx).Warn("config warning", "warning", warning)
+	}
+	if err != nil {
+		return config, fmt.Errorf("invalid config: %w", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// executedCommandRegex matches the log line emitted whenever the server executes a console command (capturing the command)
+var executedCommandRegex = regexp.MustCompile(`INF Executing command '(.*?)' (?:by|from) `)
+
+// adminFileCommands are console commands that (expectedly) modify the server admin file
+var adminFileCommands = []string{"admin", "ban", "commandpermission", "cp", "whitelist"}
+
+// adminFileChangeWindow is how long after an [adminFileCommands] command a change to the server admin file is expected
+const adminFileChangeWindow = time.Minute
+
+// ConfigWatchOpts defines the options used in conjunction with the [ConfigWatcher] struct
+type ConfigWatchOpts struct {
+	// AdminFile is the path of the server admin file (modified by the game when admin commands are executed)
+	AdminFile string
+	// SettingsFile is the path of the generated server settings file (never modified while the server runs)
+	SettingsFile string
+	// WebhookUrl (if non-nil) is notified of unexpected changes
+	WebhookUrl *url.URL
+}
+
+// ConfigWatcher detects external modifications of the server's configuration files while the server runs (e.g., tampering or broken tooling on shared hosts)
+type ConfigWatcher struct {
+	Opts             ConfigWatchOpts
+	hashes           map[string]string
+	lastAdminCommand time.Time
+}
+
+// Creates a new [ConfigWatcher] - recording the current state of the watched files as the baseline
+func NewConfigWatcher(opts ConfigWatchOpts) *ConfigWatcher {
+	cw := &ConfigWatcher{Opts: opts, hashes: map[string]string{}}
+	for _, file := range []string{opts.AdminFile, opts.SettingsFile} {
+		cw.hashes[file] = hashConfigFile(file)
+	}
+	return cw
+}
+
+// Hashes the content of a file - returning an empty string if the file doesn't exist (or is unreadable)
+func hashConfigFile(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ""
+		}
+		return "unreadable"
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// Records console commands executed by the server - so that the admin file changes they cause aren't reported
+func (cw *ConfigWatcher) CheckLine(line string, now time.Time) {
+	match := executedCommandRegex.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+	name, _, _ := strings.Cut(strings.TrimSpace(match[1]), " ")
+	for _, command := range adminFileCommands {
+		if strings.EqualFold(command, name) {
+			cw.lastAdminCommand = now
+			return
+		}
+	}
+}
+
+// Checks the watched files for changes - logging (and notifying the webhook of) unexpected changes.
+// Returns the unexpectedly changed files.
+func (cw *ConfigWatcher) Check(ctx context.Context, now time.Time) []string {
+	changed := []string{}
+	for _, file := range []string{cw.Opts.AdminFile, cw.Opts.SettingsFile} {
+		hash := hashConfigFile(file)
+		previous := cw.hashes[file]
+		if hash == previous {
+			continue
+		}
+		cw.hashes[file] = hash
+		if file == cw.Opts.AdminFile && now.Sub(cw.lastAdminCommand) <= adminFileChangeWindow {
+			helper.Logger(ctx).Info("config file changed by admin command", "path", file)
+			continue
+		}
+		helper.Logger(ctx).Warn("config file changed unexpectedly", "path", file, "deleted", hash == "")
+		changed = append(changed, file)
+		if cw.Opts.WebhookUrl == nil {
+			continue
+		}
+		data, err := json.Marshal(map[string]any{"event": "config-changed", "path": file, "deleted": hash == "", "time": now})
+		if err == nil {
+			postWebhook(ctx, cw.Opts.WebhookUrl.String(), data)
+		}
+	}
+	return changed
+}
+
+// Watches the configuration files (polling every [interval]) until the context is cancelled.
+// Returns an error if the telnet session is unavailable.
+func (cw *ConfigWatcher) Run(ctx context.Context, interval time.Duration) error {
+	session := GetTelnetSession(ctx)
+	if session == nil {
+		return ErrTelnetNotConnected
+	}
+	helper.Logger(ctx).Info("start config file watch", "admin-file", cw.Opts.AdminFile, "settings-file", cw.Opts.SettingsFile)
+	lines, unsubscribe := session.Subscribe()
+	defer unsubscribe()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			cw.CheckLine(line, time.Now())
+		case <-ticker.C:
+			cw.Check(ctx, time.Now())
+		}
+	}
+}
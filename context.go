@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+)
+
+// ctxKeyDownloadConfig is the context key holding the [DownloadConfig]
+type ctxKeyDownloadConfig struct{}
+
+// Gets the [DownloadConfig] from the context
+func GetDownloadConfig(ctx context.Context) DownloadConfig {
+	config, _ := ctx.Value(ctxKeyDownloadConfig{}).(DownloadConfig)
+	return config
+}
+
+// Attaches a [DownloadConfig] to the context
+func WithDownloadConfig(ctx context.Context, config DownloadConfig) context.Context {
+	return context.WithValue(ctx, ctxKeyDownloadConfig{}, config)
+}
+
+// ctxKeyExecutionMode is the context key holding the [ExecutionMode]
+type ctxKeyExecutionMode struct{}
+
+// Gets the [ExecutionMode] from the context - defaulting to [ExecutionModeNative]
+func GetExecutionMode(ctx context.Context) ExecutionMode {
+	mode, ok := ctx.Value(ctxKeyExecutionMode{}).(ExecutionMode)
+	if !ok {
+		return ExecutionModeNative
+	}
+	return mode
+}
+
+// Attaches an [ExecutionMode] to the context
+func WithExecutionMode(ctx context.Context, mode ExecutionMode) context.Context {
+	return context.WithValue(ctx, ctxKeyExecutionMode{}, mode)
+}
+
+// ctxKeyTelnetSession is the context key holding the shared [TelnetSession]
+type ctxKeyTelnetSession struct{}
+
+// Gets the shared [TelnetSession] from the context.
+// Returns nil if no session is attached.
+func GetTelnetSession(ctx context.Context) *TelnetSession {
+	session, _ := ctx.Value(ctxKeyTelnetSession{}).(*TelnetSession)
+	return session
+}
+
+// Attaches a shared [TelnetSession] to the context
+func WithTelnetSession(ctx context.Context, session *TelnetSession) context.Context {
+	return context.WithValue(ctx, ctxKeyTelnetSession{}, session)
+}
+
+// ctxKeyStatusTracker is the context key holding the [StatusTracker]
+type ctxKeyStatusTracker struct{}
+
+// Gets the [StatusTracker] from the context.
+// Returns nil if no tracker is attached.
+func GetStatusTracker(ctx context.Context) *StatusTracker {
+	tracker, _ := ctx.Value(ctxKeyStatusTracker{}).(*StatusTracker)
+	return tracker
+}
+
+// Attaches a [StatusTracker] to the context
+func WithStatusTracker(ctx context.Context, tracker *StatusTracker) context.Context {
+	return context.WithValue(ctx, ctxKeyStatusTracker{}, tracker)
+}
+
+// ctxKeySayQueue is the context key holding the shared [SayQueue]
+type ctxKeySayQueue struct{}
+
+// Gets the shared [SayQueue] from the context.
+// Returns nil if no queue is attached.
+func GetSayQueue(ctx context.Context) *SayQueue {
+	queue, _ := ctx.Value(ctxKeySayQueue{}).(*SayQueue)
+	return queue
+}
+
+// Attaches a shared [SayQueue] to the context
+func WithSayQueue(ctx context.Context, queue *SayQueue) context.Context {
+	return context.WithValue(ctx, ctxKeySayQueue{}, queue)
+}
+
+// ctxKeyServerLogFilter is the context key holding the [ServerLogFilter]
+type ctxKeyServerLogFilter struct{}
+
+// Gets the [ServerLogFilter] from the context.
+// Returns nil if no filter is attached.
+func GetServerLogFilter(ctx context.Context) *ServerLogFilter {
+	filter, _ := ctx.Value(ctxKeyServerLogFilter{}).(*ServerLogFilter)
+	return filter
+}
+
+// Attaches a [ServerLogFilter] to the context
+func WithServerLogFilter(ctx context.Context, filter *ServerLogFilter) context.Context {
+	return context.WithValue(ctx, ctxKeyServerLogFilter{}, filter)
+}
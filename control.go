@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// ControlRequest is a JSON-RPC 2.0 request sent to the control socket (one per line)
+type ControlRequest struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      any             `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// ControlError is a JSON-RPC 2.0 error object
+type ControlError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ControlResponse is a JSON-RPC 2.0 response written to the control socket (one per line)
+type ControlResponse struct {
+	JsonRpc string        `json:"jsonrpc"`
+	Id      any           `json:"id"`
+	Result  any           `json:"result,omitempty"`
+	Error   *ControlError `json:"error,omitempty"`
+}
+
+// JSON-RPC 2.0 error codes
+const (
+	controlParseError     = -32700
+	controlInvalidRequest = -32600
+	controlMethodNotFound = -32601
+	controlInvalidParams  = -32602
+	controlServerError    = -32000
+)
+
+// controlMethod handles a control socket method - returning its result
+type controlMethod func(ctx context.Context, params json.RawMessage) (any, *ControlError)
+
+// ControlSocket serves a JSON-RPC control interface over a unix domain socket - allowing host-level tooling to manage the server without network ports.
+// Access is controlled by the socket's file permissions.
+type ControlSocket struct {
+	Auditor    *Auditor
+	BackupOpts BackupOpts
+	// ControlPlane (if non-nil) reloads the entrypoint configuration (with 'reload')
+	ControlPlane *ControlPlane
+	Path         string
+}
+
+// Decodes method params into a value.
+// Returns a [ControlError] if the params are malformed.
+func decodeControlParams(params json.RawMessage, value any) *ControlError {
+	if len(params) == 0 {
+		return nil
+	}
+	err := json.Unmarshal(params, value)
+	if err != nil {
+		return &ControlError{Code: controlInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// Handles 'status' - returning the current [ServerStatus]
+func (cs *ControlSocket) handleStatus(ctx context.Context, params json.RawMessage) (any, *ControlError) {
+	tracker := GetStatusTracker(ctx)
+	if tracker == nil {
+		return nil, &ControlError{Code: controlServerError, Message: "status unavailable"}
+	}
+	return tracker.Get(), nil
+}
+
+// Handles 'shutdown' - gracefully shutting down the server
+func (cs *ControlSocket) handleShutdown(ctx context.Context, params json.RawMessage) (any, *ControlError) {
+	cs.audit(ctx, "shutdown", nil)
+	go ShutdownServer(context.WithoutCancel(ctx))
+	return map[string]any{"accepted": true}, nil
+}
+
+// Handles 'backup' - creating a manual backup (with an optional label)
+func (cs *ControlSocket) handleBackup(ctx context.Context, params json.RawMessage) (any, *ControlError) {
+	body := struct {
+		Label string `json:"label"`
+	}{}
+	cerr := decodeControlParams(params, &body)
+	if cerr != nil {
+		return nil, cerr
+	}
+	opts := cs.BackupOpts
+	opts.Label = body.Label
+	metadata, err := CreateBackup(ctx, opts)
+	cs.audit(ctx, strings.TrimSpace(fmt.Sprintf("backup create %s", body.Label)), err)
+	if err != nil {
+		return nil, &ControlError{Code: controlServerError, Message: err.Error()}
+	}
+	return metadata, nil
+}
+
+// Handles 'exec' - executing a console command (and recording an audit record)
+func (cs *ControlSocket) handleExec(ctx context.Context, params json.RawMessage) (any, *ControlError) {
+	body := struct {
+		Command string `json:"command"`
+	}{}
+	cerr := decodeControlParams(params, &body)
+	if cerr != nil {
+		return nil, cerr
+	}
+	if strings.TrimSpace(body.Command) == "" {
+		return nil, &ControlError{Code: controlInvalidParams, Message: "params must be a JSON object with a 'command'"}
+	}
+	output, err := ExecAuditedCommand(ctx, cs.Auditor, nil, "host", "socket", body.Command)
+	if err != nil {
+		return nil, &ControlError{Code: controlServerError, Message: err.Error()}
+	}
+	return map[string]any{"output": output}, nil
+}
+
+// Handles 'reload' - reloading the entrypoint configuration (see [ControlPlane.Reload])
+func (cs *ControlSocket) handleReload(ctx context.Context, params json.RawMessage) (any, *ControlError) {
+	if cs.ControlPlane == nil {
+		return nil, &ControlError{Code: controlServerError, Message: "reload unavailable"}
+	}
+	result, err := cs.ControlPlane.Reload(ctx)
+	cs.audit(ctx, "reload", err)
+	if err != nil {
+		return nil, &ControlError{Code: controlServerError, Message: err.Error()}
+	}
+	return result, nil
+}
+
+// Records an audit record for a control action (other than console commands)
+func (cs *ControlSocket) audit(ctx context.Context, action string, err error) {
+	record := AuditRecord{Time: time.Now(), Actor: "host", Source: "socket", Command: action, Allowed: true, Success: err == nil}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	cs.Auditor.Record(ctx, record)
+}
+
+// Gets the methods served by the control socket
+func (cs *ControlSocket) methods() map[string]controlMethod {
+	return map[string]controlMethod{
+		"backup":   cs.handleBackup,
+		"exec":     cs.handleExec,
+		"reload":   cs.handleReload,
+		"shutdown": cs.handleShutdown,
+		"status":   cs.handleStatus,
+	}
+}
+
+// Handles a single request line - returning its response
+func (cs *ControlSocket) handle(ctx context.Context, line []byte) ControlResponse {
+	response := ControlResponse{JsonRpc: "2.0"}
+	request := ControlRequest{}
+	err := json.Unmarshal(line, &request)
+	if err != nil {
+		response.Error = &ControlError{Code: controlParseError, Message: err.Error()}
+		return response
+	}
+	response.Id = request.Id
+	if request.JsonRpc != "2.0" || request.Method == "" {
+		response.Error = &ControlError{Code: controlInvalidRequest, Message: "request must be a JSON-RPC 2.0 request"}
+		return response
+	}
+	method, ok := cs.methods()[request.Method]
+	if !ok {
+		response.Error = &ControlError{Code: controlMethodNotFound, Message: fmt.Sprintf("unknown method %s", request.Method)}
+		return response
+	}
+	helper.Logger(ctx).Info("control request", "method", request.Method)
+	response.Result, response.Error = method(ctx, request.Params)
+	return response
+}
+
+// Serves requests on a single connection until it's closed
+func (cs *ControlSocket) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		response := cs.handle(ctx, line)
+		err := encoder.Encode(response)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Serves the control socket until the context is cancelled.
+// Returns an error if the socket cannot be created.
+func (cs *ControlSocket) Run(ctx context.Context) error {
+	helper.Logger(ctx).Info("start control socket", "path", cs.Path)
+	err := helper.RemovePaths(ctx, cs.Path)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("unix", cs.Path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(cs.Path)
+	err = os.Chmod(cs.Path, 0660)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go cs.serve(ctx, conn)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+const (
+	// dataLockHeartbeat is how often the holder of the data lock refreshes it
+	dataLockHeartbeat = 15 * time.Second
+	// dataLockStaleAfter is how long after its last refresh the data lock is considered abandoned (e.g., by a crashed container)
+	dataLockStaleAfter = time.Minute
+)
+
+// ErrDataLocked is returned when the data directory is in use by another entrypoint
+var ErrDataLocked = errors.New("data directory locked")
+
+// DataLockOwner describes the entrypoint holding the data lock
+type DataLockOwner struct {
+	Host        string    `json:"host"`
+	Pid         int       `json:"pid"`
+	Token       string    `json:"token"`
+	StartedAt   time.Time `json:"startedAt"`
+	HeartbeatAt time.Time `json:"heartbeatAt"`
+}
+
+// Determines whether the owner has abandoned the lock (i.e., its heartbeat is stale).
+// The host and pid of the owner aren't considered - containers sharing the host's network share its hostname, and containers' entrypoints commonly share a pid (in separate pid namespaces).
+func (dlo DataLockOwner) abandoned() bool {
+	return time.Since(dlo.HeartbeatAt) > dataLockStaleAfter
+}
+
+// Gets the path of the data lock
+func getDataLockFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "entrypoint.lock")
+}
+
+// Reads the owner of the data lock.
+// Returns an error wrapping [os.ErrNotExist] if the data lock isn't held.
+func readDataLock(ctx context.Context) (DataLockOwner, error) {
+	owner := DataLockOwner{}
+	data, err := os.ReadFile(getDataLockFile(ctx))
+	if err != nil {
+		return owner, err
+	}
+	err = json.Unmarshal(data, &owner)
+	return owner, err
+}
+
+// DataLock is a lock file ('[data]/entrypoint.lock') held while the entrypoint runs - preventing two entrypoints sharing a data directory (e.g., two containers accidentally given the same persistent volume) from both starting the server and corrupting the save.
+// The lock is refreshed while held - locks whose holder stops refreshing them are considered stale and taken over.
+type DataLock struct {
+	owner DataLockOwner
+}
+
+// Acquires the data lock - taking over stale locks.
+// Returns an error wrapping [ErrDataLocked] if another entrypoint holds the lock.
+// Returns an error if the lock cannot be written.
+func AcquireDataLock(ctx context.Context) (*DataLock, error) {
+	host, _ := os.Hostname()
+	token := make([]byte, 8)
+	_, err := rand.Read(token)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	dl := DataLock{owner: DataLockOwner{Host: host, Pid: os.Getpid(), Token: hex.EncodeToString(token), StartedAt: now, HeartbeatAt: now}}
+	data, err := json.Marshal(dl.owner)
+	if err != nil {
+		return nil, err
+	}
+	file := getDataLockFile(ctx)
+	for attempt := 0; attempt < 2; attempt++ {
+		handle, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = handle.Write(data)
+			handle.Close()
+			if err != nil {
+				return nil, err
+			}
+			helper.Logger(ctx).Info("acquired data lock", "path", file)
+			return &dl, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		owner, err := readDataLock(ctx)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			// an unreadable (e.g., partially written) lock is only taken over once it's old enough to be stale
+			info, statErr := os.Stat(file)
+			if statErr != nil || time.Since(info.ModTime()) <= dataLockStaleAfter {
+				return nil, fmt.Errorf("%w: unreadable lock %s: %w", ErrDataLocked, file, err)
+			}
+		} else if err == nil && !owner.abandoned() {
+			return nil, fmt.Errorf("%w: %s is in use by the entrypoint on host %s (pid %d, started %s) - if no other container uses this volume, wait %s for the lock to become stale or delete %s", ErrDataLocked, helper.Dirs(ctx)["data"], owner.Host, owner.Pid, owner.StartedAt.Format(time.RFC3339), dataLockStaleAfter, file)
+		}
+		helper.Logger(ctx).Warn("take over stale data lock", "path", file, "host", owner.Host, "pid", owner.Pid, "heartbeat-at", owner.HeartbeatAt)
+		err = os.Remove(file)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%w: lock %s was concurrently acquired", ErrDataLocked, file)
+}
+
+// Refreshes the data lock.
+// Returns an error wrapping [ErrDataLocked] if the lock was taken over by another entrypoint.
+// Returns an error if the lock cannot be written.
+func (dl *DataLock) refresh(ctx context.Context) error {
+	owner, err := readDataLock(ctx)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil && owner.Token != dl.owner.Token {
+		return fmt.Errorf("%w: taken over by the entrypoint on host %s (pid %d)", ErrDataLocked, owner.Host, owner.Pid)
+	}
+	dl.owner.HeartbeatAt = time.Now().UTC()
+	data, err := json.Marshal(dl.owner)
+	if err != nil {
+		return err
+	}
+	file := getDataLockFile(ctx)
+	err = os.WriteFile(file+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+// Refreshes the data lock until the context is cancelled.
+// If the lock is taken over by another entrypoint (i.e., this entrypoint stopped refreshing it for too long), the server is shut down to protect the save.
+func (dl *DataLock) Run(ctx context.Context) {
+	ticker := time.NewTicker(dataLockHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := dl.refresh(ctx)
+		if errors.Is(err, ErrDataLocked) {
+			helper.Logger(ctx).Error("data lock lost - shutting down server", "error", err.Error())
+			ShutdownServer(ctx)
+			return
+		}
+		if err != nil {
+			helper.Logger(ctx).Warn("refresh data lock failed", "error", err.Error())
+		}
+	}
+}
+
+// Releases the data lock (if it's still held by this entrypoint).
+// Failures are logged and otherwise ignored.
+func (dl *DataLock) Release(ctx context.Context) {
+	owner, err := readDataLock(ctx)
+	if err != nil || owner.Token != dl.owner.Token {
+		return
+	}
+	err = os.Remove(getDataLockFile(ctx))
+	if err != nil {
+		helper.Logger(ctx).Warn("release data lock failed", "error", err.Error())
+		return
+	}
+	helper.Logger(ctx).Info("released data lock")
+}
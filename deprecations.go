@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// SettingDeprecation describes a server setting that was renamed (or removed) by a game version
+type SettingDeprecation struct {
+	Name string
+	// Until is the game version that renamed (or removed) the setting
+	Until string
+	// Replacement is the setting's new name - empty if the setting was removed without a replacement
+	Replacement string
+	// Convert translates a value of the setting to a value of its replacement (if the values differ)
+	Convert func(value string) string
+	// Note explains how to replace a setting removed without a replacement
+	Note string
+}
+
+// settingDeprecations are the server settings renamed (or removed) by past game versions
+var settingDeprecations = []SettingDeprecation{
+	{Name: "ServerIsPublic", Until: "A17", Replacement: "ServerVisibility", Convert: func(value string) string {
+		if value == "true" {
+			return "2"
+		}
+		return "0"
+	}},
+	{Name: "ZombiesRun", Until: "A17", Note: "use ZombieMove, ZombieMoveNight, ZombieFeralMove and ZombieBMMove"},
+	{Name: "BlockDurabilityModifier", Until: "A20", Replacement: "BlockDamagePlayer"},
+	{Name: "SaveGameFolder", Until: "A20", Note: "saves are stored within UserDataFolder (which the entrypoint manages)"},
+	{Name: "ControlPanelEnabled", Until: "A21", Replacement: "WebDashboardEnabled"},
+	{Name: "ControlPanelPort", Until: "A21", Replacement: "WebDashboardPort"},
+	{Name: "ControlPanelPassword", Until: "A21", Note: "web dashboard users authenticate with web tokens (see the 'webtokens' console command)"},
+}
+
+// Determines whether the deprecation applies to the given game version.
+// An empty version refers to the latest game version.
+func (sd SettingDeprecation) AppliesTo(version string) bool {
+	if version == "" {
+		return true
+	}
+	current, err := ParseGameVersion(version)
+	if err != nil {
+		return true
+	}
+	until, _ := ParseGameVersion(sd.Until)
+	return current.Compare(until) >= 0
+}
+
+// Translates deprecated settings (see [settingDeprecations]) to their replacements in the given game version - rather than writing properties the server ignores.
+// Settings already set under their replacement's name take precedence.
+// Settings removed without a replacement are deleted.
+// Each translated (or deleted) setting is logged as a warning.
+func TranslateDeprecatedSettings(ctx context.Context, version string, settings ServerSettings) ServerSettings {
+	translated := MergeServerSettings(settings)
+	for _, deprecation := range settingDeprecations {
+		value, ok := translated.Get(deprecation.Name)
+		if !ok || !deprecation.AppliesTo(version) {
+			continue
+		}
+		translated.Delete(deprecation.Name)
+		if deprecation.Replacement == "" {
+			helper.Logger(ctx).Warn("removed server setting ignored", "name", deprecation.Name, "until", deprecation.Until, "note", deprecation.Note)
+			continue
+		}
+		_, ok = translated.Get(deprecation.Replacement)
+		if ok {
+			helper.Logger(ctx).Warn("deprecated server setting ignored", "name", deprecation.Name, "replacement", deprecation.Replacement, "reason", "replacement already set")
+			continue
+		}
+		if deprecation.Convert != nil {
+			value = deprecation.Convert(value)
+		}
+		helper.Logger(ctx).Warn("deprecated server setting translated", "name", deprecation.Name, "replacement", deprecation.Replacement, "value", value, "until", deprecation.Until)
+		translated.Set(deprecation.Replacement, value)
+	}
+	return translated
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// DiagnosticResult is the outcome of a single diagnostic check
+type DiagnosticResult struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+const (
+	// DiagnosticOk indicates that a check passed
+	DiagnosticOk = "ok"
+	// DiagnosticWarn indicates that a check passed with caveats
+	DiagnosticWarn = "warn"
+	// DiagnosticFail indicates that a check failed
+	DiagnosticFail = "fail"
+	// DiagnosticSkip indicates that a check could not be performed
+	DiagnosticSkip = "skip"
+)
+
+// diagnosticEndpoints are the Steam and EOS endpoints the dedicated server connects to
+var diagnosticEndpoints = []string{
+	"api.steampowered.com:443",
+	"steamcommunity.com:443",
+	"api.epicgames.dev:443",
+}
+
+// stunServers are public STUN servers used to discover the public address (and NAT behaviour) of the game port
+var stunServers = []string{
+	"stun.l.google.com:19302",
+	"stun.cloudflare.com:3478",
+}
+
+// stunMagicCookie is the fixed STUN magic cookie (RFC 5389)
+const stunMagicCookie = 0x2112A442
+
+// Sends a STUN binding request over a udp connection.
+// Returns the public (mapped) address observed by the STUN server.
+// Returns an error if the request fails or the response is malformed.
+func stunBindingRequest(conn *net.UDPConn, server string, timeout time.Duration) (*net.UDPAddr, error) {
+	addr, err := net.ResolveUDPAddr("udp4", server)
+	if err != nil {
+		return nil, err
+	}
+	request := make([]byte, 20)
+	binary.BigEndian.PutUint16(request[0:], 0x0001)
+	binary.BigEndian.PutUint32(request[4:], stunMagicCookie)
+	_, err = rand.Read(request[8:20])
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.WriteToUDP(request, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	response := make([]byte, 1024)
+	for {
+		count, _, err := conn.ReadFromUDP(response)
+		if err != nil {
+			return nil, err
+		}
+		if count < 20 || string(response[8:20]) != string(request[8:20]) {
+			continue
+		}
+		return parseStunMappedAddress(response[20:count])
+	}
+}
+
+// Parses the (xor-)mapped address attribute of a STUN binding response.
+// Returns an error if no mapped address attribute is present.
+func parseStunMappedAddress(attributes []byte) (*net.UDPAddr, error) {
+	for len(attributes) >= 4 {
+		kind := binary.BigEndian.Uint16(attributes[0:])
+		length := int(binary.BigEndian.Uint16(attributes[2:]))
+		if len(attributes) < 4+length {
+			break
+		}
+		value := attributes[4 : 4+length]
+		if (kind == 0x0020 || kind == 0x0001) && length >= 8 && value[1] == 0x01 {
+			port := binary.BigEndian.Uint16(value[2:])
+			ip := net.IP(append([]byte{}, value[4:8]...))
+			if kind == 0x0020 {
+				port ^= uint16(stunMagicCookie >> 16)
+				cookie := make([]byte, 4)
+				binary.BigEndian.PutUint32(cookie, stunMagicCookie)
+				for index := range ip {
+					ip[index] ^= cookie[index]
+				}
+			}
+			return &net.UDPAddr{IP: ip, Port: int(port)}, nil
+		}
+		// attributes are padded to 4 byte boundaries
+		attributes = attributes[4+(length+3)/4*4:]
+	}
+	return nil, errors.New("stun response has no mapped address")
+}
+
+// Checks that each endpoint resolves and accepts tcp connections
+func diagnoseEndpoints(ctx context.Context) []DiagnosticResult {
+	results := []DiagnosticResult{}
+	for _, endpoint := range diagnosticEndpoints {
+		name := fmt.Sprintf("connect %s", endpoint)
+		start := time.Now()
+		conn, err := (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, "tcp", endpoint)
+		if err != nil {
+			results = append(results, DiagnosticResult{Name: name, Status: DiagnosticFail, Detail: err.Error()})
+			continue
+		}
+		conn.Close()
+		results = append(results, DiagnosticResult{Name: name, Status: DiagnosticOk, Detail: fmt.Sprintf("connected in %s", time.Since(start).Round(time.Millisecond))})
+	}
+	return results
+}
+
+// Discovers the public address of the game port with STUN - and infers the NAT type by comparing the addresses observed by multiple STUN servers.
+// If the game port is in use (i.e., the server is running), an ephemeral port is used instead.
+func diagnoseNat(port int) []DiagnosticResult {
+	results := []DiagnosticResult{}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+	if err != nil {
+		results = append(results, DiagnosticResult{Name: "bind game port", Status: DiagnosticWarn, Detail: fmt.Sprintf("udp port %d unavailable (is the server running?) - using an ephemeral port: %s", port, err.Error())})
+		conn, err = net.ListenUDP("udp4", &net.UDPAddr{})
+		if err != nil {
+			return append(results, DiagnosticResult{Name: "bind udp port", Status: DiagnosticFail, Detail: err.Error()})
+		}
+	}
+	defer conn.Close()
+	localPort := conn.LocalAddr().(*net.UDPAddr).Port
+	mapped := []*net.UDPAddr{}
+	for _, server := range stunServers {
+		name := fmt.Sprintf("stun %s", server)
+		addr, err := stunBindingRequest(conn, server, 3*time.Second)
+		if err != nil {
+			results = append(results, DiagnosticResult{Name: name, Status: DiagnosticFail, Detail: fmt.Sprintf("no response (outbound udp may be blocked): %s", err.Error())})
+			continue
+		}
+		mapped = append(mapped, addr)
+		results = append(results, DiagnosticResult{Name: name, Status: DiagnosticOk, Detail: fmt.Sprintf("public address %s", addr)})
+	}
+	if len(mapped) < 2 {
+		return append(results, DiagnosticResult{Name: "nat type", Status: DiagnosticSkip, Detail: "requires responses from at least two stun servers"})
+	}
+	switch {
+	case !mapped[0].IP.Equal(mapped[1].IP) || mapped[0].Port != mapped[1].Port:
+		results = append(results, DiagnosticResult{Name: "nat type", Status: DiagnosticFail, Detail: "symmetric nat - the public port changes per destination, so players can't connect without a port forward (or a public ip)"})
+	case mapped[0].Port != localPort:
+		results = append(results, DiagnosticResult{Name: "nat type", Status: DiagnosticWarn, Detail: fmt.Sprintf("cone nat without port preservation (local port %d, public port %d) - forward udp %d on your router", localPort, mapped[0].Port, localPort)})
+	default:
+		results = append(results, DiagnosticResult{Name: "nat type", Status: DiagnosticOk, Detail: fmt.Sprintf("port preserved (%s) - ports must still be forwarded if behind a router", mapped[0])})
+	}
+	return results
+}
+
+// Checks udp reachability by sending a datagram to a udp echo service and waiting for it to be echoed back
+func diagnoseUdpEcho(echoAddr string) DiagnosticResult {
+	name := "udp echo"
+	if echoAddr == "" {
+		return DiagnosticResult{Name: name, Status: DiagnosticSkip, Detail: "set DIAGNOSE_UDP_ECHO_ADDR to check udp reachability with an external echo service"}
+	}
+	conn, err := net.DialTimeout("udp", echoAddr, 5*time.Second)
+	if err != nil {
+		return DiagnosticResult{Name: name, Status: DiagnosticFail, Detail: err.Error()}
+	}
+	defer conn.Close()
+	payload := []byte(fmt.Sprintf("sdtd-diagnose-%d", time.Now().UnixNano()))
+	_, err = conn.Write(payload)
+	if err != nil {
+		return DiagnosticResult{Name: name, Status: DiagnosticFail, Detail: err.Error()}
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	response := make([]byte, len(payload))
+	count, err := conn.Read(response)
+	if err != nil || string(response[:count]) != string(payload) {
+		return DiagnosticResult{Name: name, Status: DiagnosticFail, Detail: fmt.Sprintf("no echo from %s", echoAddr)}
+	}
+	return DiagnosticResult{Name: name, Status: DiagnosticOk, Detail: fmt.Sprintf("echoed by %s", echoAddr)}
+}
+
+// Checks whether the game's telnet port is accepting connections (i.e., the server is running)
+func diagnoseServer(ctx context.Context) DiagnosticResult {
+	name := "server telnet"
+	err := DialServer(ctx, DialServerOpts{}, func(conn Conn) error {
+		return nil
+	})
+	if err != nil {
+		return DiagnosticResult{Name: name, Status: DiagnosticWarn, Detail: fmt.Sprintf("server not running: %s", err.Error())}
+	}
+	return DiagnosticResult{Name: name, Status: DiagnosticOk, Detail: fmt.Sprintf("listening on %s", telnetAddr)}
+}
+
+// Runs networking diagnostics - checking outbound connectivity to Steam/EOS endpoints, discovering the public address and NAT type of the game port and (optionally) checking udp reachability with an echo service.
+// Prints a readable report to stdout.
+// Returns an error if any check fails.
+func DiagnoseSubcommand(ctx context.Context) error {
+	port, err := strconv.Atoi(os.Getenv("SETTING_ServerPort"))
+	if err != nil {
+		port = 26900
+	}
+	helper.Logger(ctx).Info("run diagnostics", "port", port)
+	results := diagnoseEndpoints(ctx)
+	results = append(results, diagnoseNat(port)...)
+	results = append(results, diagnoseUdpEcho(os.Getenv("DIAGNOSE_UDP_ECHO_ADDR")))
+	results = append(results, diagnoseServer(ctx))
+	failed := 0
+	fmt.Println("networking diagnostics")
+	for _, result := range results {
+		fmt.Printf("  [%-4s] %-36s %s\n", result.Status, result.Name, result.Detail)
+		if result.Status == DiagnosticFail {
+			failed++
+		}
+	}
+	fmt.Printf("\nplayers connect to tcp/udp %d and udp %d-%d - these must be forwarded to this host when behind a router.\n", port, port+1, port+3)
+	if failed > 0 {
+		return fmt.Errorf("%d diagnostic(s) failed: %s", failed, strings.Join(failedNames(results), ", "))
+	}
+	return nil
+}
+
+// Gets the names of failed diagnostic results
+func failedNames(results []DiagnosticResult) []string {
+	names := []string{}
+	for _, result := range results {
+		if result.Status == DiagnosticFail {
+			names = append(names, result.Name)
+		}
+	}
+	return names
+}
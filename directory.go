@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// DirectoryHeartbeat is the server listing posted to a community server directory
+type DirectoryHeartbeat struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Address     string      `json:"address,omitempty"`
+	Port        int         `json:"port"`
+	GameVersion string      `json:"gameVersion"`
+	World       string      `json:"world"`
+	Players     int         `json:"players"`
+	MaxPlayers  int         `json:"maxPlayers"`
+	Password    bool        `json:"password"`
+	Eac         bool        `json:"eac"`
+	State       ServerState `json:"state"`
+	StartedAt   *time.Time  `json:"startedAt"`
+	NextRestart *time.Time  `json:"nextRestart"`
+	SentAt      time.Time   `json:"sentAt"`
+}
+
+// DirectoryOpts defines the options used by a [DirectoryPublisher]
+type DirectoryOpts struct {
+	// Address overrides the address advertised to the directory (e.g., the public address of a server behind nat) - defaulting to the 'ServerIP' setting
+	Address     string
+	GameVersion string
+	Interval    time.Duration
+	Token       string
+	Url         *url.URL
+}
+
+// DirectoryPublisher periodically posts heartbeats to a community server directory - allowing networks running multiple servers to maintain their own server browser
+type DirectoryPublisher struct {
+	Opts     DirectoryOpts
+	Settings ServerSettings
+}
+
+// Builds a heartbeat from the server settings and the current status
+func (dp *DirectoryPublisher) Heartbeat(ctx context.Context) DirectoryHeartbeat {
+	get := func(name string) string {
+		value, _ := dp.Settings.Get(name)
+		return value
+	}
+	port, _ := dp.Settings.GetInt("ServerPort")
+	maxPlayers, _ := dp.Settings.GetInt("ServerMaxPlayerCount")
+	eac, _ := dp.Settings.GetBool("EACEnabled")
+	address := dp.Opts.Address
+	if address == "" {
+		address = get("ServerIP")
+	}
+	heartbeat := DirectoryHeartbeat{
+		Name:        get("ServerName"),
+		Description: get("ServerDescription"),
+		Address:     address,
+		Port:        port,
+		GameVersion: dp.Opts.GameVersion,
+		World:       get("GameWorld"),
+		MaxPlayers:  maxPlayers,
+		Password:    get("ServerPassword") != "",
+		Eac:         eac,
+		SentAt:      time.Now(),
+	}
+	tracker := GetStatusTracker(ctx)
+	if tracker != nil {
+		status := tracker.Get()
+		heartbeat.Players = status.Players
+		heartbeat.State = status.State
+		heartbeat.StartedAt = status.StartedAt
+		heartbeat.NextRestart = status.NextRestart
+	}
+	return heartbeat
+}
+
+// Posts a heartbeat to the directory - authenticating with a bearer token (when configured).
+// Returns an error if the request fails or the directory responds with a non-2xx status code.
+func (dp *DirectoryPublisher) Publish(ctx context.Context) error {
+	heartbeat := dp.Heartbeat(ctx)
+	data, err := json.Marshal(heartbeat)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, dp.Opts.Url.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if dp.Opts.Token != "" {
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", dp.Opts.Token))
+	}
+	response, err := getHttpClient(ctx).Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("directory responded with status %d", response.StatusCode)
+	}
+	helper.Logger(ctx).Debug("directory heartbeat sent", "url", dp.Opts.Url.Redacted(), "state", heartbeat.State, "players", heartbeat.Players)
+	return nil
+}
+
+// Posts heartbeats to the directory on an interval until the context is cancelled.
+// Failed heartbeats are logged and otherwise ignored.
+func (dp *DirectoryPublisher) Run(ctx context.Context) {
+	for {
+		err := dp.Publish(ctx)
+		if err != nil {
+			helper.Logger(ctx).Warn("directory heartbeat failed", "url", dp.Opts.Url.Redacted(), "error", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(dp.Opts.Interval):
+		}
+	}
+}
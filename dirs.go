@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// dirEnvPrefix prefixes the environment variables that override (and expose) the entrypoint's directories (e.g., 'SDTD_DIR_DATA')
+const dirEnvPrefix = "SDTD_DIR_"
+
+// dirNames are the names of the entrypoint's directories
+var dirNames = []string{"backups", "cache", "data", "emulator", "generated", "proton", "sdtd"}
+
+// Gets the name of the environment variable that overrides (and exposes) a directory
+func getDirEnvName(name string) string {
+	return dirEnvPrefix + strings.ToUpper(name)
+}
+
+// Gets the entrypoint's directories - located within the working directory, unless overridden by a 'SDTD_DIR_[NAME]' environment variable.
+// Relative overrides are resolved against the working directory.
+func getDirs(wd string) map[string]string {
+	dirs := map[string]string{}
+	for _, name := range dirNames {
+		path := os.Getenv(getDirEnvName(name))
+		if path == "" {
+			path = name
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(wd, path)
+		}
+		dirs[name] = path
+	}
+	return dirs
+}
+
+// Gets the environment variables ('SDTD_DIR_[NAME]=[path]') exposing the entrypoint's resolved directories to child processes (e.g., plugins and settings transforms) - so that they can locate files without assuming the working directory's layout.
+func getDirsEnv(ctx context.Context) []string {
+	dirs := helper.Dirs(ctx)
+	env := []string{}
+	for name, path := range dirs {
+		env = append(env, fmt.Sprintf("%s=%s", getDirEnvName(name), path))
+	}
+	slices.Sort(env)
+	return env
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// ErrLowDisk is returned when an operation is refused because free disk space is critically low
+var ErrLowDisk = errors.New("free disk space critically low")
+
+// diskCheckInterval is how often free disk space is checked
+const diskCheckInterval = 30 * time.Second
+
+// Gets the free disk space (in bytes) available to unprivileged users on the filesystem containing [path] - along with the filesystem's id.
+// Returns an error if the filesystem cannot be queried.
+func getFreeDiskSpace(path string) (uint64, syscall.Fsid, error) {
+	stat := syscall.Statfs_t{}
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return 0, syscall.Fsid{}, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), stat.Fsid, nil
+}
+
+// Deletes the least recently accessed file cache entries until at least [size] bytes are freed (or the cache is empty).
+// Records of deleted entries are removed from the file cache manifest the next time the file cache is initialized.
+// Returns the number of bytes freed.
+// Returns an error if the file cache manifest is unreadable or an entry cannot be deleted.
+func trimFileCache(ctx context.Context, size uint64) (uint64, error) {
+	cacheDir, ok := helper.Dirs(ctx)["cache"]
+	if !helper.FileCacheEnabled(ctx) || !ok {
+		return 0, nil
+	}
+	type item struct {
+		LastAccessed time.Time `json:"lastAccessed"`
+		Path         string    `json:"path"`
+		Size         int       `json:"size"`
+	}
+	manifest := struct {
+		Contents map[string]item `json:"contents"`
+	}{}
+	err := helper.UnmarshalFile(ctx, filepath.Join(cacheDir, "manifest.json"), &manifest)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	items := []item{}
+	for _, value := range manifest.Contents {
+		items = append(items, value)
+	}
+	slices.SortFunc(items, func(a item, b item) int {
+		return a.LastAccessed.Compare(b.LastAccessed)
+	})
+	freed := uint64(0)
+	for _, value := range items {
+		if freed >= size {
+			break
+		}
+		_, err := os.Lstat(value.Path)
+		if err != nil {
+			continue
+		}
+		helper.Logger(ctx).Info("trim file cache entry", "path", value.Path, "size", value.Size)
+		err = helper.RemovePaths(ctx, value.Path)
+		if err != nil {
+			return freed, err
+		}
+		freed += uint64(value.Size)
+	}
+	return freed, nil
+}
+
+// DiskMonitorOpts defines the options used in conjunction with the [DiskMonitor] struct
+type DiskMonitorOpts struct {
+	// Threshold is the free disk space (in bytes) below which emergency measures are taken
+	Threshold uint64
+	// WebhookUrl (if non-nil) is notified when free disk space becomes critically low (and when it recovers)
+	WebhookUrl *url.URL
+}
+
+// DiskMonitor watches the free disk space of the data directory - saving the world, pausing backups, trimming the file cache and notifying admins when it becomes critically low (instead of letting the save writer corrupt region files when the disk fills)
+type DiskMonitor struct {
+	Opts DiskMonitorOpts
+	lock sync.Mutex
+	low  bool
+}
+
+// Determines whether free disk space is currently critically low (backups are paused while it is)
+func (dm *DiskMonitor) Low() bool {
+	dm.lock.Lock()
+	defer dm.lock.Unlock()
+	return dm.low
+}
+
+// Notifies the webhook (if configured) of a disk space event
+func (dm *DiskMonitor) notify(ctx context.Context, event string, free uint64) {
+	if dm.Opts.WebhookUrl == nil {
+		return
+	}
+	data, err := json.Marshal(map[string]any{"event": event, "free": free, "path": helper.Dirs(ctx)["data"], "threshold": dm.Opts.Threshold})
+	if err != nil {
+		return
+	}
+	postWebhook(ctx, dm.Opts.WebhookUrl.String(), data)
+}
+
+// Checks the free disk space of the data directory - taking emergency measures when it first drops below the threshold.
+// Returns an error if the free disk space cannot be determined.
+func (dm *DiskMonitor) Check(ctx context.Context) error {
+	dataDir := helper.Dirs(ctx)["data"]
+	free, fsid, err := getFreeDiskSpace(dataDir)
+	if err != nil {
+		return fmt.Errorf("get free disk space: %w", err)
+	}
+	dm.lock.Lock()
+	wasLow := dm.low
+	dm.low = free < dm.Opts.Threshold
+	dm.lock.Unlock()
+
+	if !dm.low {
+		if wasLow {
+			helper.Logger(ctx).Info("free disk space recovered - resuming backups", "free", free, "threshold", dm.Opts.Threshold)
+			dm.notify(ctx, "low-disk-recovered", free)
+		}
+		return nil
+	}
+	if wasLow {
+		return nil
+	}
+	helper.Logger(ctx).Error("free disk space critically low - pausing backups", "free", free, "threshold", dm.Opts.Threshold)
+
+	// trimming the file cache first (if it shares the filesystem) leaves room for the world save
+	cacheDir, ok := helper.Dirs(ctx)["cache"]
+	if ok {
+		_, cacheFsid, err := getFreeDiskSpace(cacheDir)
+		if err == nil && cacheFsid == fsid {
+			freed, err := trimFileCache(ctx, dm.Opts.Threshold-free)
+			if err != nil {
+				helper.Logger(ctx).Warn("trim file cache failed", "error", err.Error())
+			}
+			helper.Logger(ctx).Info("trimmed file cache", "freed", freed)
+		}
+	}
+
+	err = SaveWorld(ctx)
+	if err != nil {
+		helper.Logger(ctx).Warn("save world failed", "error", err.Error())
+	}
+
+	free, _, err = getFreeDiskSpace(dataDir)
+	if err != nil {
+		return fmt.Errorf("get free disk space: %w", err)
+	}
+	dm.notify(ctx, "low-disk", free)
+	return nil
+}
+
+// Periodically checks free disk space until the context is cancelled.
+// Failing checks are logged and otherwise ignored.
+func (dm *DiskMonitor) Run(ctx context.Context) {
+	for {
+		err := dm.Check(ctx)
+		if err != nil {
+			helper.Logger(ctx).Warn("disk space check failed", "error", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(diskCheckInterval):
+		}
+	}
+}
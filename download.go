@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// MirrorRule rewrites urls beginning with [From] to instead begin with [To]
+type MirrorRule struct {
+	From string
+	To   string
+}
+
+// Parses mirror rules formatted as 'from=to'.
+// Returns an error if a rule is malformed.
+func ParseMirrorRules(rules []string) ([]MirrorRule, error) {
+	parsed := []MirrorRule{}
+	for _, rule := range rules {
+		from, to, ok := strings.Cut(rule, "=")
+		if !ok || from == "" {
+			return nil, fmt.Errorf("invalid mirror rule %s", rule)
+		}
+		parsed = append(parsed, MirrorRule{From: from, To: to})
+	}
+	return parsed, nil
+}
+
+// DownloadConfig holds network configuration used by all downloads made by the entrypoint
+type DownloadConfig struct {
+	Mirrors []MirrorRule
+	Offline bool
+	Proxy   *url.URL
+}
+
+// ErrOffline is returned when network access is attempted while offline mode is enabled
+var ErrOffline = errors.New("network access disabled (offline mode)")
+
+// Rewrites a url using the first matching mirror rule.
+// Returns the url unchanged if no rules match.
+func RewriteUrl(ctx context.Context, value string) string {
+	for _, rule := range GetDownloadConfig(ctx).Mirrors {
+		if strings.HasPrefix(value, rule.From) {
+			rewritten := rule.To + strings.TrimPrefix(value, rule.From)
+			helper.Logger(ctx).Info("rewrite url", "url", value, "mirror", rewritten)
+			return rewritten
+		}
+	}
+	return value
+}
+
+// Gets an http client honoring the configured proxy.
+// If no proxy is configured, the standard HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables are honored.
+func getHttpClient(ctx context.Context) *http.Client {
+	proxy := GetDownloadConfig(ctx).Proxy
+	if proxy == nil {
+		return http.DefaultClient
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+	return &http.Client{Transport: transport}
+}
+
+// Gets environment variables that configure the proxy for external commands (e.g., DepotDownloader).
+// Returns nil if no proxy is configured (and the command should inherit the current environment).
+func getProxyEnv(ctx context.Context) []string {
+	proxy := GetDownloadConfig(ctx).Proxy
+	if proxy == nil {
+		return nil
+	}
+	value := proxy.String()
+	return append(os.Environ(), fmt.Sprintf("HTTP_PROXY=%s", value), fmt.Sprintf("HTTPS_PROXY=%s", value), fmt.Sprintf("http_proxy=%s", value), fmt.Sprintf("https_proxy=%s", value))
+}
+
+// DownloadMetadata records http caching metadata of a previously downloaded url
+type DownloadMetadata struct {
+	ETag         string    `json:"etag"`
+	Hash         string    `json:"hash"`
+	LastModified string    `json:"lastModified"`
+	CheckedAt    time.Time `json:"checkedAt"`
+}
+
+// downloadMetadataLock serializes access to the download metadata file
+var downloadMetadataLock = sync.Mutex{}
+
+// Gets the path of the file that records download metadata.
+// This is stored in the data directory as the file cache removes files it doesn't track.
+func getDownloadMetadataFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "download-cache.json")
+}
+
+// Gets the recorded download metadata for a url.
+// Returns nil if no metadata has been recorded.
+// Returns an error if the download metadata file is unreadable.
+func GetDownloadMetadata(ctx context.Context, url string) (*DownloadMetadata, error) {
+	downloadMetadataLock.Lock()
+	defer downloadMetadataLock.Unlock()
+	data := map[string]DownloadMetadata{}
+	err := helper.UnmarshalFile(ctx, getDownloadMetadataFile(ctx), &data)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	metadata, ok := data[url]
+	if !ok {
+		return nil, nil
+	}
+	return &metadata, nil
+}
+
+// Records the download metadata for a url.
+// Returns an error if the download metadata file cannot be read or written.
+func SetDownloadMetadata(ctx context.Context, url string, metadata DownloadMetadata) error {
+	downloadMetadataLock.Lock()
+	defer downloadMetadataLock.Unlock()
+	data := map[string]DownloadMetadata{}
+	err := helper.UnmarshalFile(ctx, getDownloadMetadataFile(ctx), &data)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	data[url] = metadata
+	return helper.MarshalFile(ctx, data, getDownloadMetadataFile(ctx))
+}
+
+// Downloads a url (rewritten by any matching mirror rules) to the target path.
+// If [previous] is provided, a conditional request is made - and if the content is unmodified, nothing is downloaded and the previous metadata is returned.
+// Returns the metadata of the downloaded content and whether the content was modified.
+// Returns an error if offline mode is enabled.
+// Returns an error if the download fails.
+func DownloadFile(ctx context.Context, url string, dest string, previous *DownloadMetadata) (DownloadMetadata, bool, error) {
+	fail := func(err error) (DownloadMetadata, bool, error) {
+		return DownloadMetadata{}, false, err
+	}
+	if GetDownloadConfig(ctx).Offline {
+		return fail(fmt.Errorf("download %s: %w", url, ErrOffline))
+	}
+	url = RewriteUrl(ctx, url)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fail(err)
+	}
+	if previous != nil {
+		if previous.ETag != "" {
+			request.Header.Set("If-None-Match", previous.ETag)
+		}
+		if previous.LastModified != "" {
+			request.Header.Set("If-Modified-Since", previous.LastModified)
+		}
+	}
+	helper.Logger(ctx).Info("download", "url", url, "file", dest, "conditional", previous != nil)
+	response, err := getHttpClient(ctx).Do(request)
+	if err != nil {
+		return fail(err)
+	}
+	defer response.Body.Close()
+	if previous != nil && response.StatusCode == http.StatusNotModified {
+		metadata := *previous
+		metadata.CheckedAt = time.Now()
+		return metadata, false, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return fail(fmt.Errorf("GET %s sent non-200 status code: %d", url, response.StatusCode))
+	}
+	handle, err := os.Create(dest)
+	if err != nil {
+		return fail(err)
+	}
+	defer handle.Close()
+	hash := sha256.New()
+	chunkSize := 1024 * 1024
+	written, err := io.CopyBuffer(io.MultiWriter(handle, hash), response.Body, make([]byte, chunkSize))
+	recordDownloadBandwidth(ctx, func(usage *BandwidthUsage) {
+		usage.DownloadBytes += written
+	})
+	if err != nil {
+		return fail(err)
+	}
+	metadata := DownloadMetadata{
+		CheckedAt:    time.Now(),
+		ETag:         response.Header.Get("ETag"),
+		Hash:         hex.EncodeToString(hash.Sum(nil)),
+		LastModified: response.Header.Get("Last-Modified"),
+	}
+	return metadata, true, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// gamePrefRegex matches a line of 'getgamepref' output (capturing the preference name and value)
+var gamePrefRegex = regexp.MustCompile(`^GamePref\.(\w+)\s*=\s*(.*)$`)
+
+// Parses the output of the 'getgamepref' console command
+func ParseGamePrefs(lines []string) ServerSettings {
+	prefs := ServerSettings{}
+	for _, line := range lines {
+		match := gamePrefRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		prefs[match[1]] = strings.TrimSpace(match[2])
+	}
+	return prefs
+}
+
+// Gets the runtime values of game preferences from the running server.
+// Returns an error if the console command fails.
+func GetGamePrefs(ctx context.Context) (ServerSettings, error) {
+	lines, err := SendCommand(ctx, "getgamepref")
+	if err != nil {
+		return nil, fmt.Errorf("get game prefs: %w", err)
+	}
+	return ParseGamePrefs(lines), nil
+}
+
+// driftIgnoredSettings are settings whose runtime values are expected to differ from the rendered settings (e.g., forced overrides and paths resolved by the game)
+var driftIgnoredSettings = []string{"SaveGameFolder", "TelnetPort", "UserDataFolder", "WebDashboardPort"}
+
+// Compares runtime game preferences against rendered settings - returning the settings whose runtime values differ.
+// Only settings present in both (and not in [ignored]) are compared, and values are compared case-insensitively (the game reports booleans as 'True'/'False').
+func DiffGamePrefs(rendered ServerSettings, runtime ServerSettings, ignored []string) []SettingChange {
+	changes := []SettingChange{}
+	for _, name := range rendered.Names() {
+		from := rendered[name]
+		to, ok := runtime[name]
+		if !ok || strings.EqualFold(from, to) || slices.Contains(driftIgnoredSettings, name) || slices.Contains(ignored, name) {
+			continue
+		}
+		changes = append(changes, SettingChange{Name: name, From: &from, To: &to})
+	}
+	return changes
+}
+
+// Gets the path of the file that persists drifted settings (so that they survive restarts)
+func getPersistedSettingsFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "settings-overrides.json")
+}
+
+// Loads settings persisted from runtime drift.
+// Returns empty settings if none have been persisted.
+// Returns an error if the file is unreadable.
+func LoadPersistedSettings(ctx context.Context) (ServerSettings, error) {
+	settings := ServerSettings{}
+	err := helper.UnmarshalFile(ctx, getPersistedSettingsFile(ctx), &settings)
+	if errors.Is(err, os.ErrNotExist) {
+		return ServerSettings{}, nil
+	}
+	return settings, err
+}
+
+// Formats a setting value for logging - masking passwords
+func formatSettingValue(name string, value *string) string {
+	if value == nil {
+		return "<unset>"
+	}
+	if strings.HasSuffix(name, "Password") {
+		return "xxxxx"
+	}
+	return *value
+}
+
+// DriftCheckOpts defines the options used in conjunction with the [CheckSettingsDrift] function
+type DriftCheckOpts struct {
+	// Ignored are settings excluded from drift detection (e.g., settings changed at runtime by settings profiles)
+	Ignored []string
+	// Persist writes drifted values to the persisted settings (so that they survive restarts)
+	Persist bool
+}
+
+// Checks runtime game preferences for drift from the rendered settings (e.g., caused by in-game admin changes).
+// Drift is logged - and optionally persisted.
+// Returns the drifted settings.
+// Returns an error if the game preferences cannot be read or persisted.
+func CheckSettingsDrift(ctx context.Context, rendered ServerSettings, opts DriftCheckOpts) ([]SettingChange, error) {
+	runtime, err := GetGamePrefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changes := DiffGamePrefs(rendered, runtime, opts.Ignored)
+	for _, change := range changes {
+		helper.Logger(ctx).Warn("setting drift detected", "setting", change.Name, "rendered", formatSettingValue(change.Name, change.From), "runtime", formatSettingValue(change.Name, change.To))
+	}
+	if !opts.Persist || len(changes) == 0 {
+		return changes, nil
+	}
+	persisted, err := LoadPersistedSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		persisted[change.Name] = *change.To
+		// the runtime value is now the expected value
+		rendered[change.Name] = *change.To
+	}
+	helper.Logger(ctx).Info("persist drifted settings", "count", len(changes))
+	return changes, helper.MarshalFile(ctx, persisted, getPersistedSettingsFile(ctx))
+}
+
+// Periodically checks for settings drift until the context is cancelled.
+// Failing checks are logged and otherwise ignored.
+func RunSettingsDriftChecks(ctx context.Context, interval time.Duration, rendered ServerSettings, opts DriftCheckOpts) {
+	rendered = MergeServerSettings(rendered)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		_, err := CheckSettingsDrift(ctx, rendered, opts)
+		if err != nil {
+			helper.Logger(ctx).Warn("settings drift check failed", "error", err.Error())
+		}
+	}
+}
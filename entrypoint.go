@@ -3,288 +3,353 @@ package main
 import (
 	"context"
 	_ "embed"
-	"encoding/xml"
 	"fmt"
-	"net"
 	"os"
 	"path/filepath"
-	"strings"
+	"slices"
+	"sync"
 	"time"
 
 	helper "github.com/benfiola/game-server-helper/pkg"
 )
 
-// Conn wraps [net.Conn] and provides helper methods
-type Conn struct {
-	netConn net.Conn
-	ctx     context.Context
+// Starts the seven days to die server - applying the process tuning options (see [ProcessTuning]).
+// The server's output is filtered by the [ServerLogFilter] attached to the context (if any).
+// Returns an error if the underlying command fails.
+func StartServer(ctx context.Context, config string, tuning ProcessTuning) error {
+	helper.Logger(ctx).Info("start server", "config", config, "mode", GetExecutionMode(ctx), "cpu-affinity", tuning.CpuAffinity)
+	cmdFinished := make(chan bool, 1)
+	unregister := helper.HandleSignal(ctx, func(sig os.Signal) {
+		ShutdownServer(ctx)
+		<-cmdFinished
+	})
+	defer unregister()
+	cmd, env, err := GetExecutionMode(ctx).ServerCommand(ctx, "-batchmode", fmt.Sprintf("-configfile=%s", config), "-dedicated", "-logfile", "-", "-nographics", "-quit")
+	if err != nil {
+		return err
+	}
+	cmd = tuning.Command(cmd)
+	opts := helper.CmdOpts{Attach: true, Cwd: helper.Dirs(ctx)["sdtd"], Env: append(env, getDirsEnv(ctx)...), IgnoreSignals: true}
+	var run func() (string, error)
+	assemble := func() {
+		run = helper.Command(ctx, cmd, opts).Run
+	}
+	if filter := GetServerLogFilter(ctx); filter != nil {
+		// attached commands write to the stdout in use when they're assembled
+		stop, err := filter.Attach(assemble)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	} else {
+		assemble()
+	}
+	_, err = run()
+	cmdFinished <- true
+	return err
 }
 
-// Reads from [Conn] until a pattern is found or a timeout occurs.
-// Raises an error if the connection read fails.
-// Raises an error if a timeout occurs
-func (conn Conn) ReadUntilPattern(pattern string, timeout time.Duration) error {
-	start := time.Now()
-	data := ""
-	buf := make([]byte, 128)
-	for {
-		now := time.Now()
-		if now.Sub(start) >= timeout {
-			return fmt.Errorf("timed out reading until pattern")
+// Deletes default mods located in the sdtd 'Mods' folder.  This is done by deleting the folder and recreating it.
+// Returns an error if the initial folder deletion fails.
+// Returns an error if the subsequent folder creation fails.
+func DeleteDefaultMods(ctx context.Context) error {
+	helper.Logger(ctx).Info("delete default mods")
+	path := filepath.Join(helper.Dirs(ctx)["sdtd"], "Mods")
+	subpaths, err := helper.ListDir(ctx, path)
+	if err != nil {
+		return err
+	}
+	return helper.RemovePaths(ctx, subpaths...)
+}
+
+// Downloads sdtd with DepotDownloader (validating file checksums) and performs post-download fixups.
+// The depot is selected by the current execution mode.
+// If a mirror rule rewrites the 'steam://294420/[depot]/[manifest].tar.gz' source, a pre-packaged tar.gz archive of the server folder is downloaded from the mirror and extracted instead.
+// When offline mode is enabled, a pre-seeded sdtd directory is used as-is - otherwise, sdtd is only restored from the file cache.
+// The file list of the installed manifest is recorded to the data directory (see [RecordSdtdManifest]).
+// Returns an error if the download fails.
+// Returns an error if the downloaded manifest is incomplete.
+func DownloadSdtd(ctx context.Context, manifestId string) error {
+	key := fmt.Sprintf("sdtd-%s", manifestId)
+	depot := GetExecutionMode(ctx).Depot()
+	source := fmt.Sprintf("steam://294420/%s/%s.tar.gz", depot, manifestId)
+	download := func(dest string) error {
+		if GetDownloadConfig(ctx).Offline {
+			return fmt.Errorf("sdtd manifest %s missing from file cache: %w", manifestId, ErrOffline)
 		}
-		read, err := conn.netConn.Read(buf)
-		if err != nil {
-			return nil
+		mirror := RewriteUrl(ctx, source)
+		if mirror != source {
+			helper.Logger(ctx).Info("download sdtd from mirror", "manifest", manifestId, "url", mirror)
+			return helper.CreateTempDir(ctx, func(tempDir string) error {
+				downloadPath := filepath.Join(tempDir, filepath.Base(mirror))
+				_, _, err := DownloadFile(ctx, mirror, downloadPath, nil)
+				if err != nil {
+					return err
+				}
+				return helper.Extract(ctx, downloadPath, dest)
+			})
 		}
-		data += string(buf[:read])
-		if strings.Contains(data, pattern) {
-			break
+		helper.Logger(ctx).Info("download sdtd", "depot", depot, "manifest", manifestId)
+		output, err := helper.Command(ctx, []string{"DepotDownloader", "-app", "294420", "-depot", depot, "-manifest", manifestId, "-dir", dest, "-validate"}, helper.CmdOpts{Env: getProxyEnv(ctx)}).Run()
+		if err == nil {
+			recordDepotBandwidth(ctx, output)
+		}
+		return err
+	}
+	if GetDownloadConfig(ctx).Offline && isSdtdPreseeded(ctx) {
+		helper.Logger(ctx).Info("use pre-seeded sdtd (offline)", "manifest", manifestId)
+	} else {
+		err := helper.CacheFile(ctx, key, helper.Dirs(ctx)["sdtd"], func(dest string) error {
+			err := download(dest)
+			if err != nil {
+				return err
+			}
+			// avoid caching incomplete manifests
+			return CheckSdtdFiles(ctx, dest, manifestId)
+		})
+		if err != nil {
+			return err
 		}
 	}
-	return nil
+	err := FixupSdtd(ctx, manifestId)
+	if err != nil {
+		return err
+	}
+	return RecordSdtdManifest(ctx, manifestId)
 }
 
-// dialServerCb is a callback provided to [dialServer] - allowing callers to futher operate on a connection to the server
-type dialServerCb func(conn Conn) error
-
-// DialServer connects to the running seven days to die server, waits for the server to accept commands, and then invokes the provided callback with the opened connection.
-// Raises an error if the server is not connectable
-// Raises an error if the server times out while waiting to accept commands
-// Raises an error if the callback raises an error
-func DialServer(ctx context.Context, cb dialServerCb) error {
-	addr := "localhost:8081"
-	helper.Logger(ctx).Info("dialing server", "addr", addr)
-	nconn, err := net.Dial("tcp", addr)
+// Performs initial setup and the launches the seven days to die server.
+// Assumes that the local runtime environment has been bootstrapped.
+// Returns an error if any part of the process fails.
+func Entrypoint(ctx context.Context) error {
+	helper.Logger(ctx).Info("entrypoint")
+
+	// mounts are awaited (and the data directory locked) before anything is read from (or written to) the entrypoint's directories - including config bundles, which are commonly stored on mounts.
+	// the config is loaded again (and validated) once config bundles are imported.
+	startupConfig := EntrypointConfig{}
+	err := helper.ParseEnv(ctx, &startupConfig)
+	if err != nil {
+		return err
+	}
+	if len(startupConfig.MountWait) > 0 {
+		err = WaitForMounts(ctx, MountWaitOpts{Dirs: startupConfig.MountWait, Timeout: startupConfig.MountWaitTimeout})
+		if err != nil {
+			return err
+		}
+	}
+	if startupConfig.DataLock {
+		lock, err := AcquireDataLock(ctx)
+		if err != nil {
+			return err
+		}
+		defer lock.Release(ctx)
+		go lock.Run(ctx)
+	}
+
+	// config bundles provide environment variables - and so are imported before the config is loaded
+	bundleSettings := ServerSettings{}
+	bundleFile := os.Getenv("CONFIG_BUNDLE")
+	if bundleFile != "" {
+		bundleSettings, err = ImportConfigBundle(ctx, bundleFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// env files take precedence over bundles (and the container's environment) - and are re-applied when the config is reloaded
+	var envFile *EnvFile
+	if os.Getenv("ENV_FILE") != "" {
+		envFile = &EnvFile{Path: os.Getenv("ENV_FILE")}
+		err = envFile.Apply(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// migrations run after bundles are imported (bundles may have been exported by older images)
+	err = MigrateConfigEnv(ctx)
 	if err != nil {
 		return err
 	}
-	conn := Conn{ctx: ctx, netConn: nconn}
-	defer conn.netConn.Close()
-	pattern := "Press 'help' to get a list of all commands. Press 'exit' to end session."
-	err = conn.ReadUntilPattern(pattern, 5*time.Second)
+	config, err := LoadEntrypointConfig(ctx)
 	if err != nil {
 		return err
 	}
-	return cb(conn)
-}
+	// reloads are compared against the loaded config (rather than the config adjusted during startup - e.g., by mod updates)
+	controlPlane := &ControlPlane{Config: config, EnvFile: envFile}
+	tracker := NewStatusTracker(ctx, config.ManifestId)
+	ctx = WithStatusTracker(ctx, tracker)
+	tracker.SetState(ctx, ServerStateDownloading)
+	watchdog := NewSdWatchdog()
+	go watchdog.Run(ctx)
 
-// Shuts down a seven days to die server by connecting to its telnet port and sending the 'shutdown' command.
-// Raises an error if connecting to the server fails.
-// Raises an error if the server fails to send the command.
-func ShutdownServer(ctx context.Context) error {
-	helper.Logger(ctx).Info("shutdown server")
-	return DialServer(ctx, func(conn Conn) error {
-		_, err := conn.netConn.Write([]byte("shutdown\n"))
+	// snapshots are undone first - a pending backup restore supersedes them
+	err = ApplySnapshotUndo(ctx)
+	if err != nil {
 		return err
-	})
-}
+	}
+	err = ApplyPlayerSnapshotRestores(ctx)
+	if err != nil {
+		return err
+	}
+	err = ApplyBackupRestore(ctx)
+	if err != nil {
+		return err
+	}
 
-// Starts the seven days to die server.
-// Returns an error if the underlying command fails.
-func StartServer(ctx context.Context, config string) error {
-	helper.Logger(ctx).Info("start server", "config", config)
-	cmdFinished := make(chan bool, 1)
-	unregister := helper.HandleSignal(ctx, func(sig os.Signal) {
-		ShutdownServer(ctx)
-		<-cmdFinished
+	stagedUpdateApplied := false
+	var installStaging *InstallStaging
+	config.ManifestId, stagedUpdateApplied, err = ApplyStagedUpdate(ctx, config.ManifestId)
+	if err != nil {
+		return err
+	}
+	tracker.Update(ctx, func(status *ServerStatus) {
+		status.ManifestId = config.ManifestId
 	})
-	defer unregister()
-	env := append(os.Environ(), "LD_LIBRARY_PATH=.")
-	cmd := []string{"./7DaysToDieServer.x86_64", "-batchmode", fmt.Sprintf("-configfile=%s", config), "-dedicated", "-logfile", "-", "-nographics", "-quit"}
-	_, err := helper.Command(ctx, cmd, helper.CmdOpts{Attach: true, Cwd: helper.Dirs(ctx)["sdtd"], Env: env, IgnoreSignals: true}).Run()
-	cmdFinished <- true
-	return err
-}
 
-// Wraps map[string]string and is capable of assembling an xml payload
-type ServerSettings map[string]string
+	mirrors, err := ParseMirrorRules(config.DownloadMirrors)
+	if err != nil {
+		return err
+	}
+	ctx = WithDownloadConfig(ctx, DownloadConfig{Mirrors: mirrors, Offline: config.Offline, Proxy: config.DownloadProxy})
+
+	ctx = WithExecutionMode(ctx, config.ExecutionMode)
 
-// Converts the [ServerSettings] data into an XML payload
-func (ss *ServerSettings) Xml() XmlServerSettings {
-	xss := XmlServerSettings{Properties: []XmlServerProperty{}}
-	for name, value := range *ss {
-		xsp := XmlServerProperty{Name: name, Value: value}
-		xss.Properties = append(xss.Properties, xsp)
+	if config.Maintenance {
+		helper.Logger(ctx).Warn("maintenance mode enabled - mod auto-updates are disabled")
+		config.ModAutoUpdate = false
 	}
-	return xss
-}
 
-// XmlServerSettings is the xml representation of sdtd server settings
-type XmlServerSettings struct {
-	XMLName    xml.Name            `xml:"ServerSettings"`
-	Properties []XmlServerProperty `xml:"property"`
-}
+	// update checks are performed against (and keyed by) the configured urls - rather than the urls replaced by recorded updates
+	modUpdateUrls := slices.Concat(config.RootUrls, config.ModUrls)
+	if config.ModAutoUpdate {
+		config.RootUrls, err = ApplyModUpdates(ctx, config.RootUrls)
+		if err != nil {
+			return err
+		}
+		config.ModUrls, err = ApplyModUpdates(ctx, config.ModUrls)
+		if err != nil {
+			return err
+		}
+	}
 
-// XmlServerProperty is an item within a seven days to die server settings XML file.
-type XmlServerProperty struct {
-	Name  string `xml:"name,attr"`
-	Value string `xml:"value,attr"`
-}
+	if config.Offline {
+		err = CheckOfflineArtifacts(ctx, config.ManifestId, slices.Concat(config.RootUrls, config.ModUrls, config.PrefabPackUrls)...)
+		if err != nil {
+			return err
+		}
+	}
 
-// Converts the [XmlServerSettings] data into a typical golang map
-func (xss *XmlServerSettings) Map() ServerSettings {
-	data := map[string]string{}
-	for _, p := range xss.Properties {
-		data[p.Name] = p.Value
+	// installs are staged before anything is written to the sdtd (or generated) directory
+	if config.InstallStaging {
+		// pre-seeded and staged installs are used as-is (rather than downloaded) - and so are copied into staging
+		seed := stagedUpdateApplied || (config.Offline && isSdtdPreseeded(ctx))
+		staging, err := BeginInstallStaging(ctx, seed)
+		if err != nil {
+			return err
+		}
+		defer staging.Abort(ctx)
+		installStaging = staging
 	}
-	return data
-}
 
-// Parses server settings from the seven days to die server root directory.  Assumes the data is unmodified.
-// Returns an error if the server settings configuration file is unreadable
-// Returns an error if the server settings data is unparseable
-func GetDefaultServerSettings(ctx context.Context) (ServerSettings, error) {
-	fail := func(err error) (ServerSettings, error) {
-		return nil, err
-	}
-	file := filepath.Join(helper.Dirs(ctx)["sdtd"], "serverconfig.xml")
-	helper.Logger(ctx).Info("get default server settings", "path", file)
-	xss := XmlServerSettings{}
-	err := helper.UnmarshalFile(ctx, file, &xss)
+	if stagedUpdateApplied {
+		err = FixupSdtd(ctx, config.ManifestId)
+		if err == nil {
+			err = RecordSdtdManifest(ctx, config.ManifestId)
+		}
+	} else {
+		err = DownloadSdtd(ctx, config.ManifestId)
+	}
 	if err != nil {
-		return fail(err)
+		return err
 	}
-	return xss.Map(), nil
-}
 
-// Parses server settings from the environment (identified as environment variables prefixed with SETTING_).
-func GetEnvServerSettings(ctx context.Context) ServerSettings {
-	data := ServerSettings{}
-	prefix := "SETTING_"
-	for _, item := range os.Environ() {
-		parts := strings.SplitN(item, "=", 2)
-		if !strings.HasPrefix(parts[0], prefix) {
-			continue
+	if config.ExecutionMode == ExecutionModeProton {
+		err = DownloadProton(ctx, config.ProtonUrl)
+		if err != nil {
+			return err
+		}
+	}
+	if config.ExecutionMode.Emulated() && config.EmulatorUrl != "" {
+		err = config.ExecutionMode.DownloadEmulator(ctx, config.EmulatorUrl)
+		if err != nil {
+			return err
 		}
-		parts[0] = strings.TrimPrefix(parts[0], prefix)
-		data[parts[0]] = parts[1]
 	}
-	helper.Logger(ctx).Info("get env server settings", "count", len(data))
-	return data
-}
 
-// Merges a list of [ServerSettings] (in order) - producing a final [ServerSettings].
-func MergeServerSettings(items ...ServerSettings) ServerSettings {
-	data := ServerSettings{}
-	for _, item := range items {
-		for k, v := range item {
-			data[k] = v
+	if config.DeleteDefaultMods {
+		err := DeleteDefaultMods(ctx)
+		if err != nil {
+			return err
 		}
 	}
-	return data
-}
 
-// Writes server settings (presented as a map) as a server settings XML file stored at [path]
-// Returns an error if the data cannot be serialized into XML
-// Returns an error if the data cannot be written to [path]
-func WriteServerSettings(ctx context.Context, settings ServerSettings) (string, error) {
-	fail := func(err error) (string, error) {
-		return "", err
-	}
-	path := filepath.Join(helper.Dirs(ctx)["generated"], "serverconfig.xml")
-	helper.Logger(ctx).Info("write server settings", "path", path)
-	xmlServerSettings := settings.Xml()
-	err := helper.MarshalFile(ctx, xmlServerSettings, path)
+	plugins, err := LoadPlugins(ctx, config.PluginsDir)
 	if err != nil {
-		return fail(err)
+		return err
 	}
-	return path, nil
-}
 
-// Downloads and extracts a list of mod urls to the given path.
-// Returns an error if the download fails.
-// Returns an error if the extraction fails.
-func InstallMods(ctx context.Context, path string, mods ...string) error {
-	for _, mod := range mods {
-		helper.Logger(ctx).Info("install mod", "path", path, "mod", mod)
-		key := fmt.Sprintf("mod-%s", filepath.Base(mod))
-		err := helper.CacheFile(ctx, key, path, func(dest string) error {
-			return helper.CreateTempDir(ctx, func(tempDir string) error {
-				downloadPath := filepath.Join(tempDir, filepath.Base(mod))
-				err := helper.Download(ctx, mod, downloadPath)
-				if err != nil {
-					return err
-				}
-				return helper.Extract(ctx, downloadPath, dest)
-			})
-		})
+	installModsOpts := InstallModsOpts{}
+	if config.ModPolicyFile != "" {
+		installModsOpts.Policy, err = LoadModPolicy(ctx, config.ModPolicyFile)
 		if err != nil {
 			return err
 		}
 	}
-	return nil
-}
 
-// Deletes default mods located in the sdtd 'Mods' folder.  This is done by deleting the folder and recreating it.
-// Returns an error if the initial folder deletion fails.
-// Returns an error if the subsequent folder creation fails.
-func DeleteDefaultMods(ctx context.Context) error {
-	helper.Logger(ctx).Info("delete default mods")
-	path := filepath.Join(helper.Dirs(ctx)["sdtd"], "Mods")
-	subpaths, err := helper.ListDir(ctx, path)
-	if err != nil {
-		return err
+	var modRollback *ModRollback
+	if config.ModsRollback {
+		modRollback, err = PrepareModRollback(ctx, ModRollbackOpts{Bisect: config.ModsBisect})
+		if err != nil {
+			return err
+		}
 	}
-	return helper.RemovePaths(ctx, subpaths...)
-}
 
-// Downloads sdtd with DepotDownloader
-func DownloadSdtd(ctx context.Context, manifestId string) error {
-	key := fmt.Sprintf("sdtd-%s", manifestId)
-	err := helper.CacheFile(ctx, key, helper.Dirs(ctx)["sdtd"], func(dest string) error {
-		helper.Logger(ctx).Info("download sdtd", "manifest", manifestId)
-		_, err := helper.Command(ctx, []string{"DepotDownloader", "-app", "294420", "-depot", "294422", "-manifest", manifestId, "-dir", dest}, helper.CmdOpts{}).Run()
-		return err
-	})
+	err = InstallMods(ctx, installModsOpts, helper.Dirs(ctx)["sdtd"], config.RootUrls...)
 	if err != nil {
 		return err
 	}
-	helper.Logger(ctx).Info("set server binary executable")
-	serverBin := filepath.Join(helper.Dirs(ctx)["sdtd"], "7DaysToDieServer.x86_64")
-	return os.Chmod(serverBin, 0755)
-}
-
-// EntrypointConfig is the configuration for the
-type EntrypointConfig struct {
-	DeleteDefaultMods  bool           `env:"DELETE_DEFAULT_MODS"`
-	ManifestId         string         `env:"MANIFEST_ID"`
-	ModUrls            []string       `env:"MOD_URLS"`
-	RootUrls           []string       `env:"ROOT_URLS"`
-	AutoRestart        *time.Duration `env:"AUTO_RESTART"`
-	AutoRestartMessage string         `env:"AUTO_RESTART_MESSAGE" envDefault:"Restarting server in 1 minute"`
-}
-
-// Performs initial setup and the launches the seven days to die server.
-// Assumes that the local runtime environment has been bootstrapped.
-// Returns an error if any part of the process fails.
-func Entrypoint(ctx context.Context) error {
-	helper.Logger(ctx).Info("entrypoint")
 
-	config := EntrypointConfig{}
-	err := helper.ParseEnv(ctx, &config)
+	err = InstallMods(ctx, installModsOpts, filepath.Join(helper.Dirs(ctx)["sdtd"], "Mods"), config.ModUrls...)
 	if err != nil {
 		return err
 	}
-	config.AutoRestartMessage = strings.ReplaceAll(config.AutoRestartMessage, "\"", "\"\"")
 
-	err = DownloadSdtd(ctx, config.ManifestId)
+	err = InstallPrefabPacks(ctx, installModsOpts, config.PrefabPackUrls...)
 	if err != nil {
 		return err
 	}
 
-	if config.DeleteDefaultMods {
-		err := DeleteDefaultMods(ctx)
+	if modRollback != nil {
+		err = modRollback.Check(ctx)
 		if err != nil {
 			return err
 		}
 	}
 
-	err = InstallMods(ctx, helper.Dirs(ctx)["sdtd"], config.RootUrls...)
-	if err != nil {
-		return err
+	// a server language (or localization packs) implies merging - its overrides are merged as fragments
+	if config.LocalizationMerge || config.ServerLanguage != "" || len(config.LocalizationUrls) > 0 {
+		err := InstallLocalizationPacks(ctx, config.LocalizationUrls...)
+		if err != nil {
+			return err
+		}
+		fragments, err := GetLocalizationFragments(ctx, config.ServerLanguage)
+		if err != nil {
+			return err
+		}
+		_, err = MergeLocalizations(ctx, fragments...)
+		if err != nil {
+			return err
+		}
+		if config.ServerLanguage != "" {
+			err = LogLocalizationCoverage(ctx, config.ServerLanguage)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	err = InstallMods(ctx, filepath.Join(helper.Dirs(ctx)["sdtd"], "Mods"), config.ModUrls...)
+	codeMods, err := GetCodeMods(ctx, filepath.Join(helper.Dirs(ctx)["sdtd"], "Mods"))
 	if err != nil {
 		return err
 	}
@@ -293,41 +358,694 @@ func Entrypoint(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	settingsFile, err := WriteServerSettings(ctx, MergeServerSettings(
+	presetSettings := ServerSettings{}
+	if config.Preset != "" {
+		helper.Logger(ctx).Info("apply preset", "preset", config.Preset)
+		presetSettings, err = GetPresetSettings(config.Preset)
+		if err != nil {
+			return err
+		}
+	}
+	panelSettings := ServerSettings{}
+	if config.PanelMode {
+		panelSettings, err = GetPanelServerSettings(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	persistedSettings, err := LoadPersistedSettings(ctx)
+	if err != nil {
+		return err
+	}
+	settings := MergeServerSettings(
 		defaultSettings,
 		ServerSettings{
 			"WebDashboardEnabled": "true",
 		},
-		GetEnvServerSettings(ctx),
+		GetServerLanguageSettings(config.ServerLanguage),
+		presetSettings,
+		bundleSettings,
+		panelSettings,
+		TranslateDeprecatedSettings(ctx, config.GameVersion, GetEnvServerSettings(ctx)),
+		persistedSettings,
+	)
+	DeleteServerSettings(ctx, settings, config.DeleteSettings...)
+	err = RunPluginsPreStart(ctx, plugins, settings)
+	if err != nil {
+		return err
+	}
+	profileBase := settings
+	profiles := []SettingsProfile{}
+	if config.SettingsProfilesFile != "" {
+		profiles, err = LoadSettingsProfiles(ctx, config.SettingsProfilesFile)
+		if err != nil {
+			return err
+		}
+		settings = ApplySettingsProfiles(ctx, settings, profiles, time.Now())
+	}
+	hordeEvents := []HordeEvent{}
+	if config.HordeEventsFile != "" {
+		hordeEvents, err = LoadHordeEvents(ctx, config.HordeEventsFile)
+		if err != nil {
+			return err
+		}
+	}
+	if config.Maintenance {
+		settings = MergeServerSettings(settings, GetMaintenanceSettings(ctx, config.MaintenancePassword))
+	}
+	// transforms run over the merged settings - settings forced by the entrypoint (below) can't be transformed
+	if config.SettingsTransform != "" {
+		settings, err = TransformServerSettings(ctx, config.SettingsTransform, settings)
+		if err != nil {
+			return err
+		}
+	}
+	settings = MergeServerSettings(
+		settings,
 		ServerSettings{
 			"TelnetEnabled":    "true",                   // force telnet to be enabled (for graceful shutdown and health checks)
 			"TelnetPort":       "8081",                   // force telnet port to match exposed docker port
 			"UserDataFolder":   helper.Dirs(ctx)["data"], // force user data folder to be located at [folderData]
 			"WebDashboardPort": "8080",                   // force web dashboard port to match exposed docker port
 		},
-	))
+	)
+	templateVars, err := GetTemplateVars(ctx, TemplateVarsOpts{GameVersion: config.GameVersion, ManifestId: config.ManifestId}, settings)
+	if err != nil {
+		return err
+	}
+	RenderSettingTemplates(ctx, settings, templateVars)
+	CheckServerIp(ctx, settings)
+	CheckEacCompatibility(ctx, settings, codeMods, config.EacAutoDisable)
+	CheckServerSettings(ctx, config.GameVersion, defaultSettings, settings)
+	settingsFile, err := WriteServerSettings(ctx, settings)
 	if err != nil {
 		return err
 	}
+	if installStaging != nil {
+		settingsFile = installStaging.Resolve(settingsFile)
+		err = installStaging.Commit(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	// standbys idle once the server is installed and configured - so that promotion only needs to restore the save
+	if config.FailoverPrimaryUrl != nil {
+		source, _ := ParseReplicationTarget(config.FailoverSource)
+		failoverOpts := FailoverOpts{
+			CheckInterval: config.FailoverCheckInterval,
+			PrimaryToken:  config.FailoverPrimaryToken,
+			PrimaryUrl:    config.FailoverPrimaryUrl,
+			Source:        source.Destination,
+			Threshold:     config.FailoverThreshold,
+			WebhookUrl:    config.FailoverWebhookUrl,
+		}
+		err = RunFailoverStandby(ctx, failoverOpts)
+		if err != nil {
+			return err
+		}
+	}
+	// the world settings are checked once the save is final (e.g., after a standby restores the primary's save)
+	recordWorld, err := CheckWorldSettings(ctx, settings, config.AllowWorldChange)
+	if err != nil {
+		return err
+	}
+	session := NewTelnetSession(telnetAddr)
+	if config.TelnetTranscript {
+		transcript := NewTelnetTranscript(ctx, TelnetTranscriptOpts{MaxFiles: config.TelnetTranscriptFiles, MaxSize: int64(config.TelnetTranscriptSize) * 1000 * 1000})
+		defer transcript.Close()
+		session.SetTranscript(transcript)
+	}
+	go session.Run(ctx)
+	ctx = WithTelnetSession(ctx, session)
+	watchdog.SetSession(session)
+	sayQueue := NewSayQueue()
+	go sayQueue.Run(ctx)
+	ctx = WithSayQueue(ctx, sayQueue)
+
+	tracker.SetState(ctx, ServerStateStarting)
 	if config.AutoRestart != nil {
+		tracker.Update(ctx, func(status *ServerStatus) {
+			nextRestart := status.StartedAt.Add(*config.AutoRestart)
+			status.NextRestart = &nextRestart
+		})
+	}
+	go tracker.Run(ctx, 15*time.Second, config.ServerReadyTimeout)
+
+	var directory *DirectoryPublisher
+	if config.DirectoryUrl != nil {
+		directory = &DirectoryPublisher{
+			Opts:     DirectoryOpts{Address: config.PublicAddress, GameVersion: config.GameVersion, Interval: config.DirectoryInterval, Token: config.DirectoryToken, Url: config.DirectoryUrl},
+			Settings: settings,
+		}
+		go directory.Run(ctx)
+	}
+
+	playerCount := PlayerCountPublisher{
+		Opts:     PlayerCountOpts{Annotate: config.PlayerCountAnnotate, Interval: 15 * time.Second, Pod: config.PodName},
+		Settings: settings,
+	}
+	go playerCount.Run(ctx)
+
+	if len(config.PostStartCommands) > 0 {
 		go func() {
-			time.Sleep(*config.AutoRestart - time.Minute)
-			DialServer(ctx, func(conn Conn) error {
-				_, err := conn.netConn.Write([]byte(fmt.Sprintf("say \"%s\"\n", config.AutoRestartMessage)))
+			err := RunPostStartCommands(ctx, config.ServerReadyTimeout, config.PostStartCommands...)
+			if err != nil {
+				helper.Logger(ctx).Warn("post-start commands failed", "error", err.Error())
+			}
+		}()
+	}
+
+	proxies, _ := ParseTrustedProxies(config.TrustedProxies)
+	if config.WebdavEnabled {
+		go func() {
+			err := RunWebdavServer(ctx, listenAddr(config.BindAddress, config.WebdavPort), config.WebdavUsername, config.WebdavPassword, proxies)
+			if err != nil {
+				helper.Logger(ctx).Error("webdav server failed", "error", err.Error())
+			}
+		}()
+	}
+
+	go func() {
+		err := session.WaitReady(ctx, config.ServerReadyTimeout)
+		if err != nil {
+			return
+		}
+		port, err := settings.GetInt("ServerPort")
+		if err != nil {
+			port = 26900
+		}
+		CheckGamePortBinding(ctx, port)
+	}()
+
+	if recordWorld {
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err == nil {
+				err = RecordWorldSettings(ctx, settings)
+			}
+			if err != nil {
+				helper.Logger(ctx).Warn("record world settings failed", "error", err.Error())
+			}
+		}()
+	}
+
+	if len(profiles) > 0 {
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err != nil {
+				helper.Logger(ctx).Warn("settings profiles stopped", "error", err.Error())
+				return
+			}
+			RunSettingsProfiles(ctx, profileBase, profiles)
+		}()
+	}
+
+	if len(hordeEvents) > 0 {
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err == nil {
+				err = RunHordeEvents(ctx, HordeEventOpts{Events: hordeEvents, WebhookUrl: config.HordeEventsWebhookUrl})
+			}
+			if err != nil {
+				helper.Logger(ctx).Warn("horde events stopped", "error", err.Error())
+			}
+		}()
+	}
+
+	if config.PlayerSnapshotDeaths {
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err == nil {
+				err = RunPlayerDeathSnapshots(ctx, PlayerSnapshotOpts{Limit: config.PlayerSnapshotLimit})
+			}
+			if err != nil {
+				helper.Logger(ctx).Warn("player death snapshots stopped", "error", err.Error())
+			}
+		}()
+	}
+
+	if config.DriftCheckInterval != nil {
+		driftOpts := DriftCheckOpts{Persist: config.DriftPersist}
+		for _, profile := range profiles {
+			driftOpts.Ignored = append(driftOpts.Ignored, profile.Settings.Names()...)
+		}
+		for _, event := range hordeEvents {
+			driftOpts.Ignored = append(driftOpts.Ignored, event.Settings.Names()...)
+		}
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err != nil {
+				helper.Logger(ctx).Warn("settings drift checks stopped", "error", err.Error())
+				return
+			}
+			RunSettingsDriftChecks(ctx, *config.DriftCheckInterval, settings, driftOpts)
+		}()
+	}
+
+	backupTargets, _ := ParseReplicationTargets(config.BackupDestinations)
+	backupOpts := BackupOpts{Mode: config.BackupMode, Retention: config.BackupRetention, Targets: backupTargets, VerifyExtract: config.BackupVerifyExtract, WebhookUrl: config.BackupWebhookUrl}
+	if config.LowDiskThreshold > 0 {
+		monitor := DiskMonitor{Opts: DiskMonitorOpts{Threshold: uint64(config.LowDiskThreshold) * 1000 * 1000, WebhookUrl: config.LowDiskWebhookUrl}}
+		backupOpts.Paused = monitor.Low
+		go monitor.Run(ctx)
+	}
+	auditor := NewAuditor(ctx, config.AuditWebhookUrl)
+	var macros *MacroRunner
+	if config.MacrosFile != "" {
+		macroList, err := LoadMacros(ctx, config.MacrosFile)
+		if err != nil {
+			return err
+		}
+		macros = &MacroRunner{Auditor: auditor, Macros: macroList}
+		go macros.Run(ctx)
+	}
+	var banSync *BanSync
+	if len(config.BanSyncPeers) > 0 {
+		peers, _ := ParseBanSyncPeers(config.BanSyncPeers)
+		origin, _ := os.Hostname()
+		banSync = NewBanSync(BanSyncOpts{Origin: origin, Peers: peers, Token: config.BanSyncToken})
+		go func() {
+			err := banSync.Run(ctx, config.ServerReadyTimeout)
+			if err != nil {
+				helper.Logger(ctx).Warn("ban sync stopped", "error", err.Error())
+			}
+		}()
+	}
+	var metrics *MetricHistory
+	if config.MetricsHistory {
+		metrics, err = NewMetricHistory(ctx, MetricHistoryOpts{Interval: time.Minute, Retention: config.MetricsRetention})
+		if err != nil {
+			return err
+		}
+		go func() {
+			err := metrics.Run(ctx)
+			if err != nil {
+				helper.Logger(ctx).Warn("metrics history stopped", "error", err.Error())
+			}
+		}()
+	}
+	var logFilter *ServerLogFilter
+	if config.AdminApiEnabled || len(config.ServerLogHide) > 0 || len(config.ServerLogSuppress) > 0 {
+		logFilter, err = NewServerLogFilter(config.ServerLogHide, config.ServerLogSuppress)
+		if err != nil {
+			return err
+		}
+		ctx = WithServerLogFilter(ctx, logFilter)
+		session.OnConnect(logFilter.OnTelnetConnect)
+	}
+	if config.AdminApiEnabled {
+		tokens, _ := ParseAdminTokens(config.AdminApiTokens)
+		if config.AdminApiTokensFile != "" {
+			fileTokens, err := LoadAdminTokens(ctx, config.AdminApiTokensFile)
+			if err != nil {
 				return err
-			})
+			}
+			tokens = append(tokens, fileTokens...)
+		}
+		gamePort, err := settings.GetInt("ServerPort")
+		if err != nil {
+			gamePort = 26900
+		}
+		var positions *PositionTracker
+		if config.PlayerPositions != nil {
+			positions = &PositionTracker{Interval: *config.PlayerPositions}
+			go positions.Run(ctx)
+		}
+		api := AdminApi{Auditor: auditor, BackupOpts: backupOpts, BanSync: banSync, ControlPlane: controlPlane, GamePort: gamePort, LogFilter: logFilter, Macros: macros, Metrics: metrics, Positions: positions, Tokens: tokens, TrustedProxies: proxies, Whitelist: config.AdminCommandWhitelist}
+		go func() {
+			err := api.Run(ctx, listenAddr(config.BindAddress, config.AdminApiPort))
+			if err != nil {
+				helper.Logger(ctx).Error("admin api failed", "error", err.Error())
+			}
+		}()
+	}
+	if config.ControlSocket != "" {
+		socket := ControlSocket{Auditor: auditor, BackupOpts: backupOpts, ControlPlane: controlPlane, Path: config.ControlSocket}
+		go func() {
+			err := socket.Run(ctx)
+			if err != nil {
+				helper.Logger(ctx).Error("control socket failed", "error", err.Error())
+			}
+		}()
+	}
+
+	if config.PanelMode {
+		stdin, err := DetachStdin()
+		if err != nil {
+			return err
+		}
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err != nil {
+				helper.Logger(ctx).Warn("panel console stopped", "error", err.Error())
+				return
+			}
+			WritePanelMarker("server ready")
+			RunPanelConsole(ctx, auditor, stdin)
+		}()
+	}
+
+	if config.Maintenance {
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err != nil {
+				helper.Logger(ctx).Warn("maintenance banner stopped", "error", err.Error())
+				return
+			}
+			RunMaintenanceBanner(ctx, maintenanceBannerInterval, config.MaintenanceMessage)
+		}()
+	}
+
+	if config.PingKickThreshold > 0 {
+		pingOpts := PingPolicyOpts{Duration: config.PingKickDuration, Exempt: config.PingKickExempt, Threshold: config.PingKickThreshold}
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err != nil {
+				helper.Logger(ctx).Warn("ping policy stopped", "error", err.Error())
+				return
+			}
+			RunPingPolicy(ctx, 30*time.Second, pingOpts)
+		}()
+	}
+
+	if config.ChatCommandsEnabled {
+		chatOpts := ChatCommandOpts{Admins: config.ChatCommandAdmins, Cooldown: config.ChatCommandCooldown, Macros: macros, Restricted: config.ChatCommandRestricted}
+		chatCommands, err := NewChatCommandService(ctx, chatOpts)
+		if err != nil {
+			return err
+		}
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err == nil {
+				err = chatCommands.Run(ctx)
+			}
+			if err != nil {
+				helper.Logger(ctx).Warn("chat commands stopped", "error", err.Error())
+			}
+		}()
+	}
+
+	if config.AllocsFixesEnabled {
+		tokens, _ := ParseAdminTokens(config.AllocsFixesTokens)
+		endpoints := map[string]string{"webmap": fmt.Sprintf("http://localhost:%s/", settings["WebDashboardPort"])}
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err == nil {
+				err = CreateWebTokens(ctx, tokens)
+			}
+			if err != nil {
+				helper.Logger(ctx).Warn("alloc's server fixes provisioning failed", "error", err.Error())
+				return
+			}
+			RunEndpointChecks(ctx, time.Minute, endpoints)
+		}()
+	}
+
+	controlPlane.Register(ctx, ReloadableSubsystem{
+		Name: "config-watch",
+		Env:  []string{"CONFIG_WATCH_WEBHOOK_URL"},
+		Start: func(ctx context.Context, reloaded EntrypointConfig) {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err == nil {
+				// the baseline is recorded once the server is ready (the game creates the admin file on startup)
+				watchOpts := ConfigWatchOpts{AdminFile: filepath.Join(helper.Dirs(ctx)["data"], getServerAdminFile(settings)), SettingsFile: settingsFile, WebhookUrl: reloaded.ConfigWatchWebhookUrl}
+				err = NewConfigWatcher(watchOpts).Run(ctx, 30*time.Second)
+			}
+			if err != nil && ctx.Err() == nil {
+				helper.Logger(ctx).Warn("config file watch stopped", "error", err.Error())
+			}
+		},
+	})
+
+	controlPlane.Register(ctx, ReloadableSubsystem{
+		Name: "alerts",
+		Env:  []string{"ALERT_EXEMPT", "ALERT_MAX_SPEED", "ALERT_SPAWN_LIMIT", "ALERT_WEBHOOK_URL"},
+		Start: func(ctx context.Context, reloaded EntrypointConfig) {
+			if reloaded.AlertWebhookUrl == nil {
+				return
+			}
+			alerts := NewAlertMonitor(AlertOpts{Exempt: reloaded.AlertExempt, MaxSpeed: reloaded.AlertMaxSpeed, SpawnLimit: reloaded.AlertSpawnLimit, WebhookUrl: reloaded.AlertWebhookUrl})
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err == nil {
+				err = alerts.Run(ctx, 10*time.Second)
+			}
+			if err != nil && ctx.Err() == nil {
+				helper.Logger(ctx).Warn("suspicious activity alerts stopped", "error", err.Error())
+			}
+		},
+	})
+
+	if config.ChunkResetFile != "" {
+		rules, err := LoadChunkResetRules(ctx, config.ChunkResetFile)
+		if err != nil {
+			return err
+		}
+		if config.Maintenance {
+			helper.Logger(ctx).Warn("chunk resets skipped while MAINTENANCE is enabled")
+		} else {
+			landClaimSize, err := settings.GetInt("LandClaimSize")
+			if err != nil {
+				landClaimSize = 41
+			}
+			count, err := ResetChunks(ctx, ChunkResetOpts{GameName: settings["GameName"], LandClaimSize: landClaimSize, Rules: rules})
+			if err != nil {
+				return err
+			}
+			helper.Logger(ctx).Info("chunk reset complete", "regions", count)
+		}
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err != nil {
+				helper.Logger(ctx).Warn("land claim tracking stopped", "error", err.Error())
+				return
+			}
+			RunLandClaimTracking(ctx, 10*time.Minute)
+		}()
+	}
+
+	controlPlane.Register(ctx, ReloadableSubsystem{
+		Name: "kill-feed",
+		Env:  []string{"KILL_FEED_ADMIN_WEBHOOK_URL", "KILL_FEED_DEATHS", "KILL_FEED_WEBHOOK_URL"},
+		Start: func(ctx context.Context, reloaded EntrypointConfig) {
+			if reloaded.KillFeedWebhookUrl == nil && reloaded.KillFeedAdminUrl == nil {
+				return
+			}
+			killFeedOpts := KillFeedOpts{AdminWebhookUrl: reloaded.KillFeedAdminUrl, IncludeDeaths: reloaded.KillFeedDeaths, WebhookUrl: reloaded.KillFeedWebhookUrl}
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err == nil {
+				err = RunKillFeed(ctx, killFeedOpts)
+			}
+			if err != nil && ctx.Err() == nil {
+				helper.Logger(ctx).Warn("kill feed stopped", "error", err.Error())
+			}
+		},
+	})
+
+	var events *EventStore
+	if config.EventsDb {
+		events = &EventStore{Opts: EventStoreOpts{FlushInterval: time.Minute, Retention: config.EventsDbRetention}}
+		err = events.Init(ctx)
+		if err != nil {
+			return err
+		}
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err == nil {
+				events.Record(ServerEvent{Time: time.Now().UTC(), Type: EventServerReady})
+			}
+		}()
+		go func() {
+			err := events.Run(ctx)
+			if err != nil {
+				helper.Logger(ctx).Warn("events database stopped", "error", err.Error())
+			}
+		}()
+	}
+
+	if config.PlaytimeRewardsFile != "" {
+		rewards, err := LoadPlaytimeRewards(ctx, config.PlaytimeRewardsFile)
+		if err != nil {
+			return err
+		}
+		rewarder, err := NewPlaytimeRewarder(ctx, auditor, rewards)
+		if err != nil {
+			return err
+		}
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err != nil {
+				helper.Logger(ctx).Warn("playtime rewards stopped", "error", err.Error())
+				return
+			}
+			rewarder.Run(ctx, time.Minute)
+		}()
+	}
+
+	if config.AfkKickTimeout != nil {
+		maxPlayers, err := settings.GetInt("ServerMaxPlayerCount")
+		if err != nil {
+			maxPlayers = 8
+		}
+		afkOpts := AfkPolicyOpts{Exempt: config.AfkKickExempt, FreeSlots: config.AfkKickFreeSlots, MaxPlayers: maxPlayers, Timeout: *config.AfkKickTimeout}
+		go func() {
+			err := session.WaitReady(ctx, config.ServerReadyTimeout)
+			if err == nil {
+				err = RunAfkPolicy(ctx, time.Minute, afkOpts)
+			}
+			if err != nil {
+				helper.Logger(ctx).Warn("afk policy stopped", "error", err.Error())
+			}
+		}()
+	}
+
+	if len(plugins) > 0 {
+		go RunPluginEvents(ctx, plugins, config.ServerReadyTimeout)
+	}
+
+	if config.AutoRestart != nil {
+		go func() {
+			time.Sleep(*config.AutoRestart - time.Minute)
+			SayServer(ctx, config.AutoRestartMessage)
 			time.Sleep(time.Minute)
 			ShutdownServer(ctx)
 		}()
 	}
-	return StartServer(ctx, settingsFile)
+	var idleShutdown *IdleShutdown
+	if config.IdleShutdownTimeout != nil {
+		idleShutdown = &IdleShutdown{Opts: IdleShutdownOpts{Instance: InstanceProvider(config.IdleShutdownInstance), Timeout: *config.IdleShutdownTimeout, WebhookUrl: config.IdleShutdownWebhookUrl}}
+		go idleShutdown.Run(ctx, 30*time.Second)
+	}
+	controlPlane.Register(ctx, ReloadableSubsystem{
+		Name: "maintenance",
+		Env:  []string{"MAINTENANCE_INTERVAL", "MAINTENANCE_LOG_MAX_AGE", "MAINTENANCE_TILE_MAX_AGE"},
+		Start: func(ctx context.Context, reloaded EntrypointConfig) {
+			if reloaded.MaintenanceInterval == nil {
+				return
+			}
+			maintenanceOpts := MaintenanceOpts{LogMaxAge: reloaded.MaintenanceLogMaxAge, TileMaxAge: reloaded.MaintenanceTileMaxAge}
+			RunMaintenanceSchedule(ctx, *reloaded.MaintenanceInterval, maintenanceOpts)
+		},
+	})
+	controlPlane.Register(ctx, ReloadableSubsystem{
+		Name: "backups",
+		Env:  []string{"BACKUP_DESTINATIONS", "BACKUP_INTERVAL", "BACKUP_MODE", "BACKUP_RETENTION", "BACKUP_VERIFY_EXTRACT", "BACKUP_WEBHOOK_URL"},
+		Start: func(ctx context.Context, reloaded EntrypointConfig) {
+			if reloaded.BackupInterval == nil {
+				tracker.Update(ctx, func(status *ServerStatus) {
+					status.NextBackup = nil
+				})
+				return
+			}
+			// scheduled backups keep the startup's low disk monitor
+			scheduledOpts := backupOpts
+			scheduledOpts.Targets, _ = ParseReplicationTargets(reloaded.BackupDestinations)
+			scheduledOpts.Mode, scheduledOpts.Retention, scheduledOpts.VerifyExtract, scheduledOpts.WebhookUrl = reloaded.BackupMode, reloaded.BackupRetention, reloaded.BackupVerifyExtract, reloaded.BackupWebhookUrl
+			RunBackupSchedule(ctx, *reloaded.BackupInterval, scheduledOpts)
+		},
+	})
+	bloodMoonFrequency, err := settings.GetInt("BloodMoonFrequency")
+	if err != nil {
+		bloodMoonFrequency = 7
+	}
+	bloodMoonRange, _ := settings.GetInt("BloodMoonRange")
+	controlPlane.Register(ctx, ReloadableSubsystem{
+		Name: "save-schedule",
+		Env:  []string{"SAVE_INTERVAL", "SAVE_JITTER"},
+		Start: func(ctx context.Context, reloaded EntrypointConfig) {
+			if reloaded.SaveInterval == nil {
+				tracker.Update(ctx, func(status *ServerStatus) {
+					status.NextSave = nil
+				})
+				return
+			}
+			saveOpts := SaveScheduleOpts{BloodMoonFrequency: bloodMoonFrequency, BloodMoonRange: bloodMoonRange, Interval: *reloaded.SaveInterval, Jitter: reloaded.SaveJitter}
+			RunSaveSchedule(ctx, saveOpts)
+		},
+	})
+	go controlPlane.Run(ctx)
+	var standby *StandbyReplicator
+	if len(config.StandbyDestinations) > 0 {
+		standbyTargets, _ := ParseReplicationTargets(config.StandbyDestinations)
+		standby = &StandbyReplicator{Opts: StandbyOpts{BandwidthLimit: config.StandbyBandwidthLimit, Interval: config.StandbyInterval, Targets: standbyTargets}}
+		go standby.Run(ctx)
+	}
+	if config.ModUpdateCheckInterval != nil && !config.Offline {
+		var onUpdates func(updates map[string]ModUpdate)
+		if config.ModAutoUpdate && config.UpdateVoteDeadline != nil {
+			voteOpts := UpdateVoteOpts{Command: config.UpdateVoteCommand, Deadline: *config.UpdateVoteDeadline, Reminder: 15 * time.Minute}
+			voteOnce := sync.Once{}
+			onUpdates = func(updates map[string]ModUpdate) {
+				voteOnce.Do(func() {
+					go func() {
+						err := session.WaitReady(ctx, config.ServerReadyTimeout)
+						if err == nil {
+							err = RunUpdateVote(ctx, voteOpts, updates)
+						}
+						if err != nil {
+							helper.Logger(ctx).Warn("update vote failed", "error", err.Error())
+						}
+					}()
+				})
+			}
+		}
+		go RunModUpdateChecks(ctx, *config.ModUpdateCheckInterval, onUpdates, modUpdateUrls...)
+	}
+	if !config.PreflightSkip {
+		err = CheckPreflight(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	LogStartupBanner(ctx, config, settings)
+	// the server runs with its own context - so that a server that fails readiness (and doesn't accept a shutdown) can be killed
+	serverCtx, killServer := context.WithCancel(ctx)
+	defer killServer()
+	if modRollback != nil {
+		go modRollback.Watch(ctx, config.ServerReadyTimeout, killServer)
+	}
+	err = StartServer(serverCtx, settingsFile, ProcessTuning{CpuAffinity: config.CpuAffinity, Priority: config.ProcessPriority})
+	if modRollback != nil {
+		// the outcome is recorded before the server is marked stopped - deliberate shutdowns are identified by the 'shutting-down' state
+		rollbackErr := modRollback.Finish(ctx)
+		if err == nil {
+			err = rollbackErr
+		}
+	}
+	tracker.SetState(ctx, ServerStateStopped)
+	if directory != nil {
+		// directories are notified immediately (rather than waiting for the listing to expire)
+		err := directory.Publish(ctx)
+		if err != nil {
+			helper.Logger(ctx).Warn("directory heartbeat failed", "error", err.Error())
+		}
+	}
+	if events != nil {
+		events.Record(ServerEvent{Time: time.Now().UTC(), Type: EventServerStopped})
+		err := events.Flush(ctx)
+		if err != nil {
+			helper.Logger(ctx).Warn("write events failed", "error", err.Error())
+		}
+	}
+	if standby != nil {
+		// a final replication captures the world as it was saved during shutdown
+		standby.Sync(ctx)
+	}
+	if config.PanelMode {
+		WritePanelMarker("server stopped")
+	}
+	RunPluginsShutdown(ctx, plugins)
+	if err == nil && idleShutdown != nil && idleShutdown.Triggered() {
+		// the instance is stopped last - everything else (e.g., replication) has finished with the save
+		err = idleShutdown.StopInstance(ctx)
+	}
+	return err
 }
 
 // Checks the health of the seven days to die server by attempting to connect to the server's telnet port.
 // If the connection fails, returns an error
 func CheckHealth(ctx context.Context) error {
 	healthy := false
-	err := DialServer(ctx, func(conn Conn) error {
+	err := DialServer(ctx, DialServerOpts{}, func(conn Conn) error {
 		healthy = true
 		return nil
 	})
@@ -335,21 +1053,41 @@ func CheckHealth(ctx context.Context) error {
 	return err
 }
 
+// subcommands are additional commands (invoked as '<entrypoint> <subcommand> [args...]') used to interact with a running server
+var subcommands = map[string]func(ctx context.Context) error{
+	"backup":    BackupSubcommand,
+	"bandwidth": BandwidthSubcommand,
+	"config":    ConfigSubcommand,
+	"diagnose":  DiagnoseSubcommand,
+	"events":    EventsSubcommand,
+	"exec":      ExecSubcommand,
+	"mods":      ModsSubcommand,
+	"player":    PlayerSubcommand,
+	"reload":    ReloadSubcommand,
+	"settings":  SettingsSubcommand,
+	"snapshot":  SnapshotSubcommand,
+	"update":    UpdateSubcommand,
+}
+
 //go:embed version.txt
 var Version string
 
 // The main function for the entrypoint.
 func main() {
 	wd, _ := os.Getwd()
+	run := Entrypoint
+	if len(os.Args) > 1 {
+		subcommand, ok := subcommands[os.Args[1]]
+		if ok {
+			// subcommands run directly (without bootstrapping) - their arguments remain at os.Args[2:]
+			run = subcommand
+			os.Args[1] = "entrypoint"
+		}
+	}
 	(&helper.Entrypoint{
-		Dirs: map[string]string{
-			"cache":     filepath.Join(wd, "cache"),
-			"data":      filepath.Join(wd, "data"),
-			"generated": filepath.Join(wd, "generated"),
-			"sdtd":      filepath.Join(wd, "sdtd"),
-		},
+		Dirs:        getDirs(wd),
 		CheckHealth: CheckHealth,
-		Main:        Entrypoint,
+		Main:        run,
 		Version:     Version,
 	}).Run()
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckHealth(t *testing.T) {
+	ctx := newTestContext(t)
+	startFakeTelnet(t)
+	err := CheckHealth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckHealthFailsWithoutServer(t *testing.T) {
+	ctx := newTestContext(t)
+	setTelnetAddr(t, getFreeAddr(t))
+	err := CheckHealth(ctx)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestCheckHealthFailsWhenServerStops(t *testing.T) {
+	ctx := newTestContext(t)
+	fake := startFakeTelnet(t)
+	fake.Close()
+	err := CheckHealth(ctx)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// PlayerInfo identifies a player connected to the server
+type PlayerInfo struct {
+	EntityId   string `json:"entityId"`
+	Name       string `json:"name"`
+	PlatformId string `json:"platformId"`
+	CrossId    string `json:"crossId"`
+	Ip         string `json:"ip"`
+}
+
+// logLinePrefix matches the start of the game's informational log lines (e.g., '2024-01-01T00:00:00 12.345 INF ') - event patterns are anchored to it so that players can't fake events by chatting their text
+const logLinePrefix = `^\S+T\S+ \d+\.\d+ INF `
+
+// playerConnectedRegex matches the log line emitted when a player connects (capturing its comma-separated 'key=value' fields)
+var playerConnectedRegex = regexp.MustCompile(logLinePrefix + `Player connected, (.+)$`)
+
+// logFieldRegex matches a single 'key=value' field - where values can be parenthesized lists (e.g., 'pos=(1.0, 2.0, 3.0)') or single-quoted (e.g., PlayerName='ben, jr')
+var logFieldRegex = regexp.MustCompile(`(\w+)=(\([^)]*\)|'[^']*'|[^,]*)`)
+
+// Parses comma-separated 'key=value' fields (e.g., 'entityid=171, name=ben, pos=(1.0, 2.0, 3.0)')
+func parseLogFields(value string) map[string]string {
+	fields := map[string]string{}
+	for _, match := range logFieldRegex.FindAllStringSubmatch(value, -1) {
+		fields[strings.ToLower(match[1])] = strings.TrimSpace(match[2])
+	}
+	return fields
+}
+
+// Parses a 'Player connected' log line.
+// Returns nil if the line is not a 'Player connected' log line.
+func ParsePlayerConnected(line string) *PlayerInfo {
+	match := playerConnectedRegex.FindStringSubmatch(line)
+	if match == nil {
+		return nil
+	}
+	fields := parseLogFields(match[1])
+	return &PlayerInfo{
+		EntityId:   fields["entityid"],
+		Name:       fields["name"],
+		PlatformId: fields["pltfmid"],
+		CrossId:    fields["crossid"],
+		Ip:         fields["ip"],
+	}
+}
+
+// playerDisconnectedRegex matches the log line emitted when a player disconnects (capturing its comma-separated 'key=value' fields)
+var playerDisconnectedRegex = regexp.MustCompile(logLinePrefix + `Player disconnected: (.+)$`)
+
+// Parses a 'Player disconnected' log line (whose values are single-quoted - e.g., EntityID=171, PltfmId='Steam_...', PlayerName='ben').
+// Returns nil if the line is not a 'Player disconnected' log line.
+func ParsePlayerDisconnected(line string) *PlayerInfo {
+	match := playerDisconnectedRegex.FindStringSubmatch(line)
+	if match == nil {
+		return nil
+	}
+	fields := parseLogFields(match[1])
+	unquote := func(value string) string {
+		return strings.Trim(value, "'")
+	}
+	return &PlayerInfo{
+		EntityId:   unquote(fields["entityid"]),
+		Name:       unquote(fields["playername"]),
+		PlatformId: unquote(fields["pltfmid"]),
+		CrossId:    unquote(fields["crossid"]),
+	}
+}
+
+// ChatMessage is a chat message sent by a player
+type ChatMessage struct {
+	EntityId   string `json:"entityId"`
+	PlatformId string `json:"platformId"`
+	Name       string `json:"name"`
+	Target     string `json:"target"`
+	Message    string `json:"message"`
+}
+
+// chatMessageRegex matches the log line emitted when a player sends a chat message (capturing the platform id, entity id, target, name and message)
+var chatMessageRegex = regexp.MustCompile(`INF Chat \(from '([^']*)', entity id '([^']*)', to '([^']*)'\): '(.*?)': (.*)$`)
+
+// Parses a 'Chat' log line.
+// Returns nil if the line is not a 'Chat' log line.
+func ParseChatMessage(line string) *ChatMessage {
+	match := chatMessageRegex.FindStringSubmatch(line)
+	if match == nil {
+		return nil
+	}
+	return &ChatMessage{
+		PlatformId: match[1],
+		EntityId:   match[2],
+		Target:     match[3],
+		Name:       match[4],
+		Message:    strings.TrimSpace(match[5]),
+	}
+}
+
+// KillEvent is a player death - killed by another player (PvP) or otherwise (e.g., by zombies or falling)
+type KillEvent struct {
+	Victim string `json:"victim"`
+	Killer string `json:"killer,omitempty"`
+}
+
+// playerKilledRegex matches the log line emitted when a player is killed by another player (capturing the victim and killer)
+var playerKilledRegex = regexp.MustCompile(logLinePrefix + `GMSG: Player '(.*)' killed by '(.*)'$`)
+
+// playerDiedRegex matches the log line emitted when a player dies other than by another player (capturing the victim)
+var playerDiedRegex = regexp.MustCompile(logLinePrefix + `GMSG: Player '(.*)' died$`)
+
+// Parses a player 'killed by' or 'died' log line.
+// Returns nil if the line is not a player death log line.
+func ParseKillEvent(line string) *KillEvent {
+	match := playerKilledRegex.FindStringSubmatch(line)
+	if match != nil {
+		return &KillEvent{Victim: match[1], Killer: match[2]}
+	}
+	match = playerDiedRegex.FindStringSubmatch(line)
+	if match != nil {
+		return &KillEvent{Victim: match[1]}
+	}
+	return nil
+}
+
+// ClientCommand is a console command executed by a player (rather than by telnet or the server console)
+type ClientCommand struct {
+	Command  string `json:"command"`
+	ClientId string `json:"clientId"`
+}
+
+// clientCommandRegex matches the log line emitted when a player executes a console command (capturing the command and the player's id)
+var clientCommandRegex = regexp.MustCompile(logLinePrefix + `Executing command '(.*)' from client (\S+)`)
+
+// Parses an 'Executing command ... from client' log line.
+// Returns nil if the line is not a player console command log line.
+func ParseClientCommand(line string) *ClientCommand {
+	match := clientCommandRegex.FindStringSubmatch(line)
+	if match == nil {
+		return nil
+	}
+	return &ClientCommand{Command: match[1], ClientId: match[2]}
+}
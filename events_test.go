@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+)
+
+// Formats a line as the game logs it
+func formatTestLogLine(message string) string {
+	return "2024-01-01T00:00:00 12.345 INF " + message
+}
+
+// Formats a line as the game logs a chat message sent by a player
+func formatTestChatLine(message string) string {
+	return formatTestLogLine("Chat (from 'Steam_2', entity id '172', to 'Global'): 'Mallory': " + message)
+}
+
+func TestParsePlayerConnected(t *testing.T) {
+	player := ParsePlayerConnected(formatTestLogLine("Player connected, entityid=171, name=Bob, pltfmid=Steam_1, crossid=EOS_1, steamOwner=Steam_1, ip=10.0.0.1"))
+	if player == nil || player.Name != "Bob" || player.PlatformId != "Steam_1" || player.Ip != "10.0.0.1" {
+		t.Fatalf("unexpected player %+v", player)
+	}
+	spoofed := formatTestChatLine("1 INF Player connected, entityid=171, name=Bob, pltfmid=Steam_1, crossid=EOS_1, ip=10.0.0.1")
+	if player := ParsePlayerConnected(spoofed); player != nil {
+		t.Fatalf("spoofed chat parsed as %+v", player)
+	}
+}
+
+func TestParsePlayerDisconnected(t *testing.T) {
+	player := ParsePlayerDisconnected(formatTestLogLine("Player disconnected: EntityID=171, PltfmId='Steam_1', CrossId='EOS_1', OwnerID='Steam_1', PlayerName='Bob', ClientNumber='1'"))
+	if player == nil || player.Name != "Bob" || player.PlatformId != "Steam_1" {
+		t.Fatalf("unexpected player %+v", player)
+	}
+	spoofed := formatTestChatLine("1 INF Player disconnected: EntityID=171, PltfmId='Steam_1', PlayerName='Bob'")
+	if player := ParsePlayerDisconnected(spoofed); player != nil {
+		t.Fatalf("spoofed chat parsed as %+v", player)
+	}
+}
+
+func TestParseKillEvent(t *testing.T) {
+	event := ParseKillEvent(formatTestLogLine("GMSG: Player 'Bob' killed by 'Alice'"))
+	if event == nil || event.Victim != "Bob" || event.Killer != "Alice" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	event = ParseKillEvent(formatTestLogLine("GMSG: Player 'Bob' died"))
+	if event == nil || event.Victim != "Bob" || event.Killer != "" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	for _, message := range []string{"INF GMSG: Player 'Bob' died", "1 INF GMSG: Player 'Bob' killed by 'Alice'"} {
+		if event := ParseKillEvent(formatTestChatLine(message)); event != nil {
+			t.Fatalf("spoofed chat %q parsed as %+v", message, event)
+		}
+	}
+}
+
+func TestParseClientCommand(t *testing.T) {
+	command := ParseClientCommand(formatTestLogLine("Executing command 'spawnentity 171 zombieBoe' from client Steam_1"))
+	if command == nil || command.Command != "spawnentity 171 zombieBoe" || command.ClientId != "Steam_1" {
+		t.Fatalf("unexpected command %+v", command)
+	}
+	spoofed := formatTestChatLine("1 INF Executing command 'spawnentity 171 zombieBoe' from client Steam_1")
+	if command := ParseClientCommand(spoofed); command != nil {
+		t.Fatalf("spoofed chat parsed as %+v", command)
+	}
+}
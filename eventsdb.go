@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+const (
+	// EventChat is recorded when a player sends a chat message
+	EventChat = "chat"
+	// EventDeath is recorded when a player dies (or is killed by another player)
+	EventDeath = "death"
+	// EventJoin is recorded when a player connects
+	EventJoin = "join"
+	// EventLeave is recorded when a player disconnects
+	EventLeave = "leave"
+	// EventSave is recorded when the world is saved (with 'saveworld')
+	EventSave = "save"
+	// EventServerReady is recorded when the server has finished loading
+	EventServerReady = "server-ready"
+	// EventServerStopped is recorded when the server process exits
+	EventServerStopped = "server-stopped"
+)
+
+// ServerEvent is a typed server event persisted to the events database (see [EventStore])
+type ServerEvent struct {
+	Time       time.Time
+	Type       string
+	Player     string
+	PlatformId string
+	// Data holds type-specific fields (e.g., the killer of a death, the message of a chat message)
+	Data map[string]string
+}
+
+// eventsSchema creates the events database's tables (if they don't exist)
+const eventsSchema = `CREATE TABLE IF NOT EXISTS events (id INTEGER PRIMARY KEY, time TEXT NOT NULL, type TEXT NOT NULL, player TEXT, platform_id TEXT, data TEXT);
+CREATE INDEX IF NOT EXISTS events_time ON events (time);
+CREATE INDEX IF NOT EXISTS events_type_time ON events (type, time);
+`
+
+// saveWorldRegex matches the log line emitted when the 'saveworld' command is executed (by telnet, a player or the console)
+var saveWorldRegex = regexp.MustCompile(`INF Executing command 'saveworld'`)
+
+// Parses a server log line into a [ServerEvent].
+// Returns nil if the line doesn't describe a recorded event.
+func ParseServerEvent(line string) *ServerEvent {
+	now := time.Now().UTC()
+	if player := ParsePlayerConnected(line); player != nil {
+		return &ServerEvent{Time: now, Type: EventJoin, Player: player.Name, PlatformId: player.PlatformId}
+	}
+	if player := ParsePlayerDisconnected(line); player != nil {
+		return &ServerEvent{Time: now, Type: EventLeave, Player: player.Name, PlatformId: player.PlatformId}
+	}
+	if kill := ParseKillEvent(line); kill != nil {
+		event := &ServerEvent{Time: now, Type: EventDeath, Player: kill.Victim}
+		if kill.Killer != "" {
+			event.Data = map[string]string{"killer": kill.Killer}
+		}
+		return event
+	}
+	// messages sent by the server (e.g., 'say') have an entity id of -1
+	if chat := ParseChatMessage(line); chat != nil && chat.EntityId != "-1" {
+		return &ServerEvent{Time: now, Type: EventChat, Player: chat.Name, PlatformId: chat.PlatformId, Data: map[string]string{"target": chat.Target, "message": chat.Message}}
+	}
+	if saveWorldRegex.MatchString(line) {
+		return &ServerEvent{Time: now, Type: EventSave}
+	}
+	return nil
+}
+
+// Quotes a value as an sql string literal
+func sqlQuote(value string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(strings.ReplaceAll(value, "\x00", ""), "'", "''"))
+}
+
+// Gets the path of the events database
+func getEventsDbFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "events.db")
+}
+
+// Runs sql against the events database with the 'sqlite3' command line tool.
+// The sql is passed through a file so that large batches aren't limited by the maximum command line length.
+// Returns the output (tab-separated rows, with a header) of the final statement.
+// Returns an error if the sql fails.
+func execEventsSql(ctx context.Context, sql string) (string, error) {
+	output := ""
+	err := helper.CreateTempDir(ctx, func(tempDir string) error {
+		file := filepath.Join(tempDir, "events.sql")
+		err := os.WriteFile(file, []byte(sql), 0644)
+		if err != nil {
+			return err
+		}
+		output, err = helper.Command(ctx, []string{"sqlite3", "-batch", "-bail", "-header", "-separator", "\t", getEventsDbFile(ctx), fmt.Sprintf(".read %s", file)}, helper.CmdOpts{}).Run()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("events database: %w", err)
+	}
+	return output, nil
+}
+
+// EventStoreOpts are options for [EventStore]
+type EventStoreOpts struct {
+	FlushInterval time.Duration
+	// Retention is how long events are kept - 0 keeps events forever
+	Retention time.Duration
+}
+
+// EventStore persists the server's event stream (see [ParseServerEvent]) to a sqlite database ('[data]/events.db') - enabling analytics without external pipelines.
+// Events are buffered and written in batches, and events older than [EventStoreOpts.Retention] are deleted as batches are written.
+type EventStore struct {
+	Opts    EventStoreOpts
+	lock    sync.Mutex
+	pending []ServerEvent
+}
+
+// Creates the events database's tables (if they don't exist).
+// Returns an error if the database cannot be created.
+func (es *EventStore) Init(ctx context.Context) error {
+	_, err := execEventsSql(ctx, eventsSchema)
+	return err
+}
+
+// Buffers an event until the next [EventStore.Flush]
+func (es *EventStore) Record(event ServerEvent) {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+	es.pending = append(es.pending, event)
+}
+
+// Writes buffered events to the database (in a single transaction) and deletes expired events.
+// Returns an error if the events cannot be written - in which case they're kept for the next flush.
+func (es *EventStore) Flush(ctx context.Context) error {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+	if len(es.pending) == 0 {
+		return nil
+	}
+	sql := strings.Builder{}
+	sql.WriteString("BEGIN;\n")
+	for _, event := range es.pending {
+		data := "NULL"
+		if len(event.Data) > 0 {
+			value, err := json.Marshal(event.Data)
+			if err != nil {
+				return err
+			}
+			data = sqlQuote(string(value))
+		}
+		fmt.Fprintf(&sql, "INSERT INTO events (time, type, player, platform_id, data) VALUES (%s, %s, %s, %s, %s);\n", sqlQuote(event.Time.UTC().Format(time.RFC3339)), sqlQuote(event.Type), sqlQuote(event.Player), sqlQuote(event.PlatformId), data)
+	}
+	if es.Opts.Retention > 0 {
+		fmt.Fprintf(&sql, "DELETE FROM events WHERE time < %s;\n", sqlQuote(time.Now().UTC().Add(-es.Opts.Retention).Format(time.RFC3339)))
+	}
+	sql.WriteString("COMMIT;\n")
+	_, err := execEventsSql(ctx, sql.String())
+	if err != nil {
+		return err
+	}
+	es.pending = nil
+	return nil
+}
+
+// Records events parsed from the telnet session - periodically writing them to the database - until the context is cancelled.
+// Buffered events are flushed by the caller (see [EventStore.Flush]) once the server stops.
+// Returns an error if the telnet session is unavailable.
+func (es *EventStore) Run(ctx context.Context) error {
+	session := GetTelnetSession(ctx)
+	if session == nil {
+		return ErrTelnetNotConnected
+	}
+	lines, unsubscribe := session.Subscribe()
+	defer unsubscribe()
+	ticker := time.NewTicker(es.Opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := es.Flush(ctx)
+			if err != nil {
+				helper.Logger(ctx).Warn("write events failed", "error", err.Error())
+			}
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			event := ParseServerEvent(line)
+			if event != nil {
+				es.Record(*event)
+			}
+		}
+	}
+}
+
+// eventQueries are the canned analytics queries (see [EventsSubcommand]) - each filters events newer than ':since' (replaced with a timestamp literal)
+var eventQueries = map[string]string{
+	// players ranked by playtime - sessions end when the player disconnects (or the server stops)
+	"top-players": `SELECT player, platform_id, COUNT(*) AS sessions, ROUND(SUM(julianday(COALESCE(ended, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))) - julianday(time)) * 24, 1) AS hours
+FROM (SELECT player, platform_id, time, (SELECT MIN(e.time) FROM events e WHERE e.time >= j.time AND e.id > j.id AND ((e.type = 'leave' AND e.platform_id = j.platform_id) OR e.type = 'server-stopped')) AS ended FROM events j WHERE j.type = 'join' AND j.time >= :since)
+GROUP BY platform_id ORDER BY hours DESC LIMIT 10;`,
+	// hours of the day (utc) ranked by the number of players joining
+	"busiest-hours": `SELECT strftime('%H', time) AS hour, COUNT(*) AS joins, COUNT(DISTINCT platform_id) AS players
+FROM events WHERE type = 'join' AND time >= :since
+GROUP BY hour ORDER BY joins DESC;`,
+}
+
+// Runs a canned analytics query over events from the past [days] days.
+// Returns the query's output (tab-separated rows, with a header).
+// Returns an error if the query is unknown or fails.
+func QueryEvents(ctx context.Context, name string, days int) (string, error) {
+	query, ok := eventQueries[name]
+	if !ok {
+		return "", fmt.Errorf("unknown query %s", name)
+	}
+	since := sqlQuote(time.Now().UTC().AddDate(0, 0, -days).Format(time.RFC3339))
+	return execEventsSql(ctx, strings.ReplaceAll(query, ":since", since))
+}
+
+// Queries the events database from the command line:
+//   - 'events top-players [days]' lists the players with the most playtime
+//   - 'events busiest-hours [days]' lists the hours of the day (utc) when the most players join
+//
+// Queries cover the past 30 days by default.
+// Returns an error if the arguments are invalid.
+// Returns an error if the query fails.
+func EventsSubcommand(ctx context.Context) error {
+	usage := fmt.Errorf("usage: %s events [top-players | busiest-hours] [days]", filepath.Base(os.Args[0]))
+	args := os.Args[2:]
+	if len(args) == 0 || len(args) > 2 {
+		return usage
+	}
+	_, ok := eventQueries[args[0]]
+	if !ok {
+		return usage
+	}
+	days := 30
+	if len(args) == 2 {
+		var err error
+		days, err = strconv.Atoi(args[1])
+		if err != nil || days <= 0 {
+			return usage
+		}
+	}
+	output, err := QueryEvents(ctx, args[0], days)
+	if err != nil {
+		return err
+	}
+	fmt.Print(output)
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// ExecutionMode determines which dedicated server build is downloaded and how it is launched
+type ExecutionMode string
+
+const (
+	// ExecutionModeNative runs the linux dedicated server
+	ExecutionModeNative ExecutionMode = "native"
+	// ExecutionModeProton runs the windows dedicated server under Proton
+	ExecutionModeProton ExecutionMode = "proton"
+	// ExecutionModeBox64 runs the linux dedicated server under the Box64 x86_64 emulator (for arm64 hosts)
+	ExecutionModeBox64 ExecutionMode = "box64"
+	// ExecutionModeFex runs the linux dedicated server under the FEX-Emu x86_64 emulator (for arm64 hosts)
+	ExecutionModeFex ExecutionMode = "fex"
+)
+
+// DefaultProtonUrl is the Proton build downloaded when running in [ExecutionModeProton]
+const DefaultProtonUrl = "https://github.com/GloriousEggroll/proton-ge-custom/releases/download/GE-Proton9-27/GE-Proton9-27.tar.gz"
+
+// Parses an execution mode.
+// Returns an error if the execution mode is unrecognized.
+func ParseExecutionMode(value string) (ExecutionMode, error) {
+	mode := ExecutionMode(strings.ToLower(value))
+	switch mode {
+	case ExecutionModeNative, ExecutionModeProton, ExecutionModeBox64, ExecutionModeFex:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unrecognized execution mode %s", value)
+}
+
+// Parses (and validates) an execution mode from text - allowing [ExecutionMode] to be parsed from the environment.
+// Returns an error if the execution mode is unrecognized.
+func (em *ExecutionMode) UnmarshalText(text []byte) error {
+	mode, err := ParseExecutionMode(string(text))
+	if err != nil {
+		return err
+	}
+	*em = mode
+	return nil
+}
+
+// Returns true if the execution mode runs the server under an x86_64 emulator
+func (em ExecutionMode) Emulated() bool {
+	return em == ExecutionModeBox64 || em == ExecutionModeFex
+}
+
+// Gets the emulator binary that wraps the server for the execution mode (empty if the execution mode isn't emulated)
+func (em ExecutionMode) emulatorBinary() string {
+	switch em {
+	case ExecutionModeBox64:
+		return "box64"
+	case ExecutionModeFex:
+		return "FEXInterpreter"
+	}
+	return ""
+}
+
+// Gets the steam depot containing the dedicated server build for the execution mode
+func (em ExecutionMode) Depot() string {
+	if em == ExecutionModeProton {
+		return "294421"
+	}
+	return "294422"
+}
+
+// Gets the dedicated server binary (relative to the sdtd directory) for the execution mode
+func (em ExecutionMode) ServerBinary() string {
+	if em == ExecutionModeProton {
+		return "7DaysToDieServer.exe"
+	}
+	return "7DaysToDieServer.x86_64"
+}
+
+// Gets the paths (relative to the sdtd directory) required to launch the dedicated server for the execution mode
+func (em ExecutionMode) CriticalPaths() []string {
+	player := "UnityPlayer.so"
+	if em == ExecutionModeProton {
+		player = "UnityPlayer.dll"
+	}
+	return []string{em.ServerBinary(), "7DaysToDieServer_Data", "Data/Config", player, "serverconfig.xml"}
+}
+
+// Finds the 'proton' launcher script within the proton directory.
+// Returns an error if the launcher script cannot be found.
+func findProtonLauncher(ctx context.Context) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(helper.Dirs(ctx)["proton"], "*", "proton"))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("proton launcher not found in %s", helper.Dirs(ctx)["proton"])
+	}
+	return matches[0], nil
+}
+
+// Downloads and extracts Proton to the proton directory (caching it when the file cache is enabled).
+// Returns an error if the download or extraction fails.
+// Returns an error if the extracted archive does not contain a 'proton' launcher script.
+func DownloadProton(ctx context.Context, protonUrl string) error {
+	helper.Logger(ctx).Info("download proton", "url", protonUrl)
+	key := fmt.Sprintf("proton-%s", strings.TrimSuffix(filepath.Base(protonUrl), ".tar.gz"))
+	err := helper.CacheFile(ctx, key, helper.Dirs(ctx)["proton"], func(dest string) error {
+		return helper.CreateTempDir(ctx, func(tempDir string) error {
+			downloadPath := filepath.Join(tempDir, filepath.Base(protonUrl))
+			_, _, err := DownloadFile(ctx, protonUrl, downloadPath, nil)
+			if err != nil {
+				return err
+			}
+			return helper.Extract(ctx, downloadPath, dest)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	_, err = findProtonLauncher(ctx)
+	return err
+}
+
+// emulatorBinaryPatterns are glob patterns (relative to the emulator directory) searched for the emulator binary - covering archives with (and without) a top-level folder and a 'bin' folder
+var emulatorBinaryPatterns = []string{"%s", "bin/%s", "usr/bin/%s", "*/%s", "*/bin/%s", "*/usr/bin/%s"}
+
+// Finds the emulator binary for the execution mode - within the emulator directory first, and then the PATH.
+// Returns an error if the emulator binary cannot be found.
+func (em ExecutionMode) findEmulator(ctx context.Context) (string, error) {
+	dir := helper.Dirs(ctx)["emulator"]
+	for _, pattern := range emulatorBinaryPatterns {
+		matches, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf(pattern, em.emulatorBinary())))
+		if err != nil {
+			return "", err
+		}
+		if len(matches) > 0 {
+			return matches[0], nil
+		}
+	}
+	path, err := exec.LookPath(em.emulatorBinary())
+	if err != nil {
+		return "", fmt.Errorf("emulator %s not found in %s or PATH", em.emulatorBinary(), dir)
+	}
+	return path, nil
+}
+
+// Downloads and extracts an emulator release archive to the emulator directory (caching it when the file cache is enabled).
+// Returns an error if the download or extraction fails.
+// Returns an error if the extracted archive does not contain the emulator binary for the execution mode.
+func (em ExecutionMode) DownloadEmulator(ctx context.Context, emulatorUrl string) error {
+	helper.Logger(ctx).Info("download emulator", "url", emulatorUrl, "mode", em)
+	key := fmt.Sprintf("emulator-%s-%s", em, filepath.Base(emulatorUrl))
+	err := helper.CacheFile(ctx, key, helper.Dirs(ctx)["emulator"], func(dest string) error {
+		return helper.CreateTempDir(ctx, func(tempDir string) error {
+			downloadPath := filepath.Join(tempDir, filepath.Base(emulatorUrl))
+			_, _, err := DownloadFile(ctx, emulatorUrl, downloadPath, nil)
+			if err != nil {
+				return err
+			}
+			return helper.Extract(ctx, downloadPath, dest)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	emulator, err := em.findEmulator(ctx)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(emulator)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(emulator, info.Mode()|0755)
+}
+
+// Builds the command (and environment) used to launch the dedicated server for the execution mode.
+// In [ExecutionModeProton], the server is launched with the downloaded 'proton' launcher script - and the wine prefix is persisted to the data directory.
+// In emulated execution modes, the server is launched with the emulator binary (see [ExecutionMode.findEmulator]) - which also loads the server's bundled libraries (via 'BOX64_LD_LIBRARY_PATH').
+// Returns an error if the proton launcher (or emulator) cannot be found.
+// Returns an error if the wine prefix cannot be created.
+func (em ExecutionMode) ServerCommand(ctx context.Context, args ...string) ([]string, []string, error) {
+	fail := func(err error) ([]string, []string, error) {
+		return nil, nil, err
+	}
+	binary := fmt.Sprintf("./%s", em.ServerBinary())
+	if em.Emulated() {
+		emulator, err := em.findEmulator(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		return append([]string{emulator, binary}, args...), append(os.Environ(), "LD_LIBRARY_PATH=.", "BOX64_LD_LIBRARY_PATH=."), nil
+	}
+	if em != ExecutionModeProton {
+		return append([]string{binary}, args...), append(os.Environ(), "LD_LIBRARY_PATH=."), nil
+	}
+	proton, err := findProtonLauncher(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	prefix := filepath.Join(helper.Dirs(ctx)["data"], "proton-prefix")
+	err = helper.CreateDirs(ctx, prefix)
+	if err != nil {
+		return fail(err)
+	}
+	env := append(
+		os.Environ(),
+		fmt.Sprintf("STEAM_COMPAT_CLIENT_INSTALL_PATH=%s", filepath.Dir(proton)),
+		fmt.Sprintf("STEAM_COMPAT_DATA_PATH=%s", prefix),
+	)
+	return append([]string{proton, "run", binary}, args...), env, nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// FailoverOpts are options for [RunFailoverStandby]
+type FailoverOpts struct {
+	CheckInterval time.Duration
+	// PrimaryToken is sent as a bearer token with http health checks (e.g., an admin api token with the 'status' scope)
+	PrimaryToken string
+	// PrimaryUrl is the primary's health endpoint - either an http(s) url (healthy when it responds with a 2xx status) or 'tcp://[host]:[port]' (healthy when it accepts connections)
+	PrimaryUrl *url.URL
+	// Source is the standby destination the primary replicates its save to (see [StandbyReplicator])
+	Source BackupDestination
+	// Threshold is how long the primary must be continuously unhealthy before the standby is promoted
+	Threshold  time.Duration
+	WebhookUrl *url.URL
+}
+
+// FailoverPromotion records that a standby was promoted to primary
+type FailoverPromotion struct {
+	Primary      string    `json:"primary"`
+	PromotedAt   time.Time `json:"promotedAt"`
+	ReplicatedAt time.Time `json:"replicatedAt"`
+}
+
+// Gets the path of the file recording that this standby was promoted.
+// While it exists, the entrypoint starts the server directly (rather than replacing the save it has been running since promotion).
+func getFailoverPromotionFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "failover-promoted.json")
+}
+
+// Checks the health of the primary server.
+// Returns an error if the primary's health endpoint is unreachable or reports a failure.
+func CheckPrimaryHealth(ctx context.Context, opts FailoverOpts) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if opts.PrimaryUrl.Scheme == "tcp" {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", opts.PrimaryUrl.Host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, opts.PrimaryUrl.String(), nil)
+	if err != nil {
+		return err
+	}
+	if opts.PrimaryToken != "" {
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", opts.PrimaryToken))
+	}
+	response, err := getHttpClient(ctx).Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("primary health check failed with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// Decompresses a replicated file object to [dest] - verifying its checksum.
+// Returns an error if the object cannot be decompressed or its checksum doesn't match.
+func extractStandbyObject(object string, dest string, checksum string) error {
+	source, err := os.Open(object)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	reader, err := gzip.NewReader(source)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	handle, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(handle, hash), reader)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != checksum {
+		return fmt.Errorf("checksum mismatch for %s", dest)
+	}
+	return handle.Close()
+}
+
+// Restores the latest save replicated to a standby destination - replacing the saves within the data directory.
+// The save is assembled (and verified) in a staging directory first - so that a failed restore leaves the existing saves untouched.
+// Returns the restored manifest.
+// Returns an error if the manifest or an object cannot be downloaded or verified.
+func RestoreStandbySave(ctx context.Context, destination BackupDestination) (*StandbyManifest, error) {
+	fail := func(err error) (*StandbyManifest, error) {
+		return nil, fmt.Errorf("restore standby save: %w", err)
+	}
+	data := helper.Dirs(ctx)["data"]
+	staging := filepath.Join(data, ".standby-restore")
+	err := helper.RemovePaths(ctx, staging)
+	if err != nil {
+		return fail(err)
+	}
+	defer helper.RemovePaths(ctx, staging)
+	err = helper.CreateDirs(ctx, filepath.Join(staging, "objects"))
+	if err != nil {
+		return fail(err)
+	}
+	helper.Logger(ctx).Info("restore standby save", "source", destination.Name())
+	manifestFile := filepath.Join(staging, standbyManifestName)
+	err = destination.Download(ctx, standbyManifestName, manifestFile)
+	if err != nil {
+		return fail(err)
+	}
+	manifest := StandbyManifest{}
+	err = helper.UnmarshalFile(ctx, manifestFile, &manifest)
+	if err != nil {
+		return fail(err)
+	}
+	for relpath, checksum := range manifest.Files {
+		if !strings.HasPrefix(relpath, "Saves/") || !filepath.IsLocal(relpath) {
+			return fail(fmt.Errorf("invalid path %s", relpath))
+		}
+		// identical files share an object - which is only downloaded once
+		object := filepath.Join(staging, "objects", checksum)
+		_, err := os.Lstat(object)
+		if errors.Is(err, os.ErrNotExist) {
+			err = destination.Download(ctx, getStandbyObjectName(checksum), object)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		dest := filepath.Join(staging, filepath.FromSlash(relpath))
+		err = helper.CreateDirs(ctx, filepath.Dir(dest))
+		if err != nil {
+			return fail(err)
+		}
+		err = extractStandbyObject(object, dest, checksum)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	saves := filepath.Join(data, "Saves")
+	err = helper.RemovePaths(ctx, saves)
+	if err != nil {
+		return fail(err)
+	}
+	err = helper.CreateDirs(ctx, filepath.Join(staging, "Saves"))
+	if err != nil {
+		return fail(err)
+	}
+	err = os.Rename(filepath.Join(staging, "Saves"), saves)
+	if err != nil {
+		return fail(err)
+	}
+	return &manifest, nil
+}
+
+// Notifies the webhook (if configured) of a failover event
+func notifyFailover(ctx context.Context, opts FailoverOpts, event string, fields map[string]any) {
+	if opts.WebhookUrl == nil {
+		return
+	}
+	payload := map[string]any{"event": event, "primary": opts.PrimaryUrl.Redacted()}
+	for key, value := range fields {
+		payload[key] = value
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	postWebhook(ctx, opts.WebhookUrl.String(), data)
+}
+
+// Idles as a warm standby - monitoring the primary's health until it has been continuously unhealthy for [FailoverOpts.Threshold].
+// The standby is then promoted: the latest replicated save is restored, the promotion is recorded (see [getFailoverPromotionFile]) and admins are notified - after which the caller starts the server.
+// Returns immediately if this standby was previously promoted.
+// Returns an error if the context is cancelled before promotion.
+// Returns an error if the replicated save cannot be restored.
+func RunFailoverStandby(ctx context.Context, opts FailoverOpts) error {
+	promotion := FailoverPromotion{}
+	err := helper.UnmarshalFile(ctx, getFailoverPromotionFile(ctx), &promotion)
+	if err == nil {
+		helper.Logger(ctx).Info("standby previously promoted - starting server", "promoted-at", promotion.PromotedAt)
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	SetServerState(ctx, ServerStateStandby)
+	helper.Logger(ctx).Info("monitoring primary", "primary", opts.PrimaryUrl.Redacted(), "threshold", opts.Threshold.String())
+	failingSince := time.Time{}
+	for {
+		err := CheckPrimaryHealth(ctx, opts)
+		switch {
+		case err == nil && !failingSince.IsZero():
+			helper.Logger(ctx).Info("primary recovered", "downtime", time.Since(failingSince).String())
+			notifyFailover(ctx, opts, "primary-recovered", nil)
+			failingSince = time.Time{}
+		case err != nil && failingSince.IsZero():
+			helper.Logger(ctx).Warn("primary unhealthy", "error", err.Error())
+			notifyFailover(ctx, opts, "primary-unhealthy", map[string]any{"error": err.Error()})
+			failingSince = time.Now()
+		}
+		if !failingSince.IsZero() && time.Since(failingSince) >= opts.Threshold {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(opts.CheckInterval):
+		}
+	}
+	helper.Logger(ctx).Warn("primary unhealthy beyond threshold - promoting standby", "downtime", time.Since(failingSince).String())
+	manifest, err := RestoreStandbySave(ctx, opts.Source)
+	if err != nil {
+		notifyFailover(ctx, opts, "promotion-failed", map[string]any{"error": err.Error()})
+		return err
+	}
+	promotion = FailoverPromotion{Primary: opts.PrimaryUrl.Redacted(), PromotedAt: time.Now().UTC(), ReplicatedAt: manifest.UpdatedAt}
+	err = helper.MarshalFile(ctx, promotion, getFailoverPromotionFile(ctx))
+	if err != nil {
+		return err
+	}
+	helper.Logger(ctx).Warn("standby promoted", "replicated-at", manifest.UpdatedAt)
+	notifyFailover(ctx, opts, "promoted", map[string]any{"promotedAt": promotion.PromotedAt, "replicatedAt": promotion.ReplicatedAt})
+	return nil
+}
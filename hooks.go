@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// Waits for the server to be ready and then executes a list of console commands (e.g., 'admin add', 'settime').
+// Commands are executed in order - failing commands are logged and otherwise ignored.
+// Returns an error if the server is not ready before the timeout.
+func RunPostStartCommands(ctx context.Context, timeout time.Duration, commands ...string) error {
+	session := GetTelnetSession(ctx)
+	err := session.WaitReady(ctx, timeout)
+	if err != nil {
+		return err
+	}
+	for _, command := range commands {
+		helper.Logger(ctx).Info("run post-start command", "command", command)
+		response, err := SendCommand(ctx, command)
+		if err != nil {
+			helper.Logger(ctx).Warn("post-start command failed", "command", command, "error", err.Error())
+			continue
+		}
+		for _, line := range response {
+			helper.Logger(ctx).Info("post-start command output", "command", command, "output", line)
+		}
+	}
+	return nil
+}
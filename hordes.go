@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// HordeEvent is a community event (e.g., a double horde weekend) active during a recurring time window - changing blood moon settings at runtime (reverted once the event ends), running console commands and announcing the event in-game and to discord
+type HordeEvent struct {
+	Name string `json:"name"`
+	// Days, Start and End define the event's window (see [SettingsProfile])
+	Days  []string `json:"days"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+	// Settings are game preferences (e.g., 'BloodMoonEnemyCount') applied with 'setgamepref' while the event is active
+	Settings ServerSettings `json:"settings"`
+	// StartCommands and EndCommands are console commands (e.g., 'spawnwanderinghorde') run when the event starts and ends
+	StartCommands []string `json:"startCommands"`
+	EndCommands   []string `json:"endCommands"`
+	StartMessage  string   `json:"startMessage"`
+	EndMessage    string   `json:"endMessage"`
+}
+
+// Validates the horde event.
+// Returns an error if the days or times are invalid, or if the event neither sets settings nor commands.
+func (he *HordeEvent) Validate() error {
+	for _, day := range he.Days {
+		_, err := parseWeekday(day)
+		if err != nil {
+			return fmt.Errorf("horde event %s: %w", he.Name, err)
+		}
+	}
+	for _, clock := range []string{he.Start, he.End} {
+		_, err := parseClock(clock)
+		if err != nil {
+			return fmt.Errorf("horde event %s: %w", he.Name, err)
+		}
+	}
+	if len(he.Settings) == 0 && len(he.StartCommands) == 0 && len(he.EndCommands) == 0 {
+		return fmt.Errorf("horde event %s: settings or commands must be set", he.Name)
+	}
+	return nil
+}
+
+// Determines whether the event is active at the given time (see [SettingsProfile.Active])
+func (he *HordeEvent) Active(now time.Time) bool {
+	window := SettingsProfile{Days: he.Days, Start: he.Start, End: he.End}
+	return window.Active(now)
+}
+
+// Loads horde events from a JSON file formatted as '{"events": [...]}'.
+// Returns an error if the file cannot be read or an event is invalid.
+func LoadHordeEvents(ctx context.Context, file string) ([]HordeEvent, error) {
+	helper.Logger(ctx).Info("load horde events", "path", file)
+	data := struct {
+		Events []HordeEvent `json:"events"`
+	}{}
+	err := helper.UnmarshalFile(ctx, file, &data)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range data.Events {
+		err := event.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data.Events, nil
+}
+
+// HordeEventOpts are options for [RunHordeEvents]
+type HordeEventOpts struct {
+	Events []HordeEvent
+	// WebhookUrl is a discord webhook to which event starts and ends are announced
+	WebhookUrl *url.URL
+}
+
+// Gets the names of the horde events active at the given time
+func getActiveHordeEvents(events []HordeEvent, now time.Time) []string {
+	active := []string{}
+	for _, event := range events {
+		if event.Active(now) {
+			active = append(active, event.Name)
+		}
+	}
+	return active
+}
+
+// Merges the settings of the named horde events (in definition order) over the base settings
+func applyHordeEvents(base ServerSettings, events []HordeEvent, active []string) ServerSettings {
+	items := []ServerSettings{base}
+	for _, event := range events {
+		if slices.Contains(active, event.Name) {
+			items = append(items, event.Settings)
+		}
+	}
+	return MergeServerSettings(items...)
+}
+
+// Announces a horde event start or end - in-game and to the discord webhook (if set)
+func announceHordeEvent(ctx context.Context, opts HordeEventOpts, message string) {
+	if message == "" {
+		return
+	}
+	SayServer(ctx, message)
+	if opts.WebhookUrl != nil {
+		go postDiscordMessage(ctx, opts.WebhookUrl, message)
+	}
+}
+
+// Runs the console commands of a horde event start or end.
+// Failing commands are logged and otherwise ignored.
+func runHordeEventCommands(ctx context.Context, event HordeEvent, commands []string) {
+	for _, command := range commands {
+		_, err := SendCommand(ctx, command)
+		if err != nil {
+			helper.Logger(ctx).Warn("horde event command failed", "event", event.Name, "command", command, "error", err.Error())
+		}
+	}
+}
+
+// Runs horde events until the context is cancelled - checking event windows every minute.
+// The runtime values of the events' settings are read (with 'getgamepref') when started - and are restored once the events changing them end.
+// When an event starts, its settings are applied with 'setgamepref', its start commands are run and its start message is announced (and likewise, when it ends).
+// Events already active when started only have their settings applied (e.g., after a restart mid-event).
+// Returns an error if the runtime values of the events' settings cannot be read.
+func RunHordeEvents(ctx context.Context, opts HordeEventOpts) error {
+	prefs, err := GetGamePrefs(ctx)
+	if err != nil {
+		return err
+	}
+	base := ServerSettings{}
+	for _, event := range opts.Events {
+		for _, name := range event.Settings.Names() {
+			value, ok := prefs[name]
+			if !ok {
+				helper.Logger(ctx).Warn("horde event setting is not a game preference - ignored", "event", event.Name, "setting", name)
+				continue
+			}
+			base[name] = value
+		}
+	}
+	apply := func(current []string, next []string) {
+		for _, change := range applyHordeEvents(base, opts.Events, current).Diff(applyHordeEvents(base, opts.Events, next)) {
+			_, ok := base[change.Name]
+			if !ok || change.To == nil {
+				continue
+			}
+			helper.Logger(ctx).Info("apply horde event setting", "setting", change.Name, "value", *change.To)
+			_, err := SendCommand(ctx, fmt.Sprintf("setgamepref %s %s", change.Name, *change.To))
+			if err != nil {
+				helper.Logger(ctx).Warn("apply horde event setting failed", "setting", change.Name, "error", err.Error())
+			}
+		}
+	}
+	current := getActiveHordeEvents(opts.Events, time.Now())
+	if len(current) > 0 {
+		helper.Logger(ctx).Info("horde events active", "events", current)
+		apply([]string{}, current)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(time.Now().Truncate(time.Minute).Add(time.Minute))):
+		}
+		next := getActiveHordeEvents(opts.Events, time.Now())
+		if slices.Equal(current, next) {
+			continue
+		}
+		helper.Logger(ctx).Info("horde events changed", "from", current, "to", next)
+		apply(current, next)
+		for _, event := range opts.Events {
+			wasActive := slices.Contains(current, event.Name)
+			isActive := slices.Contains(next, event.Name)
+			if wasActive == isActive {
+				continue
+			}
+			if isActive {
+				runHordeEventCommands(ctx, event, event.StartCommands)
+				announceHordeEvent(ctx, opts, event.StartMessage)
+			} else {
+				runHordeEventCommands(ctx, event, event.EndCommands)
+				announceHordeEvent(ctx, opts, event.EndMessage)
+			}
+		}
+		current = next
+	}
+}
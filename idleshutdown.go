@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// IdleShutdownOpts defines the options used by an [IdleShutdown]
+type IdleShutdownOpts struct {
+	// Instance stops the instance running the server once an idle server exits (see [StopInstance])
+	Instance   InstanceProvider
+	Timeout    time.Duration
+	WebhookUrl *url.URL
+}
+
+// IdleShutdown shuts down the server once it has been empty for [IdleShutdownOpts.Timeout] - and then (optionally) stops the instance running the server, so that self-hosted cloud servers stop costing money while nobody plays
+type IdleShutdown struct {
+	Opts      IdleShutdownOpts
+	idleSince *time.Time
+	triggered atomic.Bool
+}
+
+// Records the player count - returning true once the server has been empty for [IdleShutdownOpts.Timeout]
+func (is *IdleShutdown) Check(players int, now time.Time) bool {
+	if players > 0 {
+		is.idleSince = nil
+		return false
+	}
+	if is.idleSince == nil {
+		is.idleSince = &now
+	}
+	return now.Sub(*is.idleSince) >= is.Opts.Timeout
+}
+
+// Determines whether the server was shut down for being idle
+func (is *IdleShutdown) Triggered() bool {
+	return is.triggered.Load()
+}
+
+// Checks the player count (from the [StatusTracker]) on an interval - shutting down the server once it has been idle for [IdleShutdownOpts.Timeout].
+// The server is only considered idle while it's ready (e.g., a server that takes a long time to load isn't shut down).
+func (is *IdleShutdown) Run(ctx context.Context, interval time.Duration) {
+	tracker := GetStatusTracker(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		status := tracker.Get()
+		if status.State != ServerStateReady {
+			is.idleSince = nil
+			continue
+		}
+		if !is.Check(status.Players, time.Now()) {
+			continue
+		}
+		helper.Logger(ctx).Info("server idle - shutting down", "timeout", is.Opts.Timeout, "instance", is.Opts.Instance)
+		is.triggered.Store(true)
+		err := ShutdownServer(ctx)
+		if err != nil {
+			helper.Logger(ctx).Warn("shutdown server failed", "error", err.Error())
+		}
+		return
+	}
+}
+
+// Stops the instance running the server (if configured) - called once a server shut down for being idle has exited.
+// Returns an error if the instance cannot be stopped.
+func (is *IdleShutdown) StopInstance(ctx context.Context) error {
+	if is.Opts.Instance == "" {
+		return nil
+	}
+	helper.Logger(ctx).Info("stop instance", "provider", is.Opts.Instance)
+	err := StopInstance(ctx, is.Opts.Instance, is.Opts.WebhookUrl)
+	if err != nil {
+		return fmt.Errorf("stop %s instance: %w", is.Opts.Instance, err)
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// BackupMode determines how backups are stored
+type BackupMode string
+
+const (
+	// BackupModeFull stores each backup as a self-contained tar.gz archive
+	BackupModeFull BackupMode = "full"
+	// BackupModeIncremental stores each backup as a manifest referencing content-addressed (and deduplicated) file objects - only files that changed since the previous backup are stored
+	BackupModeIncremental BackupMode = "incremental"
+)
+
+// Parses (and validates) a backup mode from text - allowing [BackupMode] to be parsed from the environment.
+// Returns an error if the backup mode is unrecognized.
+func (bm *BackupMode) UnmarshalText(text []byte) error {
+	mode := BackupMode(strings.ToLower(string(text)))
+	switch mode {
+	case BackupModeFull, BackupModeIncremental:
+		*bm = mode
+		return nil
+	}
+	return fmt.Errorf("unrecognized backup mode %s", text)
+}
+
+// BackupFileStat records the size and modification time of a backed up file - used to detect unchanged files without re-hashing them
+type BackupFileStat struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// Gets the directory containing content-addressed backup objects
+func getBackupObjectsDir(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["backups"], "objects")
+}
+
+// Gets the path of a content-addressed backup object
+func getBackupObject(ctx context.Context, checksum string) string {
+	return filepath.Join(getBackupObjectsDir(ctx), checksum[:2], checksum)
+}
+
+// Computes the sha256 checksum of a file.
+// Returns an error if the file cannot be read.
+func hashFile(path string) (string, error) {
+	handle, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer handle.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, handle)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Stores a file as a (gzip-compressed) content-addressed backup object - unless an object with the same checksum already exists.
+// Returns the number of bytes written.
+// Returns an error if the object cannot be written.
+func storeBackupObject(ctx context.Context, path string, checksum string) (int64, error) {
+	object := getBackupObject(ctx, checksum)
+	_, err := os.Lstat(object)
+	if err == nil {
+		return 0, nil
+	}
+	err = helper.CreateDirs(ctx, filepath.Dir(object))
+	if err != nil {
+		return 0, err
+	}
+	source, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+	temp := fmt.Sprintf("%s.tmp", object)
+	handle, err := os.Create(temp)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(temp)
+	defer handle.Close()
+	writer := gzip.NewWriter(handle)
+	_, err = io.Copy(writer, source)
+	if err != nil {
+		return 0, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return 0, err
+	}
+	info, err := handle.Stat()
+	if err != nil {
+		return 0, err
+	}
+	err = handle.Close()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), os.Rename(temp, object)
+}
+
+// Writes an incremental backup of the saves within the data directory.
+// Files whose size and modification time are unchanged since [previous] reuse their recorded checksum (and object).
+// Returns the checksum manifest, file stats and the checksums of objects added to the object store.
+// Returns an error if a file cannot be hashed or stored.
+func writeIncrementalBackup(ctx context.Context, previous *BackupMetadata) (map[string]string, map[string]BackupFileStat, map[string]int64, error) {
+	files := map[string]string{}
+	stats := map[string]BackupFileStat{}
+	added := map[string]int64{}
+	err := walkSaves(ctx, func(relpath string, path string, info os.FileInfo) error {
+		stat := BackupFileStat{Size: info.Size(), ModTime: info.ModTime().UTC()}
+		if previous != nil {
+			previousStat, ok := previous.Stats[relpath]
+			if ok && previousStat.Size == stat.Size && previousStat.ModTime.Equal(stat.ModTime) {
+				checksum := previous.Files[relpath]
+				_, err := os.Lstat(getBackupObject(ctx, checksum))
+				if err == nil {
+					files[relpath] = checksum
+					stats[relpath] = stat
+					return nil
+				}
+			}
+		}
+		checksum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		size, err := storeBackupObject(ctx, path, checksum)
+		if err != nil {
+			return err
+		}
+		files[relpath] = checksum
+		stats[relpath] = stat
+		if size > 0 {
+			added[checksum] = size
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return files, stats, added, nil
+}
+
+// Copies a (gzip-compressed) backup object to [dest] - returning the sha256 checksum of the decompressed content.
+// Returns an error if the object cannot be read or [dest] cannot be written.
+func copyBackupObject(ctx context.Context, checksum string, dest io.Writer) (string, error) {
+	handle, err := os.Open(getBackupObject(ctx, checksum))
+	if err != nil {
+		return "", err
+	}
+	defer handle.Close()
+	reader, err := gzip.NewReader(handle)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(dest, hash), reader)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Restores the files of an incremental backup to a directory.
+// Returns an error if a backup object is missing, corrupt or cannot be restored.
+func restoreIncrementalBackup(ctx context.Context, metadata BackupMetadata, dir string) error {
+	for relpath, checksum := range metadata.Files {
+		path := filepath.Join(dir, filepath.FromSlash(relpath))
+		err := helper.CreateDirs(ctx, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		handle, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		actual, err := copyBackupObject(ctx, checksum, handle)
+		handle.Close()
+		if err != nil {
+			return fmt.Errorf("object for %s unreadable: %w", relpath, err)
+		}
+		if actual != checksum {
+			return fmt.Errorf("checksum mismatch: %s", relpath)
+		}
+		stat, ok := metadata.Stats[relpath]
+		if ok {
+			os.Chtimes(path, stat.ModTime, stat.ModTime)
+		}
+	}
+	return nil
+}
+
+// Verifies an incremental backup by checking that every object it references exists - and that the objects in [added] match their checksums.
+// Previously stored objects were verified by the backup that added them - so that unchanged (and potentially large) saves aren't re-read by every backup.
+// If [extract] is set, the backup is instead restored to a temporary directory (verifying every object) and checked for key files.
+// Returns an error describing the verification failure.
+func VerifyIncrementalBackup(ctx context.Context, metadata BackupMetadata, added map[string]int64, extract bool) error {
+	helper.Logger(ctx).Info("verify incremental backup", "name", metadata.Name, "extract", extract)
+	if extract {
+		return helper.CreateTempDir(ctx, func(tempDir string) error {
+			err := restoreIncrementalBackup(ctx, metadata, tempDir)
+			if err != nil {
+				return err
+			}
+			return checkBackupKeyFiles(tempDir, metadata.Files)
+		})
+	}
+	mismatched := []string{}
+	for relpath, checksum := range metadata.Files {
+		_, isAdded := added[checksum]
+		if !isAdded {
+			_, err := os.Lstat(getBackupObject(ctx, checksum))
+			if err != nil {
+				mismatched = append(mismatched, relpath)
+			}
+			continue
+		}
+		actual, err := copyBackupObject(ctx, checksum, io.Discard)
+		if err != nil || actual != checksum {
+			mismatched = append(mismatched, relpath)
+		}
+	}
+	sort.Strings(mismatched)
+	if len(mismatched) > 0 {
+		return fmt.Errorf("objects missing or corrupt: %s", strings.Join(mismatched, ", "))
+	}
+	return nil
+}
+
+// Deletes backup objects that are no longer referenced by any incremental backup.
+// Objects being written ('*.tmp') and objects written after collection starts are kept - they belong to backups whose metadata hasn't been written yet.
+// Must be called while holding the backup lock (see [lockBackups]).
+// Returns an error if the backups cannot be listed or objects cannot be deleted.
+func CollectBackupObjects(ctx context.Context) error {
+	start := time.Now()
+	backups, err := ListBackups(ctx)
+	if err != nil {
+		return err
+	}
+	referenced := map[string]bool{}
+	for _, backup := range backups {
+		for _, checksum := range backup.Files {
+			referenced[checksum] = true
+		}
+	}
+	matches, err := filepath.Glob(filepath.Join(getBackupObjectsDir(ctx), "*", "*"))
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, match := range matches {
+		if referenced[filepath.Base(match)] || strings.HasSuffix(match, ".tmp") {
+			continue
+		}
+		info, err := os.Lstat(match)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(start) {
+			continue
+		}
+		err = os.Remove(match)
+		if err != nil {
+			return err
+		}
+		count++
+	}
+	helper.Logger(ctx).Info("collect backup objects", "removed", count)
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// stagedDirNames are the directories prepared in staging by [InstallStaging]
+var stagedDirNames = []string{"generated", "sdtd"}
+
+// Gets the staging directory of a live directory - a sibling of the live directory, so that it can be renamed into place
+func getInstallStagingDir(live string) string {
+	return filepath.Join(filepath.Dir(live), fmt.Sprintf(".%s.staging", filepath.Base(live)))
+}
+
+// InstallStaging prepares the server installation (the game, mods, localizations and the generated settings) in staging directories - which replace the live directories only once every install step has succeeded.
+// While staging, the process's directories point at the staging directories - so install steps run unmodified (see [StageUpdate] for the same approach).
+// An install that fails mid-way leaves the live directories untouched.
+type InstallStaging struct {
+	live map[string]string
+}
+
+// Starts staging the server installation - pointing the process's directories at empty staging directories.
+// When [seed] is set, the staging directories start as copies of the live directories (e.g., for pre-seeded or already-applied installs that aren't downloaded again).
+// Returns an error if the staging directories cannot be created.
+func BeginInstallStaging(ctx context.Context, seed bool) (*InstallStaging, error) {
+	dirs := helper.Dirs(ctx)
+	is := InstallStaging{live: map[string]string{}}
+	for _, name := range stagedDirNames {
+		live := dirs[name]
+		staging := getInstallStagingDir(live)
+		helper.Logger(ctx).Info("stage install", "dir", name, "path", staging)
+		err := helper.RemovePaths(ctx, staging)
+		if err == nil {
+			err = helper.CreateDirs(ctx, staging)
+		}
+		if err == nil && seed {
+			_, err = helper.Command(ctx, []string{"cp", "-a", fmt.Sprintf("%s/.", live), staging}, helper.CmdOpts{}).Run()
+		}
+		if err != nil {
+			is.Abort(ctx)
+			return nil, err
+		}
+		is.live[name] = live
+		dirs[name] = staging
+	}
+	return &is, nil
+}
+
+// Restores the process's directories to the live directories
+func (is *InstallStaging) restoreDirs(ctx context.Context) {
+	dirs := helper.Dirs(ctx)
+	for name, live := range is.live {
+		dirs[name] = live
+	}
+}
+
+// Maps a path within a staging directory to the path it has once the staging directory replaces the live directory
+func (is *InstallStaging) Resolve(path string) string {
+	for _, live := range is.live {
+		staging := getInstallStagingDir(live)
+		relpath, err := filepath.Rel(staging, path)
+		if err == nil && relpath != ".." && !strings.HasPrefix(relpath, "../") {
+			return filepath.Join(live, relpath)
+		}
+	}
+	return path
+}
+
+// Discards the staged installation (if it hasn't been committed) - leaving the live directories untouched.
+// Failures are logged and otherwise ignored.
+func (is *InstallStaging) Abort(ctx context.Context) {
+	if len(is.live) == 0 {
+		return
+	}
+	is.restoreDirs(ctx)
+	helper.Logger(ctx).Warn("discard staged install")
+	for _, live := range is.live {
+		err := helper.RemovePaths(ctx, getInstallStagingDir(live))
+		if err != nil {
+			helper.Logger(ctx).Warn("remove staging directory failed", "path", getInstallStagingDir(live), "error", err.Error())
+		}
+	}
+	is.live = map[string]string{}
+}
+
+// Replaces a live directory with its staging directory.
+// Directories are swapped with renames - unless the live directory is a mount point (which cannot be renamed), in which case its contents are replaced.
+// Returns an error if the directory cannot be replaced.
+func swapInstallStagingDir(ctx context.Context, staging string, live string) error {
+	previous := fmt.Sprintf("%s.previous", staging)
+	err := helper.RemovePaths(ctx, previous)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(live, previous)
+	if errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.EXDEV) {
+		helper.Logger(ctx).Info("replace contents of mounted directory", "path", live)
+		subpaths, err := helper.ListDir(ctx, live)
+		if err == nil {
+			err = helper.RemovePaths(ctx, subpaths...)
+		}
+		if err == nil {
+			_, err = helper.Command(ctx, []string{"cp", "-a", fmt.Sprintf("%s/.", staging), live}, helper.CmdOpts{}).Run()
+		}
+		if err != nil {
+			return err
+		}
+		return helper.RemovePaths(ctx, staging)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	err = os.Rename(staging, live)
+	if err != nil {
+		return err
+	}
+	return helper.RemovePaths(ctx, previous)
+}
+
+// Replaces the live directories with the staging directories - and points the process's directories back at the live directories.
+// Returns an error if a live directory cannot be replaced.
+func (is *InstallStaging) Commit(ctx context.Context) error {
+	is.restoreDirs(ctx)
+	helper.Logger(ctx).Info("commit staged install")
+	for _, name := range stagedDirNames {
+		live, ok := is.live[name]
+		if !ok {
+			continue
+		}
+		err := swapInstallStagingDir(ctx, getInstallStagingDir(live), live)
+		if err != nil {
+			return fmt.Errorf("commit staged %s: %w", name, err)
+		}
+		delete(is.live, name)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// InstanceProvider identifies how the instance (i.e., the virtual machine) running the server is stopped
+type InstanceProvider string
+
+const (
+	// InstanceProviderAws stops the ec2 instance running the server (authenticating with 'AWS_ACCESS_KEY_ID'/'AWS_SECRET_ACCESS_KEY' or the instance profile)
+	InstanceProviderAws InstanceProvider = "aws"
+	// InstanceProviderGcp stops the compute engine instance running the server (authenticating with the instance's service account)
+	InstanceProviderGcp InstanceProvider = "gcp"
+	// InstanceProviderWebhook posts to a webhook - which is expected to stop the instance
+	InstanceProviderWebhook InstanceProvider = "webhook"
+)
+
+// instanceProviders are the supported [InstanceProvider]s
+var instanceProviders = []InstanceProvider{InstanceProviderAws, InstanceProviderGcp, InstanceProviderWebhook}
+
+// metadataClient requests instance metadata services - which are link-local, and are never reached through the download proxy
+var metadataClient = &http.Client{Transport: &http.Transport{}, Timeout: 5 * time.Second}
+
+// Sends a request - returning the response body.
+// Returns an error if the request fails or the response has a non-2xx status code.
+func doInstanceRequest(client *http.Client, request *http.Request) ([]byte, error) {
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1024*1024))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s responded with status %d: %s", request.Method, request.URL.Redacted(), response.StatusCode, strings.TrimSpace(string(body[:min(len(body), 512)])))
+	}
+	return body, nil
+}
+
+// awsImdsUrl is the url of the ec2 instance metadata service
+const awsImdsUrl = "http://169.254.169.254/latest"
+
+// Gets a value from the ec2 instance metadata service (using an IMDSv2 session token).
+// Returns an error if the value cannot be retrieved (e.g., when not running on ec2).
+func getAwsMetadata(ctx context.Context, token string, name string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/meta-data/%s", awsImdsUrl, name), nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("X-aws-ec2-metadata-token", token)
+	body, err := doInstanceRequest(metadataClient, request)
+	return strings.TrimSpace(string(body)), err
+}
+
+// Stops the ec2 instance running the server with the ec2 'StopInstances' api.
+// Returns an error if the instance metadata or credentials are unavailable, or if the request fails.
+func stopAwsInstance(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, fmt.Sprintf("%s/api/token", awsImdsUrl), nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "300")
+	body, err := doInstanceRequest(metadataClient, request)
+	if err != nil {
+		return fmt.Errorf("instance metadata unavailable: %w", err)
+	}
+	token := string(body)
+	instanceId, err := getAwsMetadata(ctx, token, "instance-id")
+	if err != nil {
+		return err
+	}
+	region, err := getAwsMetadata(ctx, token, "placement/region")
+	if err != nil {
+		return err
+	}
+	credentials := struct {
+		AccessKeyId     string
+		SecretAccessKey string
+		Token           string
+	}{AccessKeyId: os.Getenv("AWS_ACCESS_KEY_ID"), SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"), Token: os.Getenv("AWS_SESSION_TOKEN")}
+	if credentials.AccessKeyId == "" {
+		role, err := getAwsMetadata(ctx, token, "iam/security-credentials/")
+		if err != nil {
+			return fmt.Errorf("instance profile unavailable (and AWS_ACCESS_KEY_ID is unset): %w", err)
+		}
+		data, err := getAwsMetadata(ctx, token, fmt.Sprintf("iam/security-credentials/%s", strings.Split(role, "\n")[0]))
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal([]byte(data), &credentials)
+		if err != nil {
+			return err
+		}
+	}
+	config := []string{
+		fmt.Sprintf("aws-sigv4 = %s", quoteCurlConfig(fmt.Sprintf("aws:amz:%s:ec2", region))),
+		fmt.Sprintf("user = %s", quoteCurlConfig(fmt.Sprintf("%s:%s", credentials.AccessKeyId, credentials.SecretAccessKey))),
+	}
+	if credentials.Token != "" {
+		config = append(config, fmt.Sprintf("header = %s", quoteCurlConfig(fmt.Sprintf("X-Amz-Security-Token: %s", credentials.Token))))
+	}
+	query := url.Values{"Action": {"StopInstances"}, "InstanceId.1": {instanceId}, "Version": {"2016-11-15"}}
+	_, err = runCurl(ctx, config, "--request", "POST", "--data", query.Encode(), fmt.Sprintf("https://ec2.%s.amazonaws.com/", region))
+	return err
+}
+
+// gcpMetadataUrl is the url of the compute engine metadata server
+const gcpMetadataUrl = "http://metadata.google.internal/computeMetadata/v1"
+
+// Gets a value from the compute engine metadata server.
+// Returns an error if the value cannot be retrieved (e.g., when not running on compute engine).
+func getGcpMetadata(ctx context.Context, name string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s", gcpMetadataUrl, name), nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Metadata-Flavor", "Google")
+	body, err := doInstanceRequest(metadataClient, request)
+	return strings.TrimSpace(string(body)), err
+}
+
+// Stops the compute engine instance running the server with the compute engine 'instances.stop' api.
+// Returns an error if the instance metadata or credentials are unavailable, or if the request fails.
+func stopGcpInstance(ctx context.Context) error {
+	values := map[string]string{}
+	for _, name := range []string{"project/project-id", "instance/zone", "instance/name", "instance/service-accounts/default/token"} {
+		value, err := getGcpMetadata(ctx, name)
+		if err != nil {
+			return fmt.Errorf("instance metadata unavailable: %w", err)
+		}
+		values[name] = value
+	}
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	err := json.Unmarshal([]byte(values["instance/service-accounts/default/token"]), &token)
+	if err != nil {
+		return err
+	}
+	// the zone is formatted 'projects/[number]/zones/[zone]'
+	zone := path.Base(values["instance/zone"])
+	url := fmt.Sprintf("https://compute.googleapis.com/compute/v1/projects/%s/zones/%s/instances/%s/stop", values["project/project-id"], zone, values["instance/name"])
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
+	_, err = doInstanceRequest(getHttpClient(ctx), request)
+	return err
+}
+
+// Posts an 'idle-shutdown' event to a webhook - which is expected to stop the instance running the server.
+// Returns an error if the request fails or the webhook responds with a non-2xx status code.
+func postInstanceWebhook(ctx context.Context, webhookUrl *url.URL) error {
+	host, _ := os.Hostname()
+	data, err := json.Marshal(map[string]any{"event": "idle-shutdown", "host": host, "time": time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookUrl.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	_, err = doInstanceRequest(getHttpClient(ctx), request)
+	return err
+}
+
+// Stops the instance running the server.
+// Returns an error if the provider is unsupported or the instance cannot be stopped.
+func StopInstance(ctx context.Context, provider InstanceProvider, webhookUrl *url.URL) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	switch provider {
+	case InstanceProviderAws:
+		return stopAwsInstance(ctx)
+	case InstanceProviderGcp:
+		return stopGcpInstance(ctx)
+	case InstanceProviderWebhook:
+		return postInstanceWebhook(ctx, webhookUrl)
+	}
+	return fmt.Errorf("unsupported instance provider %s", provider)
+}
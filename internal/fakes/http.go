@@ -0,0 +1,96 @@
+package fakes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// HttpResponse is a canned response served by [HttpServer]
+type HttpResponse struct {
+	Status  int
+	Headers map[string]string
+	Body    []byte
+}
+
+// HttpServer is a fake http server serving canned responses (e.g., mod archives for 'MOD_URLS' or the github releases api)
+type HttpServer struct {
+	lock      sync.Mutex
+	requests  []string
+	responses map[string]HttpResponse
+	server    *http.Server
+}
+
+// Creates a new [HttpServer].  Call [HttpServer.Listen] to accept connections.
+func NewHttpServer() *HttpServer {
+	return &HttpServer{responses: map[string]HttpResponse{}}
+}
+
+// Sets the response to requests for a path (e.g., '/mods/mod.zip') - unknown paths respond with 404
+func (hs *HttpServer) Respond(path string, response HttpResponse) {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+	if response.Status == 0 {
+		response.Status = http.StatusOK
+	}
+	hs.responses[path] = response
+}
+
+// Sets the latest github release of a repository (served at the github api's '/repos/[owner]/[repo]/releases/latest' path)
+func (hs *HttpServer) RespondGithubRelease(owner string, repo string, tag string) {
+	data, _ := json.Marshal(map[string]string{"tag_name": tag})
+	hs.Respond(fmt.Sprintf("/repos/%s/%s/releases/latest", owner, repo), HttpResponse{Headers: map[string]string{"Content-Type": "application/json"}, Body: data})
+}
+
+// Gets the requests received by the server (formatted as '[method] [path]', in order)
+func (hs *HttpServer) Requests() []string {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+	return append([]string{}, hs.requests...)
+}
+
+// Serves a request with its canned response
+func (hs *HttpServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	hs.lock.Lock()
+	hs.requests = append(hs.requests, fmt.Sprintf("%s %s", request.Method, request.URL.Path))
+	response, ok := hs.responses[request.URL.Path]
+	hs.lock.Unlock()
+	if !ok {
+		http.NotFound(writer, request)
+		return
+	}
+	for key, value := range response.Headers {
+		writer.Header().Set(key, value)
+	}
+	writer.WriteHeader(response.Status)
+	if request.Method != http.MethodHead {
+		writer.Write(response.Body)
+	}
+}
+
+// Listens on an address (e.g., 'localhost:0') and serves requests in the background.
+// Returns the address being listened on.
+// Returns an error if the address cannot be listened on.
+func (hs *HttpServer) Listen(addr string) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	hs.lock.Lock()
+	hs.server = &http.Server{Handler: hs}
+	hs.lock.Unlock()
+	go hs.server.Serve(listener)
+	return listener.Addr().String(), nil
+}
+
+// Stops the server
+func (hs *HttpServer) Close() error {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+	if hs.server == nil {
+		return nil
+	}
+	return hs.server.Close()
+}
@@ -0,0 +1,177 @@
+// Package fakes provides fake implementations of the external services the entrypoint interacts with (the seven days to die telnet console and http apis) - allowing lifecycle features to be developed and exercised without a real server.
+package fakes
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TelnetPrompt is sent once a telnet connection is ready to accept commands (matching the real server)
+const TelnetPrompt = "Press 'help' to get a list of all commands. Press 'exit' to end session."
+
+// TelnetHandler produces the response to a console command (given its arguments)
+type TelnetHandler func(args string) []string
+
+// TelnetServer is a fake seven days to die telnet console.
+// Like the real server, it sends a prompt to new connections, acknowledges each command with an 'Executing command' log line and then writes the command's response.
+type TelnetServer struct {
+	commands []string
+	conns    map[net.Conn]bool
+	handlers map[string]TelnetHandler
+	listener net.Listener
+	lock     sync.Mutex
+	ready    bool
+	started  time.Time
+}
+
+// Creates a new [TelnetServer] with canned responses for common commands.  Call [TelnetServer.Listen] to accept connections.
+func NewTelnetServer() *TelnetServer {
+	ts := &TelnetServer{conns: map[net.Conn]bool{}, handlers: map[string]TelnetHandler{}, ready: true, started: time.Now()}
+	ts.Respond("gettime", func(args string) []string {
+		ts.lock.Lock()
+		defer ts.lock.Unlock()
+		if !ts.ready {
+			return []string{"World not loaded yet"}
+		}
+		return []string{"Day 1, 07:00"}
+	})
+	ts.Respond("getgamepref", func(args string) []string {
+		return []string{"GamePref.GameName = My Game", "GamePref.GameWorld = Navezgane", "GamePref.ServerMaxPlayerCount = 8"}
+	})
+	ts.Respond("listplayers", func(args string) []string {
+		return []string{"Total of 0 in the game"}
+	})
+	ts.Respond("listlandprotection", func(args string) []string {
+		return []string{"Total of 0 keystones in the game"}
+	})
+	ts.Respond("saveworld", func(args string) []string {
+		return []string{}
+	})
+	ts.Respond("shutdown", func(args string) []string {
+		go ts.Close()
+		return []string{"Shutting server down..."}
+	})
+	return ts
+}
+
+// Sets whether the server has finished loading the world (the 'gettime' readiness probe fails until it has)
+func (ts *TelnetServer) SetReady(ready bool) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ts.ready = ready
+}
+
+// Sets the response to a console command (by name) - replacing any existing response
+func (ts *TelnetServer) Respond(name string, handler TelnetHandler) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ts.handlers[strings.ToLower(name)] = handler
+}
+
+// Sets a fixed response to a console command (by name)
+func (ts *TelnetServer) RespondLines(name string, lines ...string) {
+	ts.Respond(name, func(args string) []string {
+		return lines
+	})
+}
+
+// Gets the commands received by the server (in order)
+func (ts *TelnetServer) Commands() []string {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	return append([]string{}, ts.commands...)
+}
+
+// Formats a server log line (e.g., '2024-01-01T00:00:00 12.345 INF ...')
+func (ts *TelnetServer) formatLogLine(message string) string {
+	now := time.Now()
+	return fmt.Sprintf("%s %.3f INF %s", now.Format("2006-01-02T15:04:05"), now.Sub(ts.started).Seconds(), message)
+}
+
+// Writes a log line to every connection (e.g., 'Chat (from ...)' or 'GMSG: Player ... died' - simulating game events)
+func (ts *TelnetServer) Emit(message string) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	for conn := range ts.conns {
+		fmt.Fprintf(conn, "%s\r\n", ts.formatLogLine(message))
+	}
+}
+
+// Listens on an address (e.g., 'localhost:8081') and serves connections in the background.
+// Returns the address being listened on.
+// Returns an error if the address cannot be listened on.
+func (ts *TelnetServer) Listen(addr string) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	ts.lock.Lock()
+	ts.listener = listener
+	ts.lock.Unlock()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go ts.serve(conn)
+		}
+	}()
+	return listener.Addr().String(), nil
+}
+
+// Serves a single connection until it's closed (or the client exits)
+func (ts *TelnetServer) serve(conn net.Conn) {
+	defer func() {
+		ts.lock.Lock()
+		delete(ts.conns, conn)
+		ts.lock.Unlock()
+		conn.Close()
+	}()
+	ts.lock.Lock()
+	ts.conns[conn] = true
+	fmt.Fprintf(conn, "*** Connected with 7DTD server.\r\n*** Server version: fake\r\n\r\n%s\r\n\r\n", TelnetPrompt)
+	ts.lock.Unlock()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		command := strings.TrimSpace(scanner.Text())
+		if command == "" {
+			continue
+		}
+		if command == "exit" {
+			return
+		}
+		name, args, _ := strings.Cut(command, " ")
+		ts.lock.Lock()
+		ts.commands = append(ts.commands, command)
+		handler, ok := ts.handlers[strings.ToLower(name)]
+		ts.lock.Unlock()
+		lines := []string{fmt.Sprintf("*** ERROR: unknown command '%s'", name)}
+		if ok {
+			lines = handler(args)
+		}
+		ts.lock.Lock()
+		fmt.Fprintf(conn, "%s\r\n", ts.formatLogLine(fmt.Sprintf("Executing command '%s' by Telnet from %s", command, conn.RemoteAddr())))
+		for _, line := range lines {
+			fmt.Fprintf(conn, "%s\r\n", line)
+		}
+		ts.lock.Unlock()
+	}
+}
+
+// Stops listening and closes every connection (simulating a server shutdown)
+func (ts *TelnetServer) Close() error {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	for conn := range ts.conns {
+		conn.Close()
+	}
+	if ts.listener == nil {
+		return nil
+	}
+	return ts.listener.Close()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// KillFeedOpts defines the options used in conjunction with the [RunKillFeed] function
+type KillFeedOpts struct {
+	AdminWebhookUrl *url.URL
+	IncludeDeaths   bool
+	WebhookUrl      *url.URL
+}
+
+// Escapes discord markdown in a player name
+func escapeDiscord(value string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "*", "\\*", "_", "\\_", "~", "\\~", "`", "\\`", "|", "\\|", ">", "\\>")
+	return replacer.Replace(value)
+}
+
+// Formats a kill event as a discord message - optionally including the victim's position
+func formatKillEvent(event KillEvent, position string) string {
+	message := fmt.Sprintf("**%s** died", escapeDiscord(event.Victim))
+	if event.Killer != "" {
+		message = fmt.Sprintf("**%s** killed **%s**", escapeDiscord(event.Killer), escapeDiscord(event.Victim))
+	}
+	if position != "" {
+		message = fmt.Sprintf("%s at `%s`", message, position)
+	}
+	return message
+}
+
+// Posts a message to a discord webhook
+func postDiscordMessage(ctx context.Context, webhookUrl *url.URL, message string) {
+	data, _ := json.Marshal(map[string]any{
+		"allowed_mentions": map[string]any{"parse": []string{}},
+		"content":          message,
+	})
+	postWebhook(ctx, webhookUrl.String(), data)
+}
+
+// Posts player kills (and optionally, other player deaths) read from the telnet session to discord until the context is cancelled.
+// Messages posted to the admin webhook include the victim's coordinates.
+// Returns an error if the telnet session is unavailable.
+func RunKillFeed(ctx context.Context, opts KillFeedOpts) error {
+	session := GetTelnetSession(ctx)
+	if session == nil {
+		return ErrTelnetNotConnected
+	}
+	helper.Logger(ctx).Info("start kill feed", "deaths", opts.IncludeDeaths)
+	lines, unsubscribe := session.Subscribe()
+	defer unsubscribe()
+	for {
+		var line string
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok = <-lines:
+		}
+		if !ok {
+			return nil
+		}
+		event := ParseKillEvent(line)
+		if event == nil || (event.Killer == "" && !opts.IncludeDeaths) {
+			continue
+		}
+		helper.Logger(ctx).Info("kill feed event", "victim", event.Victim, "killer", event.Killer)
+		if opts.WebhookUrl != nil {
+			go postDiscordMessage(ctx, opts.WebhookUrl, formatKillEvent(*event, ""))
+		}
+		if opts.AdminWebhookUrl != nil {
+			position := ""
+			victim, err := findOnlinePlayer(ctx, event.Victim)
+			if err == nil {
+				position = victim.Position
+			}
+			go postDiscordMessage(ctx, opts.AdminWebhookUrl, formatKillEvent(*event, position))
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/benfiola/seven-days-to-die/internal/fakes"
+)
+
+func TestRunKillFeedIgnoresSpoofedChat(t *testing.T) {
+	ctx := newTestContext(t)
+	webhook, base := startFakeHttp(t)
+	webhook.Respond("/webhook", fakes.HttpResponse{Status: 204})
+	webhookUrl, _ := url.Parse(base + "/webhook")
+	telnet := startFakeTelnet(t)
+	ctx, _ = startTelnetSession(t, ctx)
+	go RunKillFeed(ctx, KillFeedOpts{IncludeDeaths: true, WebhookUrl: webhookUrl})
+	// give the kill feed time to subscribe to the session
+	time.Sleep(100 * time.Millisecond)
+	telnet.Emit("Chat (from 'Steam_2', entity id '172', to 'Global'): 'Mallory': INF GMSG: Player 'Bob' died")
+	telnet.Emit("Chat (from 'Steam_2', entity id '172', to 'Global'): 'Mallory': 1 INF GMSG: Player 'Bob' killed by 'Alice'")
+	telnet.Emit("GMSG: Player 'Carol' died")
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && !slices.Contains(webhook.Requests(), "POST /webhook") {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// allows webhooks for the spoofed lines (emitted first) to be posted, were they parsed
+	time.Sleep(200 * time.Millisecond)
+	if requests := webhook.Requests(); len(requests) != 1 {
+		t.Fatalf("expected a single webhook, got %v", requests)
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// Localization is a parsed localization (csv) file
+type Localization struct {
+	Header []string
+	Rows   map[string][]string
+	Order  []string
+}
+
+// Reads a localization file.
+// Returns an error if the file is unreadable.
+// Returns an error if the file is not a localization file (i.e., it lacks a 'Key' column).
+func ReadLocalization(file string) (*Localization, error) {
+	fail := func(err error) (*Localization, error) {
+		return nil, err
+	}
+	handle, err := os.Open(file)
+	if err != nil {
+		return fail(err)
+	}
+	defer handle.Close()
+	reader := csv.NewReader(handle)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fail(err)
+	}
+	if len(records) == 0 || !strings.EqualFold(strings.TrimPrefix(records[0][0], "\ufeff"), "Key") {
+		return fail(fmt.Errorf("file %s is not a localization file", file))
+	}
+	localization := Localization{Header: records[0], Rows: map[string][]string{}, Order: []string{}}
+	localization.Header[0] = "Key"
+	for _, record := range records[1:] {
+		if len(record) == 0 || record[0] == "" {
+			continue
+		}
+		if _, ok := localization.Rows[record[0]]; !ok {
+			localization.Order = append(localization.Order, record[0])
+		}
+		localization.Rows[record[0]] = record
+	}
+	return &localization, nil
+}
+
+// Writes the localization to a file.
+// Returns an error if the file cannot be written.
+func (l *Localization) Write(file string) error {
+	handle, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	writer := csv.NewWriter(handle)
+	err = writer.Write(l.Header)
+	if err != nil {
+		return err
+	}
+	for _, key := range l.Order {
+		err := writer.Write(l.Rows[key])
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Gets a column value for a row, returning an empty string if the column is missing.
+func getLocalizationColumn(header []string, row []string, column string) string {
+	index := slices.IndexFunc(header, func(value string) bool { return strings.EqualFold(value, column) })
+	if index == -1 || index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
+
+// LocalizationConflict describes a localization key defined differently by multiple sources
+type LocalizationConflict struct {
+	Key     string
+	Sources []string
+}
+
+// Projects a fragment row onto the given header - mapping columns by name.
+// Columns missing from the fragment are left empty.
+func (l *Localization) project(header []string, key string) []string {
+	row := make([]string, len(header))
+	for index, column := range header {
+		row[index] = getLocalizationColumn(l.Header, l.Rows[key], column)
+	}
+	return row
+}
+
+// Merges a localization fragment into the localization - mapping fragment columns onto the localization's columns by name.
+// Empty fragment values (and columns unknown to the localization) leave existing values untouched.
+func (l *Localization) Merge(fragment *Localization) {
+	for _, key := range fragment.Order {
+		row := make([]string, len(l.Header))
+		existing, exists := l.Rows[key]
+		if exists {
+			copy(row, existing)
+		} else {
+			l.Order = append(l.Order, key)
+		}
+		for index, value := range fragment.project(l.Header, key) {
+			if value != "" {
+				row[index] = value
+			}
+		}
+		l.Rows[key] = row
+	}
+}
+
+// serverLanguages maps the languages of the game's localization (i.e., its columns) to their English names - the format of the 'Language' server setting
+var serverLanguages = map[string]string{
+	"brazilian": "Brazilian Portuguese",
+	"english":   "English",
+	"french":    "French",
+	"german":    "German",
+	"italian":   "Italian",
+	"japanese":  "Japanese",
+	"koreana":   "Korean",
+	"latam":     "Latin American Spanish",
+	"polish":    "Polish",
+	"russian":   "Russian",
+	"schinese":  "Simplified Chinese",
+	"spanish":   "Spanish",
+	"tchinese":  "Traditional Chinese",
+	"turkish":   "Turkish",
+}
+
+// Validates a server language (a localization column - e.g., 'german').
+// Returns an error if the language is unknown.
+func ValidateServerLanguage(language string) error {
+	_, ok := serverLanguages[strings.ToLower(language)]
+	if !ok {
+		names := []string{}
+		for name := range serverLanguages {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return fmt.Errorf("unknown language %s (expected one of %s)", language, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// Gets the server settings related to a server language (i.e., the 'Language' advertised in the server browser).
+// Returns empty settings if no language is set.
+func GetServerLanguageSettings(language string) ServerSettings {
+	settings := ServerSettings{}
+	name, ok := serverLanguages[strings.ToLower(language)]
+	if ok {
+		settings.Set("Language", name)
+	}
+	return settings
+}
+
+// Gets the directory that localization packs are installed to
+func getLocalizationPacksDir(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["generated"], "localization")
+}
+
+// Downloads and installs localization packs - either localization files ('.txt' or '.csv', downloaded on every start) or archives of them (cached like mods).
+// Each pack is installed to its own directory (in order) - previously installed packs are removed first.
+// Returns an error if a pack cannot be downloaded or extracted.
+func InstallLocalizationPacks(ctx context.Context, urls ...string) error {
+	err := helper.RemovePaths(ctx, getLocalizationPacksDir(ctx))
+	if err != nil {
+		return err
+	}
+	for index, url := range urls {
+		dest := filepath.Join(getLocalizationPacksDir(ctx), fmt.Sprintf("%03d", index))
+		helper.Logger(ctx).Info("install localization pack", "url", url, "path", dest)
+		extension := strings.ToLower(filepath.Ext(url))
+		if extension != ".txt" && extension != ".csv" {
+			err = installMod(ctx, InstallModsOpts{}, dest, url)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err = helper.CreateDirs(ctx, dest)
+		if err != nil {
+			return err
+		}
+		_, _, err = DownloadFile(ctx, url, filepath.Join(dest, filepath.Base(url)), nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Finds the localization files within the installed localization packs - in pack order, and then by path within each pack.
+// Files that aren't localization files (e.g., readmes) are skipped.
+// Returns an error if the packs cannot be walked.
+func getLocalizationPackFragments(ctx context.Context) ([]string, error) {
+	fragments := []string{}
+	err := filepath.WalkDir(getLocalizationPacksDir(ctx), func(path string, entry os.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		extension := strings.ToLower(filepath.Ext(path))
+		if !entry.Type().IsRegular() || (extension != ".txt" && extension != ".csv") {
+			return nil
+		}
+		_, err = ReadLocalization(path)
+		if err != nil {
+			helper.Logger(ctx).Info("skip localization pack file", "path", path, "error", err.Error())
+			return nil
+		}
+		fragments = append(fragments, path)
+		return nil
+	})
+	return fragments, err
+}
+
+// Finds localization fragments provided by installed mods (at 'Mods/[mod]/Config/Localization.txt'), localization packs (see [InstallLocalizationPacks]), localization overlays (at 'data/localization/*.txt') and - for a server language - language overrides (at 'data/localization/[language]/*.txt').
+// Fragments are ordered by mod name, then pack order, then overlay file name and then override file name - so that later (more specific) fragments win.
+// Returns an error if the mods, packs or overlay directories cannot be listed.
+func GetLocalizationFragments(ctx context.Context, language string) ([]string, error) {
+	fail := func(err error) ([]string, error) {
+		return nil, err
+	}
+	fragments := []string{}
+	mods, err := helper.ListDir(ctx, filepath.Join(helper.Dirs(ctx)["sdtd"], "Mods"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fail(err)
+	}
+	slices.Sort(mods)
+	for _, mod := range mods {
+		fragment := filepath.Join(mod, "Config", "Localization.txt")
+		_, err := os.Stat(fragment)
+		if err == nil {
+			fragments = append(fragments, fragment)
+		}
+	}
+	packs, err := getLocalizationPackFragments(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	fragments = append(fragments, packs...)
+	overlays, err := filepath.Glob(filepath.Join(helper.Dirs(ctx)["data"], "localization", "*.txt"))
+	if err != nil {
+		return fail(err)
+	}
+	slices.Sort(overlays)
+	fragments = append(fragments, overlays...)
+	if language != "" {
+		overrides, err := filepath.Glob(filepath.Join(helper.Dirs(ctx)["data"], "localization", strings.ToLower(language), "*.txt"))
+		if err != nil {
+			return fail(err)
+		}
+		slices.Sort(overrides)
+		fragments = append(fragments, overrides...)
+	}
+	return fragments, nil
+}
+
+// Merges localization fragments into the game's localization file ('Data/Config/Localization.txt').
+// Keys defined differently by multiple fragments are reported as conflicts (the last fragment wins).
+// Returns an error if any file cannot be read or written.
+func MergeLocalizations(ctx context.Context, fragments ...string) ([]LocalizationConflict, error) {
+	fail := func(err error) ([]LocalizationConflict, error) {
+		return nil, err
+	}
+	target := filepath.Join(helper.Dirs(ctx)["sdtd"], "Data", "Config", "Localization.txt")
+	helper.Logger(ctx).Info("merge localizations", "target", target, "count", len(fragments))
+	localization, err := ReadLocalization(target)
+	if err != nil {
+		return fail(err)
+	}
+	type definition struct {
+		source string
+		row    []string
+	}
+	definitions := map[string]definition{}
+	conflicts := []LocalizationConflict{}
+	conflictIndexes := map[string]int{}
+	for _, file := range fragments {
+		fragment, err := ReadLocalization(file)
+		if err != nil {
+			return fail(err)
+		}
+		for _, key := range fragment.Order {
+			row := fragment.project(localization.Header, key)
+			previous, ok := definitions[key]
+			definitions[key] = definition{source: file, row: row}
+			if !ok || slices.Equal(previous.row, row) {
+				continue
+			}
+			index, ok := conflictIndexes[key]
+			if !ok {
+				conflictIndexes[key] = len(conflicts)
+				conflicts = append(conflicts, LocalizationConflict{Key: key, Sources: []string{previous.source, file}})
+				continue
+			}
+			conflicts[index].Sources = append(conflicts[index].Sources, file)
+		}
+		localization.Merge(fragment)
+	}
+	for _, conflict := range conflicts {
+		helper.Logger(ctx).Warn("localization conflict", "key", conflict.Key, "sources", conflict.Sources)
+	}
+	return conflicts, localization.Write(target)
+}
+
+// Logs how much of the game's (merged) localization is translated to a language - so that untranslated keys (e.g., from mods that only ship English) can be found.
+// Keys without English text or marked 'NoTranslate' are ignored (and nothing is logged for English itself).
+// Returns an error if the localization file cannot be read.
+func LogLocalizationCoverage(ctx context.Context, language string) error {
+	language = strings.ToLower(language)
+	if language == "english" {
+		return nil
+	}
+	target := filepath.Join(helper.Dirs(ctx)["sdtd"], "Data", "Config", "Localization.txt")
+	localization, err := ReadLocalization(target)
+	if err != nil {
+		return err
+	}
+	total := 0
+	untranslated := []string{}
+	for _, key := range localization.Order {
+		row := localization.Rows[key]
+		if getLocalizationColumn(localization.Header, row, "english") == "" {
+			continue
+		}
+		noTranslate, _ := strconv.ParseBool(getLocalizationColumn(localization.Header, row, "NoTranslate"))
+		if noTranslate {
+			continue
+		}
+		total += 1
+		if getLocalizationColumn(localization.Header, row, language) == "" {
+			untranslated = append(untranslated, key)
+		}
+	}
+	helper.Logger(ctx).Info("localization coverage", "language", language, "keys", total, "untranslated", len(untranslated), "examples", untranslated[:min(len(untranslated), 10)])
+	return nil
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+var (
+	// ErrMacroNotFound is returned when a macro is run that isn't defined
+	ErrMacroNotFound = errors.New("macro not found")
+	// ErrMacroRunning is returned when a macro is run while it's already running
+	ErrMacroRunning = errors.New("macro already running")
+)
+
+// macroNameRegex matches valid macro names
+var macroNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// MacroStep is a step of a [Macro] - a console command, a delay, or both (the delay is waited after the command runs)
+type MacroStep struct {
+	Command string `json:"command"`
+	// Delay is a duration (e.g., '30s') waited before the next step
+	Delay string `json:"delay"`
+	delay time.Duration
+}
+
+// Macro is a named list of console commands (with delays between them) - run through the admin api, chat commands or a schedule (e.g., an 'event-start' macro that sets the time, spawns supply crates and broadcasts messages)
+type Macro struct {
+	Name  string      `json:"name"`
+	Steps []MacroStep `json:"steps"`
+	// Chat allows chat command admins to run the macro with '!macro [name]'
+	Chat bool `json:"chat"`
+	// Days and At schedule the macro - running it at the 'HH:MM' time (in the container's timezone) on the given weekdays (every day if empty)
+	Days []string `json:"days"`
+	At   string   `json:"at"`
+}
+
+// Validates the macro - parsing the delays of its steps.
+// Returns an error if the name, steps or schedule are invalid.
+func (m *Macro) Validate() error {
+	if !macroNameRegex.MatchString(m.Name) {
+		return fmt.Errorf("macro %s: name must only contain letters, numbers, '-' and '_'", m.Name)
+	}
+	if len(m.Steps) == 0 {
+		return fmt.Errorf("macro %s: steps must be set", m.Name)
+	}
+	for index := range m.Steps {
+		step := &m.Steps[index]
+		if strings.TrimSpace(step.Command) == "" && step.Delay == "" {
+			return fmt.Errorf("macro %s: step %d must set a command or a delay", m.Name, index+1)
+		}
+		if step.Delay == "" {
+			continue
+		}
+		delay, err := time.ParseDuration(step.Delay)
+		if err != nil || delay <= 0 {
+			return fmt.Errorf("macro %s: step %d has invalid delay %s (expected a positive duration)", m.Name, index+1, step.Delay)
+		}
+		step.delay = delay
+	}
+	if m.At == "" && len(m.Days) > 0 {
+		return fmt.Errorf("macro %s: days require at to be set", m.Name)
+	}
+	if m.At != "" {
+		_, err := parseClock(m.At)
+		if err != nil {
+			return fmt.Errorf("macro %s: %w", m.Name, err)
+		}
+	}
+	for _, day := range m.Days {
+		_, err := parseWeekday(day)
+		if err != nil {
+			return fmt.Errorf("macro %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Determines whether the macro is scheduled to run at the given time (to the minute)
+func (m *Macro) ScheduledAt(now time.Time) bool {
+	if m.At == "" {
+		return false
+	}
+	at, _ := parseClock(m.At)
+	if now.Hour()*60+now.Minute() != at {
+		return false
+	}
+	if len(m.Days) == 0 {
+		return true
+	}
+	for _, value := range m.Days {
+		day, _ := parseWeekday(value)
+		if day == now.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// Loads macros from a JSON file formatted as '{"macros": [...]}'.
+// Returns an error if the file cannot be read, a macro is invalid or macro names are duplicated.
+func LoadMacros(ctx context.Context, file string) ([]Macro, error) {
+	helper.Logger(ctx).Info("load macros", "path", file)
+	data := struct {
+		Macros []Macro `json:"macros"`
+	}{}
+	err := helper.UnmarshalFile(ctx, file, &data)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for index := range data.Macros {
+		macro := &data.Macros[index]
+		err := macro.Validate()
+		if err != nil {
+			return nil, err
+		}
+		name := strings.ToLower(macro.Name)
+		if names[name] {
+			return nil, fmt.Errorf("macro %s: defined more than once", macro.Name)
+		}
+		names[name] = true
+	}
+	return data.Macros, nil
+}
+
+// MacroRunner runs [Macro]s - at most one run of each macro at a time
+type MacroRunner struct {
+	Auditor *Auditor
+	Macros  []Macro
+	lock    sync.Mutex
+	running map[string]bool
+}
+
+// Finds a macro by (case-insensitive) name
+func (mr *MacroRunner) Find(name string) (Macro, bool) {
+	for _, macro := range mr.Macros {
+		if strings.EqualFold(macro.Name, name) {
+			return macro, true
+		}
+	}
+	return Macro{}, false
+}
+
+// Starts running a macro in the background - on behalf of an actor (recorded in the audit log alongside the source, e.g., 'api').
+// The macro runs until its steps complete (or the context is cancelled) - failing commands are logged and don't stop the macro.
+// Returns an error wrapping [ErrMacroNotFound] if the macro isn't defined.
+// Returns an error wrapping [ErrMacroRunning] if the macro is already running.
+func (mr *MacroRunner) Start(ctx context.Context, name string, actor string, source string) error {
+	macro, ok := mr.Find(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrMacroNotFound, name)
+	}
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+	if mr.running == nil {
+		mr.running = map[string]bool{}
+	}
+	if mr.running[macro.Name] {
+		return fmt.Errorf("%w: %s", ErrMacroRunning, macro.Name)
+	}
+	mr.running[macro.Name] = true
+	go func() {
+		defer func() {
+			mr.lock.Lock()
+			delete(mr.running, macro.Name)
+			mr.lock.Unlock()
+		}()
+		mr.run(ctx, macro, actor, source)
+	}()
+	return nil
+}
+
+// Runs the steps of a macro
+func (mr *MacroRunner) run(ctx context.Context, macro Macro, actor string, source string) {
+	helper.Logger(ctx).Info("run macro", "name", macro.Name, "actor", actor, "source", source)
+	for index, step := range macro.Steps {
+		if strings.TrimSpace(step.Command) != "" {
+			_, err := ExecAuditedCommand(ctx, mr.Auditor, nil, actor, source, step.Command)
+			if err != nil {
+				helper.Logger(ctx).Warn("macro command failed", "name", macro.Name, "step", index+1, "error", err.Error())
+			}
+		}
+		if step.delay == 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			helper.Logger(ctx).Warn("macro cancelled", "name", macro.Name, "step", index+1)
+			return
+		case <-time.After(step.delay):
+		}
+	}
+	helper.Logger(ctx).Info("macro complete", "name", macro.Name)
+}
+
+// Runs scheduled macros (see [Macro.ScheduledAt]) - checking schedules every minute (while the server is ready) until the context is cancelled.
+// Scheduled runs of macros that are still running are skipped.
+func (mr *MacroRunner) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(time.Now().Truncate(time.Minute).Add(time.Minute))):
+		}
+		tracker := GetStatusTracker(ctx)
+		if tracker != nil && tracker.Get().State != ServerStateReady {
+			continue
+		}
+		now := time.Now()
+		for _, macro := range mr.Macros {
+			if !macro.ScheduledAt(now) {
+				continue
+			}
+			err := mr.Start(ctx, macro.Name, "schedule", "schedule")
+			if err != nil {
+				helper.Logger(ctx).Warn("scheduled macro skipped", "name", macro.Name, "error", err.Error())
+			}
+		}
+	}
+}
+
+// Handles '!macro [name]' - running a macro that permits chat (see [Macro.Chat]) - or, without a name, listing them.
+// Macros can only be run by chat command admins.
+func (ccs *ChatCommandService) handleMacro(ctx context.Context, message ChatMessage, args []string) (string, error) {
+	if !matchesPlayerId(ccs.Opts.Admins, message.PlatformId) {
+		return fmt.Sprintf("You aren't permitted to use %smacro", chatCommandPrefix), nil
+	}
+	if len(args) == 0 {
+		names := []string{}
+		for _, macro := range ccs.Opts.Macros.Macros {
+			if macro.Chat {
+				names = append(names, macro.Name)
+			}
+		}
+		return fmt.Sprintf("Usage: %smacro [%s]", chatCommandPrefix, strings.Join(names, "|")), nil
+	}
+	macro, ok := ccs.Opts.Macros.Find(args[0])
+	if !ok || !macro.Chat {
+		return fmt.Sprintf("Unknown macro %s", args[0]), nil
+	}
+	err := ccs.Opts.Macros.Start(ctx, macro.Name, message.Name, "chat")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Running macro %s", macro.Name), nil
+}
+
+// Handles 'GET /api/macros' - listing the defined macros
+func (aa *AdminApi) handleListMacros(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	if aa.Macros == nil {
+		writeJson(writer, http.StatusNotFound, map[string]any{"error": "macros are disabled (see MACROS_FILE)"})
+		return
+	}
+	writeJson(writer, http.StatusOK, aa.Macros.Macros)
+}
+
+// Handles 'POST /api/macros/{name}' - running a macro in the background
+func (aa *AdminApi) handleRunMacro(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	if aa.Macros == nil {
+		writeJson(writer, http.StatusNotFound, map[string]any{"error": "macros are disabled (see MACROS_FILE)"})
+		return
+	}
+	name := request.PathValue("name")
+	// the macro outlives the request
+	err := aa.Macros.Start(context.WithoutCancel(request.Context()), name, token.Name, "api")
+	if errors.Is(err, ErrMacroNotFound) {
+		writeJson(writer, http.StatusNotFound, map[string]any{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJson(writer, http.StatusConflict, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(writer, http.StatusAccepted, map[string]any{"accepted": true, "macro": name})
+}
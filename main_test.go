@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+	"github.com/benfiola/seven-days-to-die/internal/fakes"
+)
+
+// testCtx is a context equivalent to the one the entrypoint runs with (see [TestMain])
+var testCtx context.Context
+
+// Runs the tests within the helper's entrypoint - so that tests receive a context with a logger and the entrypoint's directories (located within a temporary directory)
+func TestMain(m *testing.M) {
+	flag.Parse()
+	root, err := os.MkdirTemp("", "sdtd-test-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	dirs := getDirs(root)
+	for _, dir := range dirs {
+		os.MkdirAll(dir, 0755)
+	}
+	// the file cache is enabled so that cached (and offline) installs can be tested - see [useFakeSquashfs]
+	os.Setenv("CACHE_ENABLED", "true")
+	// the helper runs its main callback when invoked as '[executable] entrypoint'
+	os.Args = []string{os.Args[0], "entrypoint"}
+	(&helper.Entrypoint{
+		Dirs: dirs,
+		Main: func(ctx context.Context) error {
+			testCtx = ctx
+			code := m.Run()
+			os.RemoveAll(root)
+			if code != 0 {
+				return fmt.Errorf("tests failed (exit code %d)", code)
+			}
+			return nil
+		},
+		Version: "test",
+	}).Run()
+}
+
+// Gets a context (derived from [testCtx]) cancelled once the test finishes
+func newTestContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithCancel(testCtx)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// Gets a free local tcp address (that nothing listens on)
+func getFreeAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// Points the server's telnet address (see [telnetAddr]) at an address for the duration of a test
+func setTelnetAddr(t *testing.T, addr string) {
+	previous := telnetAddr
+	telnetAddr = addr
+	t.Cleanup(func() {
+		telnetAddr = previous
+	})
+}
+
+// Starts a fake telnet console at the server's telnet address for the duration of a test
+func startFakeTelnet(t *testing.T) *fakes.TelnetServer {
+	t.Helper()
+	fake := fakes.NewTelnetServer()
+	addr, err := fake.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		fake.Close()
+	})
+	setTelnetAddr(t, addr)
+	return fake
+}
+
+// Starts a fake http server for the duration of a test.
+// Returns the server and its base url (e.g., 'http://127.0.0.1:1234').
+func startFakeHttp(t *testing.T) (*fakes.HttpServer, string) {
+	t.Helper()
+	fake := fakes.NewHttpServer()
+	addr, err := fake.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		fake.Close()
+	})
+	return fake, "http://" + addr
+}
+
+// Starts a [TelnetSession] connected to the fake telnet console (see [startFakeTelnet]) and waits for it to be ready.
+// Returns a context the session is attached to.
+func startTelnetSession(t *testing.T, ctx context.Context) (context.Context, *TelnetSession) {
+	t.Helper()
+	session := NewTelnetSession(telnetAddr)
+	go session.Run(ctx)
+	err := session.WaitReady(ctx, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return WithTelnetSession(ctx, session), session
+}
+
+// Waits for the fake telnet console to receive a command.
+// Fails the test if the command isn't received within the timeout.
+func waitForCommand(t *testing.T, fake *fakes.TelnetServer, command string, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if slices.Contains(fake.Commands(), command) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("command %s not received (received %v)", command, fake.Commands())
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// MaintenanceOpts defines the options used in conjunction with the [RunMaintenance] function
+type MaintenanceOpts struct {
+	LogMaxAge  time.Duration
+	TileMaxAge time.Duration
+}
+
+// cleanupRule selects files to be removed by a maintenance run
+type cleanupRule struct {
+	name string
+	// globs are patterns of candidate paths
+	globs []string
+	// remove determines whether a candidate path should be removed
+	remove func(path string, info os.FileInfo) bool
+}
+
+// Creates a removal check that removes paths last modified before a maximum age
+func olderThan(maxAge time.Duration) func(string, os.FileInfo) bool {
+	return func(path string, info os.FileInfo) bool {
+		return time.Since(info.ModTime()) > maxAge
+	}
+}
+
+// Gets the cleanup rules performed during maintenance
+func getCleanupRules(ctx context.Context, opts MaintenanceOpts) []cleanupRule {
+	data := helper.Dirs(ctx)["data"]
+	sdtd := helper.Dirs(ctx)["sdtd"]
+	saves := filepath.Join(data, "Saves", "*", "*")
+	return []cleanupRule{
+		{
+			name:   "unity temp files",
+			globs:  []string{filepath.Join(os.TempDir(), "Unity*"), filepath.Join(os.TempDir(), "*.tmp")},
+			remove: olderThan(24 * time.Hour),
+		},
+		{
+			name:   "old log files",
+			globs:  []string{filepath.Join(data, "*.log"), filepath.Join(data, "logs", "*"), filepath.Join(sdtd, "7DaysToDieServer_Data", "output_log*.txt"), filepath.Join(sdtd, "*.log")},
+			remove: olderThan(opts.LogMaxAge),
+		},
+		{
+			name:  "orphaned player map caches",
+			globs: []string{filepath.Join(saves, "Player", "*.map")},
+			remove: func(path string, info os.FileInfo) bool {
+				_, err := os.Lstat(strings.TrimSuffix(path, ".map") + ".ttp")
+				return errors.Is(err, os.ErrNotExist)
+			},
+		},
+		{
+			name:   "stale dashboard tiles",
+			globs:  []string{filepath.Join(saves, "map", "*", "*", "*"), filepath.Join(saves, "MapTiles", "*", "*", "*")},
+			remove: olderThan(opts.TileMaxAge),
+		},
+	}
+}
+
+// Performs a single maintenance run - removing files matched by the cleanup rules and logging the space reclaimed.
+// Files that cannot be removed are logged and otherwise ignored.
+// Returns the total number of bytes reclaimed.
+func RunMaintenance(ctx context.Context, opts MaintenanceOpts) int64 {
+	helper.Logger(ctx).Info("run maintenance")
+	total := int64(0)
+	for _, rule := range getCleanupRules(ctx, opts) {
+		count := 0
+		reclaimed := int64(0)
+		for _, glob := range rule.globs {
+			matches, _ := filepath.Glob(glob)
+			for _, match := range matches {
+				info, err := os.Lstat(match)
+				if err != nil || info.IsDir() || !rule.remove(match, info) {
+					continue
+				}
+				err = os.Remove(match)
+				if err != nil {
+					helper.Logger(ctx).Warn("maintenance remove failed", "path", match, "error", err.Error())
+					continue
+				}
+				count += 1
+				reclaimed += info.Size()
+			}
+		}
+		if count > 0 {
+			helper.Logger(ctx).Info("maintenance cleanup", "rule", rule.name, "files", count, "reclaimedBytes", reclaimed)
+		}
+		total += reclaimed
+	}
+	helper.Logger(ctx).Info("maintenance complete", "reclaimedBytes", total)
+	return total
+}
+
+// Periodically performs maintenance until the context is cancelled.
+func RunMaintenanceSchedule(ctx context.Context, interval time.Duration, opts MaintenanceOpts) {
+	for {
+		RunMaintenance(ctx, opts)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
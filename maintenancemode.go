@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// maintenanceBannerInterval is the interval at which the maintenance banner is broadcast
+const maintenanceBannerInterval = 5 * time.Minute
+
+// Gets the settings applied in maintenance mode - gating the server behind a temporary password and hiding it from the server browser.
+// If the password is empty, a random password is generated (and logged).
+func GetMaintenanceSettings(ctx context.Context, password string) ServerSettings {
+	if password == "" {
+		data := make([]byte, 6)
+		rand.Read(data)
+		password = hex.EncodeToString(data)
+		helper.Logger(ctx).Warn("generated maintenance password", "password", password)
+	}
+	return ServerSettings{
+		"ServerPassword":   password,
+		"ServerVisibility": "0",
+	}
+}
+
+// Periodically broadcasts the maintenance banner to players until the context is cancelled.
+func RunMaintenanceBanner(ctx context.Context, interval time.Duration, message string) {
+	for {
+		err := SayServer(ctx, message)
+		if err != nil {
+			helper.Logger(ctx).Warn("maintenance banner failed", "error", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
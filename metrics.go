@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// PerformanceStats are the performance statistics periodically logged by the server (e.g., 'INF Time: 60.02m FPS: 35.41 Heap: 1821.4MB Max: 2034.1MB Chunks: 412 CGO: 18 Ply: 3 Zom: 24 Ent: 31 (402) Items: 2 CO: 3 RSS: 4788.3MB')
+type PerformanceStats struct {
+	Fps      float64
+	HeapMb   float64
+	Players  int
+	Zombies  int
+	Entities int
+}
+
+// performanceStatsRegex matches the performance statistics periodically logged by the server
+var performanceStatsRegex = regexp.MustCompile(`INF Time: [\d.]+m FPS: ([\d.]+) Heap: ([\d.]+)MB .* Ply: (\d+) Zom: (\d+) Ent: (\d+)`)
+
+// Parses the performance statistics periodically logged by the server.
+// Returns nil if the line isn't a performance statistics line.
+func ParsePerformanceStats(line string) *PerformanceStats {
+	match := performanceStatsRegex.FindStringSubmatch(line)
+	if match == nil {
+		return nil
+	}
+	stats := PerformanceStats{}
+	stats.Fps, _ = strconv.ParseFloat(match[1], 64)
+	stats.HeapMb, _ = strconv.ParseFloat(match[2], 64)
+	stats.Players, _ = strconv.Atoi(match[3])
+	stats.Zombies, _ = strconv.Atoi(match[4])
+	stats.Entities, _ = strconv.Atoi(match[5])
+	return &stats
+}
+
+// MetricSample is a sample of the server's population and performance recorded by [MetricHistory]
+type MetricSample struct {
+	Time    time.Time `json:"time"`
+	Players int       `json:"players"`
+	// Fps, HeapMb, Zombies and Entities are nil if the server hasn't logged performance statistics since the previous sample
+	Fps      *float64 `json:"fps,omitempty"`
+	HeapMb   *float64 `json:"heapMb,omitempty"`
+	Zombies  *int     `json:"zombies,omitempty"`
+	Entities *int     `json:"entities,omitempty"`
+	// CpuPercent and RssMb are nil if the server process resource usage is unavailable (see [ProcessStats])
+	CpuPercent *float64 `json:"cpuPercent,omitempty"`
+	RssMb      *float64 `json:"rssMb,omitempty"`
+	// SaveAgeSeconds is nil if the world hasn't been saved by the entrypoint (see [SaveWorld])
+	SaveAgeSeconds *float64 `json:"saveAgeSeconds,omitempty"`
+	// DownloadedMb, ReceivedMb and SentMb are the month's bandwidth usage (see [BandwidthUsage]) - nil if bandwidth usage hasn't been recorded
+	DownloadedMb *float64 `json:"downloadedMb,omitempty"`
+	ReceivedMb   *float64 `json:"receivedMb,omitempty"`
+	SentMb       *float64 `json:"sentMb,omitempty"`
+}
+
+// metricValues extract the value of each metric (see [MetricHistory.Series]) from a sample - returning false if the sample lacks the metric
+var metricValues = map[string]func(sample MetricSample) (float64, bool){
+	"players": func(sample MetricSample) (float64, bool) {
+		return float64(sample.Players), true
+	},
+	"fps": func(sample MetricSample) (float64, bool) {
+		return derefMetric(sample.Fps)
+	},
+	"heap-mb": func(sample MetricSample) (float64, bool) {
+		return derefMetric(sample.HeapMb)
+	},
+	"zombies": func(sample MetricSample) (float64, bool) {
+		return derefMetric(sample.Zombies)
+	},
+	"entities": func(sample MetricSample) (float64, bool) {
+		return derefMetric(sample.Entities)
+	},
+	"cpu-percent": func(sample MetricSample) (float64, bool) {
+		return derefMetric(sample.CpuPercent)
+	},
+	"rss-mb": func(sample MetricSample) (float64, bool) {
+		return derefMetric(sample.RssMb)
+	},
+	"save-age-seconds": func(sample MetricSample) (float64, bool) {
+		return derefMetric(sample.SaveAgeSeconds)
+	},
+	"downloaded-mb": func(sample MetricSample) (float64, bool) {
+		return derefMetric(sample.DownloadedMb)
+	},
+	"received-mb": func(sample MetricSample) (float64, bool) {
+		return derefMetric(sample.ReceivedMb)
+	},
+	"sent-mb": func(sample MetricSample) (float64, bool) {
+		return derefMetric(sample.SentMb)
+	},
+}
+
+// Dereferences an optional metric value
+func derefMetric[T int | float64](value *T) (float64, bool) {
+	if value == nil {
+		return 0, false
+	}
+	return float64(*value), true
+}
+
+// Gets the names of the metrics recorded by [MetricHistory] (sorted)
+func getMetricNames() []string {
+	names := []string{}
+	for name := range metricValues {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// MetricHistoryOpts are options for [MetricHistory]
+type MetricHistoryOpts struct {
+	Interval time.Duration
+	// Retention is how long samples are kept
+	Retention time.Duration
+}
+
+// MetricHistory records samples of the server's population and performance ('[data]/metrics.json') - served to dashboards by the admin api (see [AdminApi.handleGrafanaQuery]).
+// Player counts are taken from the [StatusTracker], frame rate and entity counts from the performance statistics periodically logged by the server (see [ParsePerformanceStats]) and resource usage from the [ProcessSampler].
+type MetricHistory struct {
+	Opts    MetricHistoryOpts
+	file    string
+	lock    sync.Mutex
+	latest  *PerformanceStats
+	samples []MetricSample
+}
+
+// Creates a new [MetricHistory] - loading previously recorded samples (if any).
+// Returns an error if previously recorded samples cannot be read.
+func NewMetricHistory(ctx context.Context, opts MetricHistoryOpts) (*MetricHistory, error) {
+	mh := MetricHistory{Opts: opts, file: filepath.Join(helper.Dirs(ctx)["data"], "metrics.json"), samples: []MetricSample{}}
+	err := helper.UnmarshalFile(ctx, mh.file, &mh.samples)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	mh.expire()
+	return &mh, nil
+}
+
+// Deletes samples older than [MetricHistoryOpts.Retention]
+func (mh *MetricHistory) expire() {
+	cutoff := time.Now().UTC().Add(-mh.Opts.Retention)
+	mh.samples = slices.DeleteFunc(mh.samples, func(sample MetricSample) bool {
+		return sample.Time.Before(cutoff)
+	})
+}
+
+// Records a sample of the server's current population and performance
+func (mh *MetricHistory) sample(ctx context.Context) {
+	sample := MetricSample{Time: time.Now().UTC()}
+	tracker := GetStatusTracker(ctx)
+	if tracker != nil {
+		status := tracker.Get()
+		if status.State != ServerStateReady {
+			return
+		}
+		sample.Players = status.Players
+		if status.Process != nil {
+			rssMb := float64(status.Process.Rss) / 1000 / 1000
+			sample.CpuPercent = &status.Process.CpuPercent
+			sample.RssMb = &rssMb
+		}
+		if status.LastSave != nil {
+			saveAge := sample.Time.Sub(*status.LastSave).Seconds()
+			sample.SaveAgeSeconds = &saveAge
+		}
+		if status.Bandwidth != nil {
+			downloadedMb := float64(status.Bandwidth.DepotBytes+status.Bandwidth.DownloadBytes) / 1000 / 1000
+			receivedMb := float64(status.Bandwidth.ReceivedBytes) / 1000 / 1000
+			sentMb := float64(status.Bandwidth.SentBytes) / 1000 / 1000
+			sample.DownloadedMb = &downloadedMb
+			sample.ReceivedMb = &receivedMb
+			sample.SentMb = &sentMb
+		}
+	}
+	mh.lock.Lock()
+	defer mh.lock.Unlock()
+	if mh.latest != nil {
+		sample.Fps = &mh.latest.Fps
+		sample.HeapMb = &mh.latest.HeapMb
+		sample.Zombies = &mh.latest.Zombies
+		sample.Entities = &mh.latest.Entities
+		mh.latest = nil
+	}
+	mh.samples = append(mh.samples, sample)
+	mh.expire()
+}
+
+// Writes the recorded samples atomically.
+// Returns an error if the samples cannot be written.
+func (mh *MetricHistory) save() error {
+	mh.lock.Lock()
+	data, err := json.Marshal(mh.samples)
+	mh.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(mh.file+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(mh.file+".tmp", mh.file)
+}
+
+// Records samples (every [MetricHistoryOpts.Interval], while the server is ready) until the context is cancelled.
+// Returns an error if the telnet session is unavailable.
+func (mh *MetricHistory) Run(ctx context.Context) error {
+	session := GetTelnetSession(ctx)
+	if session == nil {
+		return ErrTelnetNotConnected
+	}
+	lines, unsubscribe := session.Subscribe()
+	defer unsubscribe()
+	ticker := time.NewTicker(mh.Opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			mh.sample(ctx)
+			err := mh.save()
+			if err != nil {
+				helper.Logger(ctx).Warn("write metrics failed", "error", err.Error())
+			}
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			stats := ParsePerformanceStats(line)
+			if stats != nil {
+				mh.lock.Lock()
+				mh.latest = stats
+				mh.lock.Unlock()
+			}
+		}
+	}
+}
+
+// Gets the values of a metric between [from] and [to] as '[value, unix milliseconds]' pairs (the grafana json datasource's format).
+// Samples are averaged into buckets when there are more than [maxPoints] samples (if positive).
+func (mh *MetricHistory) Series(name string, from time.Time, to time.Time, maxPoints int) [][2]float64 {
+	value := metricValues[name]
+	points := [][2]float64{}
+	if value == nil {
+		return points
+	}
+	mh.lock.Lock()
+	defer mh.lock.Unlock()
+	for _, sample := range mh.samples {
+		if sample.Time.Before(from) || sample.Time.After(to) {
+			continue
+		}
+		current, ok := value(sample)
+		if !ok {
+			continue
+		}
+		points = append(points, [2]float64{current, float64(sample.Time.UnixMilli())})
+	}
+	if maxPoints <= 0 || len(points) <= maxPoints {
+		return points
+	}
+	size := (len(points) + maxPoints - 1) / maxPoints
+	buckets := [][2]float64{}
+	for start := 0; start < len(points); start += size {
+		bucket := points[start:min(start+size, len(points))]
+		total := 0.0
+		for _, point := range bucket {
+			total += point[0]
+		}
+		buckets = append(buckets, [2]float64{total / float64(len(bucket)), bucket[len(bucket)-1][1]})
+	}
+	return buckets
+}
+
+// Handles 'GET /grafana' - the grafana json datasource's connection test
+func (aa *AdminApi) handleGrafanaTest(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	if aa.Metrics == nil {
+		writeJson(writer, http.StatusNotFound, map[string]any{"error": "metrics history is disabled (see METRICS_HISTORY)"})
+		return
+	}
+	writeJson(writer, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// Handles 'POST /grafana/search' (and 'POST /grafana/metrics') - listing the metrics available to the grafana json datasource
+func (aa *AdminApi) handleGrafanaSearch(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	names := getMetricNames()
+	if request.URL.Path == "/grafana/search" {
+		writeJson(writer, http.StatusOK, names)
+		return
+	}
+	metrics := []map[string]string{}
+	for _, name := range names {
+		metrics = append(metrics, map[string]string{"label": name, "value": name})
+	}
+	writeJson(writer, http.StatusOK, metrics)
+}
+
+// Handles 'POST /grafana/query' - returning time series of the requested metrics to the grafana json datasource
+func (aa *AdminApi) handleGrafanaQuery(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	if aa.Metrics == nil {
+		writeJson(writer, http.StatusNotFound, map[string]any{"error": "metrics history is disabled (see METRICS_HISTORY)"})
+		return
+	}
+	body := struct {
+		Range struct {
+			From time.Time `json:"from"`
+			To   time.Time `json:"to"`
+		} `json:"range"`
+		MaxDataPoints int `json:"maxDataPoints"`
+		Targets       []struct {
+			Target string `json:"target"`
+		} `json:"targets"`
+	}{}
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		writeJson(writer, http.StatusBadRequest, map[string]any{"error": "request body must be a grafana json datasource query"})
+		return
+	}
+	if body.Range.To.IsZero() {
+		body.Range.To = time.Now().UTC()
+	}
+	series := []map[string]any{}
+	for _, target := range body.Targets {
+		_, ok := metricValues[target.Target]
+		if !ok {
+			writeJson(writer, http.StatusBadRequest, map[string]any{"error": "unknown metric " + target.Target, "metrics": getMetricNames()})
+			return
+		}
+		series = append(series, map[string]any{"target": target.Target, "datapoints": aa.Metrics.Series(target.Target, body.Range.From, body.Range.To, body.MaxDataPoints)})
+	}
+	writeJson(writer, http.StatusOK, series)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// CurrentConfigVersion is the version of the entrypoint's configuration layout (see [configMigrations])
+const CurrentConfigVersion = 1
+
+// ConfigMigration upgrades configuration written for an older configuration layout
+type ConfigMigration struct {
+	// Version is the configuration version the migration upgrades to
+	Version int
+	// Renames maps renamed environment variables (old to new)
+	Renames map[string]string
+}
+
+// configMigrations are the migrations (in order) applied to configuration older than [CurrentConfigVersion] - renames are added here (alongside a bump of [CurrentConfigVersion]) rather than breaking existing configuration
+var configMigrations = []ConfigMigration{}
+
+// Migrates environment variables written for an older configuration layout (identified by CONFIG_VERSION - defaulting to 1) to the current layout - logging a deprecation warning for each migrated variable.
+// Migrated variables are replaced within the process environment (so that they're parsed by [LoadEntrypointConfig]) - variables already set under their new name take precedence.
+// Old variables set alongside a CONFIG_VERSION that no longer supports them are logged (rather than silently ignored).
+// Returns an error if CONFIG_VERSION is invalid or newer than [CurrentConfigVersion].
+func MigrateConfigEnv(ctx context.Context) error {
+	version := 1
+	value := os.Getenv("CONFIG_VERSION")
+	if value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return fmt.Errorf("CONFIG_VERSION must be a positive integer (got '%s')", value)
+		}
+		version = parsed
+	}
+	if version > CurrentConfigVersion {
+		return fmt.Errorf("CONFIG_VERSION %d is newer than the latest supported version (%d) - upgrade the image", version, CurrentConfigVersion)
+	}
+	migrated := false
+	for _, migration := range configMigrations {
+		for from, to := range migration.Renames {
+			value, ok := os.LookupEnv(from)
+			if !ok {
+				continue
+			}
+			if migration.Version <= version {
+				// the configuration claims to use a layout in which the variable no longer exists
+				helper.Logger(ctx).Warn("removed env var ignored", "env", from, "replacement", to, "version", migration.Version)
+				continue
+			}
+			migrated = true
+			os.Unsetenv(from)
+			_, ok = os.LookupEnv(to)
+			if ok {
+				helper.Logger(ctx).Warn("deprecated env var ignored", "env", from, "replacement", to, "version", migration.Version)
+				continue
+			}
+			helper.Logger(ctx).Warn("deprecated env var migrated", "env", from, "replacement", to, "version", migration.Version)
+			os.Setenv(to, value)
+		}
+	}
+	if migrated {
+		helper.Logger(ctx).Warn("configuration uses an older layout - update it and set CONFIG_VERSION", "version", version, "current", CurrentConfigVersion)
+	}
+	return nil
+}
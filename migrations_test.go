@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// Replaces [configMigrations] for the duration of a test (no migrations ship yet)
+func setConfigMigrations(t *testing.T, migrations []ConfigMigration) {
+	t.Helper()
+	previous := configMigrations
+	configMigrations = migrations
+	t.Cleanup(func() {
+		configMigrations = previous
+	})
+}
+
+func TestMigrateConfigEnvRenamesOldVariables(t *testing.T) {
+	ctx := newTestContext(t)
+	setConfigMigrations(t, []ConfigMigration{{Version: 2, Renames: map[string]string{"TEST_OLD_NAME": "TEST_NEW_NAME"}}})
+	t.Setenv("CONFIG_VERSION", "")
+	t.Setenv("TEST_OLD_NAME", "value")
+	t.Setenv("TEST_NEW_NAME", "")
+	os.Unsetenv("TEST_NEW_NAME")
+
+	err := MigrateConfigEnv(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok := os.LookupEnv("TEST_OLD_NAME")
+	if ok {
+		t.Error("expected TEST_OLD_NAME to be unset")
+	}
+	if os.Getenv("TEST_NEW_NAME") != "value" {
+		t.Errorf("expected TEST_NEW_NAME to be migrated (got '%s')", os.Getenv("TEST_NEW_NAME"))
+	}
+}
+
+func TestMigrateConfigEnvPrefersNewVariables(t *testing.T) {
+	ctx := newTestContext(t)
+	setConfigMigrations(t, []ConfigMigration{{Version: 2, Renames: map[string]string{"TEST_OLD_NAME": "TEST_NEW_NAME"}}})
+	t.Setenv("CONFIG_VERSION", "1")
+	t.Setenv("TEST_OLD_NAME", "old")
+	t.Setenv("TEST_NEW_NAME", "new")
+
+	err := MigrateConfigEnv(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("TEST_NEW_NAME") != "new" {
+		t.Errorf("expected TEST_NEW_NAME to be kept (got '%s')", os.Getenv("TEST_NEW_NAME"))
+	}
+}
+
+func TestMigrateConfigEnvRejectsNewerVersions(t *testing.T) {
+	ctx := newTestContext(t)
+	for _, value := range []string{"0", "abc", "2"} {
+		t.Setenv("CONFIG_VERSION", value)
+		err := MigrateConfigEnv(ctx)
+		if err == nil {
+			t.Errorf("expected an error for CONFIG_VERSION '%s'", value)
+		}
+	}
+}
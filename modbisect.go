@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// ModBisection records the bisection of the mod folders changed by a mod set that failed readiness (see [ModRollback]).
+// Each step starts the server with the known-good mods plus half of the suspects - a step that fails readiness narrows the suspects to the tested half, a step that becomes ready narrows them to the untested half.
+// Bisection assumes that a single mod folder causes the failure.
+type ModBisection struct {
+	// Failed is the fingerprint of the mod set being bisected
+	Failed string `json:"failed"`
+	// Snapshot is the mod set being bisected - its mod folders are installed over the known-good mods
+	Snapshot ModsSnapshot `json:"snapshot"`
+	// Suspects are the mod folders that may cause the failure
+	Suspects []string `json:"suspects"`
+	// Testing are the suspects installed by the current step
+	Testing   []string  `json:"testing"`
+	Steps     int       `json:"steps"`
+	StartedAt time.Time `json:"startedAt"`
+	// Culprit is the mod folder identified as causing the failure - set once the bisection completes
+	Culprit     string     `json:"culprit,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// Creates a [ModBisection] of the mod folders that [failed] changed relative to [good].
+// If a single mod folder changed, it's identified as the culprit without bisecting.
+func NewModBisection(good ModsSnapshot, failed ModsSnapshot) *ModBisection {
+	mb := &ModBisection{Failed: failed.Fingerprint(), Snapshot: failed, Suspects: failed.Changed(good), StartedAt: time.Now().UTC()}
+	mb.next()
+	return mb
+}
+
+// Selects the suspects tested by the next step - completing the bisection once a single suspect remains
+func (mb *ModBisection) next() {
+	if len(mb.Suspects) <= 1 {
+		now := time.Now().UTC()
+		mb.Culprit = strings.Join(mb.Suspects, "")
+		mb.CompletedAt = &now
+		mb.Testing = []string{}
+		return
+	}
+	mb.Testing = slices.Clone(mb.Suspects[:len(mb.Suspects)/2])
+}
+
+// Records the outcome of the current step and selects the next step
+func (mb *ModBisection) Advance(ready bool) {
+	mb.Steps += 1
+	if ready {
+		mb.Suspects = slices.DeleteFunc(mb.Suspects, func(mod string) bool {
+			return slices.Contains(mb.Testing, mod)
+		})
+	} else {
+		mb.Suspects = mb.Testing
+	}
+	mb.next()
+}
+
+// Builds the mod set tested by the current step - the known-good mods with the tested mod folders replaced by those of the bisected mod set
+func (mb *ModBisection) TestSnapshot(good ModsSnapshot) ModsSnapshot {
+	tested := func(relpath string) bool {
+		mod, _, _ := strings.Cut(relpath, "/")
+		return slices.Contains(mb.Testing, mod)
+	}
+	snapshot := ModsSnapshot{Files: map[string]string{}, CreatedAt: time.Now().UTC()}
+	for relpath, checksum := range good.Files {
+		if !tested(relpath) {
+			snapshot.Files[relpath] = checksum
+		}
+	}
+	for relpath, checksum := range mb.Snapshot.Files {
+		if tested(relpath) {
+			snapshot.Files[relpath] = checksum
+		}
+	}
+	return snapshot
+}
+
+// Logs the bisection's progress (or its result, once complete)
+func (mb *ModBisection) log(ctx context.Context) {
+	if mb.CompletedAt != nil {
+		helper.Logger(ctx).Error("mod bisection identified the mod folder failing readiness - remove it from MOD_URLS (or ROOT_URLS) and run 'entrypoint mods retry'", "mod", mb.Culprit, "steps", mb.Steps)
+		return
+	}
+	helper.Logger(ctx).Warn("mod bisection step", "step", mb.Steps+1, "testing", mb.Testing, "suspects", mb.Suspects)
+}
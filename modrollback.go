@@ -0,0 +1,531 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// ModsSnapshot describes the state of the Mods directory (see [SnapshotMods])
+type ModsSnapshot struct {
+	// Files maps file paths (relative to the Mods directory) to their sha256 checksums
+	Files     map[string]string `json:"files"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// Computes a fingerprint identifying the snapshot's mod set - snapshots with the same files (and contents) share a fingerprint
+func (ms ModsSnapshot) Fingerprint() string {
+	relpaths := []string{}
+	for relpath := range ms.Files {
+		relpaths = append(relpaths, relpath)
+	}
+	slices.Sort(relpaths)
+	hash := sha256.New()
+	for _, relpath := range relpaths {
+		fmt.Fprintf(hash, "%s\x00%s\n", relpath, ms.Files[relpath])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Gets the names of the mod folders (the top-level folders of the Mods directory) that were added, changed or removed relative to [base]
+func (ms ModsSnapshot) Changed(base ModsSnapshot) []string {
+	changed := []string{}
+	for _, snapshots := range [][2]ModsSnapshot{{ms, base}, {base, ms}} {
+		for relpath, checksum := range snapshots[0].Files {
+			if snapshots[1].Files[relpath] == checksum {
+				continue
+			}
+			mod, _, _ := strings.Cut(relpath, "/")
+			if !slices.Contains(changed, mod) {
+				changed = append(changed, mod)
+			}
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// ModRollbackState records the mod sets known to the rollback (see [ModRollback])
+type ModRollbackState struct {
+	// Good is the mod set that last reached readiness - and which is restored when a changed mod set fails readiness
+	Good *ModsSnapshot `json:"good"`
+	// Previous is the known-good mod set replaced by [ModRollbackState.Good] - so that a mod change that reached readiness can still be rolled back manually
+	Previous *ModsSnapshot `json:"previous"`
+	// Failed maps the fingerprints of mod sets that failed readiness to when they failed
+	Failed map[string]time.Time `json:"failed"`
+	// Bisection is the bisection of the mod set that last failed readiness (see [ModRollbackOpts.Bisect])
+	Bisection *ModBisection `json:"bisection,omitempty"`
+}
+
+// Gets the path of the directory holding mod rollback state and content-addressed copies of known-good mod files
+func getModRollbackDir(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "mod-rollback")
+}
+
+// Gets the path of the file recording the mod rollback state
+func getModRollbackStateFile(ctx context.Context) string {
+	return filepath.Join(getModRollbackDir(ctx), "state.json")
+}
+
+// Gets the path of a content-addressed copy of a mod file
+func getModRollbackObject(ctx context.Context, checksum string) string {
+	return filepath.Join(getModRollbackDir(ctx), "objects", checksum[:2], checksum)
+}
+
+// Gets the path of the Mods directory
+func getModsDir(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["sdtd"], "Mods")
+}
+
+// Loads the mod rollback state.
+// Returns an empty state if none has been recorded.
+// Returns an error if the state file is unreadable.
+func LoadModRollbackState(ctx context.Context) (*ModRollbackState, error) {
+	state := ModRollbackState{}
+	err := helper.UnmarshalFile(ctx, getModRollbackStateFile(ctx), &state)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if state.Failed == nil {
+		state.Failed = map[string]time.Time{}
+	}
+	return &state, nil
+}
+
+// Writes the mod rollback state.
+// Returns an error if the state file cannot be written.
+func SaveModRollbackState(ctx context.Context, state *ModRollbackState) error {
+	err := helper.CreateDirs(ctx, getModRollbackDir(ctx))
+	if err != nil {
+		return err
+	}
+	return helper.MarshalFile(ctx, state, getModRollbackStateFile(ctx))
+}
+
+// Copies a file to [dest] atomically (so that an interrupted copy never leaves a partial file behind) - streaming the file, and syncing the copy before it's renamed into place.
+// Returns an error if the file cannot be copied.
+func copyFileAtomic(path string, dest string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	err = os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return err
+	}
+	handle, err := os.Create(dest + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	_, err = io.Copy(handle, source)
+	if err == nil {
+		err = handle.Sync()
+	}
+	if err == nil {
+		err = handle.Close()
+	}
+	if err != nil {
+		os.Remove(dest + ".tmp")
+		return err
+	}
+	return os.Rename(dest+".tmp", dest)
+}
+
+// Records the state (file list and checksums) of the Mods directory.
+// If [store] is set, a content-addressed copy of each file is kept (see [getModRollbackObject]) - so that the mod set can later be restored.
+// Returns an error if the Mods directory cannot be read or files cannot be copied.
+func SnapshotMods(ctx context.Context, store bool) (ModsSnapshot, error) {
+	snapshot := ModsSnapshot{Files: map[string]string{}, CreatedAt: time.Now().UTC()}
+	modsDir := getModsDir(ctx)
+	err := filepath.WalkDir(modsDir, func(path string, entry os.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) && path == modsDir {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relpath, err := filepath.Rel(modsDir, path)
+		if err != nil {
+			return err
+		}
+		checksum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		snapshot.Files[filepath.ToSlash(relpath)] = checksum
+		if !store {
+			return nil
+		}
+		object := getModRollbackObject(ctx, checksum)
+		_, err = os.Lstat(object)
+		if errors.Is(err, os.ErrNotExist) {
+			err = copyFileAtomic(path, object)
+		}
+		return err
+	})
+	if err != nil {
+		return ModsSnapshot{}, fmt.Errorf("snapshot mods: %w", err)
+	}
+	return snapshot, nil
+}
+
+// Replaces the contents of the Mods directory with a snapshot's files (see [SnapshotMods]).
+// Returns an error if a file's copy is missing or the Mods directory cannot be written.
+func RestoreMods(ctx context.Context, snapshot ModsSnapshot) error {
+	fail := func(err error) error {
+		return fmt.Errorf("restore mods: %w", err)
+	}
+	for relpath, checksum := range snapshot.Files {
+		if !filepath.IsLocal(relpath) {
+			return fail(fmt.Errorf("invalid path %s", relpath))
+		}
+		_, err := os.Lstat(getModRollbackObject(ctx, checksum))
+		if err != nil {
+			return fail(err)
+		}
+	}
+	modsDir := getModsDir(ctx)
+	helper.Logger(ctx).Info("restore mods", "path", modsDir, "files", len(snapshot.Files))
+	err := helper.RemovePaths(ctx, modsDir)
+	if err == nil {
+		err = helper.CreateDirs(ctx, modsDir)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	for relpath, checksum := range snapshot.Files {
+		err := copyFileAtomic(getModRollbackObject(ctx, checksum), filepath.Join(modsDir, filepath.FromSlash(relpath)))
+		if err != nil {
+			return fail(err)
+		}
+	}
+	return nil
+}
+
+// Deletes content-addressed mod file copies that aren't referenced by any of the given snapshots.
+// Returns an error if the objects directory cannot be walked or files cannot be deleted.
+func pruneModRollbackObjects(ctx context.Context, snapshots ...*ModsSnapshot) error {
+	referenced := map[string]bool{}
+	for _, snapshot := range snapshots {
+		if snapshot == nil {
+			continue
+		}
+		for _, checksum := range snapshot.Files {
+			referenced[checksum] = true
+		}
+	}
+	objectsDir := filepath.Join(getModRollbackDir(ctx), "objects")
+	return filepath.WalkDir(objectsDir, func(path string, entry os.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) && path == objectsDir {
+			return filepath.SkipAll
+		}
+		if err != nil || entry.IsDir() || referenced[entry.Name()] {
+			return err
+		}
+		return os.Remove(path)
+	})
+}
+
+// ModRollback restores the last known-good mod set when the server fails readiness after a mod change - automatically recovering from broken mod updates.
+// The Mods directory is snapshotted before mods are installed ([PrepareModRollback]) and compared with the known-good mod set afterwards ([ModRollback.Check]).
+// If a changed mod set fails readiness ([ModRollback.Watch]), it's recorded as failed and the server is stopped - and the known-good mod set is restored in its place whenever it's installed again.
+// Optionally, the failed mod set is bisected across restarts to identify the mod folder causing the failure (see [ModBisection]).
+type ModRollback struct {
+	Opts      ModRollbackOpts
+	bisecting bool
+	changed   bool
+	current   ModsSnapshot
+	lock      sync.Mutex
+	outcome   string
+	previous  ModsSnapshot
+	state     *ModRollbackState
+}
+
+// ModRollbackOpts are options for [ModRollback]
+type ModRollbackOpts struct {
+	// Bisect enables bisecting the mod folders changed by a mod set that failed readiness across restarts (see [ModBisection]) - identifying the mod folder causing the failure
+	Bisect bool
+}
+
+// Creates a [ModRollback] - snapshotting the Mods directory before mods are installed.
+// The snapshot becomes the known-good mod set if none has been recorded yet.
+// Returns an error if the rollback state cannot be read or the Mods directory cannot be snapshotted.
+func PrepareModRollback(ctx context.Context, opts ModRollbackOpts) (*ModRollback, error) {
+	state, err := LoadModRollbackState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := SnapshotMods(ctx, state.Good == nil)
+	if err != nil {
+		return nil, err
+	}
+	if state.Good == nil {
+		helper.Logger(ctx).Info("record baseline mod set", "files", len(previous.Files))
+		state.Good = &previous
+		err = SaveModRollbackState(ctx, state)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &ModRollback{Opts: opts, previous: previous, state: state}, nil
+}
+
+// Compares the installed mods with the known-good mod set.
+// If the installed mod set previously failed readiness, the known-good mod set is restored in its place - or, while the mod set is being bisected, the mod set tested by the current bisection step.
+// Returns an error if the Mods directory cannot be snapshotted or restored.
+func (mr *ModRollback) Check(ctx context.Context) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+	current, err := SnapshotMods(ctx, true)
+	if err != nil {
+		return err
+	}
+	good := *mr.state.Good
+	fingerprint := current.Fingerprint()
+	failedAt, failed := mr.state.Failed[fingerprint]
+	bisection := mr.state.Bisection
+	switch {
+	case fingerprint == good.Fingerprint():
+		mr.current = current
+	case failed && mr.Opts.Bisect && bisection != nil && bisection.Failed == fingerprint && bisection.CompletedAt == nil:
+		bisection.log(ctx)
+		tested := bisection.TestSnapshot(good)
+		err = RestoreMods(ctx, tested)
+		if err != nil {
+			return err
+		}
+		mr.current = tested
+		mr.bisecting = true
+	case failed:
+		if bisection != nil && bisection.Failed == fingerprint {
+			bisection.log(ctx)
+		}
+		helper.Logger(ctx).Warn("installed mods previously failed readiness - restoring known-good mods", "mods", current.Changed(good), "failed-at", failedAt)
+		err = RestoreMods(ctx, good)
+		if err != nil {
+			return err
+		}
+		mr.current = good
+	default:
+		helper.Logger(ctx).Info("mod change detected - known-good mods are restored if the server fails readiness", "mods", current.Changed(good))
+		mr.current = current
+		mr.changed = true
+	}
+	return nil
+}
+
+// Records the outcome of the current start - a ready mod set becomes the known-good mod set, a changed mod set that failed readiness is recorded as failed (and, if enabled, bisected).
+// While bisecting, the outcome instead advances the bisection.
+// Only the first outcome is recorded.
+// Returns whether the outcome was recorded.
+// Returns an error if the rollback state cannot be written.
+func (mr *ModRollback) record(ctx context.Context, ready bool) (bool, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+	if mr.outcome != "" {
+		return false, nil
+	}
+	fingerprint := mr.current.Fingerprint()
+	if mr.bisecting {
+		mr.outcome = "failed"
+		if ready {
+			mr.outcome = "ready"
+		}
+		mr.state.Bisection.Advance(ready)
+		if mr.state.Bisection.CompletedAt != nil {
+			mr.state.Bisection.log(ctx)
+		}
+	} else if ready {
+		mr.outcome = "ready"
+		if mr.changed {
+			mr.state.Previous = mr.state.Good
+		}
+		mr.state.Good = &mr.current
+		delete(mr.state.Failed, fingerprint)
+	} else {
+		mr.outcome = "failed"
+		if !mr.changed {
+			// failures are only attributed to mods when the mod set changed
+			return false, nil
+		}
+		helper.Logger(ctx).Error("server failed readiness after a mod change - known-good mods will be restored on restart", "mods", mr.current.Changed(*mr.state.Good))
+		mr.state.Failed[fingerprint] = time.Now().UTC()
+		if mr.Opts.Bisect {
+			mr.state.Bisection = NewModBisection(*mr.state.Good, mr.current)
+			helper.Logger(ctx).Warn("mod bisection started", "suspects", mr.state.Bisection.Suspects)
+			if mr.state.Bisection.CompletedAt != nil {
+				mr.state.Bisection.log(ctx)
+			}
+		}
+	}
+	err := SaveModRollbackState(ctx, mr.state)
+	if err != nil {
+		return true, err
+	}
+	var bisected *ModsSnapshot
+	if mr.state.Bisection != nil && mr.state.Bisection.CompletedAt == nil {
+		bisected = &mr.state.Bisection.Snapshot
+	}
+	err = pruneModRollbackObjects(ctx, mr.state.Good, mr.state.Previous, bisected)
+	if err != nil {
+		helper.Logger(ctx).Warn("prune mod rollback files failed", "error", err.Error())
+	}
+	return true, nil
+}
+
+// Waits for the server to become ready - recording the outcome (see [ModRollback.record]).
+// If a changed mod set fails readiness (or a bisection step completes), the server is shut down (or, if it doesn't accept the shutdown, killed with [kill]) so that the known-good mods (or the next bisection step) are installed on restart.
+func (mr *ModRollback) Watch(ctx context.Context, timeout time.Duration, kill func()) {
+	err := GetTelnetSession(ctx).WaitReady(ctx, timeout)
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	recorded, recordErr := mr.record(ctx, err == nil)
+	if recordErr != nil {
+		helper.Logger(ctx).Warn("record mod rollback state failed", "error", recordErr.Error())
+	}
+	if !recorded || (err == nil && !mr.bisecting) {
+		return
+	}
+	err = ShutdownServer(ctx)
+	if err != nil {
+		helper.Logger(ctx).Warn("shutdown failed - killing server", "error", err.Error())
+		kill()
+	}
+}
+
+// Records the outcome of a server process that exited before becoming ready (e.g., a crash while loading mods) - unless the server was deliberately shut down.
+// Returns an error if a changed mod set failed readiness or a bisection step completed (so that the entrypoint exits with a failure and is restarted).
+func (mr *ModRollback) Finish(ctx context.Context) error {
+	tracker := GetStatusTracker(ctx)
+	if tracker != nil && tracker.Get().State == ServerStateShuttingDown && mr.Outcome() == "" {
+		return nil
+	}
+	_, err := mr.record(ctx, false)
+	if err != nil {
+		return err
+	}
+	if mr.bisecting && mr.Outcome() != "" {
+		return fmt.Errorf("mod bisection step complete")
+	}
+	if mr.Outcome() == "failed" && mr.changed {
+		return fmt.Errorf("server failed readiness after a mod change")
+	}
+	return nil
+}
+
+// Gets the recorded outcome of the current start ('ready', 'failed' or empty if none has been recorded)
+func (mr *ModRollback) Outcome() string {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+	return mr.outcome
+}
+
+// Marks the installed mod set as failed - so that the known-good mod set is restored when the server next starts.
+// If the installed mods are the known-good mod set, the previous known-good mod set is restored instead.
+// Returns the mod folders that will be restored.
+// Returns an error if no (previous) known-good mod set has been recorded.
+// Returns an error if the Mods directory cannot be snapshotted or the rollback state cannot be written.
+func RequestModRollback(ctx context.Context) ([]string, error) {
+	fail := func(err error) ([]string, error) {
+		return nil, err
+	}
+	state, err := LoadModRollbackState(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	if state.Good == nil {
+		return fail(fmt.Errorf("no known-good mods recorded (is MODS_ROLLBACK enabled?)"))
+	}
+	current, err := SnapshotMods(ctx, false)
+	if err != nil {
+		return fail(err)
+	}
+	fingerprint := current.Fingerprint()
+	if fingerprint == state.Good.Fingerprint() {
+		if state.Previous == nil {
+			return fail(fmt.Errorf("installed mods are the known-good mods - and no previous mods are recorded"))
+		}
+		state.Good, state.Previous = state.Previous, nil
+	}
+	state.Failed[fingerprint] = time.Now().UTC()
+	err = SaveModRollbackState(ctx, state)
+	if err != nil {
+		return fail(err)
+	}
+	return current.Changed(*state.Good), nil
+}
+
+// Manages mod rollbacks from the command line:
+//   - 'mods rollback' restores the known-good mods - stopping the server so that they're restored on the next start
+//   - 'mods retry' forgets mod sets that failed readiness (and their bisection) - so that the configured mods are installed again on the next start
+//   - 'mods status' prints the known-good mods, failed mod sets and bisection progress
+//
+// Returns an error if the arguments are invalid.
+// Returns an error if the operation fails.
+func ModsSubcommand(ctx context.Context) error {
+	usage := fmt.Errorf("usage: %s mods [rollback | retry | status]", filepath.Base(os.Args[0]))
+	args := os.Args[2:]
+	if len(args) != 1 {
+		return usage
+	}
+	switch args[0] {
+	case "rollback":
+		mods, err := RequestModRollback(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mod := range mods {
+			fmt.Println(mod)
+		}
+		err = ShutdownServer(ctx)
+		if err != nil {
+			helper.Logger(ctx).Info("server not running - known-good mods will be restored on next start")
+		}
+		return nil
+	case "retry":
+		state, err := LoadModRollbackState(ctx)
+		if err != nil {
+			return err
+		}
+		helper.Logger(ctx).Info("forget failed mod sets", "count", len(state.Failed))
+		state.Failed = map[string]time.Time{}
+		state.Bisection = nil
+		return SaveModRollbackState(ctx, state)
+	case "status":
+		state, err := LoadModRollbackState(ctx)
+		if err != nil {
+			return err
+		}
+		for index, snapshot := range []*ModsSnapshot{state.Good, state.Previous} {
+			if snapshot != nil {
+				fmt.Printf("%s\t%s\t%d\t%s\n", []string{"good", "previous"}[index], snapshot.Fingerprint(), len(snapshot.Files), snapshot.CreatedAt.Format(time.RFC3339))
+			}
+		}
+		for fingerprint, failedAt := range state.Failed {
+			fmt.Printf("failed\t%s\t%s\n", fingerprint, failedAt.Format(time.RFC3339))
+		}
+		bisection := state.Bisection
+		if bisection != nil {
+			fmt.Printf("bisection\t%s\t%d\t%s\t%s\n", bisection.Failed, bisection.Steps, strings.Join(bisection.Suspects, ","), bisection.Culprit)
+		}
+		return nil
+	}
+	return usage
+}
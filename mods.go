@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// ModPolicy restricts the mod urls accepted by [InstallMods].
+// Empty allow lists permit everything - deny lists take precedence over allow lists.
+type ModPolicy struct {
+	AllowedDomains     []string `json:"allowedDomains"`
+	DeniedDomains      []string `json:"deniedDomains"`
+	AllowedUrlPrefixes []string `json:"allowedUrlPrefixes"`
+	DeniedUrlPrefixes  []string `json:"deniedUrlPrefixes"`
+	AllowedHashes      []string `json:"allowedHashes"`
+	PublicKeys         []string `json:"publicKeys"`
+}
+
+// Loads a [ModPolicy] from the given file.
+// Returns an error if the file cannot be read or parsed.
+// Returns an error if any public key is invalid.
+func LoadModPolicy(ctx context.Context, file string) (*ModPolicy, error) {
+	fail := func(err error) (*ModPolicy, error) {
+		return nil, err
+	}
+	helper.Logger(ctx).Info("load mod policy", "path", file)
+	policy := ModPolicy{}
+	err := helper.UnmarshalFile(ctx, file, &policy)
+	if err != nil {
+		return fail(err)
+	}
+	for _, key := range policy.PublicKeys {
+		_, err := decodeModPolicyPublicKey(key)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	return &policy, nil
+}
+
+// Decodes a base64-encoded ed25519 public key.
+// Returns an error if the key is not a valid ed25519 public key.
+func decodeModPolicyPublicKey(key string) (ed25519.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key %s", key)
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// Determines whether a hostname matches any of the given domain patterns (e.g., 'github.com', '*.github.com')
+func matchesDomain(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(host))
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Determines whether a url begins with any of the given prefixes
+func matchesUrlPrefix(value string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks whether a mod url is permitted by the policy (prior to download).
+// Returns an error if the url is unparseable.
+// Returns an error if the url is denied.
+func (mp *ModPolicy) CheckUrl(mod string) error {
+	parsed, err := url.Parse(mod)
+	if err != nil {
+		return err
+	}
+	host := parsed.Hostname()
+	if matchesDomain(host, mp.DeniedDomains) {
+		return fmt.Errorf("mod %s denied by policy (domain %s denied)", mod, host)
+	}
+	if matchesUrlPrefix(mod, mp.DeniedUrlPrefixes) {
+		return fmt.Errorf("mod %s denied by policy (url denied)", mod)
+	}
+	if len(mp.AllowedDomains) > 0 && !matchesDomain(host, mp.AllowedDomains) {
+		return fmt.Errorf("mod %s denied by policy (domain %s not allowed)", mod, host)
+	}
+	if len(mp.AllowedUrlPrefixes) > 0 && !matchesUrlPrefix(mod, mp.AllowedUrlPrefixes) {
+		return fmt.Errorf("mod %s denied by policy (url not allowed)", mod)
+	}
+	return nil
+}
+
+// Downloads the signature of a mod (from '[mod].sig') to '[file].sig' - where [ModPolicy.CheckFile] reads it from.
+// Returns an error if the signature cannot be downloaded.
+func (mp *ModPolicy) downloadSignature(ctx context.Context, mod string, file string) error {
+	_, _, err := DownloadFile(ctx, fmt.Sprintf("%s.sig", mod), fmt.Sprintf("%s.sig", file), nil)
+	if err != nil {
+		return fmt.Errorf("mod %s denied by policy (signature unavailable): %w", mod, err)
+	}
+	return nil
+}
+
+// Checks whether a downloaded mod file is permitted by the policy.
+// If the policy defines public keys, a base64-encoded ed25519 signature is read from '[file].sig' (downloaded from '[mod].sig' when absent) and verified.
+// Returns an error if the file hash is not allowed.
+// Returns an error if the signature cannot be downloaded or is invalid.
+func (mp *ModPolicy) CheckFile(ctx context.Context, mod string, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	hash := fmt.Sprintf("sha256:%s", hex.EncodeToString(sum[:]))
+	helper.Logger(ctx).Info("check mod file", "mod", mod, "hash", hash)
+	if len(mp.AllowedHashes) > 0 && !slices.Contains(mp.AllowedHashes, hash) {
+		return fmt.Errorf("mod %s denied by policy (hash %s not allowed)", mod, hash)
+	}
+	if len(mp.PublicKeys) == 0 {
+		return nil
+	}
+	sigFile := fmt.Sprintf("%s.sig", file)
+	_, err = os.Lstat(sigFile)
+	if errors.Is(err, os.ErrNotExist) {
+		err = mp.downloadSignature(ctx, mod, file)
+	}
+	if err != nil {
+		return err
+	}
+	sigData, err := os.ReadFile(sigFile)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigData)))
+	if err != nil {
+		return fmt.Errorf("mod %s denied by policy (signature malformed): %w", mod, err)
+	}
+	for _, key := range mp.PublicKeys {
+		publicKey, _ := decodeModPolicyPublicKey(key)
+		if ed25519.Verify(publicKey, data, sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("mod %s denied by policy (signature invalid)", mod)
+}
+
+// tfpModPrefix is the folder name prefix of the mods shipped with the game (which are EAC compatible)
+const tfpModPrefix = "0_TFP_"
+
+// Finds installed mods that contain code (i.e., DLLs - typically Harmony mods), excluding the mods shipped with the game.
+// Returns the folder names of mods containing code.
+// Returns an error if the mods directory cannot be walked.
+func GetCodeMods(ctx context.Context, modsDir string) ([]string, error) {
+	fail := func(err error) ([]string, error) {
+		return nil, err
+	}
+	codeMods := []string{}
+	subpaths, err := helper.ListDir(ctx, modsDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return codeMods, nil
+	}
+	if err != nil {
+		return fail(err)
+	}
+	for _, subpath := range subpaths {
+		name := filepath.Base(subpath)
+		if strings.HasPrefix(name, tfpModPrefix) {
+			continue
+		}
+		hasCode := false
+		err := filepath.WalkDir(subpath, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(path), ".dll") {
+				hasCode = true
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if err != nil {
+			return fail(err)
+		}
+		if hasCode {
+			codeMods = append(codeMods, name)
+		}
+	}
+	helper.Logger(ctx).Info("get code mods", "mods", codeMods)
+	return codeMods, nil
+}
+
+// Checks whether EasyAntiCheat is enabled while mods containing code are installed - a combination that prevents players from joining.
+// If [autoDisable] is set, EasyAntiCheat is disabled in [settings].  Otherwise, a warning is logged.
+func CheckEacCompatibility(ctx context.Context, settings ServerSettings, codeMods []string, autoDisable bool) {
+	if len(codeMods) == 0 {
+		return
+	}
+	enabled, err := settings.GetBool("EACEnabled")
+	if err == nil && !enabled {
+		return
+	}
+	if autoDisable {
+		helper.Logger(ctx).Warn("code mods installed - disabling EasyAntiCheat", "mods", codeMods)
+		settings.SetBool("EACEnabled", false)
+		return
+	}
+	helper.Logger(ctx).Warn("!!! code mods installed while EasyAntiCheat is enabled - players will be unable to join. set SETTING_EACEnabled=false or EAC_AUTO_DISABLE=true !!!", "mods", codeMods)
+}
+
+// InstallModsOpts defines the options used in conjunction with the [InstallMods] function
+type InstallModsOpts struct {
+	Policy *ModPolicy
+}
+
+// Gets the file cache key of a downloaded mod archive (by the sha256 hash of its content).
+// Entries are directories containing the archive - and its signature, when the mod policy requires signatures (see [ModPolicy.CheckFile]).
+func getModCacheKey(hash string) string {
+	return fmt.Sprintf("mod-archive-%s", hash)
+}
+
+// Downloads and extracts a single mod url to the given path.
+// When the file cache is enabled, conditional requests are used to avoid re-downloading unchanged content - and downloaded archives (and their signatures) are cached by content hash (see [getModCacheKey]).
+// When offline mode is enabled, mods (and their signatures) are only restored from the file cache.
+// Archives are checked against the mod policy on every install (including those restored from the file cache).
+// Returns an error if the mod is denied by the mod policy.
+// Returns an error if the download fails.
+// Returns an error if the extraction fails.
+func installMod(ctx context.Context, opts InstallModsOpts, path string, mod string) error {
+	helper.Logger(ctx).Info("install mod", "path", path, "mod", mod)
+	if opts.Policy != nil {
+		err := opts.Policy.CheckUrl(mod)
+		if err != nil {
+			return err
+		}
+	}
+	extract := func(downloadPath string, dest string) error {
+		if opts.Policy != nil {
+			err := opts.Policy.CheckFile(ctx, mod, downloadPath)
+			if err != nil {
+				return err
+			}
+		}
+		return helper.Extract(ctx, downloadPath, dest)
+	}
+	return helper.CreateTempDir(ctx, func(tempDir string) error {
+		downloadPath := filepath.Join(tempDir, filepath.Base(mod))
+		cachedDir := filepath.Join(tempDir, "cached")
+		cachedPath := filepath.Join(cachedDir, filepath.Base(mod))
+		if !helper.FileCacheEnabled(ctx) {
+			_, _, err := DownloadFile(ctx, mod, downloadPath, nil)
+			if err != nil {
+				return err
+			}
+			return extract(downloadPath, path)
+		}
+		previous, err := GetDownloadMetadata(ctx, mod)
+		if err != nil {
+			return err
+		}
+		if GetDownloadConfig(ctx).Offline {
+			if previous == nil {
+				return fmt.Errorf("mod %s never downloaded: %w", mod, ErrOffline)
+			}
+			helper.Logger(ctx).Info("install cached mod (offline)", "mod", mod, "hash", previous.Hash)
+			err = helper.CacheFile(ctx, getModCacheKey(previous.Hash), cachedDir, func(dest string) error {
+				return fmt.Errorf("mod %s missing from file cache: %w", mod, ErrOffline)
+			})
+			if err != nil {
+				return err
+			}
+			return extract(cachedPath, path)
+		}
+		metadata, modified, err := DownloadFile(ctx, mod, downloadPath, previous)
+		if err != nil {
+			return err
+		}
+		helper.Logger(ctx).Info("mod content", "mod", mod, "hash", metadata.Hash, "modified", modified)
+		err = helper.CacheFile(ctx, getModCacheKey(metadata.Hash), cachedDir, func(dest string) error {
+			if !modified {
+				// unmodified content is missing from the cache (e.g., evicted) - download it again
+				metadata, _, err = DownloadFile(ctx, mod, downloadPath, nil)
+				if err != nil {
+					return err
+				}
+			}
+			err := helper.CreateDirs(ctx, dest)
+			if err != nil {
+				return err
+			}
+			file := filepath.Join(dest, filepath.Base(mod))
+			err = copyFileAtomic(downloadPath, file)
+			if err != nil || opts.Policy == nil || len(opts.Policy.PublicKeys) == 0 {
+				return err
+			}
+			// the signature is cached alongside the archive - so that the archive can be verified offline
+			return opts.Policy.downloadSignature(ctx, mod, file)
+		})
+		if err != nil {
+			return err
+		}
+		err = extract(cachedPath, path)
+		if err != nil {
+			return err
+		}
+		return SetDownloadMetadata(ctx, mod, metadata)
+	})
+}
+
+// Downloads and extracts a list of mod urls to the given path.
+// Returns an error if a mod is denied by the mod policy.
+// Returns an error if the download fails.
+// Returns an error if the extraction fails.
+func InstallMods(ctx context.Context, opts InstallModsOpts, path string, mods ...string) error {
+	for _, mod := range mods {
+		err := installMod(ctx, opts, path, mod)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/benfiola/seven-days-to-die/internal/fakes"
+)
+
+// Replaces the squashfs tools used by the file cache with scripts storing entries as plain files (and directories as tarballs) for the duration of a test
+func useFakeSquashfs(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	scripts := map[string]string{
+		// mksquashfs [src] [dest] -no-xattrs
+		"mksquashfs": "#!/bin/sh\nif [ -d \"$1\" ]; then exec tar -C \"$1\" -cf \"$2\" .; fi\nexec cp \"$1\" \"$2\"\n",
+		// unsquashfs -cat [archive] /path, or unsquashfs -force -no-xattrs -dest [dest] [archive]
+		"unsquashfs": "#!/bin/sh\nif [ \"$1\" = \"-cat\" ]; then exec cat \"$2\"; fi\nexec tar -C \"$4\" -xf \"$5\"\n",
+	}
+	for name, script := range scripts {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// Creates a mod archive (a tar.gz containing 'ModInfo.xml' within a folder named after the mod)
+func createTestModArchive(t *testing.T, name string) []byte {
+	t.Helper()
+	buffer := bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	content := []byte("<xml><Name value=\"" + name + "\" /></xml>")
+	err := tarWriter.WriteHeader(&tar.Header{Name: name + "/ModInfo.xml", Mode: 0644, Size: int64(len(content))})
+	if err == nil {
+		_, err = tarWriter.Write(content)
+	}
+	if err == nil {
+		err = tarWriter.Close()
+	}
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestInstallModsOfflineVerifiesCachedSignature(t *testing.T) {
+	ctx := newTestContext(t)
+	useFakeSquashfs(t)
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := createTestModArchive(t, "SignedMod")
+	server, base := startFakeHttp(t)
+	server.Respond("/mods/signed-mod.tar.gz", fakes.HttpResponse{Body: archive})
+	server.Respond("/mods/signed-mod.tar.gz.sig", fakes.HttpResponse{Body: []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, archive)))})
+	mod := base + "/mods/signed-mod.tar.gz"
+	opts := InstallModsOpts{Policy: &ModPolicy{PublicKeys: []string{base64.StdEncoding.EncodeToString(publicKey)}}}
+	online := WithDownloadConfig(ctx, DownloadConfig{})
+	err = InstallMods(online, opts, filepath.Join(t.TempDir(), "online"), mod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	offline := WithDownloadConfig(ctx, DownloadConfig{Offline: true})
+	dest := filepath.Join(t.TempDir(), "offline")
+	err = InstallMods(offline, opts, dest, mod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Lstat(filepath.Join(dest, "SignedMod", "ModInfo.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the cached signature is checked (rather than trusted) - keys that didn't sign the archive are refused
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	opts = InstallModsOpts{Policy: &ModPolicy{PublicKeys: []string{base64.StdEncoding.EncodeToString(otherKey)}}}
+	err = InstallMods(offline, opts, filepath.Join(t.TempDir(), "refused"), mod)
+	if err == nil {
+		t.Fatal("expected the signature to be refused")
+	}
+}
+
+func TestInstallModsOfflineWithoutCachedSignature(t *testing.T) {
+	ctx := newTestContext(t)
+	useFakeSquashfs(t)
+	publicKey, _, _ := ed25519.GenerateKey(nil)
+	server, base := startFakeHttp(t)
+	server.Respond("/mods/unsigned-mod.tar.gz", fakes.HttpResponse{Body: createTestModArchive(t, "UnsignedMod")})
+	mod := base + "/mods/unsigned-mod.tar.gz"
+	// cached while the policy didn't require signatures
+	err := InstallMods(WithDownloadConfig(ctx, DownloadConfig{}), InstallModsOpts{Policy: &ModPolicy{}}, filepath.Join(t.TempDir(), "online"), mod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := InstallModsOpts{Policy: &ModPolicy{PublicKeys: []string{base64.StdEncoding.EncodeToString(publicKey)}}}
+	err = InstallMods(WithDownloadConfig(ctx, DownloadConfig{Offline: true}), opts, filepath.Join(t.TempDir(), "offline"), mod)
+	if !errors.Is(err, ErrOffline) {
+		t.Fatalf("expected %v, got %v", ErrOffline, err)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// MountWaitOpts are options for [WaitForMounts]
+type MountWaitOpts struct {
+	// Dirs are the names of the entrypoint's directories (e.g., 'data', 'cache') - or absolute paths - that must be mounts
+	Dirs    []string
+	Timeout time.Duration
+}
+
+// Resolves the directories awaited by [WaitForMounts] - names of the entrypoint's directories are resolved to their paths.
+// Returns an error if a directory is neither a known directory name nor an absolute path.
+func resolveMountDirs(ctx context.Context, dirs []string) ([]string, error) {
+	paths := []string{}
+	for _, dir := range dirs {
+		if filepath.IsAbs(dir) {
+			paths = append(paths, dir)
+			continue
+		}
+		if !slices.Contains(dirNames, dir) {
+			return nil, fmt.Errorf("unknown directory %s (expected an absolute path or one of %v)", dir, dirNames)
+		}
+		paths = append(paths, helper.Dirs(ctx)[dir])
+	}
+	return paths, nil
+}
+
+// Determines whether [path] is located on a different filesystem than the root filesystem - i.e., whether it's (within) a mount.
+// Returns an error if either path cannot be queried.
+func isMounted(path string) (bool, error) {
+	stat := syscall.Stat_t{}
+	err := syscall.Stat(path, &stat)
+	if err != nil {
+		return false, err
+	}
+	root := syscall.Stat_t{}
+	err = syscall.Stat("/", &root)
+	if err != nil {
+		return false, err
+	}
+	return stat.Dev != root.Dev, nil
+}
+
+// Checks that a mounted directory is usable - that it's a mount point (rather than the empty directory an unmounted volume leaves behind) and that it's writable.
+// Returns an error if the directory isn't mounted or isn't writable.
+func checkMount(path string) error {
+	mounted, err := isMounted(path)
+	if err != nil {
+		return err
+	}
+	if !mounted {
+		return fmt.Errorf("%s is not mounted", path)
+	}
+	probe := filepath.Join(path, fmt.Sprintf(".mount-probe-%d", os.Getpid()))
+	data := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+	err = func() error {
+		handle, err := os.Create(probe)
+		if err != nil {
+			return err
+		}
+		defer handle.Close()
+		_, err = handle.Write(data)
+		if err != nil {
+			return err
+		}
+		return handle.Sync()
+	}()
+	if err == nil {
+		var read []byte
+		read, err = os.ReadFile(probe)
+		if err == nil && string(read) != string(data) {
+			err = fmt.Errorf("read back unexpected data")
+		}
+	}
+	os.Remove(probe)
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", path, err)
+	}
+	return nil
+}
+
+// Waits for directories backed by network mounts (e.g., NFS or SMB) to be mounted and writable - retrying (with backoff) until [MountWaitOpts.Timeout].
+// This runs before anything is downloaded or extracted, so that files aren't installed onto the empty directory of a volume that hasn't been mounted yet.
+// Returns an error if a directory is invalid.
+// Returns an error if a directory isn't usable before the timeout.
+func WaitForMounts(ctx context.Context, opts MountWaitOpts) error {
+	paths, err := resolveMountDirs(ctx, opts.Dirs)
+	if err != nil {
+		return err
+	}
+	helper.Logger(ctx).Info("wait for mounts", "paths", paths, "timeout", opts.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	backoff := Backoff{Initial: time.Second, Max: 15 * time.Second}
+	for _, path := range paths {
+		for attempt := 1; ; attempt++ {
+			err = checkMount(path)
+			if err == nil {
+				helper.Logger(ctx).Info("mount ready", "path", path, "attempts", attempt)
+				backoff.Reset()
+				break
+			}
+			helper.Logger(ctx).Warn("mount not ready", "path", path, "attempt", attempt, "error", err.Error())
+			if backoff.Wait(ctx) != nil {
+				return fmt.Errorf("mount %s not ready after %s: %w", path, opts.Timeout, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// Dials a tcp address.
+// Addresses with the host 'localhost' are dialed via the IPv4 and then the IPv6 loopback address - so that dialing doesn't depend on how '/etc/hosts' resolves 'localhost' (which can be '::1' only in IPv6-only containers).
+// Returns an error if no address is connectable.
+func dialTcp(ctx context.Context, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	hosts := []string{host}
+	if host == "localhost" {
+		hosts = []string{"127.0.0.1", "::1"}
+	}
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	errs := []error{}
+	for _, host := range hosts {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Formats the address a server listens on - an empty bind address listens on all IPv4 and IPv6 addresses
+func listenAddr(bindAddress string, port int) string {
+	return net.JoinHostPort(strings.Trim(bindAddress, "[]"), strconv.Itoa(port))
+}
+
+// Gets the (non-loopback) IPv4 and IPv6 addresses assigned to the container's interfaces.
+// Returns an error if the interface addresses cannot be listed.
+func getInterfaceAddrs() ([]net.IP, []net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, nil, err
+	}
+	ipv4 := []net.IP{}
+	ipv6 := []net.IP{}
+	for _, addr := range addrs {
+		network, ok := addr.(*net.IPNet)
+		if !ok || network.IP.IsLoopback() || network.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if network.IP.To4() != nil {
+			ipv4 = append(ipv4, network.IP)
+		} else {
+			ipv6 = append(ipv6, network.IP)
+		}
+	}
+	return ipv4, ipv6, nil
+}
+
+// Normalizes the 'ServerIP' setting (removing the brackets of IPv6 literals, which the game doesn't accept) and checks that the address is assigned to the container.
+// The game silently fails to bind to unassigned addresses - so a warning is logged.
+func CheckServerIp(ctx context.Context, settings ServerSettings) {
+	value, ok := settings.Get("ServerIP")
+	if !ok || value == "" {
+		return
+	}
+	value = strings.Trim(value, "[]")
+	settings.Set("ServerIP", value)
+	ip := net.ParseIP(value)
+	if ip == nil {
+		helper.Logger(ctx).Warn("ServerIP is not an ip address", "value", value)
+		return
+	}
+	if ip.IsUnspecified() {
+		return
+	}
+	ipv4, ipv6, err := getInterfaceAddrs()
+	if err != nil {
+		return
+	}
+	for _, assigned := range append(ipv4, ipv6...) {
+		if assigned.Equal(ip) {
+			return
+		}
+	}
+	helper.Logger(ctx).Warn("!!! ServerIP is not assigned to this container - the server will fail to bind !!!", "value", value)
+}
+
+// Parses '/proc/net/[proto]' - returning the local addresses (ip and port) of bound sockets.
+// Returns an error if the file cannot be read.
+func getBoundSockets(file string) ([]net.UDPAddr, error) {
+	handle, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Close()
+	sockets := []net.UDPAddr{}
+	scanner := bufio.NewScanner(handle)
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		hexIp, hexPort, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		port, err := strconv.ParseUint(hexPort, 16, 16)
+		if err != nil {
+			continue
+		}
+		// addresses are written as 32-bit words in host (little-endian) byte order
+		ip := net.IP{}
+		for index := 0; index+8 <= len(hexIp); index += 8 {
+			word, err := strconv.ParseUint(hexIp[index:index+8], 16, 32)
+			if err != nil {
+				break
+			}
+			ip = append(ip, byte(word), byte(word>>8), byte(word>>16), byte(word>>24))
+		}
+		sockets = append(sockets, net.UDPAddr{IP: ip, Port: int(port)})
+	}
+	return sockets, scanner.Err()
+}
+
+// Checks that the game port is bound in a way that players can reach - logging a prominent error when the container only has IPv6 addresses but the server only bound IPv4 (or vice versa), or when the port isn't bound at all.
+// Should be called once the server is ready.
+func CheckGamePortBinding(ctx context.Context, port int) {
+	boundIpv4 := false
+	boundIpv6 := false
+	v6only, _ := os.ReadFile("/proc/sys/net/ipv6/bindv6only")
+	dualStack := strings.TrimSpace(string(v6only)) != "1"
+	for _, file := range []string{"/proc/net/udp", "/proc/net/udp6"} {
+		sockets, err := getBoundSockets(file)
+		if err != nil {
+			continue
+		}
+		for _, socket := range sockets {
+			if socket.Port != port {
+				continue
+			}
+			if file == "/proc/net/udp" {
+				boundIpv4 = true
+				continue
+			}
+			boundIpv6 = true
+			if socket.IP.IsUnspecified() && dualStack {
+				// sockets bound to '::' also accept IPv4 unless IPv6-only sockets are the default
+				boundIpv4 = true
+			}
+		}
+	}
+	ipv4, ipv6, err := getInterfaceAddrs()
+	if err != nil {
+		return
+	}
+	helper.Logger(ctx).Info("check game port binding", "port", port, "ipv4", boundIpv4, "ipv6", boundIpv6, "ipv4Addrs", len(ipv4), "ipv6Addrs", len(ipv6))
+	switch {
+	case !boundIpv4 && !boundIpv6:
+		helper.Logger(ctx).Error(fmt.Sprintf("!!! game port udp/%d is not bound - players will be unable to connect !!!", port))
+	case len(ipv4) == 0 && len(ipv6) > 0 && !boundIpv6:
+		helper.Logger(ctx).Error(fmt.Sprintf("!!! game port udp/%d is only bound on IPv4, but the container only has IPv6 addresses - players will be unable to connect. provide the container an IPv4 address (e.g., enable dual-stack networking) !!!", port))
+	case len(ipv6) == 0 && len(ipv4) > 0 && !boundIpv4:
+		helper.Logger(ctx).Error(fmt.Sprintf("!!! game port udp/%d is only bound on IPv6, but the container only has IPv4 addresses - players will be unable to connect !!!", port))
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// Gets the keys stored in the file cache by reading the file cache manifest.
+// Returns an empty map if the file cache is disabled or has no manifest.
+// Returns an error if the manifest is unreadable.
+func getFileCacheKeys(ctx context.Context) (map[string]bool, error) {
+	keys := map[string]bool{}
+	cacheDir, ok := helper.Dirs(ctx)["cache"]
+	if !helper.FileCacheEnabled(ctx) || !ok {
+		return keys, nil
+	}
+	manifest := struct {
+		Contents map[string]struct {
+			Path string `json:"path"`
+		} `json:"contents"`
+	}{}
+	err := helper.UnmarshalFile(ctx, filepath.Join(cacheDir, "manifest.json"), &manifest)
+	if errors.Is(err, os.ErrNotExist) {
+		return keys, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for key, item := range manifest.Contents {
+		_, err := os.Lstat(item.Path)
+		if err == nil {
+			keys[key] = true
+		}
+	}
+	return keys, nil
+}
+
+// Determines whether the sdtd directory has been pre-seeded with the dedicated server
+func isSdtdPreseeded(ctx context.Context) bool {
+	_, err := os.Lstat(filepath.Join(helper.Dirs(ctx)["sdtd"], GetExecutionMode(ctx).ServerBinary()))
+	return err == nil
+}
+
+// Checks that every artifact required to start the server is available without network access.
+// The dedicated server must either be pre-seeded in the sdtd directory or be present in the file cache.
+// Mods must have been previously downloaded with the file cache enabled (so that their content is present in the file cache).
+// Returns an error listing all missing artifacts.
+func CheckOfflineArtifacts(ctx context.Context, manifestId string, mods ...string) error {
+	helper.Logger(ctx).Info("check offline artifacts", "manifest", manifestId, "mods", len(mods))
+	keys, err := getFileCacheKeys(ctx)
+	if err != nil {
+		return err
+	}
+	missing := []string{}
+	if !isSdtdPreseeded(ctx) && !keys[fmt.Sprintf("sdtd-%s", manifestId)] {
+		missing = append(missing, fmt.Sprintf("sdtd (manifest %s)", manifestId))
+	}
+	for _, mod := range mods {
+		metadata, err := GetDownloadMetadata(ctx, mod)
+		if err != nil {
+			return err
+		}
+		if metadata == nil || !keys[getModCacheKey(metadata.Hash)] {
+			missing = append(missing, fmt.Sprintf("mod %s", mod))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("offline mode enabled but artifacts are missing from the file cache and sdtd directory: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
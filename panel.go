@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// PanelEnv holds the variables that game server panels (e.g., Pterodactyl, Pelican) pass to their containers
+type PanelEnv struct {
+	ServerIp     string `env:"SERVER_IP"`
+	ServerMemory int    `env:"SERVER_MEMORY"`
+	ServerPort   int    `env:"SERVER_PORT"`
+}
+
+// panelMinMemory is the memory (in MB) below which the server is likely to run out of memory
+const panelMinMemory = 8192
+
+// panelMarkerPrefix prefixes the console lines written for panels (e.g., to detect that the server has started)
+const panelMarkerPrefix = "[panel]"
+
+// Gets the server settings derived from panel variables.
+// Returns an error if the panel variables cannot be parsed.
+func GetPanelServerSettings(ctx context.Context) (ServerSettings, error) {
+	env := PanelEnv{}
+	err := helper.ParseEnv(ctx, &env)
+	if err != nil {
+		return nil, err
+	}
+	settings := ServerSettings{}
+	if env.ServerPort != 0 {
+		settings["ServerPort"] = fmt.Sprintf("%d", env.ServerPort)
+	}
+	if env.ServerIp != "" && env.ServerIp != "0.0.0.0" {
+		settings["ServerIP"] = env.ServerIp
+	}
+	if env.ServerMemory != 0 && env.ServerMemory < panelMinMemory {
+		helper.Logger(ctx).Warn("panel memory limit is low", "memory", env.ServerMemory, "recommended", panelMinMemory)
+	}
+	helper.Logger(ctx).Info("get panel server settings", "count", len(settings))
+	return settings, nil
+}
+
+// Writes a panel marker line (e.g., '[panel] server ready') to stdout - panels match these lines to detect lifecycle changes
+func WritePanelMarker(event string) {
+	fmt.Fprintf(os.Stdout, "%s %s\n", panelMarkerPrefix, event)
+}
+
+// Detaches stdin from the server process (which doesn't read console commands from stdin) - returning the original stdin so that panel console input can be forwarded over telnet.
+// Returns an error if the null device cannot be opened.
+func DetachStdin() (io.Reader, error) {
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		return nil, err
+	}
+	stdin := os.Stdin
+	os.Stdin = null
+	return stdin, nil
+}
+
+// Forwards panel console input (one command per line) to the server - writing command output to stdout - until the input is closed or the context is cancelled.
+// Commands are recorded in the audit log.
+func RunPanelConsole(ctx context.Context, auditor *Auditor, input io.Reader) {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(input)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	for {
+		var line string
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case line, ok = <-lines:
+		}
+		if !ok {
+			return
+		}
+		command := strings.TrimSpace(line)
+		if command == "" {
+			continue
+		}
+		if command == "shutdown" {
+			// the server disconnects before acknowledging the command - shut down gracefully instead
+			auditor.Record(ctx, AuditRecord{Time: time.Now(), Actor: "panel", Source: "console", Command: command, Allowed: true, Success: true})
+			go ShutdownServer(ctx)
+			continue
+		}
+		output, err := ExecAuditedCommand(ctx, auditor, nil, "panel", "console", command)
+		if err != nil {
+			WritePanelMarker(fmt.Sprintf("command failed: %s", err.Error()))
+			continue
+		}
+		for _, item := range output {
+			fmt.Fprintln(os.Stdout, item)
+		}
+	}
+}
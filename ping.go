@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// PingPolicyOpts defines the options used in conjunction with the [PingPolicy]
+type PingPolicyOpts struct {
+	Duration  time.Duration
+	Exempt    []string
+	Threshold int
+}
+
+// PingPolicy warns and then kicks players whose ping exceeds a threshold for a sustained period
+type PingPolicy struct {
+	Opts     PingPolicyOpts
+	exceeded map[string]time.Time
+}
+
+// Creates a new [PingPolicy]
+func NewPingPolicy(opts PingPolicyOpts) *PingPolicy {
+	return &PingPolicy{Opts: opts, exceeded: map[string]time.Time{}}
+}
+
+// Applies the policy to the online players.
+// Players are warned when their ping first exceeds the threshold - and are kicked if it remains above the threshold for the policy's duration.
+func (pp *PingPolicy) Check(ctx context.Context, players []OnlinePlayer, now time.Time) {
+	online := map[string]bool{}
+	for _, player := range players {
+		online[player.EntityId] = true
+		if player.Ping <= pp.Opts.Threshold || player.MatchesId(pp.Opts.Exempt) {
+			delete(pp.exceeded, player.EntityId)
+			continue
+		}
+		since, ok := pp.exceeded[player.EntityId]
+		if !ok {
+			pp.exceeded[player.EntityId] = now
+			helper.Logger(ctx).Info("player ping exceeds threshold", "player", player.Name, "ping", player.Ping, "threshold", pp.Opts.Threshold)
+			SayPlayer(ctx, player.EntityId, fmt.Sprintf("Your ping (%dms) exceeds the server limit (%dms) - you will be kicked if it stays high for %s", player.Ping, pp.Opts.Threshold, pp.Opts.Duration))
+			continue
+		}
+		if now.Sub(since) < pp.Opts.Duration {
+			continue
+		}
+		helper.Logger(ctx).Info("kick player for high ping", "player", player.Name, "ping", player.Ping, "threshold", pp.Opts.Threshold)
+		err := KickPlayer(ctx, player.EntityId, fmt.Sprintf("Ping exceeded %dms for %s", pp.Opts.Threshold, pp.Opts.Duration))
+		if err != nil {
+			helper.Logger(ctx).Warn("kick player failed", "player", player.Name, "error", err.Error())
+			continue
+		}
+		delete(pp.exceeded, player.EntityId)
+	}
+	for entityId := range pp.exceeded {
+		if !online[entityId] {
+			delete(pp.exceeded, entityId)
+		}
+	}
+}
+
+// Periodically applies a [PingPolicy] to the online players until the context is cancelled.
+func RunPingPolicy(ctx context.Context, interval time.Duration, opts PingPolicyOpts) {
+	helper.Logger(ctx).Info("start ping policy", "threshold", opts.Threshold, "duration", opts.Duration)
+	policy := NewPingPolicy(opts)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		players, err := ListPlayers(ctx)
+		if err != nil {
+			helper.Logger(ctx).Warn("ping policy check failed", "error", err.Error())
+			continue
+		}
+		policy.Check(ctx, players, time.Now())
+	}
+}
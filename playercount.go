@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// PlayerCount is the server's current and maximum player count - written to '[data]/player-count.json' for autoscalers and other tooling that shouldn't need to speak the game's protocols
+type PlayerCount struct {
+	Players    int         `json:"players"`
+	MaxPlayers int         `json:"maxPlayers"`
+	State      ServerState `json:"state"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+}
+
+// playerCountAnnotationPrefix prefixes the pod annotations written by [PlayerCountPublisher]
+const playerCountAnnotationPrefix = "seven-days-to-die.benfiola.com"
+
+// kubernetesServiceAccountDir holds the credentials of the pod's service account
+const kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// ErrKubernetesUnavailable is returned when the entrypoint isn't running within a kubernetes pod (or the pod lacks service account credentials)
+var ErrKubernetesUnavailable = errors.New("kubernetes api unavailable")
+
+// kubernetesClient patches the pod running the entrypoint through the kubernetes api - authenticating with the pod's service account
+type kubernetesClient struct {
+	client    *http.Client
+	namespace string
+	pod       string
+	url       string
+}
+
+// Creates a new [kubernetesClient] from the pod's in-cluster credentials.
+// Returns an error wrapping [ErrKubernetesUnavailable] if the in-cluster credentials are missing.
+func newKubernetesClient(pod string) (*kubernetesClient, error) {
+	host := os.Getenv("KUBERNETES_SERVICE_HOST")
+	port := os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("%w: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are unset", ErrKubernetesUnavailable)
+	}
+	namespace, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "namespace"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKubernetesUnavailable, err)
+	}
+	ca, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKubernetesUnavailable, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("%w: invalid service account ca certificate", ErrKubernetesUnavailable)
+	}
+	if pod == "" {
+		pod, _ = os.Hostname()
+	}
+	// the in-cluster api is never reached through the download proxy
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &kubernetesClient{
+		client:    &http.Client{Transport: transport, Timeout: 10 * time.Second},
+		namespace: strings.TrimSpace(string(namespace)),
+		pod:       pod,
+		url:       fmt.Sprintf("https://%s", net.JoinHostPort(host, port)),
+	}, nil
+}
+
+// Sets annotations on the pod running the entrypoint.
+// The service account token is read on every request (as projected tokens are periodically rotated).
+// Returns an error if the request fails or the kubernetes api responds with a non-2xx status code (e.g., when the service account isn't permitted to patch pods).
+func (kc *kubernetesClient) Annotate(ctx context.Context, annotations map[string]string) error {
+	token, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "token"))
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(map[string]any{"metadata": map[string]any{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/api/v1/namespaces/%s/pods/%s", kc.url, kc.namespace, kc.pod)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", strings.TrimSpace(string(token))))
+	request.Header.Set("Content-Type", "application/merge-patch+json")
+	response, err := kc.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("kubernetes api responded with status %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// PlayerCountOpts defines the options used by a [PlayerCountPublisher]
+type PlayerCountOpts struct {
+	// Annotate additionally sets the player count as annotations of the pod running the entrypoint (when running within kubernetes)
+	Annotate bool
+	Interval time.Duration
+	// Pod is the name of the pod running the entrypoint - defaulting to the hostname
+	Pod string
+}
+
+// PlayerCountPublisher periodically writes the server's player count to '[data]/player-count.json' - and optionally annotates the pod running the entrypoint with it
+type PlayerCountPublisher struct {
+	Opts       PlayerCountOpts
+	Settings   ServerSettings
+	kubernetes *kubernetesClient
+	annotated  *PlayerCount
+}
+
+// Gets the server's current player count (from the [StatusTracker])
+func (pcp *PlayerCountPublisher) Count(ctx context.Context) PlayerCount {
+	maxPlayers, _ := pcp.Settings.GetInt("ServerMaxPlayerCount")
+	count := PlayerCount{MaxPlayers: maxPlayers, UpdatedAt: time.Now()}
+	tracker := GetStatusTracker(ctx)
+	if tracker != nil {
+		status := tracker.Get()
+		count.Players = status.Players
+		count.State = status.State
+	}
+	return count
+}
+
+// Writes the player count file atomically.
+// Returns an error if the file cannot be written.
+func (pcp *PlayerCountPublisher) write(ctx context.Context, count PlayerCount) error {
+	file := filepath.Join(helper.Dirs(ctx)["data"], "player-count.json")
+	data, err := json.MarshalIndent(count, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(file+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+// Annotates the pod with the player count - skipping the request if the count hasn't changed since the last annotation.
+// Returns an error if the pod cannot be annotated.
+func (pcp *PlayerCountPublisher) annotate(ctx context.Context, count PlayerCount) error {
+	if pcp.annotated != nil && pcp.annotated.Players == count.Players && pcp.annotated.MaxPlayers == count.MaxPlayers && pcp.annotated.State == count.State {
+		return nil
+	}
+	err := pcp.kubernetes.Annotate(ctx, map[string]string{
+		fmt.Sprintf("%s/players", playerCountAnnotationPrefix):     strconv.Itoa(count.Players),
+		fmt.Sprintf("%s/max-players", playerCountAnnotationPrefix): strconv.Itoa(count.MaxPlayers),
+		fmt.Sprintf("%s/state", playerCountAnnotationPrefix):       string(count.State),
+	})
+	if err != nil {
+		return err
+	}
+	helper.Logger(ctx).Debug("annotated pod", "pod", pcp.kubernetes.pod, "players", count.Players, "max-players", count.MaxPlayers, "state", count.State)
+	pcp.annotated = &count
+	return nil
+}
+
+// Writes (and optionally annotates the pod with) the player count on an interval until the context is cancelled.
+// Failures are logged and otherwise ignored.
+func (pcp *PlayerCountPublisher) Run(ctx context.Context) {
+	if pcp.Opts.Annotate {
+		kubernetes, err := newKubernetesClient(pcp.Opts.Pod)
+		if err != nil {
+			helper.Logger(ctx).Warn("pod annotations disabled", "error", err.Error())
+		}
+		pcp.kubernetes = kubernetes
+	}
+	for {
+		count := pcp.Count(ctx)
+		err := pcp.write(ctx, count)
+		if err != nil {
+			helper.Logger(ctx).Warn("write player count failed", "error", err.Error())
+		}
+		if pcp.kubernetes != nil {
+			err = pcp.annotate(ctx, count)
+			if err != nil {
+				helper.Logger(ctx).Warn("annotate pod failed", "pod", pcp.kubernetes.pod, "error", err.Error())
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pcp.Opts.Interval):
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// playerManifestName is the name of the archive entry describing an exported player
+const playerManifestName = "player.json"
+
+// PlayerExport describes a player's data exported from a save game (see [ExportPlayer])
+type PlayerExport struct {
+	PlatformId string    `json:"platformId"`
+	SaveGame   string    `json:"saveGame"`
+	ExportedAt time.Time `json:"exportedAt"`
+	// Files maps the exported file suffixes (e.g., '.ttp') to their sha256 checksums
+	Files map[string]string `json:"files"`
+}
+
+// Finds a save game folder by name - either '[world]/[game]' or '[game]' (in which case the most recently modified world is used).
+// Returns an error if the save game folder cannot be found.
+func resolveSaveGame(ctx context.Context, name string) (string, error) {
+	path := ""
+	if strings.Contains(name, "/") {
+		path = filepath.Join(helper.Dirs(ctx)["data"], "Saves", filepath.FromSlash(name))
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			path = ""
+		}
+	} else {
+		path = findSaveGame(ctx, name)
+	}
+	if path == "" {
+		return "", fmt.Errorf("save game %s not found", name)
+	}
+	return path, nil
+}
+
+// Gets the files belonging to a player within a save game folder (e.g., 'Player/[platform id].ttp', 'Player/[platform id].map') - keyed by their suffix.
+// Returns an error if the player folder cannot be read.
+func getPlayerFiles(saveGame string, platformId string) (map[string]string, error) {
+	entries, err := os.ReadDir(filepath.Join(saveGame, "Player"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	files := map[string]string{}
+	for _, entry := range entries {
+		suffix, ok := strings.CutPrefix(entry.Name(), platformId+".")
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		files["."+suffix] = filepath.Join(saveGame, "Player", entry.Name())
+	}
+	return files, nil
+}
+
+// Exports a player's data (their profile, inventory and map) from a save game to a tar.gz archive.
+// The world should be saved (or the server stopped) beforehand so that the exported data is current.
+// Returns an error if the platform id is invalid.
+// Returns an error if the save game or player cannot be found.
+// Returns an error if the archive cannot be written.
+func ExportPlayer(ctx context.Context, saveName string, platformId string, archive string) (*PlayerExport, error) {
+	fail := func(err error) (*PlayerExport, error) {
+		return nil, err
+	}
+	if !platformIdRegex.MatchString(platformId) {
+		return fail(fmt.Errorf("invalid platform id %s", platformId))
+	}
+	saveGame, err := resolveSaveGame(ctx, saveName)
+	if err != nil {
+		return fail(err)
+	}
+	files, err := getPlayerFiles(saveGame, platformId)
+	if err != nil {
+		return fail(err)
+	}
+	if files[".ttp"] == "" {
+		return fail(fmt.Errorf("player %s not found in save game %s", platformId, saveName))
+	}
+	helper.Logger(ctx).Info("export player", "platform-id", platformId, "save-game", saveGame, "archive", archive)
+	handle, err := os.Create(archive)
+	if err != nil {
+		return fail(err)
+	}
+	defer handle.Close()
+	gzipWriter := gzip.NewWriter(handle)
+	tarWriter := tar.NewWriter(gzipWriter)
+	export := PlayerExport{PlatformId: platformId, SaveGame: saveName, ExportedAt: time.Now().UTC(), Files: map[string]string{}}
+	for suffix, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fail(err)
+		}
+		checksum, err := addTarFile(tarWriter, "Player/"+suffix, path, info)
+		if err != nil {
+			return fail(err)
+		}
+		export.Files[suffix] = checksum
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return fail(err)
+	}
+	err = tarWriter.WriteHeader(&tar.Header{Name: playerManifestName, Mode: 0644, Size: int64(len(data)), ModTime: export.ExportedAt, Typeflag: tar.TypeReg})
+	if err == nil {
+		_, err = tarWriter.Write(data)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	for _, closer := range []io.Closer{tarWriter, gzipWriter, handle} {
+		err = closer.Close()
+		if err != nil {
+			return fail(err)
+		}
+	}
+	return &export, nil
+}
+
+// Reads a player export archive created by [ExportPlayer] - returning its manifest and file contents (keyed by suffix).
+// Returns an error if the archive is unreadable, has no manifest or contains unexpected entries.
+func readPlayerExport(archive string) (*PlayerExport, map[string][]byte, error) {
+	fail := func(err error) (*PlayerExport, map[string][]byte, error) {
+		return nil, nil, fmt.Errorf("read player export %s: %w", archive, err)
+	}
+	handle, err := os.Open(archive)
+	if err != nil {
+		return fail(err)
+	}
+	defer handle.Close()
+	gzipReader, err := gzip.NewReader(handle)
+	if err != nil {
+		return fail(err)
+	}
+	defer gzipReader.Close()
+	tarReader := tar.NewReader(gzipReader)
+	var export *PlayerExport
+	contents := map[string][]byte{}
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			return fail(err)
+		}
+		suffix, ok := strings.CutPrefix(header.Name, "Player/")
+		switch {
+		case header.Name == playerManifestName:
+			export = &PlayerExport{}
+			err = json.Unmarshal(data, export)
+			if err != nil {
+				return fail(err)
+			}
+		case ok && strings.HasPrefix(suffix, ".") && !strings.ContainsAny(suffix, "/\\"):
+			contents[suffix] = data
+		default:
+			return fail(fmt.Errorf("unexpected entry %s", header.Name))
+		}
+	}
+	if export == nil {
+		return fail(fmt.Errorf("manifest %s not found", playerManifestName))
+	}
+	if contents[".ttp"] == nil {
+		return fail(fmt.Errorf("player profile not found"))
+	}
+	return export, contents, nil
+}
+
+// ImportPlayerOpts are options for [ImportPlayer]
+type ImportPlayerOpts struct {
+	// PlatformId maps the imported player to a different platform id (e.g., 'Steam_...' to 'EOS_...') - defaults to the exported platform id
+	PlatformId string
+	// Replace allows existing data for the player to be replaced
+	Replace bool
+}
+
+// Imports a player's data from an archive created by [ExportPlayer] into a save game - optionally under a different platform id.
+// Because the game overwrites player files as players are saved, the server must be stopped.
+// Returns an error if the server is running.
+// Returns an error if the archive or save game is invalid.
+// Returns an error if the player already exists in the save game (unless [ImportPlayerOpts.Replace] is set).
+// Returns an error if the player files cannot be written.
+func ImportPlayer(ctx context.Context, archive string, saveName string, opts ImportPlayerOpts) (*PlayerExport, error) {
+	fail := func(err error) (*PlayerExport, error) {
+		return nil, err
+	}
+	if CheckHealth(ctx) == nil {
+		return fail(fmt.Errorf("server is running - stop the server before importing player data"))
+	}
+	export, contents, err := readPlayerExport(archive)
+	if err != nil {
+		return fail(err)
+	}
+	platformId := opts.PlatformId
+	if platformId == "" {
+		platformId = export.PlatformId
+	}
+	if !platformIdRegex.MatchString(platformId) {
+		return fail(fmt.Errorf("invalid platform id %s", platformId))
+	}
+	saveGame, err := resolveSaveGame(ctx, saveName)
+	if err != nil {
+		return fail(err)
+	}
+	existing, err := getPlayerFiles(saveGame, platformId)
+	if err != nil {
+		return fail(err)
+	}
+	if len(existing) > 0 && !opts.Replace {
+		return fail(fmt.Errorf("player %s already exists in save game %s", platformId, saveName))
+	}
+	helper.Logger(ctx).Info("import player", "from", export.PlatformId, "to", platformId, "save-game", saveGame, "archive", archive)
+	dir := filepath.Join(saveGame, "Player")
+	err = helper.CreateDirs(ctx, dir)
+	if err != nil {
+		return fail(err)
+	}
+	for _, path := range existing {
+		err = helper.RemovePaths(ctx, path)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	for suffix, data := range contents {
+		// files are written atomically so that a failed import never leaves a truncated profile behind
+		path := filepath.Join(dir, platformId+suffix)
+		err = os.WriteFile(path+".tmp", data, 0644)
+		if err == nil {
+			err = os.Rename(path+".tmp", path)
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+	return export, nil
+}
+
+// Migrates player data between save games (and servers) from the command line:
+//   - 'player export [save] [platform id] [file]' exports a player's data to an archive
+//   - 'player import [--replace] [file] [save] [platform id]' imports a player's data from an archive - optionally under a different platform id
+//   - 'player snapshot [platform id] [save]' snapshots a player's data (see [CreatePlayerSnapshot]) - from the running server's save game, by default
+//   - 'player snapshots [platform id]' lists a player's snapshots
+//   - 'player restore [platform id] [name]' restores a player's snapshot (the most recent, by default) - stopping the server so that it's restored on the next start
+//
+// Saves are referred to as '[world]/[game]' or '[game]'.
+// Returns an error if the arguments are invalid.
+// Returns an error if the operation fails.
+func PlayerSubcommand(ctx context.Context) error {
+	usage := fmt.Errorf("usage: %s player [export [save] [platform id] [file] | import [--replace] [file] [save] [platform id] | snapshot [platform id] [save] | snapshots [platform id] | restore [platform id] [name]]", filepath.Base(os.Args[0]))
+	args := os.Args[2:]
+	if len(args) == 0 {
+		return usage
+	}
+	opts := ImportPlayerOpts{}
+	if args[0] == "import" && len(args) > 1 && args[1] == "--replace" {
+		opts.Replace = true
+		args = append(args[:1], args[2:]...)
+	}
+	switch {
+	case args[0] == "export" && len(args) == 4:
+		export, err := ExportPlayer(ctx, args[1], args[2], args[3])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\t%s\t%d\n", export.PlatformId, args[3], len(export.Files))
+		return nil
+	case args[0] == "import" && (len(args) == 3 || len(args) == 4):
+		if len(args) == 4 {
+			opts.PlatformId = args[3]
+		}
+		export, err := ImportPlayer(ctx, args[1], args[2], opts)
+		if err != nil {
+			return err
+		}
+		platformId := opts.PlatformId
+		if platformId == "" {
+			platformId = export.PlatformId
+		}
+		fmt.Printf("%s\t%s\t%d\n", export.PlatformId, platformId, len(export.Files))
+		return nil
+	case slices.Contains([]string{"snapshot", "snapshots", "restore"}, args[0]) && len(args) > 1 && !platformIdRegex.MatchString(args[1]):
+		return fmt.Errorf("invalid platform id %s", args[1])
+	case args[0] == "snapshot" && (len(args) == 2 || len(args) == 3):
+		config := EntrypointConfig{}
+		err := helper.ParseEnv(ctx, &config)
+		if err != nil {
+			return err
+		}
+		saveGame := ""
+		if len(args) == 3 {
+			saveGame = args[2]
+		} else {
+			saveGame, err = getRunningSaveGame(ctx)
+			if err != nil {
+				return fmt.Errorf("save game of running server not found (specify a save): %w", err)
+			}
+		}
+		if CheckHealth(ctx) == nil {
+			// the world is saved so that the player's files are current
+			err = SaveWorld(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		snapshot, err := CreatePlayerSnapshot(ctx, saveGame, args[1], PlayerSnapshotManual, config.PlayerSnapshotLimit)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\t%s\n", snapshot.PlatformId, snapshot.Name)
+		return nil
+	case args[0] == "snapshots" && len(args) == 2:
+		snapshots, err := ListPlayerSnapshots(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		for _, snapshot := range snapshots {
+			fmt.Printf("%s\t%s\t%s\n", snapshot.Name, snapshot.Reason, snapshot.CreatedAt.Format(time.RFC3339))
+		}
+		return nil
+	case args[0] == "restore" && (len(args) == 2 || len(args) == 3):
+		name := ""
+		if len(args) == 3 {
+			name = args[2]
+		}
+		_, err := RequestPlayerSnapshotRestore(ctx, args[1], name)
+		if err != nil {
+			return err
+		}
+		err = ShutdownServer(ctx)
+		if err != nil {
+			helper.Logger(ctx).Info("server not running - player snapshot will be restored on next start")
+		}
+		return nil
+	}
+	return usage
+}
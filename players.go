@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// OnlinePlayer describes a player currently connected to the server (as reported by 'listplayers')
+type OnlinePlayer struct {
+	PlayerInfo
+	Position string `json:"position"`
+	Health   int    `json:"health"`
+	Deaths   int    `json:"deaths"`
+	Zombies  int    `json:"zombies"`
+	Players  int    `json:"players"`
+	Score    int    `json:"score"`
+	Level    int    `json:"level"`
+	Ping     int    `json:"ping"`
+}
+
+// listPlayersRegex matches a player line of the 'listplayers' command (e.g., '1. id=171, name=ben, ...')
+var listPlayersRegex = regexp.MustCompile(`^\d+\. (id=.+)$`)
+
+// Parses the output of the 'listplayers' command.
+func ParseListPlayers(lines []string) []OnlinePlayer {
+	players := []OnlinePlayer{}
+	atoi := func(value string) int {
+		parsed, _ := strconv.Atoi(value)
+		return parsed
+	}
+	for _, line := range lines {
+		match := listPlayersRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		fields := parseLogFields(match[1])
+		players = append(players, OnlinePlayer{
+			PlayerInfo: PlayerInfo{
+				EntityId:   fields["id"],
+				Name:       fields["name"],
+				PlatformId: fields["pltfmid"],
+				CrossId:    fields["crossid"],
+				Ip:         fields["ip"],
+			},
+			Position: fields["pos"],
+			Health:   atoi(fields["health"]),
+			Deaths:   atoi(fields["deaths"]),
+			Zombies:  atoi(fields["zombies"]),
+			Players:  atoi(fields["players"]),
+			Score:    atoi(fields["score"]),
+			Level:    atoi(fields["level"]),
+			Ping:     atoi(fields["ping"]),
+		})
+	}
+	return players
+}
+
+// Lists the players currently connected to the server.
+// Returns an error if the 'listplayers' command fails.
+func ListPlayers(ctx context.Context) ([]OnlinePlayer, error) {
+	lines, err := SendCommand(ctx, "listplayers")
+	if err != nil {
+		return nil, fmt.Errorf("list players: %w", err)
+	}
+	return ParseListPlayers(lines), nil
+}
+
+// Kicks a player (by entity id) with a reason shown to the player.
+// Returns an error if the 'kick' command fails.
+func KickPlayer(ctx context.Context, entityId string, reason string) error {
+	_, err := SendCommand(ctx, fmt.Sprintf("kick %s \"%s\"", entityId, strings.ReplaceAll(reason, "\"", "\"\"")))
+	if err != nil {
+		return fmt.Errorf("kick player: %w", err)
+	}
+	return nil
+}
+
+// Checks whether a player matches any of the given ids - either its platform id (e.g., 'Steam_76561198000000000'), its cross-platform id (e.g., 'EOS_0002...') or its platform id without the platform prefix (e.g., '76561198000000000')
+func (op OnlinePlayer) MatchesId(ids []string) bool {
+	return matchesPlayerId(ids, op.PlatformId, op.CrossId)
+}
+
+// Checks whether any of a player's ids (e.g., 'Steam_76561198000000000') matches any of the given ids - ids can omit the platform prefix (e.g., '76561198000000000')
+func matchesPlayerId(ids []string, playerIds ...string) bool {
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		for _, playerId := range playerIds {
+			_, unprefixed, _ := strings.Cut(playerId, "_")
+			if playerId != "" && (id == playerId || id == unprefixed) {
+				return true
+			}
+		}
+	}
+	return false
+}
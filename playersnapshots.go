@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+const (
+	// PlayerSnapshotDeath is the reason of player snapshots taken when players die
+	PlayerSnapshotDeath = "death"
+	// PlayerSnapshotManual is the reason of player snapshots taken on demand
+	PlayerSnapshotManual = "manual"
+)
+
+// playerSnapshotTimeFormat is the format of the timestamp prefixing player snapshot names
+const playerSnapshotTimeFormat = "20060102T150405.000Z"
+
+// PlayerSnapshot is a copy of a player's data (their profile, inventory and map - see [ExportPlayer]) - taken on demand or when the player dies, so that claims of lost items can be investigated (and the data restored)
+type PlayerSnapshot struct {
+	Name       string    `json:"name"`
+	PlatformId string    `json:"platformId"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Gets the directory that stores player snapshots
+func getPlayerSnapshotsDir(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "player-snapshots")
+}
+
+// Gets the path of the archive of a player snapshot
+func getPlayerSnapshotFile(ctx context.Context, platformId string, name string) string {
+	return filepath.Join(getPlayerSnapshotsDir(ctx), platformId, name+".tar.gz")
+}
+
+// Gets the path of the file that records pending player snapshot restores
+func getPlayerSnapshotRestoreFile(ctx context.Context) string {
+	return filepath.Join(getPlayerSnapshotsDir(ctx), "restore.json")
+}
+
+// Gets the save game ('[world]/[game]') of the running server.
+// Returns an error if the game preferences cannot be read or the save game folder cannot be found.
+func getRunningSaveGame(ctx context.Context) (string, error) {
+	prefs, err := GetGamePrefs(ctx)
+	if err != nil {
+		return "", err
+	}
+	saveGame := findSaveGame(ctx, prefs["GameName"])
+	if saveGame == "" {
+		return "", fmt.Errorf("save game %s not found", prefs["GameName"])
+	}
+	relpath, err := filepath.Rel(filepath.Join(helper.Dirs(ctx)["data"], "Saves"), saveGame)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(relpath), nil
+}
+
+// Takes a snapshot of a player's data within a save game ('[world]/[game]') - deleting the player's oldest snapshots beyond the limit.
+// Snapshots copy the player's files as last written by the game (the game writes player files periodically, and as players disconnect).
+// Returns an error if the player cannot be found in the save game.
+// Returns an error if the snapshot cannot be written.
+func CreatePlayerSnapshot(ctx context.Context, saveGame string, platformId string, reason string, limit int) (*PlayerSnapshot, error) {
+	now := time.Now().UTC()
+	snapshot := PlayerSnapshot{Name: fmt.Sprintf("%s-%s", now.Format(playerSnapshotTimeFormat), reason), PlatformId: platformId, Reason: reason, CreatedAt: now}
+	file := getPlayerSnapshotFile(ctx, platformId, snapshot.Name)
+	helper.Logger(ctx).Info("create player snapshot", "platform-id", platformId, "name", snapshot.Name)
+	err := helper.CreateDirs(ctx, filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+	_, err = ExportPlayer(ctx, saveGame, platformId, file+".tmp")
+	if err == nil {
+		err = os.Rename(file+".tmp", file)
+	}
+	if err != nil {
+		helper.RemovePaths(ctx, file+".tmp")
+		return nil, err
+	}
+	snapshots, err := ListPlayerSnapshots(ctx, platformId)
+	if err != nil {
+		return nil, err
+	}
+	for index := 0; index < len(snapshots)-limit; index++ {
+		helper.Logger(ctx).Info("delete player snapshot", "platform-id", platformId, "name", snapshots[index].Name)
+		err = helper.RemovePaths(ctx, getPlayerSnapshotFile(ctx, platformId, snapshots[index].Name))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &snapshot, nil
+}
+
+// Lists a player's snapshots (oldest first).
+// Returns an error if the player's snapshots directory cannot be read.
+func ListPlayerSnapshots(ctx context.Context, platformId string) ([]PlayerSnapshot, error) {
+	snapshots := []PlayerSnapshot{}
+	entries, err := os.ReadDir(filepath.Join(getPlayerSnapshotsDir(ctx), platformId))
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshots, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".tar.gz")
+		if !ok {
+			continue
+		}
+		timestamp, reason, _ := strings.Cut(name, "-")
+		createdAt, err := time.Parse(playerSnapshotTimeFormat, timestamp)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, PlayerSnapshot{Name: name, PlatformId: platformId, Reason: reason, CreatedAt: createdAt})
+	}
+	slices.SortFunc(snapshots, func(a PlayerSnapshot, b PlayerSnapshot) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Finds a player's snapshot by name - an empty name refers to the most recent snapshot.
+// Returns an error if the snapshots cannot be listed.
+// Returns an error if no snapshot matches.
+func FindPlayerSnapshot(ctx context.Context, platformId string, name string) (*PlayerSnapshot, error) {
+	snapshots, err := ListPlayerSnapshots(ctx, platformId)
+	if err != nil {
+		return nil, err
+	}
+	for index := len(snapshots) - 1; index >= 0; index-- {
+		if name == "" || snapshots[index].Name == name {
+			return &snapshots[index], nil
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no snapshots found for player %s", platformId)
+	}
+	return nil, fmt.Errorf("snapshot %s not found for player %s", name, platformId)
+}
+
+// Requests that a player snapshot be restored the next time the server starts (the game overwrites player files while the server is running).
+// Returns an error if the snapshot cannot be found.
+// Returns an error if the request cannot be written.
+func RequestPlayerSnapshotRestore(ctx context.Context, platformId string, name string) (*PlayerSnapshot, error) {
+	snapshot, err := FindPlayerSnapshot(ctx, platformId, name)
+	if err != nil {
+		return nil, err
+	}
+	requests := map[string]string{}
+	err = helper.UnmarshalFile(ctx, getPlayerSnapshotRestoreFile(ctx), &requests)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	helper.Logger(ctx).Info("request player snapshot restore", "platform-id", platformId, "name", snapshot.Name)
+	requests[platformId] = snapshot.Name
+	return snapshot, helper.MarshalFile(ctx, requests, getPlayerSnapshotRestoreFile(ctx))
+}
+
+// Restores the player snapshots requested by [RequestPlayerSnapshotRestore] (if any) - replacing the players' data within the save games they were taken from - and then clears the requests.
+// Returns an error if a requested snapshot cannot be restored.
+func ApplyPlayerSnapshotRestores(ctx context.Context) error {
+	requests := map[string]string{}
+	err := helper.UnmarshalFile(ctx, getPlayerSnapshotRestoreFile(ctx), &requests)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for platformId, name := range requests {
+		snapshot, err := FindPlayerSnapshot(ctx, platformId, name)
+		if err != nil {
+			return err
+		}
+		file := getPlayerSnapshotFile(ctx, platformId, snapshot.Name)
+		export, _, err := readPlayerExport(file)
+		if err != nil {
+			return err
+		}
+		helper.Logger(ctx).Info("restore player snapshot", "platform-id", platformId, "name", snapshot.Name, "save-game", export.SaveGame)
+		_, err = ImportPlayer(ctx, file, export.SaveGame, ImportPlayerOpts{Replace: true})
+		if err != nil {
+			return err
+		}
+	}
+	return helper.RemovePaths(ctx, getPlayerSnapshotRestoreFile(ctx))
+}
+
+// PlayerSnapshotOpts are options for [RunPlayerDeathSnapshots]
+type PlayerSnapshotOpts struct {
+	// Limit is the number of snapshots kept per player (the oldest snapshots are deleted first)
+	Limit int
+}
+
+// Snapshots the data of players (see [CreatePlayerSnapshot]) as they die - reading deaths from the telnet session until the context is cancelled.
+// Failing snapshots are logged and otherwise ignored.
+// Returns an error if the telnet session is unavailable or the running server's save game cannot be found.
+func RunPlayerDeathSnapshots(ctx context.Context, opts PlayerSnapshotOpts) error {
+	session := GetTelnetSession(ctx)
+	if session == nil {
+		return ErrTelnetNotConnected
+	}
+	saveGame, err := getRunningSaveGame(ctx)
+	if err != nil {
+		return err
+	}
+	helper.Logger(ctx).Info("start player death snapshots", "save-game", saveGame)
+	lines, unsubscribe := session.Subscribe()
+	defer unsubscribe()
+	for {
+		var line string
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok = <-lines:
+		}
+		if !ok {
+			return nil
+		}
+		event := ParseKillEvent(line)
+		if event == nil {
+			continue
+		}
+		victim, err := findOnlinePlayer(ctx, event.Victim)
+		if err == nil {
+			_, err = CreatePlayerSnapshot(ctx, saveGame, victim.PlatformId, PlayerSnapshotDeath, opts.Limit)
+		}
+		if err != nil {
+			helper.Logger(ctx).Warn("player death snapshot failed", "player", event.Victim, "error", err.Error())
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// Plugin extends the entrypoint with hooks called throughout the server lifecycle
+type Plugin interface {
+	// Name identifies the plugin in logs
+	Name() string
+	// OnPreStart is called before the server starts and can modify the generated server settings
+	OnPreStart(ctx context.Context, settings ServerSettings) error
+	// OnReady is called once the server has finished loading
+	OnReady(ctx context.Context) error
+	// OnPlayerJoin is called whenever a player connects to the server
+	OnPlayerJoin(ctx context.Context, player PlayerInfo) error
+	// OnShutdown is called after the server has stopped
+	OnShutdown(ctx context.Context) error
+}
+
+// ExecPlugin is a [Plugin] implemented by an executable.
+// Each hook runs the executable with the hook name as its only argument (e.g., 'pre-start') and a JSON payload on stdin.
+type ExecPlugin struct {
+	Path string
+}
+
+// Runs the plugin executable for a hook.
+// Returns the plugin's stdout.
+// Returns an error if the executable exits with a non-zero status code.
+func (ep ExecPlugin) run(ctx context.Context, hook string, payload any) ([]byte, error) {
+	fail := func(err error) ([]byte, error) {
+		return nil, fmt.Errorf("plugin %s hook %s failed: %w", ep.Name(), hook, err)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fail(err)
+	}
+	helper.Logger(ctx).Info("run plugin hook", "plugin", ep.Name(), "hook", hook)
+	stdout := bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, ep.Path, hook)
+	cmd.Dir = helper.Dirs(ctx)["sdtd"]
+	cmd.Env = append(os.Environ(), getDirsEnv(ctx)...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return fail(err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// Gets the name of the plugin (the executable's file name)
+func (ep ExecPlugin) Name() string {
+	return filepath.Base(ep.Path)
+}
+
+// Calls the 'pre-start' hook with the server settings as the payload.
+// If the plugin prints a JSON object to stdout, it is merged into the server settings.
+// Returns an error if the hook fails or prints invalid JSON.
+func (ep ExecPlugin) OnPreStart(ctx context.Context, settings ServerSettings) error {
+	stdout, err := ep.run(ctx, "pre-start", map[string]any{"settings": settings})
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(stdout)) == 0 {
+		return nil
+	}
+	overrides := ServerSettings{}
+	err = json.Unmarshal(stdout, &overrides)
+	if err != nil {
+		return fmt.Errorf("plugin %s hook pre-start printed invalid settings: %w", ep.Name(), err)
+	}
+	for name, value := range overrides {
+		settings.Set(name, value)
+	}
+	return nil
+}
+
+// Calls the 'ready' hook.
+// Returns an error if the hook fails.
+func (ep ExecPlugin) OnReady(ctx context.Context) error {
+	_, err := ep.run(ctx, "ready", map[string]any{})
+	return err
+}
+
+// Calls the 'player-join' hook with the player as the payload.
+// Returns an error if the hook fails.
+func (ep ExecPlugin) OnPlayerJoin(ctx context.Context, player PlayerInfo) error {
+	_, err := ep.run(ctx, "player-join", map[string]any{"player": player})
+	return err
+}
+
+// Calls the 'shutdown' hook.
+// Returns an error if the hook fails.
+func (ep ExecPlugin) OnShutdown(ctx context.Context) error {
+	_, err := ep.run(ctx, "shutdown", map[string]any{})
+	return err
+}
+
+// Loads the executables within a directory (in name order) as plugins.
+// Returns no plugins if the directory does not exist.
+// Returns an error if the directory cannot be read.
+func LoadPlugins(ctx context.Context, dir string) ([]Plugin, error) {
+	plugins := []Plugin{}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return plugins, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(a int, b int) bool {
+		return entries[a].Name() < entries[b].Name()
+	})
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode()&0111 == 0 {
+			helper.Logger(ctx).Warn("skip non-executable plugin", "path", filepath.Join(dir, entry.Name()))
+			continue
+		}
+		plugins = append(plugins, ExecPlugin{Path: filepath.Join(dir, entry.Name())})
+	}
+	names := []string{}
+	for _, plugin := range plugins {
+		names = append(names, plugin.Name())
+	}
+	helper.Logger(ctx).Info("load plugins", "dir", dir, "plugins", names)
+	return plugins, nil
+}
+
+// Calls the 'pre-start' hook of every plugin.
+// Returns an error if any hook fails.
+func RunPluginsPreStart(ctx context.Context, plugins []Plugin, settings ServerSettings) error {
+	for _, plugin := range plugins {
+		err := plugin.OnPreStart(ctx, settings)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Calls the 'shutdown' hook of every plugin - failing hooks are logged and otherwise ignored.
+func RunPluginsShutdown(ctx context.Context, plugins []Plugin) {
+	for _, plugin := range plugins {
+		err := plugin.OnShutdown(ctx)
+		if err != nil {
+			helper.Logger(ctx).Warn("plugin hook failed", "error", err.Error())
+		}
+	}
+}
+
+// Calls the 'ready' hook of every plugin once the server is ready - and then calls the 'player-join' hook of every plugin whenever a player connects, until the context is cancelled.
+// Failing hooks are logged and otherwise ignored.
+func RunPluginEvents(ctx context.Context, plugins []Plugin, readyTimeout time.Duration) {
+	session := GetTelnetSession(ctx)
+	err := session.WaitReady(ctx, readyTimeout)
+	if err != nil {
+		helper.Logger(ctx).Warn("plugin events stopped", "error", err.Error())
+		return
+	}
+	lines, unsubscribe := session.Subscribe()
+	defer unsubscribe()
+	for _, plugin := range plugins {
+		err := plugin.OnReady(ctx)
+		if err != nil {
+			helper.Logger(ctx).Warn("plugin hook failed", "error", err.Error())
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			player := ParsePlayerConnected(line)
+			if player == nil {
+				continue
+			}
+			for _, plugin := range plugins {
+				err := plugin.OnPlayerJoin(ctx, *player)
+				if err != nil {
+					helper.Logger(ctx).Warn("plugin hook failed", "error", err.Error())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// PlayerPositionProperties are the properties of a [PlayerPositionFeature]
+type PlayerPositionProperties struct {
+	EntityId   string `json:"entityId"`
+	Name       string `json:"name"`
+	PlatformId string `json:"platformId"`
+	Level      int    `json:"level"`
+	Health     int    `json:"health"`
+	// Elevation is the player's height (the game's 'y' coordinate)
+	Elevation float64 `json:"elevation"`
+}
+
+// PlayerPositionFeature is a geojson point feature locating an online player.
+// Coordinates are the game's map coordinates - '[x, z]' (i.e., east and north of the world's center, in blocks).
+type PlayerPositionFeature struct {
+	Type     string `json:"type"`
+	Geometry struct {
+		Type        string     `json:"type"`
+		Coordinates [2]float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties PlayerPositionProperties `json:"properties"`
+}
+
+// PlayerPositions is a geojson feature collection of online players - served by 'GET /api/players/positions'
+type PlayerPositions struct {
+	Type     string                  `json:"type"`
+	Features []PlayerPositionFeature `json:"features"`
+	// UpdatedAt is when the positions were polled (nil if they haven't been polled yet)
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// Converts online players into a [PlayerPositions] feature collection - skipping players whose position is unparseable
+func NewPlayerPositions(players []OnlinePlayer, updatedAt *time.Time) PlayerPositions {
+	positions := PlayerPositions{Type: "FeatureCollection", Features: []PlayerPositionFeature{}, UpdatedAt: updatedAt}
+	for _, player := range players {
+		position, ok := parsePosition(player.Position)
+		if !ok {
+			continue
+		}
+		feature := PlayerPositionFeature{Type: "Feature"}
+		feature.Geometry.Type = "Point"
+		feature.Geometry.Coordinates = [2]float64{position[0], position[2]}
+		feature.Properties = PlayerPositionProperties{
+			EntityId:   player.EntityId,
+			Name:       player.Name,
+			PlatformId: player.PlatformId,
+			Level:      player.Level,
+			Health:     player.Health,
+			Elevation:  position[1],
+		}
+		positions.Features = append(positions.Features, feature)
+	}
+	return positions
+}
+
+// PositionTracker polls the positions of online players - so that (e.g.) live maps polled by many clients don't each send console commands to the server
+type PositionTracker struct {
+	Interval  time.Duration
+	lock      sync.Mutex
+	players   []OnlinePlayer
+	updatedAt *time.Time
+}
+
+// Gets the most recently polled player positions
+func (pt *PositionTracker) Get() PlayerPositions {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+	return NewPlayerPositions(pt.players, pt.updatedAt)
+}
+
+// Polls player positions (every [PositionTracker.Interval], while the server is ready) until the context is cancelled.
+// Failed polls are logged and otherwise ignored.
+func (pt *PositionTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pt.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		tracker := GetStatusTracker(ctx)
+		if tracker != nil && tracker.Get().State != ServerStateReady {
+			continue
+		}
+		players, err := ListPlayers(ctx)
+		if err != nil {
+			helper.Logger(ctx).Warn("poll player positions failed", "error", err.Error())
+			continue
+		}
+		now := time.Now().UTC()
+		pt.lock.Lock()
+		pt.players = players
+		pt.updatedAt = &now
+		pt.lock.Unlock()
+	}
+}
+
+// Handles 'GET /api/players/positions' - serving the positions of online players as a geojson feature collection
+func (aa *AdminApi) handlePlayerPositions(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	if aa.Positions == nil {
+		writeJson(writer, http.StatusNotFound, map[string]any{"error": "player positions are disabled (see PLAYER_POSITIONS_INTERVAL)"})
+		return
+	}
+	writeJson(writer, http.StatusOK, aa.Positions.Get())
+}
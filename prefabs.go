@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// prefabPackDirs maps the top-level folders of a prefab pack to the folders (relative to the sdtd directory) they're merged into.
+// A pack's 'Config' folder (XPath patches - e.g., rwgmixer.xml additions) is installed as a modlet instead (see [InstallPrefabPacks]).
+var prefabPackDirs = map[string]string{
+	"Data":    "Data",
+	"Mods":    "Mods",
+	"Prefabs": filepath.Join("Data", "Prefabs"),
+	"Worlds":  filepath.Join("Data", "Worlds"),
+}
+
+// prefabPackNameRegex matches characters that aren't permitted in the name of a prefab pack's modlet
+var prefabPackNameRegex = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// Gets the name of a prefab pack from its url (e.g., 'CompoPack_v51' for 'https://example.com/CompoPack_v51.tar.gz')
+func getPrefabPackName(pack string) string {
+	name := pack
+	parsed, err := url.Parse(pack)
+	if err == nil {
+		name = parsed.Path
+	}
+	name = path.Base(name)
+	for _, extension := range []string{".gz", ".bz2", ".xz", ".tar", ".tgz", ".zip", ".7z", ".rar"} {
+		name = strings.TrimSuffix(name, extension)
+	}
+	return prefabPackNameRegex.ReplaceAllString(name, "_")
+}
+
+// Finds the root of an extracted prefab pack - descending through lone top-level folders (e.g., 'CompoPack_v51/') until a pack folder (see [prefabPackDirs]) is found.
+// Returns an error if the pack contains no pack folders.
+func findPrefabPackRoot(dir string) (string, error) {
+	for {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range entries {
+			_, ok := prefabPackDirs[entry.Name()]
+			if entry.IsDir() && (ok || entry.Name() == "Config") {
+				return dir, nil
+			}
+		}
+		if len(entries) != 1 || !entries[0].IsDir() {
+			return "", fmt.Errorf("prefab pack contains none of %s", strings.Join(append(getPrefabPackDirNames(), "Config"), ", "))
+		}
+		dir = filepath.Join(dir, entries[0].Name())
+	}
+}
+
+// Gets the names of the pack folders (sorted)
+func getPrefabPackDirNames() []string {
+	names := []string{}
+	for name := range prefabPackDirs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Lists the files within a directory (relative to the directory)
+func listPrefabPackFiles(dir string) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		relpath, err := filepath.Rel(dir, current)
+		files = append(files, relpath)
+		return err
+	})
+	return files, err
+}
+
+// prefabPackModInfo is the ModInfo.xml written for a prefab pack's XPath patches
+const prefabPackModInfo = `<?xml version="1.0" encoding="UTF-8" ?>
+<xml>
+	<Name value="%[1]s" />
+	<DisplayName value="%[1]s" />
+	<Description value="Config patches of the %[1]s prefab pack (installed by the entrypoint)" />
+	<Author value="seven-days-to-die" />
+	<Version value="1.0.0" />
+</xml>
+`
+
+// Gets the path of the file recording the files installed by prefab packs (see [InstallPrefabPacks])
+func getPrefabPacksFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["sdtd"], "prefab-packs.json")
+}
+
+// Installs a prefab pack - merging its pack folders (see [prefabPackDirs]) into the sdtd directory and installing its XPath patches as a modlet.
+// Returns the files (relative to the sdtd directory) installed by the pack that aren't part of the game (i.e., that didn't exist before the pack was first installed).
+// Returns an error if the pack cannot be downloaded, extracted or installed.
+func installPrefabPack(ctx context.Context, opts InstallModsOpts, pack string, previous []string) ([]string, error) {
+	sdtd := helper.Dirs(ctx)["sdtd"]
+	name := getPrefabPackName(pack)
+	installed := []string{}
+	err := helper.CreateTempDir(ctx, func(tempDir string) error {
+		err := installMod(ctx, opts, tempDir, pack)
+		if err != nil {
+			return err
+		}
+		root, err := findPrefabPackRoot(tempDir)
+		if err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			dest, ok := prefabPackDirs[entry.Name()]
+			if entry.Name() == "Config" {
+				dest, ok = filepath.Join("Mods", name, "Config"), true
+			}
+			if !ok || !entry.IsDir() {
+				helper.Logger(ctx).Warn("prefab pack entry ignored", "pack", name, "entry", entry.Name())
+				continue
+			}
+			src := filepath.Join(root, entry.Name())
+			files, err := listPrefabPackFiles(src)
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				target := filepath.Join(dest, file)
+				_, err := os.Stat(filepath.Join(sdtd, target))
+				if errors.Is(err, os.ErrNotExist) || slices.Contains(previous, target) {
+					installed = append(installed, target)
+				}
+			}
+			helper.Logger(ctx).Info("install prefab pack folder", "pack", name, "folder", entry.Name(), "dest", dest, "files", len(files))
+			err = helper.CreateDirs(ctx, filepath.Join(sdtd, dest))
+			if err != nil {
+				return err
+			}
+			_, err = helper.Command(ctx, []string{"cp", "-a", fmt.Sprintf("%s/.", src), filepath.Join(sdtd, dest)}, helper.CmdOpts{}).Run()
+			if err != nil {
+				return err
+			}
+			if entry.Name() != "Config" {
+				continue
+			}
+			modInfo := filepath.Join("Mods", name, "ModInfo.xml")
+			_, err = os.Stat(filepath.Join(sdtd, modInfo))
+			if errors.Is(err, os.ErrNotExist) || slices.Contains(previous, modInfo) {
+				err = os.WriteFile(filepath.Join(sdtd, modInfo), []byte(fmt.Sprintf(prefabPackModInfo, name)), 0644)
+				if err != nil {
+					return err
+				}
+				installed = append(installed, modInfo)
+			}
+		}
+		return nil
+	})
+	return installed, err
+}
+
+// Installs prefab (POI) packs (e.g., CompoPack) - each downloaded and installed as a single unit:
+//   - 'Data', 'Prefabs' and 'Worlds' folders are merged into the game's 'Data', 'Data/Prefabs' and 'Data/Worlds' folders
+//   - 'Mods' folders are merged into the game's 'Mods' folder
+//   - 'Config' folders (XPath patches - e.g., rwgmixer.xml additions) are installed as a modlet named after the pack
+//
+// Packs are downloaded like mods (see [InstallMods]) - and are subject to the mod policy, the file cache and offline mode.
+// Files installed by packs that are no longer listed are removed (files that replaced game files are left as-is).
+// Returns an error if a pack cannot be installed.
+func InstallPrefabPacks(ctx context.Context, opts InstallModsOpts, packs ...string) error {
+	sdtd := helper.Dirs(ctx)["sdtd"]
+	previous := map[string][]string{}
+	err := helper.UnmarshalFile(ctx, getPrefabPacksFile(ctx), &previous)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	current := map[string][]string{}
+	for _, pack := range packs {
+		installed, err := installPrefabPack(ctx, opts, pack, previous[pack])
+		if err != nil {
+			return fmt.Errorf("install prefab pack %s: %w", pack, err)
+		}
+		current[pack] = installed
+	}
+	stale := []string{}
+	for pack, files := range previous {
+		for _, file := range files {
+			if !slices.ContainsFunc(packs, func(other string) bool { return slices.Contains(current[other], file) }) {
+				stale = append(stale, filepath.Join(sdtd, file))
+			}
+		}
+		if !slices.Contains(packs, pack) {
+			helper.Logger(ctx).Info("remove prefab pack", "pack", getPrefabPackName(pack))
+		}
+	}
+	err = helper.RemovePaths(ctx, stale...)
+	if err != nil {
+		return err
+	}
+	for pack := range previous {
+		if !slices.Contains(packs, pack) {
+			// modlets of removed packs are removed once empty (os.Remove fails for non-empty directories)
+			modlet := filepath.Join(sdtd, "Mods", getPrefabPackName(pack))
+			os.Remove(filepath.Join(modlet, "Config"))
+			os.Remove(modlet)
+		}
+	}
+	if len(current) == 0 && len(previous) == 0 {
+		return nil
+	}
+	return helper.MarshalFile(ctx, current, getPrefabPacksFile(ctx))
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+const (
+	// preflightMinGlibc is the oldest glibc supported by the (Unity) dedicated server
+	preflightMinGlibc = "2.17"
+	// preflightMinOpenFiles is the open file limit below which the server can fail to load large worlds and mods
+	preflightMinOpenFiles = 8192
+	// preflightMinUdpBuffer is the socket buffer size (in bytes) below which packets are dropped under load
+	preflightMinUdpBuffer = 2 * 1024 * 1024
+)
+
+// Parses a dotted version (e.g., '2.36') into its numeric parts
+func parseDottedVersion(value string) []int {
+	parts := []int{}
+	for _, part := range strings.Split(value, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		parts = append(parts, number)
+	}
+	return parts
+}
+
+// Checks that the host's glibc is new enough to run the dedicated server
+func preflightGlibc(ctx context.Context) DiagnosticResult {
+	name := "glibc version"
+	output, err := helper.Command(ctx, []string{"getconf", "GNU_LIBC_VERSION"}, helper.CmdOpts{}).Run()
+	if err != nil {
+		return DiagnosticResult{Name: name, Status: DiagnosticSkip, Detail: fmt.Sprintf("version unknown (not glibc?): %s", err.Error())}
+	}
+	version := strings.TrimPrefix(strings.TrimSpace(output), "glibc ")
+	current := parseDottedVersion(version)
+	minimum := parseDottedVersion(preflightMinGlibc)
+	for index := range minimum {
+		if index >= len(current) || current[index] < minimum[index] {
+			return DiagnosticResult{Name: name, Status: DiagnosticFail, Detail: fmt.Sprintf("glibc %s is older than %s - run the server on a newer distribution (or use the container image)", version, preflightMinGlibc)}
+		}
+		if current[index] > minimum[index] {
+			break
+		}
+	}
+	return DiagnosticResult{Name: name, Status: DiagnosticOk, Detail: fmt.Sprintf("glibc %s", version)}
+}
+
+// lddMissingRegex matches libraries that the dynamic linker cannot find in 'ldd' output (e.g., 'libfoo.so.1 => not found')
+var lddMissingRegex = regexp.MustCompile(`^\s*(\S+) => not found`)
+
+// Checks that the shared libraries linked by the server binary (and Unity player) are installed.
+// Only performed for [ExecutionModeNative] - emulated and proton builds can't be inspected with the host's 'ldd'.
+func preflightSharedLibraries(ctx context.Context) DiagnosticResult {
+	name := "shared libraries"
+	mode := GetExecutionMode(ctx)
+	if mode != ExecutionModeNative {
+		return DiagnosticResult{Name: name, Status: DiagnosticSkip, Detail: fmt.Sprintf("unsupported in execution mode %s", mode)}
+	}
+	sdtdDir := helper.Dirs(ctx)["sdtd"]
+	missing := []string{}
+	for _, file := range []string{mode.ServerBinary(), "UnityPlayer.so"} {
+		output, err := helper.Command(ctx, []string{"ldd", filepath.Join(sdtdDir, file)}, helper.CmdOpts{Cwd: sdtdDir, Env: append(os.Environ(), "LD_LIBRARY_PATH=.")}).Run()
+		if err != nil {
+			return DiagnosticResult{Name: name, Status: DiagnosticSkip, Detail: fmt.Sprintf("ldd %s failed: %s", file, err.Error())}
+		}
+		for _, line := range strings.Split(output, "\n") {
+			match := lddMissingRegex.FindStringSubmatch(line)
+			if match != nil && !slices.Contains(missing, match[1]) {
+				missing = append(missing, match[1])
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return DiagnosticResult{Name: name, Status: DiagnosticFail, Detail: fmt.Sprintf("missing %s - install the packages providing them (e.g., 'apt install lib32gcc-s1 libstdc++6')", strings.Join(missing, ", "))}
+	}
+	return DiagnosticResult{Name: name, Status: DiagnosticOk, Detail: "all linked libraries found"}
+}
+
+// Checks that 'steamclient.so' can be found by the Steamworks API - either within the sdtd directory or the Steam sdk directory ('~/.steam/sdk64').
+// Only performed for [ExecutionModeNative].
+func preflightSteamClient(ctx context.Context) DiagnosticResult {
+	name := "steamclient.so"
+	mode := GetExecutionMode(ctx)
+	if mode != ExecutionModeNative {
+		return DiagnosticResult{Name: name, Status: DiagnosticSkip, Detail: fmt.Sprintf("unsupported in execution mode %s", mode)}
+	}
+	paths := []string{filepath.Join(helper.Dirs(ctx)["sdtd"], "steamclient.so")}
+	home, err := os.UserHomeDir()
+	if err == nil {
+		paths = append(paths, filepath.Join(home, ".steam", "sdk64", "steamclient.so"))
+	}
+	for _, path := range paths {
+		_, err := os.Stat(path)
+		if err == nil {
+			return DiagnosticResult{Name: name, Status: DiagnosticOk, Detail: path}
+		}
+	}
+	return DiagnosticResult{Name: name, Status: DiagnosticWarn, Detail: fmt.Sprintf("not found in %s - the server can't register with Steam (re-download the server, or copy steamclient.so to ~/.steam/sdk64)", strings.Join(paths, ", "))}
+}
+
+// Checks that the locale doesn't break the server - the (mono) runtime parses numbers in config files using the locale's decimal separator, and non utf-8 locales garble player names
+func preflightLocale(ctx context.Context) DiagnosticResult {
+	name := "locale"
+	locale := ""
+	for _, key := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		locale = os.Getenv(key)
+		if locale != "" {
+			break
+		}
+	}
+	language, encoding, _ := strings.Cut(locale, ".")
+	if language != "" && language != "C" && language != "POSIX" && !strings.HasPrefix(language, "en_") {
+		return DiagnosticResult{Name: name, Status: DiagnosticWarn, Detail: fmt.Sprintf("%s may use ',' as a decimal separator (breaking config parsing) - set LC_ALL=C.UTF-8", locale)}
+	}
+	normalized := strings.ToLower(strings.ReplaceAll(encoding, "-", ""))
+	if locale != "" && language != "C" && language != "POSIX" && normalized != "utf8" {
+		return DiagnosticResult{Name: name, Status: DiagnosticWarn, Detail: fmt.Sprintf("%s isn't utf-8 (garbling player names and chat) - set LC_ALL=C.UTF-8", locale)}
+	}
+	if locale == "" {
+		locale = "C (default)"
+	}
+	return DiagnosticResult{Name: name, Status: DiagnosticOk, Detail: locale}
+}
+
+// Checks that the kernel's maximum udp socket buffer sizes are large enough for the game's traffic
+func preflightUdpBuffers(ctx context.Context) DiagnosticResult {
+	name := "udp buffers"
+	small := []string{}
+	sizes := []string{}
+	for _, key := range []string{"rmem_max", "wmem_max"} {
+		data, err := os.ReadFile(filepath.Join("/proc/sys/net/core", key))
+		if err != nil {
+			return DiagnosticResult{Name: name, Status: DiagnosticSkip, Detail: err.Error()}
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			return DiagnosticResult{Name: name, Status: DiagnosticSkip, Detail: err.Error()}
+		}
+		sizes = append(sizes, fmt.Sprintf("%s=%d", key, size))
+		if size < preflightMinUdpBuffer {
+			small = append(small, fmt.Sprintf("net.core.%s=%d", key, preflightMinUdpBuffer))
+		}
+	}
+	if len(small) > 0 {
+		return DiagnosticResult{Name: name, Status: DiagnosticWarn, Detail: fmt.Sprintf("%s - packets may be dropped under load (run 'sysctl -w %s' on the host)", strings.Join(sizes, ", "), strings.Join(small, " "))}
+	}
+	return DiagnosticResult{Name: name, Status: DiagnosticOk, Detail: strings.Join(sizes, ", ")}
+}
+
+// Checks that the open file limit is high enough for large worlds and mods - raising the soft limit to the hard limit when necessary (the server inherits the raised limit)
+func preflightOpenFiles(ctx context.Context) DiagnosticResult {
+	name := "open file limit"
+	limit := syscall.Rlimit{}
+	err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit)
+	if err != nil {
+		return DiagnosticResult{Name: name, Status: DiagnosticSkip, Detail: err.Error()}
+	}
+	if limit.Cur >= preflightMinOpenFiles {
+		return DiagnosticResult{Name: name, Status: DiagnosticOk, Detail: fmt.Sprintf("%d", limit.Cur)}
+	}
+	if limit.Max > limit.Cur {
+		previous := limit.Cur
+		limit.Cur = limit.Max
+		err = syscall.Setrlimit(syscall.RLIMIT_NOFILE, &limit)
+		if err == nil && limit.Cur >= preflightMinOpenFiles {
+			return DiagnosticResult{Name: name, Status: DiagnosticOk, Detail: fmt.Sprintf("raised from %d to %d", previous, limit.Cur)}
+		}
+	}
+	return DiagnosticResult{Name: name, Status: DiagnosticWarn, Detail: fmt.Sprintf("%d is below %d - raise it (e.g., 'docker run --ulimit nofile=65536:65536', or 'LimitNOFILE=65536' for systemd)", limit.Cur, preflightMinOpenFiles)}
+}
+
+// Runs preflight checks of the host environment - turning host issues that make the server exit instantly (or misbehave) into actionable diagnostics.
+func RunPreflightChecks(ctx context.Context) []DiagnosticResult {
+	return []DiagnosticResult{
+		preflightGlibc(ctx),
+		preflightSharedLibraries(ctx),
+		preflightSteamClient(ctx),
+		preflightOpenFiles(ctx),
+		preflightLocale(ctx),
+		preflightUdpBuffers(ctx),
+	}
+}
+
+// Runs (and logs) preflight checks of the host environment (see [RunPreflightChecks]).
+// Returns an error if any check fails.
+func CheckPreflight(ctx context.Context) error {
+	results := RunPreflightChecks(ctx)
+	for _, result := range results {
+		switch result.Status {
+		case DiagnosticFail:
+			helper.Logger(ctx).Error("preflight check failed", "check", result.Name, "detail", result.Detail)
+		case DiagnosticWarn:
+			helper.Logger(ctx).Warn("preflight check warning", "check", result.Name, "detail", result.Detail)
+		default:
+			helper.Logger(ctx).Info("preflight check", "check", result.Name, "status", result.Status, "detail", result.Detail)
+		}
+	}
+	failed := failedNames(results)
+	if len(failed) > 0 {
+		return fmt.Errorf("%d preflight check(s) failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// presets are curated sets of gameplay settings - selected with 'PRESET' and merged below user-defined settings
+var presets = map[string]ServerSettings{
+	// vanilla uses the game's defaults
+	"vanilla": {},
+	// casual suits new or relaxed players - slower zombies, more loot and xp and no player killing
+	"casual": {
+		"GameDifficulty":      "0",
+		"ZombieMove":          "0",
+		"ZombieMoveNight":     "1",
+		"ZombieFeralMove":     "1",
+		"ZombieBMMove":        "1",
+		"BloodMoonEnemyCount": "6",
+		"LootAbundance":       "150",
+		"XPMultiplier":        "150",
+		"DayNightLength":      "90",
+		"DeathPenalty":        "0",
+		"DropOnDeath":         "0",
+		"PlayerKillingMode":   "0",
+	},
+	// insane-feral suits veteran players - sprinting feral zombies, bigger and more frequent blood moons and scarce loot
+	"insane-feral": {
+		"GameDifficulty":      "5",
+		"EnemyDifficulty":     "1",
+		"ZombieFeralSense":    "3",
+		"ZombieMove":          "3",
+		"ZombieMoveNight":     "4",
+		"ZombieFeralMove":     "4",
+		"ZombieBMMove":        "4",
+		"BloodMoonFrequency":  "5",
+		"BloodMoonEnemyCount": "16",
+		"BlockDamageAIBM":     "150",
+		"LootAbundance":       "50",
+		"LootRespawnDays":     "14",
+		"DeathPenalty":        "2",
+		"DropOnDeath":         "1",
+	},
+	// pvp suits player-versus-player servers - everyone can be killed, everything drops on death and land claims are hardened
+	"pvp": {
+		"PlayerKillingMode":                  "3",
+		"DropOnDeath":                        "1",
+		"DropOnQuit":                         "1",
+		"LandClaimCount":                     "3",
+		"LandClaimOnlineDurabilityModifier":  "8",
+		"LandClaimOfflineDurabilityModifier": "32",
+		"PartySharedKillRange":               "200",
+	},
+}
+
+// Gets the names of the available presets (sorted)
+func PresetNames() []string {
+	names := []string{}
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Gets (a copy of) the settings of a named preset.
+// Returns an error if the preset is unrecognized.
+func GetPresetSettings(name string) (ServerSettings, error) {
+	settings, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("unrecognized preset %s (expected one of %v)", name, PresetNames())
+	}
+	return MergeServerSettings(settings), nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// procClockTicks is the unit (in ticks per second) of cpu times reported by '/proc/[pid]/stat' (USER_HZ - 100 on all common linux builds)
+const procClockTicks = 100
+
+// ProcessStats is the resource usage of the dedicated server process
+type ProcessStats struct {
+	Pid        int     `json:"pid"`
+	CpuPercent float64 `json:"cpuPercent"`
+	CpuSeconds float64 `json:"cpuSeconds"`
+	Rss        uint64  `json:"rssBytes"`
+	Threads    int     `json:"threads"`
+	OpenFiles  int     `json:"openFiles"`
+}
+
+// procStat holds the fields of '/proc/[pid]/stat' used by [ProcessSampler]
+type procStat struct {
+	Ppid     int
+	CpuTicks uint64
+	Threads  int
+	RssPages uint64
+}
+
+// Reads '/proc/[pid]/stat' (see proc(5)).
+// Returns an error if the file is unreadable or malformed.
+func readProcStat(pid int) (procStat, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return procStat{}, err
+	}
+	// the command name (field 2) is parenthesized and may contain spaces - fields are parsed after it
+	index := strings.LastIndexByte(string(data), ')')
+	if index == -1 {
+		return procStat{}, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	fields := strings.Fields(string(data[index+1:]))
+	if len(fields) < 22 {
+		return procStat{}, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	// fields[n] is field n+3 of proc(5)
+	ppid, _ := strconv.Atoi(fields[1])
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	threads, _ := strconv.Atoi(fields[17])
+	rss, _ := strconv.ParseUint(fields[21], 10, 64)
+	return procStat{Ppid: ppid, CpuTicks: utime + stime, Threads: threads, RssPages: rss}, nil
+}
+
+// Returns true if the process is a descendant of the current process
+func isDescendant(pid int) bool {
+	self := os.Getpid()
+	for pid > 1 {
+		stat, err := readProcStat(pid)
+		if err != nil {
+			return false
+		}
+		if stat.Ppid == self {
+			return true
+		}
+		pid = stat.Ppid
+	}
+	return false
+}
+
+// Finds the dedicated server process - a descendant of the current process whose command line references the server binary of the execution mode.
+// The process running the server binary is preferred - falling back to an emulator running it (e.g., 'box64 ./7DaysToDieServer.x86_64').
+// Launchers referencing the server binary further into their command line (e.g., 'proton run ./7DaysToDieServer.exe') are ignored.
+// Returns an error if the server process isn't running.
+func FindServerProcess(ctx context.Context) (int, error) {
+	binary := GetExecutionMode(ctx).ServerBinary()
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, err
+	}
+	found := 0
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "cmdline"))
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		args := strings.Split(string(data), "\x00")
+		position := slices.IndexFunc(args, func(arg string) bool {
+			// windows paths (e.g., under proton) are backslash-separated
+			return arg[strings.LastIndexAny(arg, `/\`)+1:] == binary
+		})
+		if position == -1 || position > 1 || !isDescendant(pid) {
+			continue
+		}
+		if position == 0 {
+			return pid, nil
+		}
+		found = pid
+	}
+	if found == 0 {
+		return 0, fmt.Errorf("server process (%s) not found", binary)
+	}
+	return found, nil
+}
+
+// ProcessSampler measures the resource usage of the dedicated server process.
+// CPU usage is averaged between consecutive samples.
+type ProcessSampler struct {
+	pid       int
+	lastTicks uint64
+	lastTime  time.Time
+}
+
+// Samples the resource usage of the dedicated server process (finding it first, if necessary).
+// Returns an error if the server process isn't running - or its usage cannot be read.
+func (ps *ProcessSampler) Sample(ctx context.Context) (ProcessStats, error) {
+	fail := func(err error) (ProcessStats, error) {
+		ps.pid = 0
+		return ProcessStats{}, err
+	}
+	if ps.pid == 0 {
+		pid, err := FindServerProcess(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		ps.pid = pid
+		ps.lastTicks = 0
+		ps.lastTime = time.Time{}
+	}
+	stat, err := readProcStat(ps.pid)
+	if err != nil {
+		return fail(err)
+	}
+	fds, err := os.ReadDir(filepath.Join("/proc", strconv.Itoa(ps.pid), "fd"))
+	if err != nil {
+		return fail(err)
+	}
+	now := time.Now()
+	stats := ProcessStats{
+		Pid:        ps.pid,
+		CpuSeconds: float64(stat.CpuTicks) / procClockTicks,
+		Rss:        stat.RssPages * uint64(os.Getpagesize()),
+		Threads:    stat.Threads,
+		OpenFiles:  len(fds),
+	}
+	if !ps.lastTime.IsZero() {
+		elapsed := now.Sub(ps.lastTime).Seconds()
+		stats.CpuPercent = float64(stat.CpuTicks-ps.lastTicks) / procClockTicks / elapsed * 100
+	}
+	ps.lastTicks = stat.CpuTicks
+	ps.lastTime = now
+	return stats, nil
+}
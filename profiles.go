@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// SettingsProfile defines setting overrides that are active during a recurring time window (e.g., PvP on weekends)
+type SettingsProfile struct {
+	Name string `json:"name"`
+	// Days are the weekdays (e.g., 'saturday', 'sun') on which the window starts - if empty, the window starts every day
+	Days []string `json:"days"`
+	// Start and End are the 'HH:MM' times (in the container's timezone) bounding the window - windows ending before they start span midnight
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Apply determines how the overrides are applied at window boundaries - 'restart' (the default) or 'runtime' (via 'setgamepref')
+	Apply        string         `json:"apply"`
+	Settings     ServerSettings `json:"settings"`
+	StartMessage string         `json:"startMessage"`
+	EndMessage   string         `json:"endMessage"`
+}
+
+const (
+	// ProfileApplyRestart applies settings profiles by restarting the server
+	ProfileApplyRestart = "restart"
+	// ProfileApplyRuntime applies settings profiles with console commands
+	ProfileApplyRuntime = "runtime"
+)
+
+// Parses an 'HH:MM' time into minutes since midnight.
+// Returns an error if the time is malformed.
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %s (expected HH:MM)", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// Parses a weekday name (or its three-letter abbreviation).
+// Returns an error if the weekday is unrecognized.
+func parseWeekday(value string) (time.Weekday, error) {
+	value = strings.ToLower(value)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if value == name || value == name[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day %s", value)
+}
+
+// Validates the settings profile.
+// Returns an error if the days, times or apply mode are invalid.
+func (sp *SettingsProfile) Validate() error {
+	for _, day := range sp.Days {
+		_, err := parseWeekday(day)
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", sp.Name, err)
+		}
+	}
+	for _, clock := range []string{sp.Start, sp.End} {
+		_, err := parseClock(clock)
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", sp.Name, err)
+		}
+	}
+	if sp.Apply != "" && sp.Apply != ProfileApplyRestart && sp.Apply != ProfileApplyRuntime {
+		return fmt.Errorf("profile %s: invalid apply mode %s (expected restart or runtime)", sp.Name, sp.Apply)
+	}
+	return nil
+}
+
+// Determines whether a window starts on the given weekday
+func (sp *SettingsProfile) startsOn(day time.Weekday) bool {
+	if len(sp.Days) == 0 {
+		return true
+	}
+	for _, value := range sp.Days {
+		parsed, _ := parseWeekday(value)
+		if parsed == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Determines whether the profile's window is active at the given time.
+// Windows with equal start and end times last 24 hours.
+func (sp *SettingsProfile) Active(now time.Time) bool {
+	start, _ := parseClock(sp.Start)
+	end, _ := parseClock(sp.End)
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return sp.startsOn(now.Weekday()) && minute >= start && minute < end
+	}
+	yesterday := now.AddDate(0, 0, -1).Weekday()
+	return (sp.startsOn(now.Weekday()) && minute >= start) || (sp.startsOn(yesterday) && minute < end)
+}
+
+// Loads settings profiles from a JSON file formatted as '{"profiles": [...]}'.
+// Returns an error if the file cannot be read or a profile is invalid.
+func LoadSettingsProfiles(ctx context.Context, file string) ([]SettingsProfile, error) {
+	helper.Logger(ctx).Info("load settings profiles", "path", file)
+	data := struct {
+		Profiles []SettingsProfile `json:"profiles"`
+	}{}
+	err := helper.UnmarshalFile(ctx, file, &data)
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range data.Profiles {
+		err := profile.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data.Profiles, nil
+}
+
+// Gets the names of the profiles active at the given time
+func getActiveProfiles(profiles []SettingsProfile, now time.Time) []string {
+	active := []string{}
+	for _, profile := range profiles {
+		if profile.Active(now) {
+			active = append(active, profile.Name)
+		}
+	}
+	return active
+}
+
+// Merges the settings of the named profiles (in definition order) over the base settings
+func applySettingsProfiles(base ServerSettings, profiles []SettingsProfile, active []string) ServerSettings {
+	items := []ServerSettings{base}
+	for _, profile := range profiles {
+		if slices.Contains(active, profile.Name) {
+			items = append(items, profile.Settings)
+		}
+	}
+	return MergeServerSettings(items...)
+}
+
+// Merges the settings of the profiles active at the given time over the base settings
+func ApplySettingsProfiles(ctx context.Context, base ServerSettings, profiles []SettingsProfile, now time.Time) ServerSettings {
+	active := getActiveProfiles(profiles, now)
+	if len(active) > 0 {
+		helper.Logger(ctx).Info("apply settings profiles", "profiles", active)
+	}
+	return applySettingsProfiles(base, profiles, active)
+}
+
+// settingsProfileRestartDelay is the delay between announcing a profile change and restarting the server
+const settingsProfileRestartDelay = time.Minute
+
+// Watches settings profile windows until the context is cancelled - announcing window boundaries and applying the changed settings.
+// If any profile starting or ending at a boundary uses [ProfileApplyRestart], the server is restarted (so that the settings are re-rendered on the next start).
+// Otherwise, changed settings are applied with 'setgamepref'.
+// Failing commands are logged and otherwise ignored.
+func RunSettingsProfiles(ctx context.Context, base ServerSettings, profiles []SettingsProfile) {
+	current := getActiveProfiles(profiles, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(time.Now().Truncate(time.Minute).Add(time.Minute))):
+		}
+		next := getActiveProfiles(profiles, time.Now())
+		if slices.Equal(current, next) {
+			continue
+		}
+		helper.Logger(ctx).Info("settings profiles changed", "from", current, "to", next)
+		restart := false
+		for _, profile := range profiles {
+			wasActive := slices.Contains(current, profile.Name)
+			isActive := slices.Contains(next, profile.Name)
+			if wasActive == isActive {
+				continue
+			}
+			message := profile.EndMessage
+			if isActive {
+				message = profile.StartMessage
+			}
+			if message != "" {
+				SayServer(ctx, message)
+			}
+			if profile.Apply != ProfileApplyRuntime {
+				restart = true
+			}
+		}
+		if restart {
+			SayServer(ctx, fmt.Sprintf("Restarting server in %s to apply new settings", settingsProfileRestartDelay))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(settingsProfileRestartDelay):
+			}
+			err := ShutdownServer(ctx)
+			if err != nil {
+				helper.Logger(ctx).Warn("settings profile restart failed", "error", err.Error())
+			}
+			return
+		}
+		changes := applySettingsProfiles(base, profiles, current).Diff(applySettingsProfiles(base, profiles, next))
+		for _, change := range changes {
+			if change.To == nil {
+				helper.Logger(ctx).Warn("settings profile setting has no base value - unchanged until restart", "setting", change.Name)
+				continue
+			}
+			_, err := SendCommand(ctx, fmt.Sprintf("setgamepref %s %s", change.Name, *change.To))
+			if err != nil {
+				helper.Logger(ctx).Warn("apply setting failed", "setting", change.Name, "error", err.Error())
+			}
+		}
+		current = next
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// TrustedProxies are the addresses of reverse proxies (and load balancers) whose 'X-Forwarded-For' headers are honored
+type TrustedProxies []netip.Prefix
+
+// Parses trusted proxies formatted as cidrs (e.g., '10.0.0.0/8') or addresses (e.g., '192.168.1.10').
+// Returns an error if a value is neither.
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	proxies := TrustedProxies{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(value)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %s (expected a cidr or an ip address)", value)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// Determines whether an address belongs to a trusted proxy
+func (tp TrustedProxies) trusts(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range tp {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Gets the address of the client that sent a request.
+// Requests from trusted proxies are attributed to the nearest untrusted address of their 'X-Forwarded-For' header (proxies append the address they received a request from - so addresses left of an untrusted address can be spoofed).
+func (tp TrustedProxies) ClientAddr(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+	remote, err := netip.ParseAddr(host)
+	if err != nil || !tp.trusts(remote) {
+		return host
+	}
+	forwarded := []string{}
+	for _, header := range request.Header.Values("X-Forwarded-For") {
+		for _, value := range strings.Split(header, ",") {
+			forwarded = append(forwarded, strings.TrimSpace(value))
+		}
+	}
+	client := host
+	for index := len(forwarded) - 1; index >= 0; index-- {
+		addr, err := netip.ParseAddr(forwarded[index])
+		if err != nil {
+			break
+		}
+		client = addr.Unmap().String()
+		if !tp.trusts(addr) {
+			break
+		}
+	}
+	return client
+}
+
+// ctxKeyClientAddr is the context key holding the address of the client that sent a request
+type ctxKeyClientAddr struct{}
+
+// Gets the address of the client that sent the request being handled (see [TrustedProxies.ClientAddr]).
+// Returns an empty string outside of requests.
+func GetClientAddr(ctx context.Context) string {
+	addr, _ := ctx.Value(ctxKeyClientAddr{}).(string)
+	return addr
+}
+
+// Attaches the address of the client that sent a request to the context
+func WithClientAddr(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, ctxKeyClientAddr{}, addr)
+}
+
+// statusRecorder records the status code of an http response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// Records the status code - and then writes it
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Wraps an http handler - attributing requests to their client (see [TrustedProxies.ClientAddr]) and logging them
+func (tp TrustedProxies) Handler(ctx context.Context, name string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		client := tp.ClientAddr(request)
+		request = request.WithContext(WithClientAddr(request.Context(), client))
+		recorder := &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+		start := time.Now()
+		handler.ServeHTTP(recorder, request)
+		helper.Logger(ctx).Debug(fmt.Sprintf("%s request", name), "method", request.Method, "path", request.URL.Path, "client", client, "status", recorder.status, "duration", time.Since(start))
+	})
+}
+
+// authLimiter rejects clients that repeatedly fail to authenticate (e.g., that are guessing tokens)
+type authLimiter struct {
+	lock     sync.Mutex
+	failures map[string][]time.Time
+	max      int
+	window   time.Duration
+}
+
+// Creates a new [authLimiter] - rejecting clients after [max] authentication failures within [window]
+func newAuthLimiter(max int, window time.Duration) *authLimiter {
+	return &authLimiter{failures: map[string][]time.Time{}, max: max, window: window}
+}
+
+// Removes failures older than the window
+func (al *authLimiter) expire(client string) {
+	cutoff := time.Now().Add(-al.window)
+	failures := al.failures[client]
+	for len(failures) > 0 && failures[0].Before(cutoff) {
+		failures = failures[1:]
+	}
+	if len(failures) == 0 {
+		delete(al.failures, client)
+		return
+	}
+	al.failures[client] = failures
+}
+
+// Determines whether a client is permitted to attempt authentication
+func (al *authLimiter) Allow(client string) bool {
+	al.lock.Lock()
+	defer al.lock.Unlock()
+	al.expire(client)
+	return len(al.failures[client]) < al.max
+}
+
+// Records a failed authentication attempt
+func (al *authLimiter) Fail(client string) {
+	al.lock.Lock()
+	defer al.lock.Unlock()
+	al.expire(client)
+	al.failures[client] = append(al.failures[client], time.Now())
+	if len(al.failures) > 1000 {
+		for other := range al.failures {
+			al.expire(other)
+		}
+	}
+}
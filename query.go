@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// QueryInfo is the server information reported by the steam server query protocol (A2S_INFO)
+type QueryInfo struct {
+	Name       string `json:"name"`
+	Map        string `json:"map"`
+	Game       string `json:"game"`
+	Players    int    `json:"players"`
+	MaxPlayers int    `json:"maxPlayers"`
+}
+
+// a2sInfoRequest is the payload of an A2S_INFO request
+var a2sInfoRequest = append([]byte{0xFF, 0xFF, 0xFF, 0xFF, 'T'}, append([]byte("Source Engine Query"), 0)...)
+
+// Reads a null-terminated string from an A2S response
+func readA2sString(reader *bytes.Reader) (string, error) {
+	data := []byte{}
+	for {
+		value, err := reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if value == 0 {
+			return string(data), nil
+		}
+		data = append(data, value)
+	}
+}
+
+// Parses an A2S_INFO response (following the 4-byte header).
+// Returns an error if the response is malformed.
+func parseA2sInfo(data []byte) (QueryInfo, error) {
+	info := QueryInfo{}
+	reader := bytes.NewReader(data)
+	kind, _ := reader.ReadByte()
+	if kind != 'I' {
+		return info, fmt.Errorf("unexpected A2S response type 0x%x", kind)
+	}
+	// protocol version
+	_, err := reader.ReadByte()
+	if err != nil {
+		return info, err
+	}
+	fields := []*string{&info.Name, &info.Map, nil, &info.Game}
+	for _, field := range fields {
+		value, err := readA2sString(reader)
+		if err != nil {
+			return info, fmt.Errorf("malformed A2S response: %w", err)
+		}
+		if field != nil {
+			*field = value
+		}
+	}
+	// app id
+	id := uint16(0)
+	err = binary.Read(reader, binary.LittleEndian, &id)
+	if err != nil {
+		return info, err
+	}
+	players, err := reader.ReadByte()
+	if err != nil {
+		return info, err
+	}
+	maxPlayers, err := reader.ReadByte()
+	if err != nil {
+		return info, err
+	}
+	info.Players = int(players)
+	info.MaxPlayers = int(maxPlayers)
+	return info, nil
+}
+
+// Queries a server (by address) with the steam server query protocol (A2S_INFO) - answering a challenge if the server sends one.
+// Returns an error if the server does not respond before the timeout or the response is malformed.
+func QueryServerInfo(ctx context.Context, addr string, timeout time.Duration) (QueryInfo, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return QueryInfo{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	request := a2sInfoRequest
+	response := make([]byte, 1400)
+	for attempt := 0; attempt < 2; attempt++ {
+		_, err = conn.Write(request)
+		if err != nil {
+			return QueryInfo{}, err
+		}
+		count, err := conn.Read(response)
+		if err != nil {
+			return QueryInfo{}, err
+		}
+		if count < 5 || !bytes.Equal(response[:4], []byte{0xFF, 0xFF, 0xFF, 0xFF}) {
+			return QueryInfo{}, fmt.Errorf("malformed A2S response")
+		}
+		if response[4] == 'A' && count >= 9 {
+			// challenge - the request is repeated with the challenge appended
+			request = append(append([]byte{}, a2sInfoRequest...), response[5:9]...)
+			continue
+		}
+		return parseA2sInfo(response[4:count])
+	}
+	return QueryInfo{}, fmt.Errorf("A2S challenge not accepted")
+}
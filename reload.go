@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// EnvFile is a file of 'KEY=VALUE' lines (blank lines and '#' comments are ignored) applied over the process environment - at startup, and whenever the configuration is reloaded (see [ControlPlane.Reload]).
+// Values within the file take precedence over the container's environment.
+type EnvFile struct {
+	Path string
+	// original holds the values (nil if unset) of the environment variables overridden by the file - restored once they're removed from the file
+	original map[string]*string
+}
+
+// Parses a line of an env file - returning false for blank lines and comments.
+// Returns an error if the line is malformed.
+func parseEnvFileLine(line string) (string, string, bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false, nil
+	}
+	line = strings.TrimPrefix(line, "export ")
+	name, value, ok := strings.Cut(line, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", false, fmt.Errorf("expected KEY=VALUE (got '%s')", line)
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return name, value, true, nil
+}
+
+// Applies the env file over the process environment - restoring variables removed from the file since it was last applied.
+// Returns an error if the file cannot be read or is malformed.
+func (ef *EnvFile) Apply(ctx context.Context) error {
+	helper.Logger(ctx).Info("apply env file", "path", ef.Path)
+	handle, err := os.Open(ef.Path)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	values := map[string]string{}
+	scanner := bufio.NewScanner(handle)
+	for number := 1; scanner.Scan(); number++ {
+		name, value, ok, err := parseEnvFileLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s line %d: %w", ef.Path, number, err)
+		}
+		if ok {
+			values[name] = value
+		}
+	}
+	err = scanner.Err()
+	if err != nil {
+		return err
+	}
+	if ef.original == nil {
+		ef.original = map[string]*string{}
+	}
+	for name, value := range ef.original {
+		_, ok := values[name]
+		if ok {
+			continue
+		}
+		if value == nil {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, *value)
+		}
+		delete(ef.original, name)
+	}
+	for name, value := range values {
+		_, ok := ef.original[name]
+		if !ok {
+			current, set := os.LookupEnv(name)
+			ef.original[name] = nil
+			if set {
+				ef.original[name] = &current
+			}
+		}
+		os.Setenv(name, value)
+	}
+	return nil
+}
+
+// ReloadableSubsystem is a subsystem of the entrypoint (e.g., a schedule or a webhook integration) that can be restarted with a reloaded configuration - without restarting the server
+type ReloadableSubsystem struct {
+	Name string
+	// Env are the environment variables configuring the subsystem - the subsystem is restarted when any of them change
+	Env []string
+	// Start runs the subsystem with the given configuration until the context is cancelled - returning immediately if the configuration disables the subsystem
+	Start func(ctx context.Context, config EntrypointConfig)
+}
+
+// reloadableSubsystem is a running [ReloadableSubsystem]
+type reloadableSubsystem struct {
+	ReloadableSubsystem
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// ReloadResult describes the outcome of [ControlPlane.Reload]
+type ReloadResult struct {
+	// Changed are the environment variables that changed since the configuration was loaded
+	Changed []string `json:"changed"`
+	// Reloaded are the subsystems restarted with the reloaded configuration
+	Reloaded []string `json:"reloaded"`
+	// RestartRequired are the changed environment variables that only take effect once the server is restarted
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// ControlPlane reloads the entrypoint configuration at runtime - restarting the subsystems (see [ReloadableSubsystem]) whose configuration changed, without restarting the server.
+// The process environment can't be changed from outside the process - and so reloaded configuration is read from an [EnvFile] (and from files referenced by the configuration).
+type ControlPlane struct {
+	// Config is the configuration the reloadable subsystems are running with
+	Config     EntrypointConfig
+	EnvFile    *EnvFile
+	lock       sync.Mutex
+	subsystems []*reloadableSubsystem
+}
+
+// Registers a reloadable subsystem - starting it with the current configuration
+func (cp *ControlPlane) Register(ctx context.Context, subsystem ReloadableSubsystem) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	running := &reloadableSubsystem{ReloadableSubsystem: subsystem, ctx: ctx}
+	cp.start(running)
+	cp.subsystems = append(cp.subsystems, running)
+}
+
+// Starts (or restarts) a reloadable subsystem with the current configuration
+func (cp *ControlPlane) start(subsystem *reloadableSubsystem) {
+	if subsystem.cancel != nil {
+		subsystem.cancel()
+	}
+	ctx, cancel := context.WithCancel(subsystem.ctx)
+	subsystem.cancel = cancel
+	go subsystem.Start(ctx, cp.Config)
+}
+
+// Gets the environment variables whose parsed values differ between two configurations
+func diffEntrypointConfig(from EntrypointConfig, to EntrypointConfig) []string {
+	changed := []string{}
+	fromValue := reflect.ValueOf(from)
+	toValue := reflect.ValueOf(to)
+	for index := 0; index < fromValue.NumField(); index++ {
+		if !reflect.DeepEqual(fromValue.Field(index).Interface(), toValue.Field(index).Interface()) {
+			changed = append(changed, fromValue.Type().Field(index).Tag.Get("env"))
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// Copies the values of the given environment variables' fields from one configuration to another
+func copyEntrypointConfig(from EntrypointConfig, to *EntrypointConfig, names []string) {
+	fromValue := reflect.ValueOf(from)
+	toValue := reflect.ValueOf(to).Elem()
+	for index := 0; index < fromValue.NumField(); index++ {
+		if slices.Contains(names, fromValue.Type().Field(index).Tag.Get("env")) {
+			toValue.Field(index).Set(fromValue.Field(index))
+		}
+	}
+}
+
+// Reloads the entrypoint configuration - re-applying the env file (if any) and restarting the subsystems whose environment variables changed.
+// Other changes are logged (and reported) as requiring a server restart - and aren't applied.
+// Returns an error if the env file cannot be applied or the reloaded configuration is invalid (in which case nothing is restarted).
+func (cp *ControlPlane) Reload(ctx context.Context) (ReloadResult, error) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	helper.Logger(ctx).Info("reload config")
+	result := ReloadResult{Changed: []string{}, Reloaded: []string{}, RestartRequired: []string{}}
+	if cp.EnvFile != nil {
+		err := cp.EnvFile.Apply(ctx)
+		if err != nil {
+			return result, err
+		}
+	}
+	err := MigrateConfigEnv(ctx)
+	if err != nil {
+		return result, err
+	}
+	config, err := LoadEntrypointConfig(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.Changed = diffEntrypointConfig(cp.Config, config)
+	reloaded := []string{}
+	for _, subsystem := range cp.subsystems {
+		if slices.ContainsFunc(subsystem.Env, func(name string) bool { return slices.Contains(result.Changed, name) }) {
+			result.Reloaded = append(result.Reloaded, subsystem.Name)
+			reloaded = append(reloaded, subsystem.Env...)
+		}
+	}
+	for _, name := range result.Changed {
+		if !slices.Contains(reloaded, name) {
+			result.RestartRequired = append(result.RestartRequired, name)
+		}
+	}
+	copyEntrypointConfig(config, &cp.Config, reloaded)
+	for _, subsystem := range cp.subsystems {
+		if slices.Contains(result.Reloaded, subsystem.Name) {
+			helper.Logger(ctx).Info("restart subsystem", "name", subsystem.Name)
+			cp.start(subsystem)
+		}
+	}
+	if len(result.RestartRequired) > 0 {
+		helper.Logger(ctx).Warn("config changes require a restart", "env", strings.Join(result.RestartRequired, ","))
+	}
+	helper.Logger(ctx).Info("reload config complete", "reloaded", strings.Join(result.Reloaded, ","))
+	return result, nil
+}
+
+// Reloads the configuration whenever the entrypoint receives SIGHUP - until the context is cancelled.
+// Failed reloads are logged and otherwise ignored.
+func (cp *ControlPlane) Run(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+		}
+		_, err := cp.Reload(ctx)
+		if err != nil {
+			helper.Logger(ctx).Error("reload config failed", "error", err.Error())
+		}
+	}
+}
+
+// Handles 'POST /api/reload' - reloading the entrypoint configuration (see [ControlPlane.Reload])
+func (aa *AdminApi) handleReload(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	if aa.ControlPlane == nil {
+		writeJson(writer, http.StatusNotFound, map[string]any{"error": "reload unavailable"})
+		return
+	}
+	result, err := aa.ControlPlane.Reload(request.Context())
+	aa.audit(request.Context(), token, "reload", err)
+	if err != nil {
+		writeJson(writer, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(writer, http.StatusOK, result)
+}
+
+// Requests that the running entrypoint reload its configuration - by sending SIGHUP to the entrypoint holding the data lock.
+// Returns an error if the data lock cannot be read (e.g., DATA_LOCK is disabled), or is held by another host.
+func ReloadSubcommand(ctx context.Context) error {
+	owner, err := readDataLock(ctx)
+	if err != nil {
+		return fmt.Errorf("entrypoint not found (requires DATA_LOCK): %w", err)
+	}
+	host, _ := os.Hostname()
+	if owner.Host != host {
+		return fmt.Errorf("entrypoint runs on another host (%s)", owner.Host)
+	}
+	process, err := os.FindProcess(owner.Pid)
+	if err != nil {
+		return err
+	}
+	err = process.Signal(syscall.SIGHUP)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("reload requested (pid %d) - see the entrypoint's logs for the result\n", owner.Pid)
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// Writes a replicated backup (its archive and metadata) to a local destination
+func writeReplicatedBackup(t *testing.T, dir string, metadata BackupMetadata) {
+	t.Helper()
+	err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%s.tar.gz", metadata.Name)), []byte("archive"), 0644)
+	if err == nil {
+		err = helper.MarshalFile(newTestContext(t), metadata, filepath.Join(dir, fmt.Sprintf("%s.json", metadata.Name)))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPruneReplicatedBackupsKeepsLabeledBackups(t *testing.T) {
+	ctx := newTestContext(t)
+	writeTestSaves(t, map[string]string{})
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	name := func(offset int) string {
+		return fmt.Sprintf("backup-%s", now.Add(time.Duration(offset)*time.Minute).Format("20060102T150405.000Z"))
+	}
+	// labeled remotely (e.g., deleted locally since) and labeled locally
+	writeReplicatedBackup(t, dir, BackupMetadata{Name: name(0), Label: "pre-darknessfalls-install", Mode: BackupModeFull})
+	writeReplicatedBackup(t, dir, BackupMetadata{Name: name(1), Mode: BackupModeFull})
+	local := BackupMetadata{Name: name(2), Label: "pre-wipe", CreatedAt: now, Mode: BackupModeFull}
+	writeReplicatedBackup(t, dir, local)
+	err := helper.MarshalFile(ctx, local, getBackupMetadataFile(ctx, local.Name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeReplicatedBackup(t, dir, BackupMetadata{Name: name(3), Mode: BackupModeFull})
+	writeReplicatedBackup(t, dir, BackupMetadata{Name: name(4), Mode: BackupModeFull})
+	err = pruneReplicatedBackups(ctx, ReplicationTarget{Destination: LocalDestination{Dir: dir}, Retention: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := LocalDestination{Dir: dir}.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, kept := range []bool{true, false, true, true, true} {
+		archive := fmt.Sprintf("%s.tar.gz", name(index))
+		if slices.Contains(names, archive) != kept {
+			t.Errorf("backup %d: expected kept=%t (remaining %v)", index, kept, names)
+		}
+	}
+}
+
+func TestSftpDestinationRemoveCommandQuotesPath(t *testing.T) {
+	destination := SftpDestination{Path: "/srv/game backups/"}
+	expected := `rm "/srv/game backups/backup-1.tar.gz"`
+	if actual := destination.removeCommand("backup-1.tar.gz"); actual != expected {
+		t.Fatalf("expected %s, got %s", expected, actual)
+	}
+	destination = SftpDestination{Path: `/srv/"quoted"`}
+	expected = `rm "/srv/\"quoted\"/backup-1.tar.gz"`
+	if actual := destination.removeCommand("backup-1.tar.gz"); actual != expected {
+		t.Fatalf("expected %s, got %s", expected, actual)
+	}
+}
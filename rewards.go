@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// PlaytimeReward defines items granted to players for every interval of playtime (e.g., a stack of dukes per hour)
+type PlaytimeReward struct {
+	Name string `json:"name"`
+	// Every is the playtime (e.g., '1h') after which the reward is granted (repeatedly)
+	Every    string `json:"every"`
+	Item     string `json:"item"`
+	Count    int    `json:"count"`
+	Quality  int    `json:"quality"`
+	Message  string `json:"message"`
+	interval time.Duration
+}
+
+// Validates the reward - parsing its interval.
+// Returns an error if the interval, item or count are invalid.
+func (pr *PlaytimeReward) Validate() error {
+	interval, err := time.ParseDuration(pr.Every)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("reward %s: invalid interval %s (expected a positive duration)", pr.Name, pr.Every)
+	}
+	if pr.Item == "" {
+		return fmt.Errorf("reward %s: item must be set", pr.Name)
+	}
+	if pr.Count <= 0 {
+		return fmt.Errorf("reward %s: count must be positive", pr.Name)
+	}
+	pr.interval = interval
+	return nil
+}
+
+// Gets the 'give' console command that grants the reward to a player (by entity id)
+func (pr *PlaytimeReward) Command(entityId string) string {
+	if pr.Quality > 0 {
+		return fmt.Sprintf("give %s %s %d %d", entityId, pr.Item, pr.Count, pr.Quality)
+	}
+	return fmt.Sprintf("give %s %s %d", entityId, pr.Item, pr.Count)
+}
+
+// Loads playtime rewards from a JSON file (formatted as '{"rewards": [...]}').
+// Returns an error if the file cannot be read or a reward is invalid.
+func LoadPlaytimeRewards(ctx context.Context, file string) ([]PlaytimeReward, error) {
+	helper.Logger(ctx).Info("load playtime rewards", "path", file)
+	data := struct {
+		Rewards []PlaytimeReward `json:"rewards"`
+	}{}
+	err := helper.UnmarshalFile(ctx, file, &data)
+	if err != nil {
+		return nil, err
+	}
+	for index := range data.Rewards {
+		err := data.Rewards[index].Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data.Rewards, nil
+}
+
+// Playtime is the accumulated playtime of a player (and the rewards granted for it)
+type Playtime struct {
+	Name     string         `json:"name"`
+	Seconds  int64          `json:"seconds"`
+	Rewarded map[string]int `json:"rewarded"`
+}
+
+// Gets the path of the file that persists player playtime
+func getPlaytimeFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "playtime.json")
+}
+
+// Loads persisted player playtime (keyed by platform id).
+// Returns an error if the playtime file cannot be read.
+func LoadPlaytime(ctx context.Context) (map[string]*Playtime, error) {
+	playtime := map[string]*Playtime{}
+	err := helper.UnmarshalFile(ctx, getPlaytimeFile(ctx), &playtime)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]*Playtime{}, nil
+	}
+	return playtime, err
+}
+
+// PlaytimeRewarder tracks player playtime and grants [PlaytimeReward] items as playtime accumulates
+type PlaytimeRewarder struct {
+	Auditor  *Auditor
+	Rewards  []PlaytimeReward
+	lastSeen map[string]time.Time
+	playtime map[string]*Playtime
+}
+
+// Creates a new [PlaytimeRewarder] - loading persisted playtime.
+// Returns an error if the playtime file cannot be read.
+func NewPlaytimeRewarder(ctx context.Context, auditor *Auditor, rewards []PlaytimeReward) (*PlaytimeRewarder, error) {
+	playtime, err := LoadPlaytime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &PlaytimeRewarder{Auditor: auditor, Rewards: rewards, lastSeen: map[string]time.Time{}, playtime: playtime}, nil
+}
+
+// Accumulates the playtime of the online players since the previous check (up to a maximum, so that gaps in polling aren't counted) - and grants rewards that are due.
+// Granted rewards are recorded in the audit log.
+// Returns an error if the playtime file cannot be written.
+func (pr *PlaytimeRewarder) Check(ctx context.Context, players []OnlinePlayer, now time.Time, maxElapsed time.Duration) error {
+	online := map[string]bool{}
+	for _, player := range players {
+		if player.PlatformId == "" {
+			continue
+		}
+		online[player.PlatformId] = true
+		playtime, ok := pr.playtime[player.PlatformId]
+		if !ok {
+			playtime = &Playtime{Rewarded: map[string]int{}}
+			pr.playtime[player.PlatformId] = playtime
+		}
+		if playtime.Rewarded == nil {
+			playtime.Rewarded = map[string]int{}
+		}
+		playtime.Name = player.Name
+		for _, reward := range pr.Rewards {
+			_, ok := playtime.Rewarded[reward.Name]
+			if !ok {
+				// rewards aren't granted retroactively for playtime accumulated before the reward was defined
+				playtime.Rewarded[reward.Name] = int(time.Duration(playtime.Seconds) * time.Second / reward.interval)
+			}
+		}
+		lastSeen, ok := pr.lastSeen[player.PlatformId]
+		pr.lastSeen[player.PlatformId] = now
+		if !ok {
+			continue
+		}
+		playtime.Seconds += int64(min(now.Sub(lastSeen), maxElapsed).Seconds())
+		for _, reward := range pr.Rewards {
+			due := int(time.Duration(playtime.Seconds)*time.Second/reward.interval) - playtime.Rewarded[reward.Name]
+			for ; due > 0; due-- {
+				helper.Logger(ctx).Info("grant playtime reward", "player", player.Name, "reward", reward.Name)
+				_, err := ExecAuditedCommand(ctx, pr.Auditor, nil, "rewards", "rewards", reward.Command(player.EntityId))
+				if err != nil {
+					helper.Logger(ctx).Warn("grant playtime reward failed", "player", player.Name, "reward", reward.Name, "error", err.Error())
+					break
+				}
+				playtime.Rewarded[reward.Name] += 1
+				if reward.Message != "" {
+					SayPlayer(ctx, player.EntityId, reward.Message)
+				}
+			}
+		}
+	}
+	for platformId := range pr.lastSeen {
+		if !online[platformId] {
+			delete(pr.lastSeen, platformId)
+		}
+	}
+	return helper.MarshalFile(ctx, pr.playtime, getPlaytimeFile(ctx))
+}
+
+// Periodically accumulates playtime and grants rewards until the context is cancelled.
+func (pr *PlaytimeRewarder) Run(ctx context.Context, interval time.Duration) {
+	helper.Logger(ctx).Info("start playtime rewards", "rewards", len(pr.Rewards))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		players, err := ListPlayers(ctx)
+		if err == nil {
+			err = pr.Check(ctx, players, time.Now(), 2*interval)
+		}
+		if err != nil {
+			helper.Logger(ctx).Warn("playtime rewards check failed", "error", err.Error())
+		}
+	}
+}
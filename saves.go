@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// Saves the world (with 'saveworld') - recording the time of the save in the status (see [ServerStatus.LastSave]).
+// Returns an error if the console command fails.
+func SaveWorld(ctx context.Context) error {
+	_, err := SendCommand(ctx, "saveworld")
+	if err != nil {
+		return err
+	}
+	tracker := GetStatusTracker(ctx)
+	if tracker != nil {
+		tracker.Update(ctx, func(status *ServerStatus) {
+			now := time.Now().UTC()
+			status.LastSave = &now
+		})
+	}
+	return nil
+}
+
+// Determines whether a blood moon can occur on the given (in-game) day.
+// With a non-zero range, the blood moon day is randomized - and so every day within the range of a blood moon day is considered.
+func isBloodMoonDay(day int, frequency int, dayRange int) bool {
+	if frequency <= 0 || day <= 0 {
+		return false
+	}
+	remainder := day % frequency
+	return min(remainder, frequency-remainder) <= dayRange
+}
+
+// Determines whether a blood moon is (or could be) in progress at the given in-game time.
+// Blood moon hordes run from 22:00 on the blood moon day until 04:00 the following day.
+func IsBloodMoon(gameTime GameTime, frequency int, dayRange int) bool {
+	if gameTime.Hour >= 22 {
+		return isBloodMoonDay(gameTime.Day, frequency, dayRange)
+	}
+	if gameTime.Hour < 4 {
+		return isBloodMoonDay(gameTime.Day-1, frequency, dayRange)
+	}
+	return false
+}
+
+// SaveScheduleOpts are options for [RunSaveSchedule]
+type SaveScheduleOpts struct {
+	Interval time.Duration
+	// Jitter is the maximum random delay added to each interval - so that saves don't repeatedly coincide with other periodic work (e.g., hordes, backups)
+	Jitter time.Duration
+	// BloodMoonFrequency and BloodMoonRange are the server's blood moon settings - saves are deferred while a blood moon is in progress (a zero frequency disables the deferral)
+	BloodMoonFrequency int
+	BloodMoonRange     int
+}
+
+// Waits until no blood moon is in progress (see [IsBloodMoon]) - checking the in-game time every minute.
+// The wait ends early if the in-game time cannot be retrieved.
+// Returns an error if the context is cancelled.
+func waitBloodMoon(ctx context.Context, opts SaveScheduleOpts) error {
+	deferred := false
+	for {
+		gameTime, err := GetGameTime(ctx)
+		if err != nil || !IsBloodMoon(gameTime, opts.BloodMoonFrequency, opts.BloodMoonRange) {
+			return nil
+		}
+		if !deferred {
+			helper.Logger(ctx).Info("save deferred during blood moon", "day", gameTime.Day, "hour", gameTime.Hour)
+			deferred = true
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Minute):
+		}
+	}
+}
+
+// Periodically saves the world (every [SaveScheduleOpts.Interval] plus up to [SaveScheduleOpts.Jitter], while the server is ready) until the context is cancelled.
+// Saves are independent of the game's own autosaves - and are deferred while a blood moon is in progress (when the server is under the most load).
+// Failing saves are logged and otherwise ignored.
+func RunSaveSchedule(ctx context.Context, opts SaveScheduleOpts) {
+	tracker := GetStatusTracker(ctx)
+	for {
+		delay := opts.Interval
+		if opts.Jitter > 0 {
+			delay += rand.N(opts.Jitter)
+		}
+		if tracker != nil {
+			tracker.Update(ctx, func(status *ServerStatus) {
+				nextSave := time.Now().Add(delay)
+				status.NextSave = &nextSave
+			})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if tracker != nil && tracker.Get().State != ServerStateReady {
+			continue
+		}
+		if opts.BloodMoonFrequency > 0 {
+			err := waitBloodMoon(ctx, opts)
+			if err != nil {
+				return
+			}
+		}
+		err := SaveWorld(ctx)
+		if err != nil {
+			helper.Logger(ctx).Warn("scheduled save failed", "error", err.Error())
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// ErrSayQueueFull is returned when a chat message is dropped because the [SayQueue] is full
+var ErrSayQueueFull = errors.New("say queue full")
+
+const (
+	// sayInterval is the minimum time between chat messages sent by the [SayQueue]
+	sayInterval = time.Second
+	// sayDedupWindow is how long an identical chat message (to the same recipient) is suppressed after it's queued
+	sayDedupWindow = 30 * time.Second
+	// sayQueueSize is the maximum number of chat messages waiting to be sent
+	sayQueueSize = 32
+)
+
+// SayQueue serializes chat messages ('say' and 'sayplayer' commands) sent by every subsystem - rate limiting and deduplicating them so that the in-game chat isn't flooded
+type SayQueue struct {
+	commands chan string
+	lock     sync.Mutex
+	queued   map[string]time.Time
+}
+
+// Creates a new [SayQueue].  Call [SayQueue.Run] to send queued messages.
+func NewSayQueue() *SayQueue {
+	return &SayQueue{commands: make(chan string, sayQueueSize), queued: map[string]time.Time{}}
+}
+
+// Queues a chat command - identical commands queued within [sayDedupWindow] are dropped.
+// Returns an error if the queue is full.
+func (sq *SayQueue) Enqueue(ctx context.Context, command string) error {
+	sq.lock.Lock()
+	defer sq.lock.Unlock()
+	now := time.Now()
+	for key, queuedAt := range sq.queued {
+		if now.Sub(queuedAt) > sayDedupWindow {
+			delete(sq.queued, key)
+		}
+	}
+	_, ok := sq.queued[command]
+	if ok {
+		helper.Logger(ctx).Debug("duplicate chat message dropped", "command", command)
+		return nil
+	}
+	select {
+	case sq.commands <- command:
+		sq.queued[command] = now
+		return nil
+	default:
+		helper.Logger(ctx).Warn("chat message dropped - say queue full", "command", command)
+		return ErrSayQueueFull
+	}
+}
+
+// Sends queued chat commands (at most one every [sayInterval]) until the context is cancelled.
+// Failing commands are logged and otherwise ignored.
+func (sq *SayQueue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case command := <-sq.commands:
+			_, err := SendCommand(ctx, command)
+			if err != nil {
+				helper.Logger(ctx).Warn("send chat message failed", "error", err.Error())
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(sayInterval):
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// sdtdExecutablePatterns are glob patterns (relative to the sdtd directory) of files that must be executable
+var sdtdExecutablePatterns = []string{
+	"*.sh",
+	"*.x86_64",
+	"EasyAntiCheat/*",
+}
+
+// Checks that a sdtd directory contains every path critical to the current execution mode.
+// Returns an error listing all missing paths.
+func CheckSdtdFiles(ctx context.Context, dir string, manifestId string) error {
+	helper.Logger(ctx).Info("check sdtd files", "dir", dir, "manifest", manifestId)
+	missing := []string{}
+	for _, path := range GetExecutionMode(ctx).CriticalPaths() {
+		_, err := os.Lstat(filepath.Join(dir, path))
+		if err != nil {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("sdtd (manifest %s) is incomplete - missing: %s", manifestId, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Sets the executable bit on the server binary, scripts and EasyAntiCheat binaries (which can be lost when restored from mirrors or archives).
+// Returns an error if a file cannot be made executable.
+func SetSdtdExecutables(ctx context.Context) error {
+	sdtdDir := helper.Dirs(ctx)["sdtd"]
+	for _, pattern := range sdtdExecutablePatterns {
+		matches, err := filepath.Glob(filepath.Join(sdtdDir, pattern))
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || info.Mode()&0111 == 0111 {
+				continue
+			}
+			helper.Logger(ctx).Info("set executable", "path", match)
+			err = os.Chmod(match, info.Mode()|fs.FileMode(0755))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Performs post-download fixups of the sdtd directory - verifying that critical files are present and setting executable bits.
+// Returns an error if critical files are missing.
+// Returns an error if executable bits cannot be set.
+func FixupSdtd(ctx context.Context, manifestId string) error {
+	err := CheckSdtdFiles(ctx, helper.Dirs(ctx)["sdtd"], manifestId)
+	if err != nil {
+		return err
+	}
+	return SetSdtdExecutables(ctx)
+}
+
+// SdtdFileList records the files (and their sizes) of a downloaded sdtd manifest
+type SdtdFileList struct {
+	Manifest string           `json:"manifest"`
+	Files    map[string]int64 `json:"files"`
+}
+
+// SdtdDiff summarizes the files changed between two sdtd manifests
+type SdtdDiff struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+	// Config lists added, removed and changed files within 'Data/Config' - which are most likely to conflict with mods
+	Config []string `json:"config"`
+}
+
+// Gets the path of the file that records the file list of the installed sdtd manifest
+func getSdtdFileListFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "sdtd-files.json")
+}
+
+// Gets the path of the file that records the diff summary of the most recent sdtd manifest change
+func getSdtdDiffFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "sdtd-diff.json")
+}
+
+// Lists the files (and their sizes) within the sdtd directory.
+// Returns an error if the sdtd directory cannot be walked.
+func GetSdtdFileList(ctx context.Context, manifestId string) (SdtdFileList, error) {
+	fileList := SdtdFileList{Manifest: manifestId, Files: map[string]int64{}}
+	sdtdDir := helper.Dirs(ctx)["sdtd"]
+	err := filepath.WalkDir(sdtdDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relpath, err := filepath.Rel(sdtdDir, path)
+		if err != nil {
+			return err
+		}
+		fileList.Files[filepath.ToSlash(relpath)] = info.Size()
+		return nil
+	})
+	return fileList, err
+}
+
+// Compares the file lists of two sdtd manifests.
+// Files are considered changed when their sizes differ.
+func DiffSdtdFileLists(from SdtdFileList, to SdtdFileList) SdtdDiff {
+	diff := SdtdDiff{From: from.Manifest, To: to.Manifest, Added: []string{}, Removed: []string{}, Changed: []string{}, Config: []string{}}
+	for path, size := range to.Files {
+		fromSize, ok := from.Files[path]
+		if !ok {
+			diff.Added = append(diff.Added, path)
+		} else if fromSize != size {
+			diff.Changed = append(diff.Changed, path)
+		} else {
+			continue
+		}
+		if strings.HasPrefix(path, "Data/Config/") {
+			diff.Config = append(diff.Config, path)
+		}
+	}
+	for path := range from.Files {
+		_, ok := to.Files[path]
+		if !ok {
+			diff.Removed = append(diff.Removed, path)
+			if strings.HasPrefix(path, "Data/Config/") {
+				diff.Config = append(diff.Config, path)
+			}
+		}
+	}
+	for _, paths := range [][]string{diff.Added, diff.Removed, diff.Changed, diff.Config} {
+		sort.Strings(paths)
+	}
+	return diff
+}
+
+// Records the file list of the installed sdtd manifest.
+// If a different manifest was previously installed, a diff summary is logged and written to the data directory.
+// Must be called before mods are installed (so that mod files aren't included in the file list).
+// Returns an error if the file lists cannot be read or written.
+func RecordSdtdManifest(ctx context.Context, manifestId string) error {
+	previous := SdtdFileList{}
+	err := helper.UnmarshalFile(ctx, getSdtdFileListFile(ctx), &previous)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if previous.Manifest == manifestId {
+		return nil
+	}
+	current, err := GetSdtdFileList(ctx, manifestId)
+	if err != nil {
+		return err
+	}
+	if previous.Manifest != "" {
+		diff := DiffSdtdFileLists(previous, current)
+		helper.Logger(ctx).Info("sdtd manifest changed", "from", diff.From, "to", diff.To, "added", len(diff.Added), "removed", len(diff.Removed), "changed", len(diff.Changed))
+		if len(diff.Config) > 0 {
+			helper.Logger(ctx).Warn("sdtd manifest changed game config files - mods may be affected", "files", diff.Config)
+		}
+		err = helper.MarshalFile(ctx, diff, getSdtdDiffFile(ctx))
+		if err != nil {
+			return err
+		}
+	}
+	return helper.MarshalFile(ctx, current, getSdtdFileListFile(ctx))
+}
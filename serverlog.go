@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// serverLogLevels are the levels of the server's log lines
+var serverLogLevels = []string{"INF", "WRN", "ERR", "EXC"}
+
+// serverGameLogLevels are the levels that can be hidden with the game's 'loglevel' command - 'INF' lines are relied upon by the telnet session (e.g., to correlate command responses)
+var serverGameLogLevels = []string{"WRN", "ERR", "EXC"}
+
+// serverLogLevelRegex matches the level of a server log line (e.g., '2024-01-01T00:00:00 12.345 WRN ...')
+var serverLogLevelRegex = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2} [\d.]+ ([A-Z]{3}) `)
+
+// Parses server log levels (e.g., 'wrn' or 'WRN').
+// Returns an error if a level is unknown.
+func ParseServerLogLevels(values []string) ([]string, error) {
+	levels := []string{}
+	for _, value := range values {
+		level := strings.ToUpper(strings.TrimSpace(value))
+		if !slices.Contains(serverLogLevels, level) {
+			return nil, fmt.Errorf("unknown log level %s (expected one of %v)", value, serverLogLevels)
+		}
+		if !slices.Contains(levels, level) {
+			levels = append(levels, level)
+		}
+	}
+	return levels, nil
+}
+
+// Parses server log suppression patterns (regular expressions).
+// Returns an error if a pattern is invalid.
+func ParseServerLogPatterns(values []string) ([]*regexp.Regexp, error) {
+	patterns := []*regexp.Regexp{}
+	for _, value := range values {
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log pattern %s: %w", value, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// ServerLogFilterState is the current configuration of a [ServerLogFilter]
+type ServerLogFilterState struct {
+	// Hidden are the log levels omitted from the server's output
+	Hidden []string `json:"hidden"`
+	// Patterns are the regular expressions matching log lines omitted from the server's output
+	Patterns []string `json:"patterns"`
+	// GameHidden are the log levels the game stops sending (with its 'loglevel' command) - the game applies these to the telnet connection sending the command (i.e., the entrypoint's telnet session and its transcript), rather than to the server's output
+	GameHidden []string `json:"gameHidden"`
+	// Until is when a temporary configuration reverts to the default configuration
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// ServerLogFilter omits log lines (by level, or matching a pattern) from the server's output - and can be reconfigured without restarting the server (see [AdminApi.handleSetLogLevel]).
+// Lines that continue a log line (e.g., exception stack traces) follow the log line they continue.
+// Additionally, the game's own log levels are toggled over the telnet session (see [ServerLogFilterState.GameHidden]).
+type ServerLogFilter struct {
+	defaults   ServerLogFilterState
+	gameHidden []string
+	gameLock   sync.Mutex
+	lock       sync.Mutex
+	patterns   []*regexp.Regexp
+	revert     *time.Timer
+	state      ServerLogFilterState
+}
+
+// Creates a new [ServerLogFilter] with a default configuration.
+// Returns an error if a level or pattern is invalid.
+func NewServerLogFilter(hidden []string, patterns []string) (*ServerLogFilter, error) {
+	slf := ServerLogFilter{defaults: ServerLogFilterState{Hidden: []string{}, Patterns: []string{}, GameHidden: []string{}}, gameHidden: []string{}}
+	err := slf.apply(ServerLogFilterState{Hidden: hidden, Patterns: patterns})
+	if err != nil {
+		return nil, err
+	}
+	slf.defaults = slf.state
+	return &slf, nil
+}
+
+// Applies a configuration.
+// Returns an error if a level or pattern is invalid.
+func (slf *ServerLogFilter) apply(state ServerLogFilterState) error {
+	hidden, err := ParseServerLogLevels(state.Hidden)
+	if err != nil {
+		return err
+	}
+	patterns, err := ParseServerLogPatterns(state.Patterns)
+	if err != nil {
+		return err
+	}
+	gameHidden, err := ParseServerLogLevels(state.GameHidden)
+	if err != nil {
+		return err
+	}
+	for _, level := range gameHidden {
+		if !slices.Contains(serverGameLogLevels, level) {
+			return fmt.Errorf("log level %s cannot be hidden by the game (expected one of %v)", level, serverGameLogLevels)
+		}
+	}
+	slf.state = ServerLogFilterState{Hidden: hidden, Patterns: slices.Clone(state.Patterns), GameHidden: gameHidden, Until: state.Until}
+	if slf.state.Patterns == nil {
+		slf.state.Patterns = []string{}
+	}
+	slf.patterns = patterns
+	return nil
+}
+
+// Gets the current configuration
+func (slf *ServerLogFilter) Get() ServerLogFilterState {
+	slf.lock.Lock()
+	defer slf.lock.Unlock()
+	return slf.state
+}
+
+// Replaces the current configuration - reverting to the default configuration after [duration] (if positive).
+// Returns an error if a level or pattern is invalid.
+func (slf *ServerLogFilter) Set(ctx context.Context, state ServerLogFilterState, duration time.Duration) error {
+	err := func() error {
+		slf.lock.Lock()
+		defer slf.lock.Unlock()
+		state.Until = nil
+		if duration > 0 {
+			until := time.Now().Add(duration)
+			state.Until = &until
+		}
+		err := slf.apply(state)
+		if err != nil {
+			return err
+		}
+		if slf.revert != nil {
+			slf.revert.Stop()
+			slf.revert = nil
+		}
+		helper.Logger(ctx).Info("set server log filter", "hidden", slf.state.Hidden, "patterns", slf.state.Patterns, "game-hidden", slf.state.GameHidden, "duration", duration)
+		if duration > 0 {
+			// the revert outlives the request that set the configuration
+			revertCtx := context.WithoutCancel(ctx)
+			slf.revert = time.AfterFunc(duration, func() {
+				slf.Reset(revertCtx)
+			})
+		}
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+	slf.syncGameLevels(ctx)
+	return nil
+}
+
+// Reverts to the default configuration
+func (slf *ServerLogFilter) Reset(ctx context.Context) {
+	func() {
+		slf.lock.Lock()
+		defer slf.lock.Unlock()
+		if slf.revert != nil {
+			slf.revert.Stop()
+			slf.revert = nil
+		}
+		slf.apply(slf.defaults)
+		helper.Logger(ctx).Info("reset server log filter", "hidden", slf.state.Hidden, "patterns", slf.state.Patterns, "game-hidden", slf.state.GameHidden)
+	}()
+	slf.syncGameLevels(ctx)
+}
+
+// Sends the game's 'loglevel' command for each level whose visibility differs from the configuration (see [ServerLogFilterState.GameHidden]).
+// Failing commands are logged and otherwise ignored (and are retried by the next sync).
+func (slf *ServerLogFilter) syncGameLevels(ctx context.Context) {
+	slf.gameLock.Lock()
+	defer slf.gameLock.Unlock()
+	hidden := slf.Get().GameHidden
+	for _, level := range serverGameLogLevels {
+		hide := slices.Contains(hidden, level)
+		if hide == slices.Contains(slf.gameHidden, level) {
+			continue
+		}
+		_, err := SendCommand(ctx, fmt.Sprintf("loglevel %s %t", level, !hide))
+		if err != nil {
+			helper.Logger(ctx).Warn("set game log level failed", "level", level, "error", err.Error())
+			continue
+		}
+		if hide {
+			slf.gameHidden = append(slf.gameHidden, level)
+		} else {
+			slf.gameHidden = slices.DeleteFunc(slf.gameHidden, func(item string) bool { return item == level })
+		}
+	}
+}
+
+// Re-applies the game's log levels once the telnet session reconnects (as the game shows every level on new connections) - see [TelnetSession.OnConnect]
+func (slf *ServerLogFilter) OnTelnetConnect(ctx context.Context) {
+	slf.gameLock.Lock()
+	slf.gameHidden = []string{}
+	slf.gameLock.Unlock()
+	slf.syncGameLevels(ctx)
+}
+
+// Determines whether a log line is omitted - [previous] is whether the previous line was omitted (inherited by lines that continue a log line)
+func (slf *ServerLogFilter) omit(line string, previous bool) bool {
+	slf.lock.Lock()
+	defer slf.lock.Unlock()
+	match := serverLogLevelRegex.FindStringSubmatch(line)
+	if match == nil {
+		return previous
+	}
+	if slices.Contains(slf.state.Hidden, match[1]) {
+		return true
+	}
+	for _, pattern := range slf.patterns {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// Copies server output to [writer] - omitting filtered log lines - until the output is closed
+func (slf *ServerLogFilter) copy(output io.Reader, writer io.Writer) {
+	reader := bufio.NewReader(output)
+	omitted := false
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			omitted = slf.omit(strings.TrimRight(line, "\r\n"), omitted)
+			if !omitted {
+				io.WriteString(writer, line)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Redirects stdout through the filter while [cb] runs (e.g., while an attached command is assembled) - the returned function stops the filter once the server has exited.
+// Returns an error if the pipe cannot be created.
+func (slf *ServerLogFilter) Attach(cb func()) (func(), error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout := os.Stdout
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		slf.copy(reader, stdout)
+	}()
+	os.Stdout = writer
+	cb()
+	os.Stdout = stdout
+	return func() {
+		writer.Close()
+		// processes spawned by the server (and still holding the pipe) shouldn't block the entrypoint
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+		reader.Close()
+	}, nil
+}
+
+// Handles 'GET /api/loglevel' - getting the server log filter
+func (aa *AdminApi) handleGetLogLevel(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	writeJson(writer, http.StatusOK, aa.LogFilter.Get())
+}
+
+// Handles 'PUT /api/loglevel' - replacing the server log filter and the game's log levels (optionally for a limited duration), or reverting to the default filter (with 'DELETE /api/loglevel')
+func (aa *AdminApi) handleSetLogLevel(writer http.ResponseWriter, request *http.Request, token AdminToken) {
+	ctx := request.Context()
+	if request.Method == http.MethodDelete {
+		aa.LogFilter.Reset(ctx)
+		aa.audit(ctx, token, "loglevel reset", nil)
+		writeJson(writer, http.StatusOK, aa.LogFilter.Get())
+		return
+	}
+	body := struct {
+		Hidden     []string `json:"hidden"`
+		Patterns   []string `json:"patterns"`
+		GameHidden []string `json:"gameHidden"`
+		Duration   string   `json:"duration"`
+	}{}
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		writeJson(writer, http.StatusBadRequest, map[string]any{"error": "request body must be a JSON object with 'hidden' levels, 'patterns', 'gameHidden' levels and an optional 'duration'"})
+		return
+	}
+	duration := time.Duration(0)
+	if body.Duration != "" {
+		duration, err = time.ParseDuration(body.Duration)
+		if err != nil || duration < 0 {
+			writeJson(writer, http.StatusBadRequest, map[string]any{"error": fmt.Sprintf("invalid duration %s", body.Duration)})
+			return
+		}
+	}
+	err = aa.LogFilter.Set(ctx, ServerLogFilterState{Hidden: body.Hidden, Patterns: body.Patterns, GameHidden: body.GameHidden}, duration)
+	aa.audit(ctx, token, fmt.Sprintf("loglevel hidden=%s game-hidden=%s duration=%s", strings.Join(body.Hidden, ","), strings.Join(body.GameHidden, ","), duration), err)
+	if err != nil {
+		writeJson(writer, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(writer, http.StatusOK, aa.LogFilter.Get())
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// ServerSettings is a collection of seven days to die server settings keyed by property name.
+// Values are stored as their raw string representation - use the typed accessors to convert them.
+type ServerSettings map[string]string
+
+// Gets the raw value of a setting.
+// Returns false if the setting is not defined.
+func (ss ServerSettings) Get(name string) (string, bool) {
+	value, ok := ss[name]
+	return value, ok
+}
+
+// Sets the raw value of a setting.
+func (ss ServerSettings) Set(name string, value string) {
+	ss[name] = value
+}
+
+// Deletes a setting.
+func (ss ServerSettings) Delete(name string) {
+	delete(ss, name)
+}
+
+// Gets the value of a setting as a boolean.
+// Returns an error if the setting is not defined.
+// Returns an error if the setting is not a boolean.
+func (ss ServerSettings) GetBool(name string) (bool, error) {
+	value, ok := ss[name]
+	if !ok {
+		return false, fmt.Errorf("setting %s not defined", name)
+	}
+	return strconv.ParseBool(value)
+}
+
+// Sets the value of a setting from a boolean.
+func (ss ServerSettings) SetBool(name string, value bool) {
+	ss[name] = strconv.FormatBool(value)
+}
+
+// Gets the value of a setting as an integer.
+// Returns an error if the setting is not defined.
+// Returns an error if the setting is not an integer.
+func (ss ServerSettings) GetInt(name string) (int, error) {
+	value, ok := ss[name]
+	if !ok {
+		return 0, fmt.Errorf("setting %s not defined", name)
+	}
+	return strconv.Atoi(value)
+}
+
+// Sets the value of a setting from an integer.
+func (ss ServerSettings) SetInt(name string, value int) {
+	ss[name] = strconv.Itoa(value)
+}
+
+// Gets the value of a setting as a duration - using the unit defined for the setting in the settings catalog.
+// Returns an error if the setting is not a known duration setting.
+// Returns an error if the setting is not defined.
+// Returns an error if the setting is not an integer.
+func (ss ServerSettings) GetDuration(name string) (time.Duration, error) {
+	definition, ok := GetSettingDefinition(name, "")
+	if !ok || definition.Type != SettingTypeDuration {
+		return 0, fmt.Errorf("setting %s is not a duration", name)
+	}
+	value, err := ss.GetInt(name)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(value) * definition.Unit, nil
+}
+
+// Sets the value of a setting from a duration - using the unit defined for the setting in the settings catalog.
+// Returns an error if the setting is not a known duration setting.
+func (ss ServerSettings) SetDuration(name string, value time.Duration) error {
+	definition, ok := GetSettingDefinition(name, "")
+	if !ok || definition.Type != SettingTypeDuration {
+		return fmt.Errorf("setting %s is not a duration", name)
+	}
+	ss.SetInt(name, int(value/definition.Unit))
+	return nil
+}
+
+// Returns a sorted list of setting names
+func (ss ServerSettings) Names() []string {
+	names := []string{}
+	for name := range ss {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SettingChange describes a single difference between two [ServerSettings]
+type SettingChange struct {
+	Name string
+	From *string
+	To   *string
+}
+
+// Computes the differences required to turn the current [ServerSettings] into [other].
+// Changes are sorted by setting name.
+func (ss ServerSettings) Diff(other ServerSettings) []SettingChange {
+	names := MergeServerSettings(ss, other).Names()
+	changes := []SettingChange{}
+	for _, name := range names {
+		from, fromOk := ss[name]
+		to, toOk := other[name]
+		if fromOk && toOk && from == to {
+			continue
+		}
+		change := SettingChange{Name: name}
+		if fromOk {
+			change.From = &from
+		}
+		if toOk {
+			change.To = &to
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// Validates the [ServerSettings] against the settings catalog for the given game version.
+// Returns an error describing every unknown or invalid setting.
+func (ss ServerSettings) Validate(version string) error {
+	errs := []error{}
+	for _, name := range ss.Names() {
+		definition, ok := GetSettingDefinition(name, version)
+		if !ok {
+			errs = append(errs, fmt.Errorf("setting %s unknown", name))
+			continue
+		}
+		err := definition.Validate(ss[name])
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Converts the [ServerSettings] data into an XML payload
+func (ss *ServerSettings) Xml() XmlServerSettings {
+	xss := XmlServerSettings{Properties: []XmlServerProperty{}}
+	for _, name := range ss.Names() {
+		xsp := XmlServerProperty{Name: name, Value: (*ss)[name]}
+		xss.Properties = append(xss.Properties, xsp)
+	}
+	return xss
+}
+
+// XmlServerSettings is the xml representation of sdtd server settings
+type XmlServerSettings struct {
+	XMLName    xml.Name            `xml:"ServerSettings"`
+	Properties []XmlServerProperty `xml:"property"`
+}
+
+// XmlServerProperty is an item within a seven days to die server settings XML file.
+type XmlServerProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// Converts the [XmlServerSettings] data into a typical golang map
+func (xss *XmlServerSettings) Map() ServerSettings {
+	data := map[string]string{}
+	for _, p := range xss.Properties {
+		data[p.Name] = p.Value
+	}
+	return data
+}
+
+// Parses server settings from the seven days to die server root directory.  Assumes the data is unmodified.
+// Returns an error if the server settings configuration file is unreadable
+// Returns an error if the server settings data is unparseable
+func GetDefaultServerSettings(ctx context.Context) (ServerSettings, error) {
+	fail := func(err error) (ServerSettings, error) {
+		return nil, err
+	}
+	file := filepath.Join(helper.Dirs(ctx)["sdtd"], "serverconfig.xml")
+	helper.Logger(ctx).Info("get default server settings", "path", file)
+	xss := XmlServerSettings{}
+	err := helper.UnmarshalFile(ctx, file, &xss)
+	if err != nil {
+		return fail(err)
+	}
+	return xss.Map(), nil
+}
+
+// Parses server settings from the environment (identified as environment variables prefixed with SETTING_).
+func GetEnvServerSettings(ctx context.Context) ServerSettings {
+	data := ServerSettings{}
+	prefix := "SETTING_"
+	for _, item := range os.Environ() {
+		parts := strings.SplitN(item, "=", 2)
+		if !strings.HasPrefix(parts[0], prefix) {
+			continue
+		}
+		parts[0] = strings.TrimPrefix(parts[0], prefix)
+		data[parts[0]] = parts[1]
+	}
+	helper.Logger(ctx).Info("get env server settings", "count", len(data))
+	return data
+}
+
+// Merges a list of [ServerSettings] (in order) - producing a final [ServerSettings].
+func MergeServerSettings(items ...ServerSettings) ServerSettings {
+	data := ServerSettings{}
+	for _, item := range items {
+		for k, v := range item {
+			data[k] = v
+		}
+	}
+	return data
+}
+
+// UnsetSettingValue is a sentinel value that (when provided via a SETTING_ environment variable) removes the setting from the rendered server settings
+const UnsetSettingValue = "__UNSET__"
+
+// Removes settings from [settings] - allowing the game to fall back to its internal defaults.
+// Removes all settings named in [names] along with any setting whose value is [UnsetSettingValue].
+func DeleteServerSettings(ctx context.Context, settings ServerSettings, names ...string) {
+	for _, name := range settings.Names() {
+		if settings[name] == UnsetSettingValue {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		helper.Logger(ctx).Info("delete server setting", "name", name)
+		settings.Delete(name)
+	}
+}
+
+// Validates server settings against the settings catalog and logs any problems along with the settings that differ from the defaults.
+// Problems are logged as warnings (rather than failing) as the catalog may lag behind new game versions.
+func CheckServerSettings(ctx context.Context, version string, defaults ServerSettings, settings ServerSettings) {
+	err := settings.Validate(version)
+	if err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			helper.Logger(ctx).Warn("invalid server setting", "version", version, "error", line)
+		}
+	}
+	changed := []string{}
+	for _, change := range defaults.Diff(settings) {
+		changed = append(changed, change.Name)
+	}
+	helper.Logger(ctx).Info("server settings changed from defaults", "settings", changed)
+}
+
+// xmlCommentRegex matches xml comments
+var xmlCommentRegex = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+// xmlPropertyRegex matches a server settings property element (and captures its name)
+var xmlPropertyRegex = regexp.MustCompile(`<property\s+name="([^"]*)"\s+value="[^"]*"\s*/>`)
+
+// xmlPropertyLineRegex matches a line holding only a server settings property element (and captures its name)
+var xmlPropertyLineRegex = regexp.MustCompile(`(?m)^[ \t]*<property\s+name="([^"]*)"\s+value="[^"]*"\s*/>[ \t]*\r?\n`)
+
+// xmlPropertyIndentRegex captures the indentation of the first server settings property element
+var xmlPropertyIndentRegex = regexp.MustCompile(`(?m)^([ \t]*)<property\s`)
+
+// xmlAttrEscaper escapes text for use within a double-quoted xml attribute
+var xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;")
+
+// Formats a server settings property element
+func formatXmlProperty(name string, value string) string {
+	return fmt.Sprintf(`<property name="%s" value="%s" />`, xmlAttrEscaper.Replace(name), xmlAttrEscaper.Replace(value))
+}
+
+// Renders [settings] into server settings XML, using [template] (typically the vanilla serverconfig.xml) as the base document.
+// Comments, ordering and formatting of [template] are preserved - property values are replaced in-place, properties absent from [settings] are removed (along with their line) and properties absent from [template] are appended.
+// If [template] is empty, the settings are rendered as a plain XML document.
+// Returns an error if [template] is not a server settings document
+func RenderServerSettings(template []byte, settings ServerSettings) ([]byte, error) {
+	if len(template) == 0 {
+		data, err := xml.MarshalIndent(settings.Xml(), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+
+	rendered := map[string]bool{}
+	renderProperties := func(data string) string {
+		data = xmlPropertyLineRegex.ReplaceAllStringFunc(data, func(match string) string {
+			name := xmlPropertyLineRegex.FindStringSubmatch(match)[1]
+			_, ok := settings[name]
+			if !ok {
+				return ""
+			}
+			return match
+		})
+		return xmlPropertyRegex.ReplaceAllStringFunc(data, func(match string) string {
+			name := xmlPropertyRegex.FindStringSubmatch(match)[1]
+			value, ok := settings[name]
+			if !ok {
+				return ""
+			}
+			rendered[name] = true
+			return formatXmlProperty(name, value)
+		})
+	}
+
+	document := string(template)
+	output := strings.Builder{}
+	offset := 0
+	for _, span := range xmlCommentRegex.FindAllStringIndex(document, -1) {
+		output.WriteString(renderProperties(document[offset:span[0]]))
+		output.WriteString(document[span[0]:span[1]])
+		offset = span[1]
+	}
+	output.WriteString(renderProperties(document[offset:]))
+	document = output.String()
+
+	end := strings.LastIndex(document, "</ServerSettings>")
+	if end == -1 {
+		return nil, fmt.Errorf("template is not a server settings document")
+	}
+	indent := "\t"
+	match := xmlPropertyIndentRegex.FindStringSubmatch(document)
+	if match != nil {
+		indent = match[1]
+	}
+	appended := strings.Builder{}
+	for _, name := range settings.Names() {
+		if rendered[name] {
+			continue
+		}
+		if appended.Len() == 0 {
+			appended.WriteString(fmt.Sprintf("%s<!-- properties not present in the default server settings -->\n", indent))
+		}
+		appended.WriteString(fmt.Sprintf("%s%s\n", indent, formatXmlProperty(name, settings[name])))
+	}
+	if appended.Len() != 0 {
+		document = fmt.Sprintf("%s\n%s%s", document[:end], appended.String(), document[end:])
+	}
+	return []byte(document), nil
+}
+
+// Writes server settings (presented as a map) as a server settings XML file stored at [path].
+// Uses the default server settings file as a template - preserving its comments and ordering.
+// Returns an error if the data cannot be serialized into XML
+// Returns an error if the data cannot be written to [path]
+func WriteServerSettings(ctx context.Context, settings ServerSettings) (string, error) {
+	fail := func(err error) (string, error) {
+		return "", err
+	}
+	path := filepath.Join(helper.Dirs(ctx)["generated"], "serverconfig.xml")
+	helper.Logger(ctx).Info("write server settings", "path", path)
+	template, err := os.ReadFile(filepath.Join(helper.Dirs(ctx)["sdtd"], "serverconfig.xml"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fail(err)
+	}
+	data, err := RenderServerSettings(template, settings)
+	if err != nil {
+		return fail(err)
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return fail(err)
+	}
+	return path, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestRenderServerSettingsRemovesDeletedProperties(t *testing.T) {
+	template := strings.Join([]string{
+		`<?xml version="1.0"?>`,
+		`<ServerSettings>`,
+		`	<!-- the server name -->`,
+		`	<property name="ServerName" value="My Game Host" />`,
+		`	<property name="ServerDescription" value="A 7 Days to Die server -- join us" />`,
+		`	<property name="ServerPort" value="26900" />`,
+		`</ServerSettings>`,
+		``,
+	}, "\n")
+	settings := ServerSettings{"ServerName": "test", "ServerPort": "26901", "Region": "--> <!--"}
+
+	data, err := RenderServerSettings([]byte(template), settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered := string(data)
+	if strings.Contains(rendered, "ServerDescription") {
+		t.Errorf("expected ServerDescription to be removed:\n%s", rendered)
+	}
+	if !strings.Contains(rendered, "\t<!-- the server name -->\n\t<property name=\"ServerName\" value=\"test\" />\n\t<property name=\"ServerPort\" value=\"26901\" />\n") {
+		t.Errorf("expected template formatting to be preserved:\n%s", rendered)
+	}
+
+	parsed := XmlServerSettings{}
+	err = xml.Unmarshal(data, &parsed)
+	if err != nil {
+		t.Fatalf("rendered settings are not valid xml: %s\n%s", err, rendered)
+	}
+	for name, value := range settings {
+		actual, ok := parsed.Map()[name]
+		if !ok || actual != value {
+			t.Errorf("expected %s to be '%s' (got '%s')", name, value, actual)
+		}
+	}
+}
+
+func TestRenderServerSettingsRemovesInlineProperties(t *testing.T) {
+	template := `<ServerSettings><property name="ServerName" value="a" /><property name="ServerPort" value="26900" /></ServerSettings>`
+
+	data, err := RenderServerSettings([]byte(template), ServerSettings{"ServerPort": "26901"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<ServerSettings><property name="ServerPort" value="26901" /></ServerSettings>`
+	if string(data) != expected {
+		t.Errorf("expected '%s' (got '%s')", expected, string(data))
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// SettingDoc documents a single SETTING_ environment variable
+type SettingDoc struct {
+	Name        string   `json:"name"`
+	Variable    string   `json:"variable"`
+	Type        string   `json:"type,omitempty"`
+	Default     string   `json:"default"`
+	Description string   `json:"description,omitempty"`
+	Min         *int     `json:"min,omitempty"`
+	Max         *int     `json:"max,omitempty"`
+	Values      []string `json:"values,omitempty"`
+	Unit        string   `json:"unit,omitempty"`
+	Known       bool     `json:"known"`
+}
+
+// SettingsDocs documents all SETTING_ environment variables supported by a game version
+type SettingsDocs struct {
+	GameVersion string       `json:"gameVersion,omitempty"`
+	Settings    []SettingDoc `json:"settings"`
+}
+
+// xmlPropertyCommentRegex matches a server settings property followed by a trailing comment on the same line (capturing the property name and comment text)
+var xmlPropertyCommentRegex = regexp.MustCompile(`<property\s+name="([^"]*)"\s+value="[^"]*"\s*/>[ \t]*<!--(.*?)-->`)
+
+// Parses the trailing comments of properties in the default serverconfig.xml (these describe each setting)
+func parseSettingComments(data []byte) map[string]string {
+	comments := map[string]string{}
+	for _, match := range xmlPropertyCommentRegex.FindAllStringSubmatch(string(data), -1) {
+		comments[match[1]] = strings.TrimSpace(match[2])
+	}
+	return comments
+}
+
+// Formats a setting unit for documentation (e.g., 'minutes')
+func formatSettingUnit(unit time.Duration) string {
+	switch unit {
+	case 0:
+		return ""
+	case time.Second:
+		return "seconds"
+	case time.Minute:
+		return "minutes"
+	case time.Hour:
+		return "hours"
+	case 24 * time.Hour:
+		return "days"
+	}
+	return unit.String()
+}
+
+// Generates documentation for every SETTING_ environment variable - combining the default serverconfig.xml (for defaults and descriptions of the installed game version) with the known settings catalog.
+// Settings present in the default serverconfig.xml take their defaults from it; settings only known to the catalog are included for completeness.
+// If the game is not installed, documentation is generated from the catalog alone.
+// Returns an error if the default serverconfig.xml is unreadable.
+func GetSettingsDocs(ctx context.Context, version string) (SettingsDocs, error) {
+	fail := func(err error) (SettingsDocs, error) {
+		return SettingsDocs{}, err
+	}
+	defaults := ServerSettings{}
+	comments := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(helper.Dirs(ctx)["sdtd"], "serverconfig.xml"))
+	if errors.Is(err, os.ErrNotExist) {
+		helper.Logger(ctx).Warn("default server settings not found - documenting settings catalog only")
+	} else if err != nil {
+		return fail(err)
+	} else {
+		defaults, err = GetDefaultServerSettings(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		comments = parseSettingComments(data)
+	}
+
+	docs := SettingsDocs{GameVersion: version, Settings: []SettingDoc{}}
+	documented := map[string]bool{}
+	for _, definition := range GetSettingsCatalog(version) {
+		doc := SettingDoc{
+			Name:        definition.Name,
+			Variable:    "SETTING_" + definition.Name,
+			Type:        string(definition.Type),
+			Default:     definition.Default,
+			Description: definition.Description,
+			Values:      definition.Values,
+			Unit:        formatSettingUnit(definition.Unit),
+			Known:       true,
+		}
+		if definition.Range != nil {
+			doc.Min = &definition.Range.Min
+			doc.Max = &definition.Range.Max
+		}
+		value, ok := defaults[definition.Name]
+		if ok {
+			doc.Default = value
+		}
+		if doc.Description == "" {
+			doc.Description = comments[definition.Name]
+		}
+		docs.Settings = append(docs.Settings, doc)
+		documented[definition.Name] = true
+	}
+	for _, name := range defaults.Names() {
+		if documented[name] {
+			continue
+		}
+		docs.Settings = append(docs.Settings, SettingDoc{
+			Name:        name,
+			Variable:    "SETTING_" + name,
+			Default:     defaults[name],
+			Description: comments[name],
+		})
+	}
+	return docs, nil
+}
+
+// markdownCellEscaper escapes text for use within a markdown table cell
+var markdownCellEscaper = strings.NewReplacer("|", "\\|", "\n", " ")
+
+// Renders settings documentation as a markdown table
+func (sd SettingsDocs) Markdown() string {
+	output := strings.Builder{}
+	output.WriteString("# Server Settings\n\n")
+	if sd.GameVersion != "" {
+		output.WriteString(fmt.Sprintf("Settings available in game version `%s`.\n\n", sd.GameVersion))
+	}
+	output.WriteString("Settings not present in the settings catalog are marked with `*` - their values are not validated.\n\n")
+	output.WriteString("| Variable | Type | Default | Allowed | Description |\n")
+	output.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, doc := range sd.Settings {
+		variable := fmt.Sprintf("`%s`", doc.Variable)
+		if !doc.Known {
+			variable = variable + " *"
+		}
+		kind := doc.Type
+		if doc.Unit != "" {
+			kind = fmt.Sprintf("%s (%s)", kind, doc.Unit)
+		}
+		allowed := ""
+		if doc.Min != nil && doc.Max != nil {
+			allowed = fmt.Sprintf("%d - %d", *doc.Min, *doc.Max)
+		}
+		if len(doc.Values) > 0 {
+			allowed = strings.Join(doc.Values, ", ")
+		}
+		if doc.Type == string(SettingTypeBool) {
+			allowed = "true, false"
+		}
+		defaultValue := ""
+		if doc.Default != "" {
+			defaultValue = fmt.Sprintf("`%s`", doc.Default)
+		}
+		output.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n", variable, kind, markdownCellEscaper.Replace(defaultValue), markdownCellEscaper.Replace(allowed), markdownCellEscaper.Replace(doc.Description)))
+	}
+	return output.String()
+}
+
+// Implements the 'settings' subcommand - generating documentation of every SETTING_ environment variable (as markdown or json).
+// The game version used to filter the settings catalog is read from GAME_VERSION.
+// Returns an error if the arguments are invalid.
+// Returns an error if the documentation cannot be generated.
+func SettingsSubcommand(ctx context.Context) error {
+	usage := fmt.Errorf("usage: %s settings docs [markdown|json]", filepath.Base(os.Args[0]))
+	args := os.Args[2:]
+	if len(args) == 0 || args[0] != "docs" || len(args) > 2 {
+		return usage
+	}
+	format := "markdown"
+	if len(args) == 2 {
+		format = args[1]
+	}
+	if format != "markdown" && format != "json" {
+		return usage
+	}
+	docs, err := GetSettingsDocs(ctx, os.Getenv("GAME_VERSION"))
+	if err != nil {
+		return err
+	}
+	if format == "markdown" {
+		fmt.Print(docs.Markdown())
+		return nil
+	}
+	data, err := json.MarshalIndent(docs, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
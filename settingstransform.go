@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// settingsTransformTimeout is how long a settings transform command may run
+const settingsTransformTimeout = 30 * time.Second
+
+// Formats a JSON value printed by a settings transform as a setting value - strings are used as-is, while numbers and booleans are formatted as JSON.
+// Returns false if the value is null (deleting the setting).
+// Returns an error if the value is an array or object.
+func formatTransformedSetting(value json.RawMessage) (string, bool, error) {
+	trimmed := bytes.TrimSpace(value)
+	switch {
+	case bytes.Equal(trimmed, []byte("null")):
+		return "", false, nil
+	case len(trimmed) > 0 && trimmed[0] == '"':
+		text := ""
+		err := json.Unmarshal(trimmed, &text)
+		return text, err == nil, err
+	case len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{'):
+		return "", false, fmt.Errorf("unsupported value %s", trimmed)
+	}
+	return string(trimmed), true, nil
+}
+
+// Transforms the merged server settings with a user-supplied shell command (e.g., a 'jq' filter) - enabling conditional logic without forking the entrypoint.
+// The command receives the settings as a JSON object on stdin and must print the complete transformed settings as a JSON object to stdout.
+// Numbers and booleans are accepted as values, and settings removed (or set to null) are deleted.
+// Changes made by the transform are logged.
+// Returns an error if the command fails, times out or prints invalid settings.
+func TransformServerSettings(ctx context.Context, command string, settings ServerSettings) (ServerSettings, error) {
+	fail := func(err error) (ServerSettings, error) {
+		return nil, fmt.Errorf("settings transform failed: %w", err)
+	}
+	helper.Logger(ctx).Info("transform server settings", "command", command)
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fail(err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, settingsTransformTimeout)
+	defer cancel()
+	stdout := bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = helper.Dirs(ctx)["sdtd"]
+	cmd.Env = append(os.Environ(), getDirsEnv(ctx)...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return fail(err)
+	}
+	values := map[string]json.RawMessage{}
+	err = json.Unmarshal(stdout.Bytes(), &values)
+	if err != nil {
+		return fail(fmt.Errorf("invalid output (expected a json object): %w", err))
+	}
+	transformed := ServerSettings{}
+	for name, value := range values {
+		text, ok, err := formatTransformedSetting(value)
+		if err != nil {
+			return fail(fmt.Errorf("setting %s: %w", name, err))
+		}
+		if ok {
+			transformed.Set(name, text)
+		}
+	}
+	for _, change := range settings.Diff(transformed) {
+		from := "<unset>"
+		if change.From != nil {
+			from = *change.From
+		}
+		to := "<unset>"
+		if change.To != nil {
+			to = *change.To
+		}
+		helper.Logger(ctx).Info("setting transformed", "name", change.Name, "from", from, "to", to)
+	}
+	if len(transformed) == 0 && len(settings) > 0 {
+		helper.Logger(ctx).Warn("settings transform removed every setting", "command", command)
+	}
+	return transformed, nil
+}
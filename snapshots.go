@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// snapshotRetention is the number of snapshots kept (the oldest snapshots are deleted first)
+const snapshotRetention = 10
+
+// riskyCommands maps high-risk console commands to the files (relative to the save game folder) they affect
+var riskyCommands = map[string][]string{
+	"chunkreset":           {"Region"},
+	"cr":                   {"Region"},
+	"debugmenu":            {"Player", "players.xml"},
+	"dm":                   {"Player", "players.xml"},
+	"removelandprotection": {"Player", "players.xml"},
+	"rlp":                  {"Player", "players.xml"},
+}
+
+// Gets the files (relative to the save game folder) affected by a high-risk console command.
+// Returns nil if the command isn't high-risk.
+func getRiskyCommandPaths(command string) []string {
+	name, _, _ := strings.Cut(strings.TrimSpace(command), " ")
+	return riskyCommands[strings.ToLower(name)]
+}
+
+// Snapshot is a copy of the save game files affected by a high-risk console command - taken before the command is executed
+type Snapshot struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	Actor     string    `json:"actor"`
+	Command   string    `json:"command"`
+	// Paths are the snapshotted files (relative to the data directory)
+	Paths []string `json:"paths"`
+}
+
+// Gets the directory that stores snapshots
+func getSnapshotsDir(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "snapshots")
+}
+
+// Gets the path of the file that records a pending snapshot restore
+func getSnapshotUndoFile(ctx context.Context) string {
+	return filepath.Join(getSnapshotsDir(ctx), "undo.json")
+}
+
+// Takes a snapshot of the save game files affected by a high-risk console command.
+// The world is saved first (so that the snapshot reflects the current state of the world) - and the oldest snapshots beyond [snapshotRetention] are deleted.
+// Returns an error if the save game folder cannot be found.
+// Returns an error if the files cannot be copied.
+func CreateSnapshot(ctx context.Context, actor string, command string, paths []string) (*Snapshot, error) {
+	fail := func(err error) (*Snapshot, error) {
+		return nil, err
+	}
+	prefs, err := GetGamePrefs(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	saveGame := findSaveGame(ctx, prefs["GameName"])
+	if saveGame == "" {
+		return fail(fmt.Errorf("save game %s not found", prefs["GameName"]))
+	}
+	err = SaveWorld(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	now := time.Now().UTC()
+	snapshot := Snapshot{Name: fmt.Sprintf("snapshot-%s", now.Format("20060102T150405.000Z")), CreatedAt: now, Actor: actor, Command: command, Paths: []string{}}
+	dir := filepath.Join(getSnapshotsDir(ctx), snapshot.Name)
+	helper.Logger(ctx).Info("create snapshot", "name", snapshot.Name, "command", command)
+	data := helper.Dirs(ctx)["data"]
+	for _, path := range paths {
+		src := filepath.Join(saveGame, path)
+		_, err := os.Lstat(src)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		relative, err := filepath.Rel(data, src)
+		if err != nil {
+			return fail(err)
+		}
+		dest := filepath.Join(dir, relative)
+		err = helper.CreateDirs(ctx, filepath.Dir(dest))
+		if err != nil {
+			return fail(err)
+		}
+		// reflinks (where supported) make snapshots of large region folders nearly free
+		_, err = helper.Command(ctx, []string{"cp", "-a", "--reflink=auto", src, dest}, helper.CmdOpts{}).Run()
+		if err != nil {
+			helper.RemovePaths(ctx, dir)
+			return fail(err)
+		}
+		snapshot.Paths = append(snapshot.Paths, relative)
+	}
+	err = helper.MarshalFile(ctx, snapshot, filepath.Join(dir, "snapshot.json"))
+	if err != nil {
+		helper.RemovePaths(ctx, dir)
+		return fail(err)
+	}
+	snapshots, err := ListSnapshots(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	for index := 0; index < len(snapshots)-snapshotRetention; index++ {
+		helper.Logger(ctx).Info("delete snapshot", "name", snapshots[index].Name)
+		err = helper.RemovePaths(ctx, filepath.Join(getSnapshotsDir(ctx), snapshots[index].Name))
+		if err != nil {
+			return fail(err)
+		}
+	}
+	return &snapshot, nil
+}
+
+// Lists snapshots (oldest first).
+// Returns an error if the snapshots directory cannot be read.
+func ListSnapshots(ctx context.Context) ([]Snapshot, error) {
+	snapshots := []Snapshot{}
+	subpaths, err := helper.ListDir(ctx, getSnapshotsDir(ctx))
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshots, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, subpath := range subpaths {
+		snapshot := Snapshot{}
+		err := helper.UnmarshalFile(ctx, filepath.Join(subpath, "snapshot.json"), &snapshot)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	slices.SortFunc(snapshots, func(a Snapshot, b Snapshot) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Finds a snapshot by name - an empty name refers to the most recent snapshot.
+// Returns an error if the snapshots cannot be listed.
+// Returns an error if no snapshot matches.
+func FindSnapshot(ctx context.Context, name string) (*Snapshot, error) {
+	snapshots, err := ListSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for index := len(snapshots) - 1; index >= 0; index-- {
+		if name == "" || snapshots[index].Name == name {
+			return &snapshots[index], nil
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no snapshots found")
+	}
+	return nil, fmt.Errorf("snapshot %s not found", name)
+}
+
+// Requests that a snapshot be restored the next time the server starts (saves can't be safely replaced while the server is running).
+// Returns an error if the snapshot cannot be found.
+// Returns an error if the request cannot be written.
+func RequestSnapshotUndo(ctx context.Context, name string) (*Snapshot, error) {
+	snapshot, err := FindSnapshot(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	helper.Logger(ctx).Info("request snapshot undo", "name", snapshot.Name, "command", snapshot.Command)
+	return snapshot, helper.MarshalFile(ctx, map[string]string{"name": snapshot.Name}, getSnapshotUndoFile(ctx))
+}
+
+// Restores the snapshot requested by [RequestSnapshotUndo] (if any) - replacing the snapshotted files within the data directory - and then clears the request.
+// Returns an error if the requested snapshot cannot be restored.
+func ApplySnapshotUndo(ctx context.Context) error {
+	request := map[string]string{}
+	err := helper.UnmarshalFile(ctx, getSnapshotUndoFile(ctx), &request)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	snapshot, err := FindSnapshot(ctx, request["name"])
+	if err != nil {
+		return err
+	}
+	helper.Logger(ctx).Info("undo snapshot", "name", snapshot.Name, "command", snapshot.Command)
+	data := helper.Dirs(ctx)["data"]
+	for _, path := range snapshot.Paths {
+		dest := filepath.Join(data, path)
+		err = helper.RemovePaths(ctx, dest)
+		if err != nil {
+			return err
+		}
+		_, err = helper.Command(ctx, []string{"cp", "-a", "--reflink=auto", filepath.Join(getSnapshotsDir(ctx), snapshot.Name, path), dest}, helper.CmdOpts{}).Run()
+		if err != nil {
+			return err
+		}
+	}
+	return helper.RemovePaths(ctx, getSnapshotUndoFile(ctx))
+}
+
+// Manages snapshots from the command line:
+//   - 'snapshot list' lists snapshots
+//   - 'snapshot undo [name]' restores a snapshot (the most recent, by default) - stopping the server so that it's restored on the next start
+//
+// Returns an error if the arguments are invalid.
+// Returns an error if the operation fails.
+func SnapshotSubcommand(ctx context.Context) error {
+	usage := fmt.Errorf("usage: %s snapshot [list | undo [name]]", filepath.Base(os.Args[0]))
+	args := os.Args[2:]
+	if len(args) == 0 {
+		return usage
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		snapshots, err := ListSnapshots(ctx)
+		if err != nil {
+			return err
+		}
+		for _, snapshot := range snapshots {
+			fmt.Printf("%s\t%s\t%s\n", snapshot.Name, snapshot.Actor, snapshot.Command)
+		}
+		return nil
+	case args[0] == "undo" && len(args) <= 2:
+		name := ""
+		if len(args) == 2 {
+			name = args[1]
+		}
+		_, err := RequestSnapshotUndo(ctx, name)
+		if err != nil {
+			return err
+		}
+		err = ShutdownServer(ctx)
+		if err != nil {
+			helper.Logger(ctx).Info("server not running - snapshot will be restored on next start")
+		}
+		return nil
+	}
+	return usage
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// standbyManifestName is the name of the replicated save manifest at a standby destination
+const standbyManifestName = "standby.json"
+
+// StandbyManifest describes a save replicated to a standby destination (see [StandbyReplicator]).
+// The manifest is uploaded only after every object it references - so that a destination always holds a consistent copy of the save.
+type StandbyManifest struct {
+	// Files maps file paths (relative to the data directory) to their sha256 checksums
+	Files map[string]string `json:"files"`
+	// Stats records the size and modification time of each file - used to detect unchanged files without re-hashing them
+	Stats     map[string]BackupFileStat `json:"stats"`
+	UpdatedAt time.Time                 `json:"updatedAt"`
+}
+
+// Gets the name of a (gzip-compressed) replicated file object at a standby destination
+func getStandbyObjectName(checksum string) string {
+	return fmt.Sprintf("standby-%s.gz", checksum)
+}
+
+// Gets the path of the file recording the manifest last replicated to each standby destination
+func getStandbyStateFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "standby-state.json")
+}
+
+// Compresses a file to [dest] - computing the checksum of the file's contents as they're read (so that the checksum always matches the compressed object, even if the file changes while it's read).
+// Returns the checksum and compressed size.
+// Returns an error if the file cannot be read or written.
+func compressStandbyObject(path string, dest string) (string, int64, error) {
+	fail := func(err error) (string, int64, error) {
+		return "", 0, err
+	}
+	source, err := os.Open(path)
+	if err != nil {
+		return fail(err)
+	}
+	defer source.Close()
+	handle, err := os.Create(dest)
+	if err != nil {
+		return fail(err)
+	}
+	defer handle.Close()
+	writer := gzip.NewWriter(handle)
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(writer, hash), source)
+	if err != nil {
+		return fail(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		return fail(err)
+	}
+	info, err := handle.Stat()
+	if err != nil {
+		return fail(err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), info.Size(), handle.Close()
+}
+
+// bandwidthThrottle paces uploads so that their average rate doesn't exceed a limit
+type bandwidthThrottle struct {
+	// limit is the maximum average rate (in bytes per second) - 0 is unlimited
+	limit int64
+	sent  int64
+	start time.Time
+}
+
+// Records that [size] bytes were sent - and then waits until the average rate falls within the limit.
+// Returns an error if the context is cancelled while waiting.
+func (bt *bandwidthThrottle) Wait(ctx context.Context, size int64) error {
+	if bt.limit <= 0 {
+		return nil
+	}
+	bt.sent += size
+	delay := time.Until(bt.start.Add(time.Duration(float64(bt.sent) / float64(bt.limit) * float64(time.Second))))
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// StandbyOpts are options for [StandbyReplicator]
+type StandbyOpts struct {
+	// BandwidthLimit is the maximum average upload rate (in KiB/s) to each destination - 0 is unlimited
+	BandwidthLimit int
+	Interval       time.Duration
+	Targets        []ReplicationTarget
+}
+
+// StandbyReplicator incrementally replicates the saves within the data directory to standby destinations - so that a warm standby server can take over with bounded data loss.
+// Files are uploaded as content-addressed objects - only files that changed since the previous replication are uploaded, and objects no longer referenced are deleted.
+type StandbyReplicator struct {
+	Opts StandbyOpts
+	lock sync.Mutex
+}
+
+// Replicates the saves to a single destination - uploading changed files, then the manifest, and then deleting unreferenced objects.
+// Returns the replicated manifest.
+// Returns an error if a file or the manifest cannot be uploaded.
+func (sr *StandbyReplicator) syncTarget(ctx context.Context, target ReplicationTarget, previous StandbyManifest) (StandbyManifest, error) {
+	fail := func(err error) (StandbyManifest, error) {
+		return StandbyManifest{}, err
+	}
+	name := target.Destination.Name()
+	manifest := StandbyManifest{Files: map[string]string{}, Stats: map[string]BackupFileStat{}}
+	uploaded := map[string]bool{}
+	for _, checksum := range previous.Files {
+		uploaded[checksum] = true
+	}
+	throttle := bandwidthThrottle{limit: int64(sr.Opts.BandwidthLimit) * 1024, start: time.Now()}
+	count := 0
+	size := int64(0)
+	err := helper.CreateTempDir(ctx, func(tempDir string) error {
+		err := walkSaves(ctx, func(relpath string, path string, info os.FileInfo) error {
+			stat := BackupFileStat{Size: info.Size(), ModTime: info.ModTime().UTC()}
+			previousStat, ok := previous.Stats[relpath]
+			if ok && previousStat.Size == stat.Size && previousStat.ModTime.Equal(stat.ModTime) {
+				manifest.Files[relpath] = previous.Files[relpath]
+				manifest.Stats[relpath] = stat
+				return nil
+			}
+			object := filepath.Join(tempDir, "object.gz")
+			checksum, objectSize, err := compressStandbyObject(path, object)
+			if err != nil {
+				return err
+			}
+			manifest.Files[relpath] = checksum
+			manifest.Stats[relpath] = stat
+			if uploaded[checksum] {
+				return nil
+			}
+			err = target.Destination.Upload(ctx, object, getStandbyObjectName(checksum))
+			if err != nil {
+				return err
+			}
+			uploaded[checksum] = true
+			count += 1
+			size += objectSize
+			return throttle.Wait(ctx, objectSize)
+		})
+		if err != nil {
+			return err
+		}
+		manifest.UpdatedAt = time.Now().UTC()
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		file := filepath.Join(tempDir, standbyManifestName)
+		err = os.WriteFile(file, data, 0644)
+		if err != nil {
+			return err
+		}
+		return target.Destination.Upload(ctx, file, standbyManifestName)
+	})
+	if err != nil {
+		return fail(err)
+	}
+	helper.Logger(ctx).Info("standby replicated", "destination", name, "files", len(manifest.Files), "uploaded", count, "bytes", size)
+	err = sr.prune(ctx, target, manifest)
+	if err != nil {
+		helper.Logger(ctx).Warn("prune standby objects failed", "destination", name, "error", err.Error())
+	}
+	return manifest, nil
+}
+
+// Deletes objects at a destination that aren't referenced by the manifest.
+// Returns an error if the destination cannot be listed or objects cannot be deleted.
+func (sr *StandbyReplicator) prune(ctx context.Context, target ReplicationTarget, manifest StandbyManifest) error {
+	referenced := map[string]bool{}
+	for _, checksum := range manifest.Files {
+		referenced[getStandbyObjectName(checksum)] = true
+	}
+	names, err := target.Destination.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if !strings.HasPrefix(name, "standby-") || !strings.HasSuffix(name, ".gz") || referenced[name] {
+			continue
+		}
+		err = target.Destination.Delete(ctx, name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Saves the world (if the server is ready) and replicates the saves to every destination.
+// Destinations are replicated independently - a failing destination doesn't prevent replication to the others.
+// Returns an error joining the failures of each destination.
+func (sr *StandbyReplicator) Sync(ctx context.Context) error {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	tracker := GetStatusTracker(ctx)
+	if tracker == nil || tracker.Get().State == ServerStateReady {
+		err := SaveWorld(ctx)
+		if err != nil {
+			helper.Logger(ctx).Warn("save world failed", "error", err.Error())
+		}
+	}
+	state := map[string]StandbyManifest{}
+	err := helper.UnmarshalFile(ctx, getStandbyStateFile(ctx), &state)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	errs := []error{}
+	for _, target := range sr.Opts.Targets {
+		name := target.Destination.Name()
+		manifest, err := sr.syncTarget(ctx, target, state[name])
+		if err != nil {
+			helper.Logger(ctx).Error("standby replication failed", "destination", name, "error", err.Error())
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		state[name] = manifest
+	}
+	err = helper.MarshalFile(ctx, state, getStandbyStateFile(ctx))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Periodically replicates the saves to every destination until the context is cancelled.
+func (sr *StandbyReplicator) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(sr.Opts.Interval):
+		}
+		sr.Sync(ctx)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// ServerState describes the lifecycle stage of the server
+type ServerState string
+
+const (
+	ServerStateDownloading  ServerState = "downloading"
+	ServerStateStandby      ServerState = "standby"
+	ServerStateStarting     ServerState = "starting"
+	ServerStateReady        ServerState = "ready"
+	ServerStateShuttingDown ServerState = "shutting-down"
+	ServerStateStopped      ServerState = "stopped"
+)
+
+// ServerStatus is a snapshot of the server's state - written to the data directory for sidecars and file-based monitors
+type ServerStatus struct {
+	State       ServerState               `json:"state"`
+	Players     int                       `json:"players"`
+	Version     string                    `json:"version"`
+	ManifestId  string                    `json:"manifestId"`
+	StartedAt   *time.Time                `json:"startedAt"`
+	Uptime      int64                     `json:"uptimeSeconds"`
+	NextRestart *time.Time                `json:"nextRestart"`
+	NextBackup  *time.Time                `json:"nextBackup,omitempty"`
+	LastBackup  *BackupReport             `json:"lastBackup,omitempty"`
+	NextSave    *time.Time                `json:"nextSave,omitempty"`
+	LastSave    *time.Time                `json:"lastSave,omitempty"`
+	Bandwidth   *BandwidthUsage           `json:"bandwidth,omitempty"`
+	Endpoints   map[string]EndpointStatus `json:"endpoints,omitempty"`
+	Process     *ProcessStats             `json:"process,omitempty"`
+	UpdatedAt   time.Time                 `json:"updatedAt"`
+}
+
+// EndpointStatus is the health of an http endpoint served by the server (e.g., a web map)
+type EndpointStatus struct {
+	Url       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// StatusTracker holds the current [ServerStatus] and persists it to the data directory
+type StatusTracker struct {
+	file   string
+	lock   sync.Mutex
+	status ServerStatus
+}
+
+// Creates a new [StatusTracker] writing to '[data]/status.json'
+func NewStatusTracker(ctx context.Context, manifestId string) *StatusTracker {
+	return &StatusTracker{
+		file: filepath.Join(helper.Dirs(ctx)["data"], "status.json"),
+		status: ServerStatus{
+			ManifestId: manifestId,
+			State:      ServerStateDownloading,
+			Version:    helper.Version(ctx),
+		},
+	}
+}
+
+// Gets a copy of the current status
+func (st *StatusTracker) Get() ServerStatus {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	status := st.status
+	if status.StartedAt != nil {
+		status.Uptime = int64(time.Since(*status.StartedAt).Seconds())
+	}
+	return status
+}
+
+// Updates the current status with a callback - and then writes the status file
+func (st *StatusTracker) Update(ctx context.Context, cb func(status *ServerStatus)) {
+	st.lock.Lock()
+	cb(&st.status)
+	st.lock.Unlock()
+	st.write(ctx)
+}
+
+// Sets the current state - and then writes the status file.
+// Once shutting down, the state can only transition to [ServerStateStopped].
+// State transitions are additionally reported to systemd (see [SdNotify]).
+func (st *StatusTracker) SetState(ctx context.Context, state ServerState) {
+	changed := false
+	st.Update(ctx, func(status *ServerStatus) {
+		if status.State == ServerStateShuttingDown && state != ServerStateStopped {
+			return
+		}
+		changed = true
+		if state == ServerStateStarting {
+			now := time.Now()
+			status.StartedAt = &now
+		}
+		helper.Logger(ctx).Info("server state", "from", status.State, "to", state)
+		status.State = state
+	})
+	if changed {
+		notifySdState(ctx, state)
+	}
+}
+
+// Writes the status file atomically (so that readers never observe a partially written file).
+// Failures are logged and otherwise ignored.
+func (st *StatusTracker) write(ctx context.Context) {
+	status := st.Get()
+	status.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err == nil {
+		err = os.WriteFile(st.file+".tmp", data, 0644)
+	}
+	if err == nil {
+		err = os.Rename(st.file+".tmp", st.file)
+	}
+	if err != nil {
+		helper.Logger(ctx).Warn("write status failed", "error", err.Error())
+	}
+}
+
+// Periodically refreshes the status (e.g., player count, uptime, process resource usage, bandwidth usage) and writes the status file until the context is cancelled.
+// Also marks the server as ready once the server has finished loading.
+func (st *StatusTracker) Run(ctx context.Context, interval time.Duration, readyTimeout time.Duration) {
+	sampler := ProcessSampler{}
+	network := NetworkSampler{}
+	go func() {
+		err := GetTelnetSession(ctx).WaitReady(ctx, readyTimeout)
+		if err == nil {
+			st.SetState(ctx, ServerStateReady)
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		st.sampleProcess(ctx, &sampler)
+		st.sampleNetwork(ctx, &network)
+		if st.Get().State == ServerStateReady {
+			players, err := ListPlayers(ctx)
+			if err == nil {
+				st.Update(ctx, func(status *ServerStatus) {
+					status.Players = len(players)
+				})
+				continue
+			}
+		}
+		st.write(ctx)
+	}
+}
+
+// Updates the process resource usage of the status - clearing it while the server process isn't running
+func (st *StatusTracker) sampleProcess(ctx context.Context, sampler *ProcessSampler) {
+	stats, err := sampler.Sample(ctx)
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if err != nil {
+		st.status.Process = nil
+		return
+	}
+	st.status.Process = &stats
+}
+
+// Records the traffic of the network interfaces since the previous sample in the bandwidth usage (see [UpdateBandwidthUsage]).
+// Failures are ignored (interface counters are unavailable outside of linux).
+func (st *StatusTracker) sampleNetwork(ctx context.Context, sampler *NetworkSampler) {
+	received, sent, err := sampler.Sample()
+	if err != nil {
+		return
+	}
+	err = UpdateBandwidthUsage(WithStatusTracker(ctx, st), func(usage *BandwidthUsage) {
+		usage.ReceivedBytes += received
+		usage.SentBytes += sent
+	})
+	if err != nil {
+		helper.Logger(ctx).Warn("record bandwidth failed", "error", err.Error())
+	}
+}
+
+// Sets the server state on the [StatusTracker] attached to the context (if any)
+func SetServerState(ctx context.Context, state ServerState) {
+	tracker := GetStatusTracker(ctx)
+	if tracker != nil {
+		tracker.SetState(ctx, state)
+	}
+}
+
+// GameTime is the in-game day and time
+type GameTime struct {
+	Day    int `json:"day"`
+	Hour   int `json:"hour"`
+	Minute int `json:"minute"`
+}
+
+// gameTimeRegex matches the output of the 'gettime' console command (e.g., 'Day 5, 13:42')
+var gameTimeRegex = regexp.MustCompile(`^Day (\d+), (\d+):(\d+)`)
+
+// Gets the in-game day and time from the running server.
+// Returns an error if the console command fails or its output is unparseable.
+func GetGameTime(ctx context.Context) (GameTime, error) {
+	lines, err := SendCommand(ctx, "gettime")
+	if err != nil {
+		return GameTime{}, fmt.Errorf("get time: %w", err)
+	}
+	for _, line := range lines {
+		match := gameTimeRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		day, _ := strconv.Atoi(match[1])
+		hour, _ := strconv.Atoi(match[2])
+		minute, _ := strconv.Atoi(match[3])
+		return GameTime{Day: day, Hour: hour, Minute: minute}, nil
+	}
+	return GameTime{}, fmt.Errorf("get time: unexpected output %v", lines)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// Sends state updates (e.g., 'READY=1') to the systemd service manager (see sd_notify(3)).
+// Does nothing when the entrypoint isn't run as a systemd notify service (i.e., 'NOTIFY_SOCKET' is unset).
+// Returns an error if the notification cannot be sent.
+func SdNotify(states ...string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		// abstract namespace sockets are prefixed with a null byte
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("sd notify: %w", err)
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(strings.Join(states, "\n")))
+	if err != nil {
+		return fmt.Errorf("sd notify: %w", err)
+	}
+	return nil
+}
+
+// Notifies systemd of a server state transition - logging (and otherwise ignoring) failures
+func notifySdState(ctx context.Context, state ServerState) {
+	states := []string{fmt.Sprintf("STATUS=%s", state)}
+	switch state {
+	// a standby is fully started while it idles - so that 'TimeoutStartSec' doesn't apply until it's promoted
+	case ServerStateReady, ServerStateStandby:
+		states = append(states, "READY=1")
+	case ServerStateShuttingDown:
+		states = append(states, "STOPPING=1")
+	}
+	err := SdNotify(states...)
+	if err != nil {
+		helper.Logger(ctx).Warn("sd notify failed", "error", err.Error())
+	}
+}
+
+// Gets the watchdog timeout configured by systemd (i.e., the service's 'WatchdogSec').
+// Returns 0 if the watchdog is disabled - or is intended for a different process.
+func getSdWatchdogTimeout() time.Duration {
+	pid := os.Getenv("WATCHDOG_PID")
+	if pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// SdWatchdog sends keep-alive pings to the systemd watchdog.
+// Once the server is ready, pings are only sent while the server responds to console commands - allowing systemd to restart a hung server.
+type SdWatchdog struct {
+	Timeout time.Duration
+	lock    sync.Mutex
+	session *TelnetSession
+}
+
+// Creates a new [SdWatchdog] using the watchdog timeout configured by systemd.  Call [SdWatchdog.Run] to send pings.
+func NewSdWatchdog() *SdWatchdog {
+	return &SdWatchdog{Timeout: getSdWatchdogTimeout()}
+}
+
+// Returns true if systemd has enabled the watchdog for this process
+func (w *SdWatchdog) Enabled() bool {
+	return w.Timeout > 0
+}
+
+// Sets the [TelnetSession] used to verify that a ready server is responsive
+func (w *SdWatchdog) SetSession(session *TelnetSession) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.session = session
+}
+
+// Checks whether the server is healthy enough to ping the watchdog.
+// Before the server is ready, the entrypoint itself is considered healthy (startup is bounded by the service's 'TimeoutStartSec' instead).
+// Returns an error if a ready server fails to respond to a console command.
+func (w *SdWatchdog) Check(ctx context.Context) error {
+	w.lock.Lock()
+	session := w.session
+	w.lock.Unlock()
+	tracker := GetStatusTracker(ctx)
+	if session == nil || tracker == nil || tracker.Get().State != ServerStateReady {
+		return nil
+	}
+	_, err := session.Exec(ctx, "gettime", w.Timeout/4)
+	if err != nil {
+		return fmt.Errorf("watchdog check: %w", err)
+	}
+	return nil
+}
+
+// Pings the watchdog (at half the watchdog timeout) until the context is cancelled - skipping pings whenever [SdWatchdog.Check] fails.
+func (w *SdWatchdog) Run(ctx context.Context) {
+	if !w.Enabled() {
+		return
+	}
+	helper.Logger(ctx).Info("systemd watchdog enabled", "timeout", w.Timeout.String())
+	for {
+		err := w.Check(ctx)
+		if err == nil {
+			err = SdNotify("WATCHDOG=1")
+		}
+		if err != nil {
+			helper.Logger(ctx).Warn("watchdog ping skipped", "error", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.Timeout / 2):
+		}
+	}
+}
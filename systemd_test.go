@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Listens on a notify socket (see [SdNotify]) for the duration of a test.
+// Returns the connection notifications are received on.
+func listenNotifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	t.Setenv("NOTIFY_SOCKET", socket)
+	return conn
+}
+
+// Reads notifications until one contains the expected state.
+// Fails the test if the state isn't received within the timeout.
+func waitForNotification(t *testing.T, conn *net.UnixConn, state string, timeout time.Duration) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	data := make([]byte, 4096)
+	for {
+		size, err := conn.Read(data)
+		if err != nil {
+			t.Fatalf("notification %s not received: %v", state, err)
+		}
+		for _, line := range strings.Split(string(data[:size]), "\n") {
+			if line == state {
+				return
+			}
+		}
+	}
+}
+
+func TestSdWatchdogCheckSkippedUntilReady(t *testing.T) {
+	ctx := newTestContext(t)
+	tracker := NewStatusTracker(ctx, "1")
+	ctx = WithStatusTracker(ctx, tracker)
+	watchdog := &SdWatchdog{Timeout: time.Second}
+	// a session that never connects would fail the check - but the server isn't ready yet
+	watchdog.SetSession(NewTelnetSession(getFreeAddr(t)))
+	err := watchdog.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSdWatchdogCheckReadyServer(t *testing.T) {
+	ctx := newTestContext(t)
+	fake := startFakeTelnet(t)
+	ctx, session := startTelnetSession(t, ctx)
+	tracker := NewStatusTracker(ctx, "1")
+	tracker.SetState(ctx, ServerStateReady)
+	ctx = WithStatusTracker(ctx, tracker)
+	watchdog := &SdWatchdog{Timeout: 4 * time.Second}
+	watchdog.SetSession(session)
+	err := watchdog.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForCommand(t, fake, "gettime", time.Second)
+}
+
+func TestSdWatchdogCheckUnresponsiveServer(t *testing.T) {
+	ctx := newTestContext(t)
+	fake := startFakeTelnet(t)
+	ctx, session := startTelnetSession(t, ctx)
+	tracker := NewStatusTracker(ctx, "1")
+	tracker.SetState(ctx, ServerStateReady)
+	ctx = WithStatusTracker(ctx, tracker)
+	// the server stops acknowledging commands
+	fake.Close()
+	watchdog := &SdWatchdog{Timeout: 2 * time.Second}
+	watchdog.SetSession(session)
+	err := watchdog.Check(ctx)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestSdWatchdogRunPings(t *testing.T) {
+	ctx := newTestContext(t)
+	conn := listenNotifySocket(t)
+	startFakeTelnet(t)
+	ctx, session := startTelnetSession(t, ctx)
+	tracker := NewStatusTracker(ctx, "1")
+	tracker.SetState(ctx, ServerStateReady)
+	ctx = WithStatusTracker(ctx, tracker)
+	watchdog := &SdWatchdog{Timeout: 4 * time.Second}
+	watchdog.SetSession(session)
+	go watchdog.Run(ctx)
+	waitForNotification(t, conn, "WATCHDOG=1", 5*time.Second)
+}
+
+func TestSdWatchdogDisabled(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "")
+	watchdog := NewSdWatchdog()
+	if watchdog.Enabled() {
+		t.Fatal("expected the watchdog to be disabled")
+	}
+	t.Setenv("WATCHDOG_USEC", "2000000")
+	t.Setenv("WATCHDOG_PID", "")
+	watchdog = NewSdWatchdog()
+	if !watchdog.Enabled() || watchdog.Timeout != 2*time.Second {
+		t.Fatalf("expected a 2s watchdog, got %s", watchdog.Timeout)
+	}
+}
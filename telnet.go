@@ -0,0 +1,500 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// telnetAddr is the address of the server's telnet port (a variable so that tests can point it at a fake server)
+var telnetAddr = "localhost:8081"
+
+// telnetPrompt is sent by the server once a telnet connection is ready to accept commands
+const telnetPrompt = "Press 'help' to get a list of all commands. Press 'exit' to end session."
+
+// Conn wraps [net.Conn] and provides helper methods
+type Conn struct {
+	netConn net.Conn
+	ctx     context.Context
+}
+
+// Reads from [Conn] until a pattern is found or a timeout occurs.
+// Raises an error if the connection read fails.
+// Raises an error if a timeout occurs
+func (conn Conn) ReadUntilPattern(pattern string, timeout time.Duration) error {
+	start := time.Now()
+	data := ""
+	buf := make([]byte, 128)
+	for {
+		now := time.Now()
+		if now.Sub(start) >= timeout {
+			return fmt.Errorf("timed out reading until pattern")
+		}
+		conn.netConn.SetReadDeadline(start.Add(timeout))
+		read, err := conn.netConn.Read(buf)
+		if err != nil {
+			return err
+		}
+		data += string(buf[:read])
+		if strings.Contains(data, pattern) {
+			break
+		}
+	}
+	return nil
+}
+
+// dialServerCb is a callback provided to [dialServer] - allowing callers to futher operate on a connection to the server
+type dialServerCb func(conn Conn) error
+
+// Backoff computes exponentially increasing delays between retries
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	current time.Duration
+}
+
+// Gets the next delay - doubling the previous delay (up to [Backoff.Max])
+func (b *Backoff) Next() time.Duration {
+	if b.current == 0 {
+		b.current = b.Initial
+	} else {
+		b.current = min(b.current*2, b.Max)
+	}
+	return b.current
+}
+
+// Resets the delay to [Backoff.Initial]
+func (b *Backoff) Reset() {
+	b.current = 0
+}
+
+// Waits for the next delay.
+// Returns an error if the context is cancelled while waiting.
+func (b *Backoff) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(b.Next()):
+		return nil
+	}
+}
+
+// DialServerOpts defines the options used in conjunction with the [DialServer] function
+type DialServerOpts struct {
+	// MaxWait is the total time spent retrying failed connections - if unset, a single attempt is made
+	MaxWait time.Duration
+	// Backoff is the initial delay between retries - defaults to 1s
+	Backoff time.Duration
+	// MaxBackoff is the maximum delay between retries - defaults to 30s
+	MaxBackoff time.Duration
+}
+
+// Connects to the server and waits for the server to accept commands.
+// Returns an error if the server is not connectable.
+// Returns an error if the server times out while waiting to accept commands.
+func dialServerOnce(ctx context.Context) (Conn, error) {
+	addr := telnetAddr
+	helper.Logger(ctx).Info("dialing server", "addr", addr)
+	nconn, err := dialTcp(ctx, addr)
+	if err != nil {
+		return Conn{}, err
+	}
+	conn := Conn{ctx: ctx, netConn: nconn}
+	err = conn.ReadUntilPattern(telnetPrompt, 5*time.Second)
+	if err != nil {
+		nconn.Close()
+		return Conn{}, err
+	}
+	nconn.SetReadDeadline(time.Time{})
+	return conn, nil
+}
+
+// DialServer connects to the running seven days to die server, waits for the server to accept commands, and then invokes the provided callback with the opened connection.
+// If [DialServerOpts.MaxWait] is set, failed connections are retried with exponential backoff until it elapses.
+// Raises an error if the server is not connectable
+// Raises an error if the server times out while waiting to accept commands
+// Raises an error if the callback raises an error
+func DialServer(ctx context.Context, opts DialServerOpts, cb dialServerCb) error {
+	if opts.Backoff == 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	deadline := time.Now().Add(opts.MaxWait)
+	backoff := Backoff{Initial: opts.Backoff, Max: opts.MaxBackoff}
+	for {
+		conn, err := dialServerOnce(ctx)
+		if err == nil {
+			defer conn.netConn.Close()
+			return cb(conn)
+		}
+		delay := backoff.Next()
+		if time.Now().Add(delay).After(deadline) {
+			return err
+		}
+		helper.Logger(ctx).Info("dial server failed - retrying", "error", err.Error(), "delay", delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// telnetLogLineRegex matches server log lines (e.g., '2024-01-01T00:00:00 12.345 INF ...') - which are interleaved with command output on telnet connections
+var telnetLogLineRegex = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2} \d+\.\d+ [A-Z]{3} `)
+
+// ErrTelnetNotConnected is returned when a command is executed while the [TelnetSession] is unable to connect
+var ErrTelnetNotConnected = errors.New("telnet session not connected")
+
+// ErrMultilineCommand is returned when a console command contains line breaks - each line would otherwise be executed as a separate command
+var ErrMultilineCommand = errors.New("command contains line breaks")
+
+// Validates that a console command is a single line.
+// Returns an error if the command contains line breaks.
+func checkCommandLine(command string) error {
+	if strings.ContainsAny(command, "\r\n") {
+		return fmt.Errorf("%w: %q", ErrMultilineCommand, command)
+	}
+	return nil
+}
+
+// TelnetResponseIdleTimeout is how long a command's response is collected after the last line of output is received
+const TelnetResponseIdleTimeout = 500 * time.Millisecond
+
+// TelnetSession maintains a single (reconnecting) telnet connection to the server that is shared by all subsystems.
+// Commands are serialized over the connection and their responses are correlated by the server's 'Executing command' log line.
+// Every line received is additionally broadcast to subscribers (e.g., for event processing).
+type TelnetSession struct {
+	addr        string
+	commandLock sync.Mutex
+	conn        net.Conn
+	connectCbs  []func(ctx context.Context)
+	connected   chan struct{}
+	lock        sync.Mutex
+	nextId      int
+	ready       chan struct{}
+	readyOnce   sync.Once
+	subscribers map[int]chan string
+	transcript  *TelnetTranscript
+}
+
+// Creates a new [TelnetSession] connecting to the given address.  Call [TelnetSession.Run] to connect.
+func NewTelnetSession(addr string) *TelnetSession {
+	return &TelnetSession{
+		addr:        addr,
+		connected:   make(chan struct{}),
+		ready:       make(chan struct{}),
+		subscribers: map[int]chan string{},
+	}
+}
+
+// Subscribes to all lines received over the session.
+// Lines are dropped for subscribers that are unable to keep up.
+// Returns a channel of lines and a function that unsubscribes (and closes the channel).
+func (ts *TelnetSession) Subscribe() (<-chan string, func()) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	id := ts.nextId
+	ts.nextId += 1
+	lines := make(chan string, 256)
+	ts.subscribers[id] = lines
+	unsubscribe := func() {
+		ts.lock.Lock()
+		defer ts.lock.Unlock()
+		_, ok := ts.subscribers[id]
+		if ok {
+			delete(ts.subscribers, id)
+			close(lines)
+		}
+	}
+	return lines, unsubscribe
+}
+
+// Registers a callback run (in its own goroutine) whenever the session connects - e.g., to re-apply per-connection state
+func (ts *TelnetSession) OnConnect(cb func(ctx context.Context)) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ts.connectCbs = append(ts.connectCbs, cb)
+}
+
+// Sets the [TelnetTranscript] that everything sent and received over the session is written to
+func (ts *TelnetSession) SetTranscript(transcript *TelnetTranscript) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ts.transcript = transcript
+}
+
+// Writes a line to the session's transcript (if any)
+func (ts *TelnetSession) record(ctx context.Context, prefix string, line string) {
+	ts.lock.Lock()
+	transcript := ts.transcript
+	ts.lock.Unlock()
+	if transcript != nil {
+		transcript.Write(ctx, prefix, line)
+	}
+}
+
+// Broadcasts a line to all subscribers
+func (ts *TelnetSession) broadcast(line string) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	for _, subscriber := range ts.subscribers {
+		select {
+		case subscriber <- line:
+		default:
+		}
+	}
+}
+
+// Connects to the server and waits for the server to accept commands.
+// Returns an error if the server is not connectable.
+// Returns an error if the server times out while waiting to accept commands.
+func (ts *TelnetSession) connect(ctx context.Context) (net.Conn, *bufio.Scanner, error) {
+	helper.Logger(ctx).Info("telnet session connect", "addr", ts.addr)
+	conn, err := dialTcp(ctx, ts.addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	scanner := bufio.NewScanner(conn)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), telnetPrompt) {
+			conn.SetReadDeadline(time.Time{})
+			return conn, scanner, nil
+		}
+	}
+	conn.Close()
+	err = scanner.Err()
+	if err == nil {
+		err = fmt.Errorf("connection closed before prompt")
+	}
+	return nil, nil, err
+}
+
+// telnetReadyCommand is a command that only succeeds once the server has finished loading the world
+const telnetReadyCommand = "gettime"
+
+// Polls the server with [telnetReadyCommand] (with backoff) until the server has finished loading - marking the session as ready.
+func (ts *TelnetSession) probeReady(ctx context.Context) {
+	backoff := Backoff{Initial: time.Second, Max: 10 * time.Second}
+	for {
+		response, err := ts.Exec(ctx, telnetReadyCommand, TelnetCommandTimeout)
+		if err == nil && len(response) > 0 && strings.HasPrefix(response[0], "Day ") {
+			helper.Logger(ctx).Info("server ready")
+			ts.readyOnce.Do(func() { close(ts.ready) })
+			return
+		}
+		if backoff.Wait(ctx) != nil {
+			return
+		}
+	}
+}
+
+// Waits until the server has finished loading and accepts commands.
+// Returns an error if the server is not ready before the timeout.
+func (ts *TelnetSession) WaitReady(ctx context.Context, timeout time.Duration) error {
+	select {
+	case <-ts.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(timeout):
+		return fmt.Errorf("server not ready after %s", timeout)
+	}
+}
+
+// Maintains the session's connection until the context is cancelled - reconnecting (with backoff) whenever the connection is lost.
+// Once connected, the server is probed until it is ready (see [TelnetSession.WaitReady]).
+func (ts *TelnetSession) Run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		ts.lock.Lock()
+		defer ts.lock.Unlock()
+		if ts.conn != nil {
+			ts.conn.Close()
+		}
+	}()
+	backoff := Backoff{Initial: time.Second, Max: 30 * time.Second}
+	go ts.probeReady(ctx)
+	for ctx.Err() == nil {
+		conn, scanner, err := ts.connect(ctx)
+		if err != nil {
+			helper.Logger(ctx).Info("telnet session connect failed", "error", err.Error())
+			backoff.Wait(ctx)
+			continue
+		}
+		backoff.Reset()
+		ts.lock.Lock()
+		ts.conn = conn
+		close(ts.connected)
+		for _, cb := range ts.connectCbs {
+			go cb(ctx)
+		}
+		ts.lock.Unlock()
+		ts.record(ctx, TranscriptEvent, fmt.Sprintf("connected to %s", ts.addr))
+		for scanner.Scan() {
+			line := strings.TrimRight(scanner.Text(), "\r")
+			ts.record(ctx, TranscriptReceived, line)
+			ts.broadcast(line)
+		}
+		helper.Logger(ctx).Info("telnet session disconnected")
+		ts.record(ctx, TranscriptEvent, "disconnected")
+		ts.lock.Lock()
+		ts.conn.Close()
+		ts.conn = nil
+		ts.connected = make(chan struct{})
+		ts.lock.Unlock()
+	}
+}
+
+// Waits until the session is connected.
+// Returns an error if the context is cancelled beforehand.
+func (ts *TelnetSession) waitConnected(ctx context.Context) (net.Conn, error) {
+	for {
+		ts.lock.Lock()
+		conn := ts.conn
+		connected := ts.connected
+		ts.lock.Unlock()
+		if conn != nil {
+			return conn, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-connected:
+		}
+	}
+}
+
+// Executes a console command over the session and collects its response.
+// The response consists of the non-log lines received after the server acknowledges the command - until no output is received for [TelnetResponseIdleTimeout].
+// Returns an error if the command contains line breaks.
+// Returns an error if the session does not connect or the server does not acknowledge the command before the timeout.
+func (ts *TelnetSession) Exec(ctx context.Context, command string, timeout time.Duration) ([]string, error) {
+	err := checkCommandLine(command)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ts.commandLock.Lock()
+	defer ts.commandLock.Unlock()
+	conn, err := ts.waitConnected(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTelnetNotConnected, err)
+	}
+	lines, unsubscribe := ts.Subscribe()
+	defer unsubscribe()
+	helper.Logger(ctx).Debug("telnet exec", "command", command)
+	ts.record(ctx, TranscriptSent, command)
+	_, err = conn.Write([]byte(fmt.Sprintf("%s\n", command)))
+	if err != nil {
+		return nil, err
+	}
+	ack := fmt.Sprintf("Executing command '%s'", command)
+	acked := false
+	response := []string{}
+	for {
+		var idle <-chan time.Time
+		if acked {
+			idle = time.After(TelnetResponseIdleTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			if acked {
+				return response, nil
+			}
+			return nil, fmt.Errorf("command %s not acknowledged: %w", command, ctx.Err())
+		case <-idle:
+			return response, nil
+		case line, ok := <-lines:
+			if !ok {
+				return response, nil
+			}
+			if !acked {
+				acked = strings.Contains(line, ack)
+				continue
+			}
+			if telnetLogLineRegex.MatchString(line) || strings.TrimSpace(line) == "" {
+				continue
+			}
+			response = append(response, line)
+		}
+	}
+}
+
+// TelnetCommandTimeout is the default timeout used when executing commands with [SendCommand]
+const TelnetCommandTimeout = 10 * time.Second
+
+// Sends a console command to the server and returns its response.
+// Uses the shared [TelnetSession] attached to the context when available - otherwise, a dedicated connection is opened (and only the send is performed).
+// Returns an error if the command contains line breaks.
+// Returns an error if the command cannot be sent.
+func SendCommand(ctx context.Context, command string) ([]string, error) {
+	session := GetTelnetSession(ctx)
+	if session != nil {
+		return session.Exec(ctx, command, TelnetCommandTimeout)
+	}
+	err := checkCommandLine(command)
+	if err != nil {
+		return nil, err
+	}
+	err = DialServer(ctx, DialServerOpts{}, func(conn Conn) error {
+		_, err := conn.netConn.Write([]byte(fmt.Sprintf("%s\n", command)))
+		return err
+	})
+	return nil, err
+}
+
+// Sends a chat command - via the [SayQueue] attached to the context when available (so that it's rate limited and deduplicated), otherwise directly.
+// Returns an error if the command cannot be sent (or queued).
+func sendSayCommand(ctx context.Context, command string) error {
+	queue := GetSayQueue(ctx)
+	if queue != nil {
+		return queue.Enqueue(ctx, command)
+	}
+	_, err := SendCommand(ctx, command)
+	return err
+}
+
+// Sends a chat message to all players
+// Returns an error if the command cannot be sent.
+func SayServer(ctx context.Context, message string) error {
+	return sendSayCommand(ctx, fmt.Sprintf("say \"%s\"", strings.ReplaceAll(message, "\"", "\"\"")))
+}
+
+// Sends a private chat message to a player (by entity id)
+// Returns an error if the command cannot be sent.
+func SayPlayer(ctx context.Context, entityId string, message string) error {
+	return sendSayCommand(ctx, fmt.Sprintf("sayplayer %s \"%s\"", entityId, strings.ReplaceAll(message, "\"", "\"\"")))
+}
+
+// Shuts down a seven days to die server by connecting to its telnet port and sending the 'shutdown' command.
+// Raises an error if connecting to the server fails.
+// Raises an error if the server fails to send the command.
+func ShutdownServer(ctx context.Context) error {
+	helper.Logger(ctx).Info("shutdown server")
+	SetServerState(ctx, ServerStateShuttingDown)
+	session := GetTelnetSession(ctx)
+	if session != nil {
+		// the server disconnects before acknowledging the command - send it without waiting for a response
+		_, err := session.Exec(ctx, "shutdown", TelnetResponseIdleTimeout)
+		if !errors.Is(err, ErrTelnetNotConnected) {
+			return nil
+		}
+	}
+	return DialServer(ctx, DialServerOpts{}, func(conn Conn) error {
+		_, err := conn.netConn.Write([]byte("shutdown\n"))
+		return err
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benfiola/seven-days-to-die/internal/fakes"
+)
+
+func TestDialServer(t *testing.T) {
+	ctx := newTestContext(t)
+	fake := startFakeTelnet(t)
+	err := DialServer(ctx, DialServerOpts{}, func(conn Conn) error {
+		_, err := conn.netConn.Write([]byte("saveworld\n"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForCommand(t, fake, "saveworld", 5*time.Second)
+}
+
+func TestDialServerReturnsCallbackError(t *testing.T) {
+	ctx := newTestContext(t)
+	startFakeTelnet(t)
+	expected := errors.New("callback failed")
+	err := DialServer(ctx, DialServerOpts{}, func(conn Conn) error {
+		return expected
+	})
+	if !errors.Is(err, expected) {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestDialServerFailsWithoutServer(t *testing.T) {
+	ctx := newTestContext(t)
+	setTelnetAddr(t, getFreeAddr(t))
+	err := DialServer(ctx, DialServerOpts{}, func(conn Conn) error {
+		t.Fatal("callback invoked without a server")
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestDialServerRetriesUntilServerListens(t *testing.T) {
+	ctx := newTestContext(t)
+	addr := getFreeAddr(t)
+	setTelnetAddr(t, addr)
+	fake := fakes.NewTelnetServer()
+	t.Cleanup(func() {
+		fake.Close()
+	})
+	listened := make(chan error, 1)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		_, err := fake.Listen(addr)
+		listened <- err
+	}()
+	dialed := false
+	err := DialServer(ctx, DialServerOpts{MaxWait: 10 * time.Second, Backoff: 100 * time.Millisecond}, func(conn Conn) error {
+		dialed = true
+		return nil
+	})
+	if listenErr := <-listened; listenErr != nil {
+		t.Fatal(listenErr)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dialed {
+		t.Fatal("callback not invoked")
+	}
+}
+
+func TestTelnetSessionExec(t *testing.T) {
+	ctx := newTestContext(t)
+	fake := startFakeTelnet(t)
+	fake.RespondLines("version", "Game version: fake")
+	ctx, _ = startTelnetSession(t, ctx)
+	response, err := SendCommand(ctx, "version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response) != 1 || response[0] != "Game version: fake" {
+		t.Fatalf("unexpected response %v", response)
+	}
+}
+
+func TestShutdownServer(t *testing.T) {
+	ctx := newTestContext(t)
+	fake := startFakeTelnet(t)
+	ctx, _ = startTelnetSession(t, ctx)
+	tracker := NewStatusTracker(ctx, "1")
+	ctx = WithStatusTracker(ctx, tracker)
+	err := ShutdownServer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForCommand(t, fake, "shutdown", 5*time.Second)
+	if state := tracker.Get().State; state != ServerStateShuttingDown {
+		t.Fatalf("expected state %s, got %s", ServerStateShuttingDown, state)
+	}
+}
+
+func TestShutdownServerFallsBackToDirectDial(t *testing.T) {
+	ctx := newTestContext(t)
+	fake := startFakeTelnet(t)
+	// the session never connects (e.g., its connection was lost) - so the command is sent over a dedicated connection
+	session := NewTelnetSession(getFreeAddr(t))
+	ctx = WithTelnetSession(ctx, session)
+	err := ShutdownServer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForCommand(t, fake, "shutdown", 5*time.Second)
+}
+
+func TestShutdownServerWithoutSession(t *testing.T) {
+	ctx := newTestContext(t)
+	fake := startFakeTelnet(t)
+	err := ShutdownServer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForCommand(t, fake, "shutdown", 5*time.Second)
+}
+
+func TestShutdownServerFailsWithoutServer(t *testing.T) {
+	ctx := newTestContext(t)
+	setTelnetAddr(t, getFreeAddr(t))
+	err := ShutdownServer(ctx)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestTelnetSessionExecRejectsLineBreaks(t *testing.T) {
+	ctx := newTestContext(t)
+	startFakeTelnet(t)
+	_, session := startTelnetSession(t, ctx)
+	for _, command := range []string{"say hi\nshutdown", "say hi\rshutdown"} {
+		_, err := session.Exec(ctx, command, time.Second)
+		if !errors.Is(err, ErrMultilineCommand) {
+			t.Fatalf("command %q: expected %v, got %v", command, ErrMultilineCommand, err)
+		}
+	}
+}
+
+func TestSendCommandRejectsLineBreaksWithoutSession(t *testing.T) {
+	ctx := newTestContext(t)
+	fake := startFakeTelnet(t)
+	_, err := SendCommand(ctx, "say hi\nshutdown")
+	if !errors.Is(err, ErrMultilineCommand) {
+		t.Fatalf("expected %v, got %v", ErrMultilineCommand, err)
+	}
+	if len(fake.Commands()) != 0 {
+		t.Fatalf("command sent (received %v)", fake.Commands())
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// templatedSettings are the settings whose values can contain template placeholders (e.g., '{version}')
+var templatedSettings = []string{"ServerName", "ServerDescription"}
+
+// Gets the path of the file that records the date each save game was first seen (i.e., its wipe date)
+func getWipesFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "wipes.json")
+}
+
+// Finds the save game folder for a game name (the world folder is unknown for generated worlds).
+// Returns an empty string if the save game doesn't exist yet.
+func findSaveGame(ctx context.Context, gameName string) string {
+	matches, _ := filepath.Glob(filepath.Join(helper.Dirs(ctx)["data"], "Saves", "*", gameName))
+	latest := ""
+	latestTime := time.Time{}
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if info.ModTime().After(latestTime) {
+			latest = match
+			latestTime = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// Gets the wipe date of the current save game - the date its save game folder was first seen (or today, if the save game doesn't exist yet).
+// When a save game folder disappears (i.e., the server was wiped), the wipe date resets.
+// Returns an error if the wipes file cannot be read or written.
+func GetWipeDate(ctx context.Context, gameName string) (time.Time, error) {
+	wipes := map[string]time.Time{}
+	err := helper.UnmarshalFile(ctx, getWipesFile(ctx), &wipes)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, err
+	}
+	savesDir := filepath.Join(helper.Dirs(ctx)["data"], "Saves")
+	for key := range wipes {
+		_, err := os.Stat(filepath.Join(savesDir, key))
+		if key != gameName && err != nil {
+			// the save game was wiped
+			delete(wipes, key)
+		}
+	}
+	key := gameName
+	saveGame := findSaveGame(ctx, gameName)
+	if saveGame != "" {
+		key, _ = filepath.Rel(savesDir, saveGame)
+		key = filepath.ToSlash(key)
+	}
+	wipeDate, ok := wipes[key]
+	if ok {
+		return wipeDate, nil
+	}
+	wipeDate = time.Now()
+	if saveGame == "" {
+		// the save game will be created when the server starts - record it under the game name until its world is known
+		key = gameName
+	} else if pending, ok := wipes[gameName]; ok {
+		wipeDate = pending
+		delete(wipes, gameName)
+	}
+	wipes[key] = wipeDate
+	return wipeDate, helper.MarshalFile(ctx, wipes, getWipesFile(ctx))
+}
+
+// Counts the installed mods (excluding the mods shipped with the game)
+func countMods(ctx context.Context) int {
+	subpaths, err := helper.ListDir(ctx, filepath.Join(helper.Dirs(ctx)["sdtd"], "Mods"))
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, subpath := range subpaths {
+		if !strings.HasPrefix(filepath.Base(subpath), tfpModPrefix) {
+			count++
+		}
+	}
+	return count
+}
+
+// TemplateVarsOpts defines the options used in conjunction with the [GetTemplateVars] function
+type TemplateVarsOpts struct {
+	GameVersion string
+	ManifestId  string
+}
+
+// Gets the values of template placeholders:
+//
+//	{version} - the game version (GAME_VERSION, or the manifest id if unset)
+//	{manifest} - the steam manifest id
+//	{modcount} - the number of installed mods
+//	{wipedate} - the date the current save game was created
+//	{world} / {gamename} - the GameWorld and GameName settings
+//	{date} - today's date
+//
+// Returns an error if the wipe date cannot be determined.
+func GetTemplateVars(ctx context.Context, opts TemplateVarsOpts, settings ServerSettings) (map[string]string, error) {
+	gameName, _ := settings.Get("GameName")
+	world, _ := settings.Get("GameWorld")
+	wipeDate, err := GetWipeDate(ctx, gameName)
+	if err != nil {
+		return nil, err
+	}
+	version := opts.GameVersion
+	if version == "" {
+		version = opts.ManifestId
+	}
+	return map[string]string{
+		"date":     time.Now().Format(time.DateOnly),
+		"gamename": gameName,
+		"manifest": opts.ManifestId,
+		"modcount": fmt.Sprintf("%d", countMods(ctx)),
+		"version":  version,
+		"wipedate": wipeDate.Format(time.DateOnly),
+		"world":    world,
+	}, nil
+}
+
+// Replaces '{name}' placeholders in the templated settings (see [templatedSettings]) with their values.
+// Unknown placeholders are left as-is.
+func RenderSettingTemplates(ctx context.Context, settings ServerSettings, vars map[string]string) {
+	pairs := []string{}
+	for name, value := range vars {
+		pairs = append(pairs, fmt.Sprintf("{%s}", name), value)
+	}
+	replacer := strings.NewReplacer(pairs...)
+	for _, name := range templatedSettings {
+		value, ok := settings.Get(name)
+		if !ok || !strings.Contains(value, "{") {
+			continue
+		}
+		rendered := replacer.Replace(value)
+		helper.Logger(ctx).Info("render setting template", "setting", name, "value", rendered)
+		settings.Set(name, rendered)
+	}
+}
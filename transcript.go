@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+const (
+	// TranscriptSent prefixes transcript lines sent to the server (i.e., commands)
+	TranscriptSent = ">"
+	// TranscriptReceived prefixes transcript lines received from the server
+	TranscriptReceived = "<"
+	// TranscriptEvent prefixes transcript lines describing the session itself (e.g., connects and disconnects)
+	TranscriptEvent = "#"
+)
+
+// TelnetTranscriptOpts are options for [TelnetTranscript]
+type TelnetTranscriptOpts struct {
+	// MaxFiles is the number of rotated transcript files kept (in addition to the current file)
+	MaxFiles int
+	// MaxSize is the size (in bytes) at which the transcript file is rotated
+	MaxSize int64
+}
+
+// TelnetTranscript persists everything sent and received over a [TelnetSession] to a rotating transcript file ('[data]/logs/telnet.log').
+// Rotated files are renamed 'telnet.1.log' (the most recent) through 'telnet.[MaxFiles].log' (the oldest).
+type TelnetTranscript struct {
+	Opts   TelnetTranscriptOpts
+	file   string
+	handle *os.File
+	lock   sync.Mutex
+	size   int64
+}
+
+// Creates a new [TelnetTranscript] writing to '[data]/logs/telnet.log'
+func NewTelnetTranscript(ctx context.Context, opts TelnetTranscriptOpts) *TelnetTranscript {
+	return &TelnetTranscript{Opts: opts, file: filepath.Join(helper.Dirs(ctx)["data"], "logs", "telnet.log")}
+}
+
+// Gets the path of a rotated transcript file
+func (tt *TelnetTranscript) rotatedFile(index int) string {
+	return fmt.Sprintf("%s.%d.log", strings.TrimSuffix(tt.file, ".log"), index)
+}
+
+// Opens the transcript file (for appending) if it isn't already open.
+// Returns an error if the file cannot be opened.
+func (tt *TelnetTranscript) open(ctx context.Context) error {
+	if tt.handle != nil {
+		return nil
+	}
+	err := helper.CreateDirs(ctx, filepath.Dir(tt.file))
+	if err != nil {
+		return err
+	}
+	handle, err := os.OpenFile(tt.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := handle.Stat()
+	if err != nil {
+		handle.Close()
+		return err
+	}
+	tt.handle = handle
+	tt.size = info.Size()
+	return nil
+}
+
+// Rotates the transcript file - shifting rotated files and deleting the oldest beyond [TelnetTranscriptOpts.MaxFiles].
+// Returns an error if the transcript files cannot be renamed.
+func (tt *TelnetTranscript) rotate() error {
+	if tt.handle != nil {
+		tt.handle.Close()
+		tt.handle = nil
+	}
+	err := os.Remove(tt.rotatedFile(tt.Opts.MaxFiles))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for index := tt.Opts.MaxFiles - 1; index >= 1; index-- {
+		err := os.Rename(tt.rotatedFile(index), tt.rotatedFile(index+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if tt.Opts.MaxFiles <= 0 {
+		return os.Remove(tt.file)
+	}
+	return os.Rename(tt.file, tt.rotatedFile(1))
+}
+
+// Appends a timestamped line to the transcript - rotating the transcript file once it exceeds [TelnetTranscriptOpts.MaxSize].
+// Failures are logged and otherwise ignored.
+func (tt *TelnetTranscript) Write(ctx context.Context, prefix string, line string) {
+	tt.lock.Lock()
+	defer tt.lock.Unlock()
+	err := tt.open(ctx)
+	if err == nil {
+		var count int
+		count, err = fmt.Fprintf(tt.handle, "%s %s %s\n", time.Now().UTC().Format(time.RFC3339Nano), prefix, line)
+		tt.size += int64(count)
+	}
+	if err == nil && tt.Opts.MaxSize > 0 && tt.size >= tt.Opts.MaxSize {
+		err = tt.rotate()
+	}
+	if err != nil {
+		helper.Logger(ctx).Warn("write telnet transcript failed", "error", err.Error())
+	}
+}
+
+// Closes the transcript file
+func (tt *TelnetTranscript) Close() error {
+	tt.lock.Lock()
+	defer tt.lock.Unlock()
+	if tt.handle == nil {
+		return nil
+	}
+	err := tt.handle.Close()
+	tt.handle = nil
+	return err
+}
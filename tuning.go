@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ProcessTuning defines the scheduling options applied to the dedicated server process
+type ProcessTuning struct {
+	// CpuAffinity is a cpu list (e.g., '2-5,7') the server is pinned to (unpinned if empty)
+	CpuAffinity string
+	// Priority is the niceness (-20 to 19) the server runs with (inherited if nil)
+	Priority *int
+}
+
+// Parses a cpu list (e.g., '2-5,7') into the cpus it contains.
+// Returns an error if the cpu list is malformed.
+func ParseCpuList(value string) ([]int, error) {
+	cpus := []int{}
+	for _, item := range strings.Split(value, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(item), "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid cpu '%s' in cpu list %s", first, value)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(last)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid cpu range '%s' in cpu list %s", item, value)
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// Wraps a command so that it's launched with the process tuning options - using 'taskset' (cpu affinity), 'nice' (cpu priority) and 'ionice' (io priority).
+// Each wrapper execs the next, so the server process itself ends up with the options applied.
+// The io priority is derived from the niceness the same way the kernel does for processes without an explicit io priority.
+func (pt ProcessTuning) Command(cmd []string) []string {
+	wrapper := []string{}
+	if pt.CpuAffinity != "" {
+		wrapper = append(wrapper, "taskset", "--cpu-list", pt.CpuAffinity)
+	}
+	if pt.Priority != nil {
+		wrapper = append(wrapper, "nice", "-n", strconv.Itoa(*pt.Priority))
+		wrapper = append(wrapper, "ionice", "--class", "2", "--classdata", strconv.Itoa((*pt.Priority+20)/5))
+	}
+	return append(wrapper, cmd...)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// githubReleaseRegex matches github release asset urls (capturing the owner, repo and tag)
+var githubReleaseRegex = regexp.MustCompile(`^https://github\.com/([^/]+)/([^/]+)/releases/download/([^/]+)/[^/]+$`)
+
+// gitlabReleaseRegex matches gitlab release asset urls (capturing the project path and tag)
+var gitlabReleaseRegex = regexp.MustCompile(`^https://gitlab\.com/(.+?)/-/releases/([^/]+)/downloads/.+$`)
+
+// versionRegex matches a semantic-ish version (e.g., 'v1.2.3', '1.2')
+var versionRegex = regexp.MustCompile(`v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ModUpdate describes a newer version of an installed mod
+type ModUpdate struct {
+	Url       string    `json:"url"`
+	LatestUrl string    `json:"latestUrl"`
+	Version   string    `json:"version"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Gets the path of the file that records available mod updates
+func getModUpdatesFile(ctx context.Context) string {
+	return filepath.Join(helper.Dirs(ctx)["data"], "mod-updates.json")
+}
+
+// Loads recorded mod updates (keyed by installed url).
+// Returns an empty map if no updates have been recorded.
+// Returns an error if the mod updates file is unreadable.
+func LoadModUpdates(ctx context.Context) (map[string]ModUpdate, error) {
+	updates := map[string]ModUpdate{}
+	err := helper.UnmarshalFile(ctx, getModUpdatesFile(ctx), &updates)
+	if errors.Is(err, os.ErrNotExist) {
+		return updates, nil
+	}
+	return updates, err
+}
+
+// Replaces mod urls with the newer versions recorded by a previous update check.
+// Returns an error if the mod updates file is unreadable.
+func ApplyModUpdates(ctx context.Context, mods []string) ([]string, error) {
+	updates, err := LoadModUpdates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := []string{}
+	for _, mod := range mods {
+		update, ok := updates[mod]
+		if ok && update.LatestUrl != "" {
+			helper.Logger(ctx).Info("apply mod update", "mod", mod, "url", update.LatestUrl, "version", update.Version)
+			mod = update.LatestUrl
+		}
+		applied = append(applied, mod)
+	}
+	return applied, nil
+}
+
+// Performs an http request and decodes the json response into [data]
+// Returns an error if the request fails or sends a non-200 status code.
+func getJson(ctx context.Context, url string, data any) error {
+	if GetDownloadConfig(ctx).Offline {
+		return fmt.Errorf("GET %s: %w", url, ErrOffline)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	response, err := getHttpClient(ctx).Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s sent non-200 status code: %d", url, response.StatusCode)
+	}
+	return json.NewDecoder(response.Body).Decode(data)
+}
+
+// Determines whether a url exists by performing a HEAD request
+func urlExists(ctx context.Context, url string) bool {
+	if GetDownloadConfig(ctx).Offline {
+		return false
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return false
+	}
+	response, err := getHttpClient(ctx).Do(request)
+	if err != nil {
+		return false
+	}
+	response.Body.Close()
+	return response.StatusCode == http.StatusOK
+}
+
+// Produces the url of a newer release by substituting the current tag (and its version) with the latest tag.
+func substituteReleaseTag(mod string, tag string, latestTag string) string {
+	latest := strings.ReplaceAll(mod, tag, latestTag)
+	version := strings.TrimPrefix(tag, "v")
+	latestVersion := strings.TrimPrefix(latestTag, "v")
+	if version != tag || latestVersion != latestTag {
+		latest = strings.ReplaceAll(latest, version, latestVersion)
+	}
+	return latest
+}
+
+// Checks a github release asset url for a newer release.
+// Returns nil if the mod is up-to-date.
+// Returns an error if the github api request fails.
+func checkGithubModUpdate(ctx context.Context, mod string, match []string) (*ModUpdate, error) {
+	owner, repo, tag := match[1], match[2], match[3]
+	release := struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			BrowserDownloadUrl string `json:"browser_download_url"`
+		} `json:"assets"`
+	}{}
+	err := getJson(ctx, fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", owner, repo), &release)
+	if err != nil {
+		return nil, err
+	}
+	if release.TagName == "" || release.TagName == tag {
+		return nil, nil
+	}
+	update := ModUpdate{Url: mod, Version: release.TagName}
+	candidate := substituteReleaseTag(mod, tag, release.TagName)
+	for _, asset := range release.Assets {
+		if asset.BrowserDownloadUrl == candidate {
+			update.LatestUrl = candidate
+		}
+	}
+	return &update, nil
+}
+
+// Checks a gitlab release asset url for a newer release.
+// Returns nil if the mod is up-to-date.
+// Returns an error if the gitlab api request fails.
+func checkGitlabModUpdate(ctx context.Context, mod string, match []string) (*ModUpdate, error) {
+	project, tag := match[1], match[2]
+	release := struct {
+		TagName string `json:"tag_name"`
+	}{}
+	err := getJson(ctx, fmt.Sprintf("https://gitlab.com/api/v4/projects/%s/releases/permalink/latest", url.PathEscape(project)), &release)
+	if err != nil {
+		return nil, err
+	}
+	if release.TagName == "" || release.TagName == tag {
+		return nil, nil
+	}
+	update := ModUpdate{Url: mod, Version: release.TagName}
+	candidate := substituteReleaseTag(mod, tag, release.TagName)
+	if urlExists(ctx, candidate) {
+		update.LatestUrl = candidate
+	}
+	return &update, nil
+}
+
+// Checks a direct download url containing a version for a newer version by probing the next major, minor and patch versions.
+// Returns nil if no newer version is found.
+func checkVersionedModUpdate(ctx context.Context, mod string) (*ModUpdate, error) {
+	locations := versionRegex.FindAllStringSubmatchIndex(mod, -1)
+	if len(locations) == 0 {
+		return nil, nil
+	}
+	location := locations[len(locations)-1]
+	match := mod[location[0]:location[1]]
+	parts := []int{}
+	for index := 2; index < 8; index += 2 {
+		part := 0
+		if location[index] != -1 {
+			part, _ = strconv.Atoi(mod[location[index]:location[index+1]])
+		}
+		parts = append(parts, part)
+	}
+	prefix := ""
+	if strings.HasPrefix(match, "v") {
+		prefix = "v"
+	}
+	format := func(major int, minor int, patch int) string {
+		if location[6] == -1 {
+			return fmt.Sprintf("%s%d.%d", prefix, major, minor)
+		}
+		return fmt.Sprintf("%s%d.%d.%d", prefix, major, minor, patch)
+	}
+	candidates := []string{format(parts[0]+1, 0, 0), format(parts[0], parts[1]+1, 0)}
+	if location[6] != -1 {
+		candidates = append(candidates, format(parts[0], parts[1], parts[2]+1))
+	}
+	for _, version := range candidates {
+		candidate := mod[:location[0]] + version + mod[location[1]:]
+		if urlExists(ctx, candidate) {
+			return &ModUpdate{Url: mod, LatestUrl: candidate, Version: version}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Checks a mod url for a newer version.
+// Returns nil if the mod is up-to-date or the url does not follow a recognized release convention.
+// Returns an error if the update check fails.
+func CheckModUpdate(ctx context.Context, mod string) (*ModUpdate, error) {
+	var update *ModUpdate
+	var err error
+	if match := githubReleaseRegex.FindStringSubmatch(mod); match != nil {
+		update, err = checkGithubModUpdate(ctx, mod, match)
+	} else if match := gitlabReleaseRegex.FindStringSubmatch(mod); match != nil {
+		update, err = checkGitlabModUpdate(ctx, mod, match)
+	} else {
+		update, err = checkVersionedModUpdate(ctx, mod)
+	}
+	if update != nil {
+		update.CheckedAt = time.Now()
+	}
+	return update, err
+}
+
+// Checks a list of configured mod urls for newer versions, logs the results and merges them into the updates recorded in the data directory (so that they can be applied on the next start).
+// Mods with a recorded update are checked from the recorded version (so that subsequent releases are found) - updates remain keyed by the configured url.
+// Failing update checks are logged and leave previously recorded updates in place.
+// Returns the updates found since the previous check (keyed by configured url).
+// Returns an error if the mod updates file cannot be read or written.
+func CheckModUpdates(ctx context.Context, mods ...string) (map[string]ModUpdate, error) {
+	helper.Logger(ctx).Info("check mod updates", "count", len(mods))
+	recorded, err := LoadModUpdates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	updates := map[string]ModUpdate{}
+	for _, mod := range mods {
+		current := mod
+		previous, ok := recorded[mod]
+		if ok && previous.LatestUrl != "" {
+			current = previous.LatestUrl
+		}
+		update, err := CheckModUpdate(ctx, current)
+		if err != nil {
+			helper.Logger(ctx).Warn("mod update check failed", "mod", mod, "error", err.Error())
+			continue
+		}
+		if update == nil {
+			if current == mod {
+				delete(recorded, mod)
+			}
+			continue
+		}
+		update.Url = mod
+		if update.LatestUrl == "" && previous.LatestUrl != "" {
+			// a newer release without a matching asset shouldn't discard the recorded (installable) update
+			continue
+		}
+		helper.Logger(ctx).Info("mod update available", "mod", mod, "version", update.Version, "url", update.LatestUrl)
+		recorded[mod] = *update
+		updates[mod] = *update
+	}
+	err = helper.MarshalFile(ctx, recorded, getModUpdatesFile(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// Periodically checks a list of mod urls for newer versions until the context is cancelled.
+// If [onUpdates] is non-nil, it is called whenever updates are available.
+func RunModUpdateChecks(ctx context.Context, interval time.Duration, onUpdates func(updates map[string]ModUpdate), mods ...string) {
+	for {
+		updates, err := CheckModUpdates(ctx, mods...)
+		if err != nil {
+			helper.Logger(ctx).Warn("mod update checks failed", "error", err.Error())
+		}
+		if len(updates) > 0 && onUpdates != nil {
+			onUpdates(updates)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
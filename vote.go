@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// UpdateVoteOpts defines the options used in conjunction with the [RunUpdateVote] function
+type UpdateVoteOpts struct {
+	// Command is the chat message players send to vote to update now
+	Command string
+	// Deadline is the time after which the update proceeds regardless of votes
+	Deadline time.Duration
+	// Reminder is the interval at which the pending update is re-announced
+	Reminder time.Duration
+}
+
+// updateVoteShutdownDelay is the delay between announcing that an update is proceeding and shutting down the server
+const updateVoteShutdownDelay = time.Minute
+
+// UpdateVote tallies votes (by entity id) to update now and determines whether a majority of online players agree
+type UpdateVote struct {
+	lock   sync.Mutex
+	voters map[string]bool
+}
+
+// Records a vote - returning the number of votes and the number of online players.
+// Returns an error if the online players cannot be listed.
+func (uv *UpdateVote) Vote(ctx context.Context, entityId string) (int, int, error) {
+	players, err := ListPlayers(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	uv.lock.Lock()
+	defer uv.lock.Unlock()
+	uv.voters[entityId] = true
+	online := map[string]bool{}
+	for _, player := range players {
+		online[player.EntityId] = true
+	}
+	// votes from players that have since disconnected are discarded
+	votes := 0
+	for voter := range uv.voters {
+		if online[voter] {
+			votes++
+		}
+	}
+	return votes, len(players), nil
+}
+
+// Announces pending mod updates in-game and lets players vote to update now via chat.
+// Once a majority of online players vote (or the deadline passes), the server is gracefully shut down (so that the updates are applied on the next start).
+// Returns an error if the telnet session is unavailable.
+func RunUpdateVote(ctx context.Context, opts UpdateVoteOpts, updates map[string]ModUpdate) error {
+	session := GetTelnetSession(ctx)
+	if session == nil {
+		return ErrTelnetNotConnected
+	}
+	helper.Logger(ctx).Info("start update vote", "updates", len(updates), "deadline", opts.Deadline)
+	lines, unsubscribe := session.Subscribe()
+	defer unsubscribe()
+	deadline := time.Now().Add(opts.Deadline)
+	tracker := GetStatusTracker(ctx)
+	if tracker != nil {
+		tracker.Update(ctx, func(status *ServerStatus) {
+			status.NextRestart = &deadline
+		})
+	}
+	announce := func() {
+		remaining := time.Until(deadline).Round(time.Minute)
+		SayServer(ctx, fmt.Sprintf("%d mod update(s) available - type %s to vote to restart now. The server restarts in %s regardless.", len(updates), opts.Command, remaining))
+	}
+	announce()
+	reminder := time.NewTicker(opts.Reminder)
+	defer reminder.Stop()
+	timeout := time.NewTimer(opts.Deadline)
+	defer timeout.Stop()
+	vote := UpdateVote{voters: map[string]bool{}}
+	reason := "deadline passed"
+vote:
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-reminder.C:
+			announce()
+		case <-timeout.C:
+			break vote
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			message := ParseChatMessage(line)
+			if message == nil || !strings.EqualFold(message.Message, opts.Command) {
+				continue
+			}
+			votes, online, err := vote.Vote(ctx, message.EntityId)
+			if err != nil {
+				helper.Logger(ctx).Warn("update vote failed", "error", err.Error())
+				continue
+			}
+			helper.Logger(ctx).Info("update vote", "player", message.Name, "votes", votes, "online", online)
+			SayServer(ctx, fmt.Sprintf("%s voted to restart now (%d/%d)", message.Name, votes, online/2+1))
+			if votes*2 > online {
+				reason = "majority voted"
+				break vote
+			}
+		}
+	}
+	helper.Logger(ctx).Info("update vote finished", "reason", reason)
+	SayServer(ctx, fmt.Sprintf("Restarting server in %s to apply mod updates", updateVoteShutdownDelay))
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(updateVoteShutdownDelay):
+	}
+	return ShutdownServer(ctx)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+	"golang.org/x/net/webdav"
+)
+
+// Determines whether a request is authenticated with the given password - either as the password of basic auth credentials or as a bearer token
+func isWebdavAuthorized(request *http.Request, username string, password string) bool {
+	equal := func(a string, b string) bool {
+		return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+	}
+	requestUsername, requestPassword, ok := request.BasicAuth()
+	if ok {
+		return equal(requestUsername, username) && equal(requestPassword, password)
+	}
+	token, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+	return ok && equal(token, password)
+}
+
+// Creates an http handler serving WebDAV access to the data and generated directories (at '/data/' and '/generated/' respectively).
+// Clients (identified through [TrustedProxies]) are rate limited after 10 failed authentication attempts within a minute.
+func NewWebdavHandler(ctx context.Context, username string, password string, proxies TrustedProxies) http.Handler {
+	limiter := newAuthLimiter(10, time.Minute)
+	mux := http.NewServeMux()
+	for _, name := range []string{"data", "generated"} {
+		prefix := fmt.Sprintf("/%s", name)
+		handler := &webdav.Handler{
+			Prefix:     prefix,
+			FileSystem: webdav.Dir(helper.Dirs(ctx)[name]),
+			LockSystem: webdav.NewMemLS(),
+			Logger: func(request *http.Request, err error) {
+				if err != nil {
+					helper.Logger(ctx).Warn("webdav request failed", "method", request.Method, "path", request.URL.Path, "client", GetClientAddr(request.Context()), "error", err.Error())
+					return
+				}
+				if request.Method != http.MethodGet && request.Method != "PROPFIND" && request.Method != http.MethodOptions {
+					helper.Logger(ctx).Info("webdav request", "method", request.Method, "path", request.URL.Path, "client", GetClientAddr(request.Context()))
+				}
+			},
+		}
+		mux.Handle(prefix+"/", handler)
+	}
+	return proxies.Handler(ctx, "webdav", http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		client := GetClientAddr(request.Context())
+		if !limiter.Allow(client) {
+			http.Error(writer, "too many failed authentication attempts", http.StatusTooManyRequests)
+			return
+		}
+		if !isWebdavAuthorized(request, username, password) {
+			limiter.Fail(client)
+			writer.Header().Set("WWW-Authenticate", `Basic realm="seven-days-to-die"`)
+			http.Error(writer, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(writer, request)
+	}))
+}
+
+// Serves WebDAV access to the data and generated directories until the context is cancelled.
+// Returns an error if the server fails to listen.
+func RunWebdavServer(ctx context.Context, addr string, username string, password string, proxies TrustedProxies) error {
+	helper.Logger(ctx).Info("start webdav server", "addr", addr)
+	server := http.Server{Addr: addr, Handler: NewWebdavHandler(ctx, username, password, proxies)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	helper "github.com/benfiola/game-server-helper/pkg"
+)
+
+// ErrWorldSettingsChanged is returned when the world settings differ from those recorded for an existing save game
+var ErrWorldSettingsChanged = errors.New("world settings changed")
+
+// WorldSettings are the settings that determine the world of a save game - changing them for an existing save game corrupts it (or loads it into a different world)
+type WorldSettings struct {
+	GameWorld string `json:"gameWorld"`
+	// WorldGenSeed and WorldGenSize are only recorded for generated ('RWG') worlds
+	WorldGenSeed string    `json:"worldGenSeed,omitempty"`
+	WorldGenSize string    `json:"worldGenSize,omitempty"`
+	RecordedAt   time.Time `json:"recordedAt"`
+}
+
+// Gets the world settings from the server settings
+func GetWorldSettings(settings ServerSettings) WorldSettings {
+	ws := WorldSettings{}
+	ws.GameWorld, _ = settings.Get("GameWorld")
+	if strings.EqualFold(ws.GameWorld, "RWG") {
+		ws.WorldGenSeed, _ = settings.Get("WorldGenSeed")
+		ws.WorldGenSize, _ = settings.Get("WorldGenSize")
+	}
+	return ws
+}
+
+// Describes the world settings that differ between [ws] and [current] (e.g., 'WorldGenSeed (MyGame -> OtherGame)')
+func (ws WorldSettings) Diff(current WorldSettings) []string {
+	changes := []string{}
+	compare := func(name string, from string, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s (%s -> %s)", name, from, to))
+		}
+	}
+	compare("GameWorld", ws.GameWorld, current.GameWorld)
+	compare("WorldGenSeed", ws.WorldGenSeed, current.WorldGenSeed)
+	compare("WorldGenSize", ws.WorldGenSize, current.WorldGenSize)
+	return changes
+}
+
+// Gets the path of the file recording the world settings of a save game
+func getWorldSettingsFile(saveGame string) string {
+	return filepath.Join(saveGame, "entrypoint-world.json")
+}
+
+// Records the world settings of the save game of the server settings.
+// Returns an error if the save game doesn't exist or the file cannot be written.
+func RecordWorldSettings(ctx context.Context, settings ServerSettings) error {
+	gameName, _ := settings.Get("GameName")
+	saveGame := findSaveGame(ctx, gameName)
+	if saveGame == "" {
+		return fmt.Errorf("save game %s not found", gameName)
+	}
+	ws := GetWorldSettings(settings)
+	ws.RecordedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(ws, "", "  ")
+	if err != nil {
+		return err
+	}
+	helper.Logger(ctx).Info("record world settings", "save", saveGame, "world", ws.GameWorld, "seed", ws.WorldGenSeed, "size", ws.WorldGenSize)
+	return os.WriteFile(getWorldSettingsFile(saveGame), data, 0644)
+}
+
+// Compares the world settings against those recorded for the existing save game (see [RecordWorldSettings]).
+// Existing save games without recorded world settings (e.g., created before the world settings were recorded) adopt the current world settings.
+// When [allowChange] is set, changes are logged and recorded rather than refused.
+// Returns true if the save game doesn't exist yet (and its world settings should be recorded once the server has created it).
+// Returns an error wrapping [ErrWorldSettingsChanged] if the world settings differ from those recorded.
+// Returns an error if the recorded world settings cannot be read or written.
+func CheckWorldSettings(ctx context.Context, settings ServerSettings, allowChange bool) (bool, error) {
+	gameName, _ := settings.Get("GameName")
+	saveGame := findSaveGame(ctx, gameName)
+	if saveGame == "" {
+		return true, nil
+	}
+	recorded := WorldSettings{}
+	err := helper.UnmarshalFile(ctx, getWorldSettingsFile(saveGame), &recorded)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, RecordWorldSettings(ctx, settings)
+	}
+	if err != nil {
+		return false, err
+	}
+	changes := recorded.Diff(GetWorldSettings(settings))
+	if len(changes) == 0 {
+		return false, nil
+	}
+	if !allowChange {
+		return false, fmt.Errorf("%w for save game %s: %s - revert the settings, change GameName to start a new save game, or set ALLOW_WORLD_SETTINGS_CHANGE=true", ErrWorldSettingsChanged, gameName, strings.Join(changes, ", "))
+	}
+	helper.Logger(ctx).Warn("world settings changed for existing save game", "save", saveGame, "changes", strings.Join(changes, ", "))
+	return false, RecordWorldSettings(ctx, settings)
+}